@@ -2,15 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/amit/npc/internal/api"
+	"github.com/amit/npc/internal/challenge"
 	"github.com/amit/npc/internal/config"
 	"github.com/amit/npc/internal/game"
 	"github.com/amit/npc/internal/observability"
+	"github.com/amit/npc/internal/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -24,12 +36,61 @@ func main() {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	// Load configuration
-	cfg, err := config.Load("config.yaml")
-	if err != nil {
+	// Load configuration, merging any per-environment overrides listed in
+	// CONFIG_OVERRIDES (comma-separated, later files win)
+	configPaths := []string{"config.yaml"}
+	for _, path := range strings.Split(os.Getenv("CONFIG_OVERRIDES"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			configPaths = append(configPaths, path)
+		}
+	}
+
+	// `npc-server validate [files...]` checks a config without starting the
+	// server (default: the files the server would load)
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		if len(os.Args) > 2 {
+			configPaths = os.Args[2:]
+		}
+		os.Exit(runValidate(configPaths))
+	}
+
+	// Only a missing config.yaml falls back to the defaults: an override the
+	// operator asked for that can't be read is an error, not a silent reset
+	cfg, err := config.LoadWithOverrides(configPaths...)
+	switch {
+	case err == nil:
+	case len(configPaths) == 1 && errors.Is(err, fs.ErrNotExist):
 		log.Printf("Warning: Could not load config: %v, using defaults", err)
 		cfg = config.Default()
+	default:
+		log.Fatalf("Could not load config: %v", err)
+	}
+
+	// `npc-server selftest [ticks]` plays a short scripted match offline and
+	// reports pass/fail per subsystem, without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		ticks := 0
+		if len(os.Args) > 2 {
+			ticks, _ = strconv.Atoi(os.Args[2])
+		}
+		os.Exit(runSelftest(cfg, ticks))
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	for _, issue := range cfg.Check() {
+		if issue.Severity == config.SeverityWarning {
+			log.Printf("⚠️ Config: %s", issue)
+		}
+	}
+
+	// Replays and logs go through the configured storage backend
+	store, err := storage.New(cfg.Storage.Backend, cfg.Storage.Path)
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
 	}
+	api.GetAuditLog().SetStore(store)
+	api.GetAuditLog().SetIncludePrompts(cfg.Observability.IncludePrompts)
 
 	// Initialize observability
 	observer := observability.GetObserver()
@@ -37,34 +98,400 @@ func main() {
 		Enabled:   cfg.Observability.TraceEnabled,
 		TracePath: cfg.Observability.TracePath,
 		AuditPath: cfg.Observability.AuditPath,
+		Store:     store,
+
+		IncludePrompts:     cfg.Observability.IncludePrompts,
+		TraceFlushInterval: time.Duration(cfg.Observability.TraceFlushMs) * time.Millisecond,
 	}); err != nil {
 		log.Printf("Warning: Could not initialize observability: %v", err)
 	}
 	defer observer.Close()
 	log.Println("📊 Observability initialized")
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if hook := cfg.Observability.Webhook; hook.URL != "" {
+		notifier := observability.NewWebhookNotifier(observability.WebhookConfig{
+			URL:    hook.URL,
+			Secret: hook.Secret,
+			Events: hook.Events,
+		})
+		observer.Subscribe(notifier.Notify)
+		go notifier.Run(ctx)
+		log.Printf("🪝 Webhook notifications enabled → %s", hook.URL)
+	}
+
 	// Initialize game world with v2 features
 	world := game.NewWorld(cfg)
 	log.Printf("🎮 Game world initialized with %d NPCs in %d zones", len(world.NPCs), len(world.Zones.Zones))
+	world.Teams.SetLedgerHook(func(teamID, kind string, amount, balance int, reason string) {
+		observer.Audit(kind+"_change", "", teamID, map[string]interface{}{
+			"amount":  amount,
+			"balance": balance,
+			"reason":  reason,
+		})
+	})
+	if world.SafeMode {
+		log.Println("🧪 Safe mode: NPCs confined to the start zone, challenges and zone generation disabled")
+	}
+	if world.PracticeMode {
+		log.Println("🎓 Practice mode: free hints, failed attempts refunded, results non-competitive")
+	}
 	log.Printf("🔴 Team Red: %v", world.Teams.Teams["red"].Members)
 	log.Printf("🔵 Team Blue: %v", world.Teams.Teams["blue"].Members)
 
+	// Load prompt template overrides before any prompts are built
+	if err := api.LoadPromptTemplates(cfg.LLM.PromptDir); err != nil {
+		log.Fatalf("Invalid prompt templates: %v", err)
+	}
+	api.SetPromptRadii(api.PromptRadii{
+		Opponent: cfg.Game.OpponentRadius,
+		Teammate: cfg.Game.SocialRadius,
+		Gate:     cfg.Game.GateFocusRadius,
+	})
+
 	// Initialize API manager (handles multiple providers)
 	apiManager := api.NewManager(cfg)
 	log.Printf("🤖 API Manager ready - SLM: %s, Brain: %s",
 		apiManager.GetActiveSLM(), apiManager.GetActiveBrain())
+	// Until a provider answers, the preflight runs again every
+	// preflightRetry, so /readyz recovers once a provider comes up even with
+	// no game traffic to prove it
+	const preflightRetry = 30 * time.Second
+	if !cfg.LLM.SkipPreflight {
+		go func() {
+			log.Println("🧪 Preflight: testing providers...")
+			apiManager.TestProviders()
+			if ready, _ := apiManager.Readiness(); ready {
+				return
+			}
+			log.Printf("⚠️ Preflight: no provider answered; /readyz stays 503 until one does (retrying every %v)", preflightRetry)
+
+			ticker := time.NewTicker(preflightRetry)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				if ready, _ := apiManager.Readiness(); ready {
+					return // Game traffic got there first
+				}
+				apiManager.TestProviders()
+				if ready, _ := apiManager.Readiness(); ready {
+					log.Println("✅ Preflight: a provider answered; ready")
+					return
+				}
+			}
+		}()
+	}
 
 	// Initialize batch decision system (cost optimization)
-	batchSystem := api.NewBatchDecisionSystem(apiManager)
+	batchSystem := api.NewBatchDecisionSystem(apiManager, cfg)
 	log.Println("💰 Batch decision system ready (cost optimization enabled)")
 
 	// Initialize zone generator (Phase 3)
 	zoneGen := game.NewZoneGenerator()
+	zoneGen.SetRewardBounds(cfg.Game.GeneratedRewards)
 	zoneGen.SetLLMFunc(func(prompt string) (string, error) {
-		return apiManager.GetStrategy(prompt) // Use brain for generation
+		response, err := apiManager.GenerateContent(prompt) // Use brain for generation
+		if errors.Is(err, api.ErrNoBrain) {
+			return "", game.ErrLLMUnavailable
+		}
+		return response, err
 	})
 	log.Println("🌍 Zone generator initialized")
 
+	// Free-form challenges (spatial, etc.) are scored by the brain judge
+	world.Challenges.SetJudge(apiManager.JudgeChallenge)
+	world.Challenges.SetGenerator(apiManager.GenerateContent) // Regenerates stuck gates' challenges
+
+	// Match results include LLM usage from the observer
+	world.SetLLMStatsFunc(observer.CallsByProvider)
+
+	// Live stats channel: pushes at most one snapshot per second to /ws/stats
+	statsHub := observability.NewHub()
+	liveStats := observability.NewLiveStats(statsHub, time.Second, func() interface{} {
+		return fiber.Map{
+			"type":        "stats",
+			"llm_stats":   observer.GetStats(),
+			"game_stats":  world.GetTeamScores(),
+			"batch_stats": batchSystem.GetStats(),
+		}
+	})
+	observer.OnChange(liveStats.MarkDirty)
+	go liveStats.Run(ctx)
+
+	// Snapshots for replay are taken from the broadcast loop
+	replayManager := observability.NewReplayManager(cfg.Observability.ReplayEnabled, "logs/replay.json")
+	replayManager.SetStore(store)
+	if cfg.Observability.ReplayEnabled {
+		defer func() {
+			if err := replayManager.SaveToFile(); err != nil {
+				log.Printf("⚠️ Failed to save replay: %v", err)
+			} else {
+				log.Printf("🎞️ Replay saved (%d snapshots)", len(replayManager.GetSnapshots()))
+			}
+		}()
+	}
+
+	// Optional lossless event log: every decision and per-tick state change
+	var tickRecorder *game.TickRecorder
+	if cfg.Observability.TickLogEnabled {
+		tickLogPath := cfg.Observability.TickLogPath
+		if tickLogPath == "" {
+			tickLogPath = "logs/ticks.jsonl"
+		}
+		tickRecorder = game.NewTickRecorder(store, tickLogPath)
+		if err := tickRecorder.Start(world); err != nil {
+			log.Printf("⚠️ Tick log disabled: %v", err)
+			tickRecorder = nil
+		} else {
+			world.SetDecisionHook(tickRecorder.RecordDecision)
+			log.Printf("📼 Recording every tick to %s", tickLogPath)
+		}
+	}
+
+	// The world advances at game.tick_rate; NewWorld rescales its tick-based
+	// timings to match. Game clients receive state deltas game.broadcast_rate
+	// times a second, with a full state resync every fullSyncEvery broadcasts
+	// (~10s).
+	worldTickRate := cfg.Game.TickRate
+	if worldTickRate <= 0 {
+		worldTickRate = 60
+	}
+	broadcastRate := cfg.Game.BroadcastRate
+	if broadcastRate <= 0 || broadcastRate > worldTickRate {
+		broadcastRate = worldTickRate
+	}
+	broadcastEvery := worldTickRate / broadcastRate
+	fullSyncEvery := 10 * broadcastRate
+	gameHub := observability.NewHub()
+
+	// Auto-pause: while most recent LLM calls fail, decision requests get
+	// default decisions instead of hitting the providers
+	var decisionsPaused atomic.Bool
+	autoPause := cfg.LLM.AutoPause
+	guard := &observability.ErrorGuard{
+		PauseRate:  autoPause.ErrorRate,
+		ResumeRate: autoPause.ResumeRate,
+		Window:     time.Duration(autoPause.WindowSec) * time.Second,
+		MinCalls:   autoPause.MinCalls,
+	}
+	if guard.PauseRate <= 0 {
+		guard.PauseRate = 0.8
+	}
+	if guard.ResumeRate <= 0 {
+		guard.ResumeRate = guard.PauseRate / 2
+	}
+	if guard.Window <= 0 {
+		guard.Window = time.Minute
+	}
+	if guard.MinCalls <= 0 {
+		guard.MinCalls = 10
+	}
+
+	countdownWindow := time.Duration(cfg.Game.ChallengeCountdownSec) * time.Second
+	if countdownWindow <= 0 {
+		countdownWindow = 10 * time.Second
+	}
+
+	// Server-driven matches (game.server_driven): every decisionEvery ticks
+	// the batch system decides for all LLM-controlled NPCs, one batch at a
+	// time; the decisions are applied on the next tick
+	decisionEvery := 1
+	if rate := cfg.Game.DecisionRate; rate > 0 && rate < worldTickRate {
+		decisionEvery = worldTickRate / rate
+	}
+	var serverDeciding atomic.Bool
+	var awaitDecision func() bool // max_decisions_per_tick; set once the simulator runs
+	limiterReady := make(chan struct{})
+	rotation := 0 // Who asks first; only touched by the one running batch
+	requestServerDecisions := func(tick int) {
+		defer serverDeciding.Store(false)
+		// Off the tick goroutine, so the simulator's step recovery doesn't cover it
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("💥 Server-driven decisions panicked: %v\n%s", r, debug.Stack())
+			}
+		}()
+
+		// NPCs whose observation hasn't changed continue their last
+		// decision; NPCs past the per-tick decision limit get the default
+		<-limiterReady
+		// Round-robin: each batch starts one NPC further along, so the same
+		// NPCs aren't always the ones left waiting for the limit
+		var observations []map[string]interface{}
+		start := rotation
+		rotation++
+		for i := range world.NPCs {
+			npc := world.NPCs[(start+i)%len(world.NPCs)]
+			if world.IsHumanControlled(npc.Name) {
+				continue
+			}
+			obs := world.Observation(npc)
+			world.TrackObservation(npc.Name, obs)
+			world.AnnotateObservation(obs)
+			if decision, ok := world.ReuseDecision(npc.Name); ok {
+				world.EnqueueDecision(npc.Name, decision)
+				continue
+			}
+			if !awaitDecision() {
+				decision := api.DefaultDecision(obs)
+				decision["deferred"] = true
+				world.EnqueueDecision(npc.Name, decision)
+				continue
+			}
+			observations = append(observations, obs)
+		}
+		if len(observations) == 0 {
+			return
+		}
+
+		reqCtx := observability.WithRequestID(ctx, observability.NewRequestID())
+		result := batchSystem.GetBatchDecisions(reqCtx, observations)
+		if result.Error != nil {
+			log.Printf("⚠️ Server-driven batch decision error: %v", result.Error)
+		}
+		liveStats.MarkDirty()
+		for i, decision := range result.Decisions {
+			if decision != nil && i < len(observations) {
+				name, _ := observations[i]["name"].(string)
+				if result.Error == nil {
+					world.RememberDecision(name, decision)
+				}
+				world.EnqueueDecision(name, decision)
+			}
+		}
+		gameHub.Broadcast(fiber.Map{
+			"type":      "server_decisions",
+			"tick":      tick,
+			"decisions": result.Decisions,
+		})
+	}
+	if world.ServerDriven() {
+		log.Printf("🖥️ Server-driven: NPCs move server-side, decisions every %d ticks", decisionEvery)
+	}
+
+	lastSent := world.Tick
+	leader := world.Teams.Leader()
+	broadcasts := 0
+	simulator := world.RunLoop(ctx, worldTickRate, func(tick int) {
+		if world.ServerDriven() && tick%decisionEvery == 0 && !world.MatchOver && !decisionsPaused.Load() &&
+			serverDeciding.CompareAndSwap(false, true) {
+			go requestServerDecisions(tick)
+		}
+		if tickRecorder != nil {
+			if err := tickRecorder.RecordTick(world); err != nil {
+				log.Printf("⚠️ Tick log: %v", err)
+			}
+		}
+
+		if autoPause.Enabled {
+			if paused, changed, rate := guard.Check(observer); changed {
+				decisionsPaused.Store(paused)
+				message := fmt.Sprintf("LLM error rate %.0f%% - decisions paused, NPCs on autopilot", rate*100)
+				if paused {
+					log.Printf("🚨 %s", message)
+				} else {
+					message = "LLM errors subsided - decisions resumed"
+					log.Printf("✅ %s", message)
+				}
+				observer.Audit("degraded_mode", "", "", map[string]interface{}{
+					"paused":     paused,
+					"error_rate": rate,
+				})
+				gameHub.Broadcast(fiber.Map{
+					"type":       "degraded",
+					"paused":     paused,
+					"error_rate": rate,
+					"message":    message,
+				})
+			}
+		}
+
+		if replayManager.ShouldSnapshot() {
+			replayManager.CreateSnapshot(world.Tick, world.GetGameState())
+		}
+
+		if current := world.Teams.Leader(); current != "" && current != leader {
+			observer.Audit("lead_change", "", current, map[string]interface{}{
+				"previous": leader,
+				"scores":   world.GetTeamScores(),
+			})
+			leader = current
+		}
+
+		if gameHub.Len() == 0 {
+			return
+		}
+		if tick%worldTickRate == 0 {
+			if countdowns := world.Challenges.Countdowns(time.Now(), countdownWindow); len(countdowns) > 0 {
+				gameHub.Broadcast(fiber.Map{
+					"type":       "challenge_countdown",
+					"countdowns": countdowns, // gate ID -> seconds remaining
+				})
+			}
+		}
+		if tick%broadcastEvery != 0 {
+			return
+		}
+		// Overlap the previous broadcast by a tick: changes made between
+		// ticks carry the tick that was already broadcast
+		since := lastSent
+		lastSent = tick - 1
+		broadcasts++
+		if broadcasts%fullSyncEvery == 0 {
+			gameHub.Broadcast(fiber.Map{
+				"type":  "game_state",
+				"state": world.GetGameState(),
+			})
+			return
+		}
+
+		delta := world.Delta(since)
+		if len(delta) > 2 { // more than tick/since
+			delta["type"] = "state_delta"
+			gameHub.Broadcast(delta)
+		}
+	})
+
+	// Decisions past max_decisions_per_tick wait for a later tick; one that
+	// hasn't had its turn after decisionWait gets the default decision
+	const decisionWait = 5 * time.Second
+	if limit := cfg.Game.MaxDecisionsPerTick; limit > 0 {
+		simulator.SetDecisionLimit(limit)
+		log.Printf("🚦 At most %d decisions start per tick (%d/sec)", limit, limit*worldTickRate)
+	}
+
+	// The world idles while no game client is connected: the first to
+	// connect resumes it, the last to leave pauses it again
+	var watchersMu sync.Mutex
+	simulator.Pause()
+	log.Printf("⏱️ World runs at %d ticks/sec while clients are connected", worldTickRate)
+	awaitDecision = func() bool {
+		waitCtx, cancel := context.WithTimeout(ctx, decisionWait)
+		defer cancel()
+		return simulator.AwaitDecision(waitCtx)
+	}
+	close(limiterReady)
+
+	// Game client messages (see messages.go)
+	handler := &messageHandler{
+		ctx:             ctx,
+		world:           world,
+		apiManager:      apiManager,
+		batchSystem:     batchSystem,
+		zoneGen:         zoneGen,
+		observer:        observer,
+		gameHub:         gameHub,
+		liveStats:       liveStats,
+		decisionsPaused: &decisionsPaused,
+		awaitDecision:   awaitDecision,
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName: "NPC Arena v2",
@@ -89,14 +516,33 @@ func main() {
 		log.Println("WebSocket client connected")
 		observer.Audit("client_connected", "", "", nil)
 
+		watchersMu.Lock()
+		client := gameHub.Register(c)
+		simulator.Resume()
+		watchersMu.Unlock()
+		defer func() {
+			handler.releaseClient(client) // NPCs this player held go back to the LLM
+			watchersMu.Lock()
+			defer watchersMu.Unlock()
+			gameHub.Unregister(client)
+			if gameHub.Len() == 0 {
+				simulator.Pause()
+			}
+		}()
+
 		// Send initial game state
-		c.WriteJSON(fiber.Map{
+		teams, _ := world.Teams.Snapshot()
+		zones, gates := world.Zones.Snapshot()
+		client.WriteJSON(fiber.Map{
 			"type":  "init",
 			"slm":   apiManager.GetActiveSLM(),
 			"brain": apiManager.GetActiveBrain(),
-			"teams": world.Teams.Teams,
-			"zones": world.Zones.Zones,
-			"gates": world.Zones.Gates,
+			"teams": teams,
+			"zones": zones,
+			"gates": gates,
+
+			"action_schema": game.ActionSchemaVersion,
+			"actions":       game.ActionRegistry,
 		})
 
 		for {
@@ -106,258 +552,324 @@ func main() {
 				break
 			}
 
-			switch msg["type"] {
-			case "decision_request":
-				obs := msg["observation"].(map[string]interface{})
-				npcName := ""
-				if name, ok := obs["name"].(string); ok {
-					npcName = name
-				}
-
-				// Get AI decision using enhanced prompts (Phase 2)
-				decision, err := apiManager.GetEnhancedDecision(obs)
-				if err != nil {
-					log.Printf("Decision error for %s: %v", npcName, err)
-					decision = api.DefaultDecision(obs)
-				}
+			// A panic in one message (e.g. a missing field) gets the client an
+			// error reply; the connection stays open
+			handle := func() {
+				observability.HandleMessage(client, msg, func(msg map[string]interface{}) {
+					handler.handleMessage(client, msg)
+				})
+			}
+			if msg["type"] == "batch_decisions" {
+				// Its NPCs may wait ticks for a decision slot, then for the
+				// LLM: keep reading this client's messages meanwhile
+				go handle()
+				continue
+			}
+			handle()
+		}
 
-				// Send decision back
-				decision["type"] = "decision"
-				c.WriteJSON(decision)
-
-			case "batch_decisions":
-				// COST OPTIMIZATION: Get decisions for ALL NPCs in a single LLM call!
-				// This reduces API calls by ~75% (4 calls → 1 call)
-				observationsRaw, ok := msg["observations"].([]interface{})
-				if !ok {
-					log.Println("⚠️ batch_decisions: invalid observations format")
-					break
-				}
+		log.Println("WebSocket client disconnected")
+		observer.Audit("client_disconnected", "", "", nil)
+	}))
 
-				observations := make([]map[string]interface{}, 0, len(observationsRaw))
-				for _, obsRaw := range observationsRaw {
-					if obs, ok := obsRaw.(map[string]interface{}); ok {
-						observations = append(observations, obs)
-					}
-				}
+	// Live stats WebSocket for dashboards
+	app.Get("/ws/stats", websocket.New(func(c *websocket.Conn) {
+		client := statsHub.Register(c)
+		defer statsHub.Unregister(client)
 
-				if len(observations) == 0 {
-					break
-				}
+		client.WriteJSON(liveStats.Snapshot())
 
-				// Use batch system with context for cancellation support
-				ctx := context.Background()
-				result := batchSystem.GetBatchDecisions(ctx, observations)
+		// Drain reads until the client disconnects
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				break
+			}
+		}
+	}))
 
-				if result.Error != nil {
-					log.Printf("⚠️ Batch decision error: %v", result.Error)
-				}
+	// Health check with provider stats
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"status": "ok",
+			"slm":    apiManager.GetActiveSLM(),
+			"brain":  apiManager.GetActiveBrain(),
+			"stats":  apiManager.GetStats(),
+			"quota":  apiManager.GetQuotaUsage(),
+		})
+	})
 
-				// Send all decisions back
-				c.WriteJSON(fiber.Map{
-					"type":       "batch_decisions",
-					"decisions":  result.Decisions,
-					"from_cache": result.FromCache,
-				})
+	// Liveness: the process is up and serving. Never depends on providers,
+	// so an LLM outage doesn't get the pod restarted.
+	app.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
 
-			case "brain_request":
-				summary := msg["summary"].(string)
+	// Readiness: 503 until some provider's latest call (or the startup
+	// preflight) succeeded
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		ready, providers := apiManager.Readiness()
+		status := "ready"
+		if !ready {
+			c.Status(503)
+			status = "not_ready"
+		}
+		return c.JSON(fiber.Map{"status": status, "providers": providers})
+	})
 
-				// Get strategic advice from brain LLM
-				strategy, err := apiManager.GetStrategy(summary)
-				if err != nil {
-					log.Printf("Brain error: %v", err)
-					strategy = "Continue exploring systematically."
-				}
+	// Valid decision actions, from the same registry the prompts use
+	app.Get("/actions", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"version": game.ActionSchemaVersion,
+			"actions": game.ActionRegistry,
+		})
+	})
 
-				c.WriteJSON(fiber.Map{
-					"type":     "brain_strategy",
-					"strategy": strategy,
-				})
+	// Action histogram per NPC and team
+	app.Get("/stats/actions", func(c *fiber.Ctx) error {
+		return c.JSON(world.Actions.Snapshot())
+	})
 
-			case "challenge_start":
-				// NPC is attempting a challenge
-				gateID := msg["gate_id"].(string)
-				npcName := msg["npc"].(string)
-				npc := world.GetNPCByName(npcName)
-				if npc == nil {
-					break
-				}
+	// Per-NPC decision latency; NPCs over the p95 threshold are flagged slow
+	slowNPCMs := int64(cfg.Observability.SlowNPCP95Ms)
+	if slowNPCMs <= 0 {
+		slowNPCMs = 3000
+	}
+	app.Get("/npc/latency", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"threshold_p95_ms": slowNPCMs,
+			"npcs":             observer.NPCLatencies(slowNPCMs),
+		})
+	})
+	app.Get("/npc/:name/latency", func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if world.GetNPCByName(name) == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Unknown NPC"})
+		}
+		return c.JSON(observer.NPCLatency(name, slowNPCMs))
+	})
 
-				gate := world.Zones.Gates[gateID]
-				if gate == nil || gate.Unlocked {
-					break
-				}
+	// Match results for comparing runs; CSV via Accept: text/csv or ?format=csv
+	app.Get("/results", func(c *fiber.Ctx) error {
+		results := world.ExportResults()
+		if c.Query("format") == "csv" || (c.Query("format") == "" && c.Accepts("application/json", "text/csv") == "text/csv") {
+			data, err := results.CSV()
+			if err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			c.Set(fiber.HeaderContentType, "text/csv")
+			c.Set(fiber.HeaderContentDisposition, `attachment; filename="results.csv"`)
+			return c.Send(data)
+		}
+		return c.JSON(results)
+	})
 
-				active, _ := world.Challenges.StartChallenge(gateID, gate.ChallengeID, npcName, npc.Team)
-				if active != nil {
-					observer.AuditChallengeStart(npcName, npc.Team, gateID, string(active.Challenge.Type))
-					c.WriteJSON(fiber.Map{
-						"type":      "challenge_active",
-						"challenge": active.Challenge,
-						"status":    active.Status,
-						"gate_id":   gateID,
-					})
-				}
+	// Game state endpoint
+	app.Get("/state", func(c *fiber.Ctx) error {
+		return c.JSON(world.GetGameState())
+	})
 
-			case "challenge_response":
-				// NPC is submitting a challenge answer
-				gateID := msg["gate_id"].(string)
-				npcName := msg["npc"].(string)
-				response := msg["response"].(string)
+	// Teams and scores
+	app.Get("/teams", func(c *fiber.Ctx) error {
+		teams, progress := world.Teams.Snapshot()
+		return c.JSON(fiber.Map{
+			"teams":       teams,
+			"progress":    progress,
+			"leaderboard": world.Teams.GetLeaderboard(),
+		})
+	})
 
-				success, feedback := world.Challenges.SubmitResponse(gateID, npcName, response)
+	// Change a team's strategy at runtime: {"strategy": "aggressive|objective|balanced"}
+	app.Post("/teams/:id/strategy", func(c *fiber.Ctx) error {
+		var body struct {
+			Strategy string `json:"strategy"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid body"})
+		}
+		if err := world.Teams.SetStrategy(c.Params("id"), body.Strategy); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		team := world.Teams.GetTeam(c.Params("id"))
+		log.Printf("🎯 Team %s strategy → %s", team.ID, team.Strategy)
+		return c.JSON(team)
+	})
 
-				// Check if ready to evaluate
-				active := world.Challenges.GetActiveChallenge(gateID)
-				if active == nil {
-					break
-				}
+	// Live tuning: replace a bottleneck gate's challenge with a fresh
+	// LLM-generated one at {"difficulty": 1-5} (default: one easier than now)
+	app.Post("/gate/:id/regenerate", func(c *fiber.Ctx) error {
+		var body struct {
+			Difficulty int `json:"difficulty"`
+		}
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&body); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "Invalid body"})
+			}
+		}
 
-				needsEval := !active.Challenge.RequiresTeamwork || len(active.Responses) >= 2
-				if needsEval && success {
-					result := world.Challenges.EvaluateChallenge(gateID)
-					if result != nil {
-						npc := world.GetNPCByName(npcName)
-						if npc != nil {
-							observer.AuditChallengeComplete(npcName, npc.Team, gateID, result.Success, result.TokensEarned)
-
-							if result.Success {
-								world.Zones.UnlockGate(gateID, npc.Team)
-								world.Teams.RecordChallengeSolved(npc.Team, result.TokensEarned)
-								observer.AuditZoneUnlock(npc.Team, world.Zones.Gates[gateID].ToZone, npcName)
-							} else {
-								world.Teams.RecordChallengeFailed(npc.Team)
-							}
-						}
-
-						c.WriteJSON(fiber.Map{
-							"type":     "challenge_result",
-							"gate_id":  gateID,
-							"success":  result.Success,
-							"feedback": result.Feedback,
-							"tokens":   result.TokensEarned,
-							"teams":    world.Teams.Teams,
-						})
-					}
-				} else {
-					c.WriteJSON(fiber.Map{
-						"type":     "challenge_waiting",
-						"gate_id":  gateID,
-						"feedback": feedback,
-					})
-				}
+		gateID := c.Params("id")
+		gate := world.Zones.GetGate(gateID)
+		if gate == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Unknown gate"})
+		}
+		if gate.Unlocked {
+			return c.Status(409).JSON(fiber.Map{"error": "Gate is already unlocked"})
+		}
+		previous := world.Challenges.GetChallenge(gate.ChallengeID)
+		if body.Difficulty == 0 && previous != nil {
+			body.Difficulty = previous.Difficulty - 1
+		}
 
-			case "team_message":
-				// NPC sending message to teammate
-				fromNPC := msg["from"].(string)
-				message := msg["message"].(string)
-				npc := world.GetNPCByName(fromNPC)
-				if npc != nil {
-					teammate := world.Teams.GetTeammate(fromNPC)
-					world.SendMessage(fromNPC, teammate, message)
-					observer.AuditTeamMessage(fromNPC, npc.Team, message)
-
-					c.WriteJSON(fiber.Map{
-						"type":    "message_sent",
-						"from":    fromNPC,
-						"to":      teammate,
-						"message": message,
-					})
-				}
+		fresh, err := world.Challenges.RegenerateChallenge(gateID, body.Difficulty)
+		switch {
+		case errors.Is(err, challenge.ErrAttemptInProgress):
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, challenge.ErrNoGenerator), errors.Is(err, api.ErrNoBrain):
+			return c.Status(503).JSON(fiber.Map{"error": err.Error()})
+		case err != nil:
+			return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+		}
 
-			case "get_commentary":
-				// Client requesting live commentary
-				events := []map[string]interface{}{}
-				if evts, ok := msg["events"].([]interface{}); ok {
-					for _, e := range evts {
-						if em, ok := e.(map[string]interface{}); ok {
-							events = append(events, em)
-						}
-					}
-				}
-				scores := world.GetTeamScores()
+		data := map[string]interface{}{
+			"gate_id":      gateID,
+			"challenge_id": fresh.ID,
+			"type":         string(fresh.Type),
+			"difficulty":   fresh.Difficulty,
+		}
+		if previous != nil {
+			data["previous"] = previous.ID
+		}
+		observer.Audit("challenge_regenerated", "", "", data)
+		log.Printf("🔁 Gate %s now guarded by %q (%s, difficulty %d)", gateID, fresh.Name, fresh.Type, fresh.Difficulty)
+		return c.JSON(fiber.Map{"gate": gate, "challenge": fresh})
+	})
 
-				commentary, err := apiManager.GetCommentary(events, scores)
-				if err != nil {
-					commentary = "The game continues..."
-				}
+	// Debug endpoints for live-demo recovery, enabled by server.debug
+	debug := app.Group("/debug", func(c *fiber.Ctx) error {
+		if !cfg.Server.Debug {
+			return c.Status(403).JSON(fiber.Map{"error": "debug endpoints are disabled (server.debug: false)"})
+		}
+		return c.Next()
+	})
 
-				c.WriteJSON(fiber.Map{
-					"type":       "commentary",
-					"commentary": commentary,
-				})
+	// Referee: force-complete a stuck challenge with the given outcome
+	debug.Post("/challenge/:gate/resolve", func(c *fiber.Ctx) error {
+		var body struct {
+			Success bool `json:"success"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid body"})
+		}
 
-			case "check_zone_generation":
-				// Check if we should generate a new zone
-				trigger := zoneGen.CheckTriggers(world)
-				if trigger.ShouldGenerate {
-					generated, err := zoneGen.GenerateZone(world, trigger)
-					if err != nil {
-						log.Printf("Zone generation failed: %v", err)
-					} else {
-						zoneGen.ApplyGeneratedZone(world, generated)
-						observer.Audit("zone_generated", "", "", map[string]interface{}{
-							"zone_id":   generated.Zone.ID,
-							"zone_name": generated.Zone.Name,
-							"trigger":   trigger.Reason,
-						})
-
-						c.WriteJSON(fiber.Map{
-							"type":  "zone_generated",
-							"zone":  generated.Zone,
-							"gate":  generated.Gate,
-							"zones": world.Zones.Zones,
-							"gates": world.Zones.Gates,
-						})
-					}
-				}
+		gateID := c.Params("gate")
+		active := world.Challenges.GetActiveChallenge(gateID)
+		result, err := world.Challenges.ForceResolve(gateID, body.Success)
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
 
-			case "get_state":
-				// Client requesting current game state
-				c.WriteJSON(fiber.Map{
-					"type":  "game_state",
-					"state": world.GetGameState(),
-				})
+		teamID := active.TeamID
+		if result.Success {
+			if gate := world.Zones.GetGate(gateID); gate != nil {
+				world.Zones.UnlockGate(gateID, teamID)
+				observer.AuditZoneUnlock(teamID, gate.ToZone, "referee")
 			}
+			world.Teams.RecordChallengeSolved(teamID, result.TokensEarned)
+		} else {
+			world.Teams.RecordChallengeFailed(teamID, 0)
 		}
+		observer.Audit("referee_override", "", teamID, map[string]interface{}{
+			"gate_id":      gateID,
+			"success":      result.Success,
+			"participants": active.Participants,
+			"tokens":       result.TokensEarned,
+		})
+		log.Printf("🧑‍⚖️ Referee resolved %s for %s: success=%v", gateID, teamID, result.Success)
+
+		gameHub.Broadcast(fiber.Map{
+			"type":     "challenge_result",
+			"gate_id":  gateID,
+			"success":  result.Success,
+			"feedback": result.Feedback,
+			"tokens":   result.TokensEarned,
+			"referee":  true,
+			"teams":    teamsSnapshot(world),
+		})
+		return c.JSON(result)
+	})
 
-		log.Println("WebSocket client disconnected")
-		observer.Audit("client_disconnected", "", "", nil)
-	}))
-
-	// Health check with provider stats
-	app.Get("/health", func(c *fiber.Ctx) error {
+	// Model comparison: the same observation decided by several providers,
+	// side by side. Body: {"observation": {...}, "providers": ["groq", ...]}
+	debug.Post("/compare", func(c *fiber.Ctx) error {
+		var body struct {
+			Observation map[string]interface{} `json:"observation"`
+			Providers   []string               `json:"providers"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.Observation == nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Body needs an observation object"})
+		}
+		if len(body.Providers) == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "List at least one provider"})
+		}
+		log.Printf("⚖️ Comparing %d providers on %v's observation", len(body.Providers), body.Observation["name"])
 		return c.JSON(fiber.Map{
-			"status": "ok",
-			"slm":    apiManager.GetActiveSLM(),
-			"brain":  apiManager.GetActiveBrain(),
-			"stats":  apiManager.GetStats(),
+			"observation": body.Observation,
+			"results":     apiManager.CompareProviders(body.Observation, body.Providers),
 		})
 	})
 
-	// Game state endpoint
-	app.Get("/state", func(c *fiber.Ctx) error {
-		return c.JSON(world.GetGameState())
+	// Observability stats
+	app.Get("/stats", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"llm_stats":      observer.GetStats(),
+			"game_stats":     world.GetTeamScores(),
+			"batch_stats":    batchSystem.GetStats(), // Cost optimization metrics
+			"simulation":     simulator.Stats(),      // Target vs actual tick rate
+			"decision_skips": world.DecisionSkips(),  // LLM calls saved by reusing decisions for unchanged observations
+			"degraded":       decisionsPaused.Load(), // Decisions auto-paused on errors
+			"rate_limiter":   apiManager.RateLimiterStats(),
+			"llm_usage":      llmUsage(apiManager), // Tokens and cost per provider, as reported by the providers
+			"recent_traces":  observer.GetRecentTraces(10),
+			"recent_events":  observer.GetRecentAudits(20),
+		})
 	})
 
-	// Teams and scores
-	app.Get("/teams", func(c *fiber.Ctx) error {
+	// Everything a status page needs in one request; the endpoints above stay
+	// for granular use
+	app.Get("/dashboard", func(c *fiber.Ctx) error {
+		calls, cost := observer.CallsByProvider()
 		return c.JSON(fiber.Map{
-			"teams":       world.Teams.Teams,
-			"progress":    world.Teams.Progress,
+			"match":       world.MatchClock(),
+			"scores":      world.GetTeamScores(),
 			"leaderboard": world.Teams.GetLeaderboard(),
+			"providers": fiber.Map{
+				"slm":      apiManager.GetActiveSLM(),
+				"brain":    apiManager.GetActiveBrain(),
+				"latency":  observer.ProviderLatencies(),
+				"quota":    apiManager.GetQuotaUsage(),
+				"degraded": decisionsPaused.Load(),
+			},
+			"usage": fiber.Map{
+				"llm":               observer.GetStats(),
+				"calls_by_provider": calls,
+				"total_cost_usd":    cost,
+				"batch":             batchSystem.GetStats(), // Cache hit rate and batching savings
+			},
+			"active_challenges": world.Challenges.ActiveSnapshot(),
+			"recent_events":     observer.GetRecentAudits(20),
 		})
 	})
 
-	// Observability stats
-	app.Get("/stats", func(c *fiber.Ctx) error {
+	// LLM call traces; ?request_id= returns everything one client request triggered
+	app.Get("/traces", func(c *fiber.Ctx) error {
+		requestID := c.Query("request_id")
+		if requestID == "" {
+			return c.JSON(fiber.Map{"traces": observer.GetRecentTraces(50)})
+		}
+		traces, audits := observer.GetRequestEntries(requestID)
 		return c.JSON(fiber.Map{
-			"llm_stats":     observer.GetStats(),
-			"game_stats":    world.GetTeamScores(),
-			"batch_stats":   batchSystem.GetStats(), // Cost optimization metrics
-			"recent_traces": observer.GetRecentTraces(10),
-			"recent_events": observer.GetRecentAudits(20),
+			"request_id": requestID,
+			"traces":     traces,
+			"audits":     audits,
 		})
 	})
 
@@ -369,17 +881,53 @@ func main() {
 		return c.JSON(results)
 	})
 
-	// Legacy audit log endpoint
+	// Audit log: LLM calls ("entries") and game events ("events"), filtered by
+	// ?team= &npc= &status= &provider= &event= &since= &until= &limit= &history=true
 	app.Get("/audit", func(c *fiber.Ctx) error {
+		since, err := parseAuditTime(c.Query("since"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid since: " + err.Error()})
+		}
+		until, err := parseAuditTime(c.Query("until"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid until: " + err.Error()})
+		}
+
+		team := c.Query("team")
+		var members []string
+		if t := world.Teams.GetTeam(team); t != nil {
+			members = t.Members
+		}
+		history := c.QueryBool("history")
+		limit := c.QueryInt("limit", 50)
+
 		auditLog := api.GetAuditLog()
 		return c.JSON(fiber.Map{
-			"entries": auditLog.GetEntries(50),
-			"stats":   auditLog.GetStats(),
+			"entries": auditLog.GetEntriesFiltered(api.AuditFilter{
+				Team:        team,
+				TeamMembers: members,
+				NPC:         c.Query("npc"),
+				Status:      c.Query("status"),
+				Provider:    c.Query("provider"),
+				Since:       since,
+				Until:       until,
+				Limit:       limit,
+				IncludeDisk: history,
+			}),
+			"events": observer.GetAuditsFiltered(observability.AuditFilter{
+				Team:        team,
+				NPC:         c.Query("npc"),
+				Event:       c.Query("event"),
+				Since:       since,
+				Until:       until,
+				Limit:       limit,
+				IncludeDisk: history,
+			}),
+			"stats": auditLog.GetStats(),
 		})
 	})
 
 	// Replay endpoints (Phase 4)
-	replayManager := observability.NewReplayManager(cfg.Observability.ReplayEnabled, "./logs/replay.json")
 
 	app.Get("/replay/timeline", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -399,8 +947,44 @@ func main() {
 		return c.JSON(snapshot)
 	})
 
-	// Create snapshots periodically in WebSocket handler (already done via world.GetGameState())
-	// The replayManager will be used to store snapshots during gameplay
+	// Replay playback: re-broadcasts recorded snapshots to game clients at their
+	// original pace, scaled by ?speed= (0 starts paused)
+	app.Post("/replay/play", func(c *fiber.Ctx) error {
+		if replayManager.Playing() {
+			return c.Status(409).JSON(fiber.Map{"error": observability.ErrReplayActive.Error()})
+		}
+		speed, err := strconv.ParseFloat(c.Query("speed", "1"), 64)
+		if err != nil || speed < 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid speed"})
+		}
+
+		go func() {
+			if err := replayManager.Play(ctx, gameHub, speed); err != nil {
+				log.Printf("📼 Replay stopped: %v", err)
+			}
+		}()
+		log.Printf("📼 Replay started at %.1fx", speed)
+		return c.JSON(fiber.Map{"playing": true, "speed": speed})
+	})
+
+	app.Post("/replay/pause", func(c *fiber.Ctx) error {
+		replayManager.SetSpeed(0)
+		return c.JSON(fiber.Map{"playing": replayManager.Playing(), "speed": 0})
+	})
+
+	app.Post("/replay/resume", func(c *fiber.Ctx) error {
+		speed, err := strconv.ParseFloat(c.Query("speed", "1"), 64)
+		if err != nil || speed <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid speed"})
+		}
+		replayManager.SetSpeed(speed)
+		return c.JSON(fiber.Map{"playing": replayManager.Playing(), "speed": speed})
+	})
+
+	app.Post("/replay/stop", func(c *fiber.Ctx) error {
+		replayManager.Stop()
+		return c.JSON(fiber.Map{"playing": false})
+	})
 
 	// Find available port
 	port := findAvailablePort()
@@ -410,7 +994,19 @@ func main() {
 	log.Printf("📊 Stats dashboard: http://localhost:%s/stats", port)
 	log.Printf("🧪 Test providers: http://localhost:%s/test", port)
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	log.Fatal(app.Listen(":" + port))
+
+	// Shut down cleanly on Ctrl-C / SIGTERM so deferred cleanup (buffered
+	// traces, replay saves) runs
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		log.Println("👋 Shutting down...")
+		app.Shutdown()
+	}()
+	if err := app.Listen(":" + port); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // findAvailablePort checks preferred port from env, then tries a range of ports
@@ -444,3 +1040,27 @@ func isPortAvailable(port string) bool {
 	ln.Close()
 	return true
 }
+
+// parseAuditTime accepts an RFC 3339 time or a duration meaning "that long ago" (e.g. 5m)
+func parseAuditTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// teamsSnapshot copies the teams for a message, so encoding it doesn't race
+// with score changes
+func teamsSnapshot(world *game.World) map[string]*game.Team {
+	teams, _ := world.Teams.Snapshot()
+	return teams
+}
+
+// llmUsage reports each provider's tokens and cost, and the total cost
+func llmUsage(m *api.Manager) fiber.Map {
+	providers, cost := m.Usage()
+	return fiber.Map{"providers": providers, "total_cost_usd": cost}
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amit/npc/internal/api"
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/observability"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Commentary streams for at most commentaryWait
+const commentaryWait = 30 * time.Second
+
+// messageHandler answers the messages game clients send over /ws
+type messageHandler struct {
+	ctx             context.Context // Server lifetime; commentary streams end with it
+	world           *game.World
+	apiManager      *api.Manager
+	batchSystem     *api.BatchDecisionSystem
+	zoneGen         *game.ZoneGenerator
+	observer        *observability.Observer
+	gameHub         *observability.Hub
+	liveStats       *observability.LiveStats
+	decisionsPaused *atomic.Bool // Auto-pause: default decisions instead of LLM calls
+	awaitDecision   func() bool  // Waits for a max_decisions_per_tick slot
+
+	controlMu   sync.Mutex
+	controllers map[string]*observability.HubClient // NPC name -> client playing it (manual_decision)
+}
+
+// handleMessage answers one message from a game client. A malformed message
+// may panic, so the /ws handler runs it through observability.HandleMessage.
+func (h *messageHandler) handleMessage(client *observability.HubClient, msg map[string]interface{}) {
+	switch msg["type"] {
+	case "decision_request":
+		if h.world.MatchOver {
+			break // No more LLM spend once the match is decided
+		}
+		obs := msg["observation"].(map[string]interface{})
+		npcName := ""
+		if name, ok := obs["name"].(string); ok {
+			npcName = name
+		}
+		h.world.SyncFromObservation(obs)
+		if h.world.IsHumanControlled(npcName) {
+			break // A player decides for this NPC (manual_decision)
+		}
+		h.world.AnnotateObservation(obs)
+		requestID := observability.NewRequestID()
+		reqCtx := observability.WithRequestID(context.Background(), requestID)
+
+		// Get AI decision using enhanced prompts (Phase 2), unless
+		// nothing has changed since the last one
+		decision, reused := h.world.ReuseDecision(npcName)
+		switch {
+		case reused:
+			// Continue the previous action
+		case h.decisionsPaused.Load():
+			decision = api.DefaultDecision(obs)
+			decision["degraded"] = true
+		case !h.awaitDecision():
+			decision = api.DefaultDecision(obs)
+			decision["deferred"] = true
+		default:
+			var err error
+			if decision, err = h.apiManager.GetEnhancedDecision(reqCtx, obs); err != nil {
+				log.Printf("Decision error for %s: %v", npcName, err)
+				decision = api.DefaultDecision(obs)
+			} else {
+				h.world.RememberDecision(npcName, decision)
+			}
+		}
+
+		decision = h.world.ApplyDecision(npcName, decision).Decision
+
+		// Send decision back
+		decision["type"] = "decision"
+		decision["request_id"] = requestID
+		client.WriteJSON(decision)
+
+	case "batch_decisions":
+		if h.world.MatchOver {
+			break
+		}
+		// COST OPTIMIZATION: Get decisions for ALL NPCs in a single LLM call!
+		// This reduces API calls by ~75% (4 calls → 1 call)
+		observationsRaw, ok := msg["observations"].([]interface{})
+		if !ok {
+			log.Println("⚠️ batch_decisions: invalid observations format")
+			break
+		}
+
+		// NPCs whose observation hasn't changed continue their last
+		// decision and stay out of the LLM call, as do NPCs still
+		// waiting their turn (max_decisions_per_tick); their
+		// decisions go out after the LLM's
+		observations := make([]map[string]interface{}, 0, len(observationsRaw))
+		var skipped []map[string]interface{}
+		var skippedBy []string
+		for _, obsRaw := range observationsRaw {
+			if obs, ok := obsRaw.(map[string]interface{}); ok {
+				h.world.SyncFromObservation(obs)
+				name, _ := obs["name"].(string)
+				if h.world.IsHumanControlled(name) {
+					continue // Decided by a player via manual_decision
+				}
+				h.world.AnnotateObservation(obs)
+				if decision, ok := h.world.ReuseDecision(name); ok {
+					skipped = append(skipped, h.world.ApplyDecision(name, decision).Decision)
+					skippedBy = append(skippedBy, name)
+					continue
+				}
+				observations = append(observations, obs)
+			}
+		}
+
+		if !h.decisionsPaused.Load() {
+			admitted := 0
+			for admitted < len(observations) && h.awaitDecision() {
+				admitted++
+			}
+			for _, obs := range observations[admitted:] {
+				name, _ := obs["name"].(string)
+				decision := api.DefaultDecision(obs)
+				decision["deferred"] = true
+				skipped = append(skipped, h.world.ApplyDecision(name, decision).Decision)
+				skippedBy = append(skippedBy, name)
+			}
+			observations = observations[:admitted]
+		}
+
+		if len(observations) == 0 {
+			if len(skipped) > 0 {
+				client.WriteJSON(fiber.Map{
+					"type":      "batch_decisions",
+					"decisions": skipped,
+				})
+			}
+			break
+		}
+
+		if h.decisionsPaused.Load() {
+			decisions := make([]map[string]interface{}, len(observations))
+			for i, obs := range observations {
+				name, _ := obs["name"].(string)
+				decisions[i] = h.world.ApplyDecision(name, api.DefaultDecision(obs)).Decision
+			}
+			client.WriteJSON(fiber.Map{
+				"type":      "batch_decisions",
+				"decisions": append(decisions, skipped...),
+				"degraded":  true,
+			})
+			break
+		}
+
+		// Use batch system with context for cancellation support;
+		// the request ID ties its traces and audits together
+		requestID := observability.NewRequestID()
+		reqCtx := observability.WithRequestID(context.Background(), requestID)
+
+		// {"stream": true}: send each decision as its chunk completes
+		if stream, _ := msg["stream"].(bool); stream {
+			for i, decision := range skipped {
+				client.WriteJSON(fiber.Map{
+					"type":       "batch_decision",
+					"request_id": requestID,
+					"index":      len(observations) + i, // After the LLM's
+					"npc":        skippedBy[i],
+					"decision":   decision,
+				})
+			}
+			for res := range h.batchSystem.GetBatchDecisionsStream(reqCtx, observations) {
+				applied := h.world.ApplyDecision(res.NPC, res.Decision).Decision
+				h.world.RememberDecision(res.NPC, res.Decision)
+				client.WriteJSON(fiber.Map{
+					"type":       "batch_decision",
+					"request_id": requestID,
+					"index":      res.Index,
+					"npc":        res.NPC,
+					"decision":   applied,
+					"from_cache": res.FromCache,
+				})
+			}
+			h.liveStats.MarkDirty()
+			client.WriteJSON(fiber.Map{
+				"type":       "batch_complete",
+				"request_id": requestID,
+			})
+			break
+		}
+
+		result := h.batchSystem.GetBatchDecisions(reqCtx, observations)
+
+		if result.Error != nil {
+			log.Printf("⚠️ Batch decision error: %v", result.Error)
+		}
+		h.liveStats.MarkDirty()
+
+		for i, decision := range result.Decisions {
+			if decision != nil && i < len(observations) {
+				name, _ := observations[i]["name"].(string)
+				result.Decisions[i] = h.world.ApplyDecision(name, decision).Decision
+				if result.Error == nil {
+					h.world.RememberDecision(name, decision)
+				}
+			}
+		}
+
+		// Send all decisions back, reused and deferred ones last
+		client.WriteJSON(fiber.Map{
+			"type":       "batch_decisions",
+			"request_id": requestID,
+			"decisions":  append(result.Decisions, skipped...),
+			"from_cache": append(result.FromCache, make([]bool, len(skipped))...),
+		})
+
+	case "brain_request":
+		summary := msg["summary"].(string)
+		team, _ := msg["team"].(string)
+
+		// Get strategic advice from brain LLM (stale = reused while the brain is failing)
+		strategy, stale, err := h.apiManager.GetStrategy(team, summary)
+		if err != nil {
+			log.Printf("Brain error: %v", err)
+		}
+
+		client.WriteJSON(fiber.Map{
+			"type":     "brain_strategy",
+			"team":     team,
+			"strategy": strategy,
+			"stale":    stale,
+		})
+
+	case "challenge_start":
+		// NPC is attempting a challenge
+		if h.world.SafeMode {
+			client.WriteJSON(fiber.Map{
+				"type":  "error",
+				"error": "challenges are disabled in safe mode",
+			})
+			break
+		}
+		gateID := msg["gate_id"].(string)
+		npcName := msg["npc"].(string)
+		npc := h.world.GetNPCByName(npcName)
+		if npc == nil {
+			break
+		}
+
+		gate := h.world.Zones.GetGate(gateID)
+		if gate == nil || gate.Unlocked {
+			break
+		}
+
+		active, err := h.world.Challenges.StartChallenge(gateID, gate.ChallengeID, npcName, npc.Team)
+		if err != nil {
+			client.WriteJSON(fiber.Map{
+				"type":    "error",
+				"error":   err.Error(),
+				"gate_id": gateID,
+			})
+			break
+		}
+		if active != nil {
+			h.observer.AuditChallengeStart(npcName, npc.Team, gateID, string(active.Challenge.Type))
+			view := h.world.Challenges.SolverView(gateID, npcName) // No solution or other NPCs' clues
+			client.WriteJSON(fiber.Map{
+				"type":      "challenge_active",
+				"challenge": view, // Options in the shown order for this attempt
+				"status":    active.Status,
+				"gate_id":   gateID,
+				"private":   view["private"], // Only this NPC's clue or role
+
+				"seconds_remaining": active.SecondsRemaining(),
+			})
+		}
+
+	case "challenge_response":
+		// NPC is submitting a challenge answer. Without a "response"
+		// the NPC's LLM solves the challenge (see llm.challenge_solver).
+		if h.world.SafeMode {
+			break
+		}
+		gateID := msg["gate_id"].(string)
+		npcName := msg["npc"].(string)
+		response, given := msg["response"].(string)
+		if !given {
+			view := h.world.Challenges.SolverView(gateID, npcName)
+			npc := h.world.GetNPCByName(npcName)
+			if view == nil || npc == nil {
+				break
+			}
+			solved, err := h.apiManager.SolveChallenge(npcName, view, h.world.ChallengeContext(npc))
+			if err != nil {
+				client.WriteJSON(fiber.Map{
+					"type":    "error",
+					"error":   "solving challenge: " + err.Error(),
+					"gate_id": gateID,
+				})
+				break
+			}
+			response = solved
+		}
+
+		success, feedback := h.world.Challenges.SubmitResponse(gateID, npcName, response)
+
+		// Check if ready to evaluate. EvaluateChallenge only returns a
+		// result once, so concurrent teammates can't double-unlock.
+		if h.world.Challenges.GetActiveChallenge(gateID) == nil {
+			break
+		}
+
+		if success && h.world.Challenges.ReadyToEvaluate(gateID) {
+			result := h.world.Challenges.EvaluateChallenge(gateID)
+			if result != nil {
+				npc := h.world.GetNPCByName(npcName)
+				if npc != nil {
+					h.observer.AuditChallengeComplete(npcName, npc.Team, gateID, result.Success, result.TokensEarned)
+
+					if result.Success {
+						// Mystery objects run challenges keyed by object ID, which unlock nothing
+						if gate := h.world.Zones.GetGate(gateID); gate != nil {
+							h.world.Zones.UnlockGate(gateID, npc.Team)
+							h.observer.AuditZoneUnlock(npc.Team, gate.ToZone, npcName)
+						}
+						h.world.Teams.RecordChallengeSolved(npc.Team, result.TokensEarned)
+					} else if !result.Refunded {
+						h.world.Teams.RecordChallengeFailed(npc.Team, result.TokensEarned)
+					}
+				}
+
+				client.WriteJSON(fiber.Map{
+					"type":           "challenge_result",
+					"gate_id":        gateID,
+					"success":        result.Success,
+					"feedback":       result.Feedback,
+					"tokens":         result.TokensEarned,
+					"partial_credit": result.PartialCredit,
+					"winner":         result.Winner,
+					"contested":      result.Contested,
+					"refunded":       result.Refunded,
+					"teams":          teamsSnapshot(h.world),
+				})
+			}
+		} else {
+			client.WriteJSON(fiber.Map{
+				"type":     "challenge_waiting",
+				"gate_id":  gateID,
+				"feedback": feedback,
+			})
+		}
+
+	case "interact":
+		// NPC using a world object (treasure, resource, mystery, landmark)
+		npcName, _ := msg["npc"].(string)
+		objectID, _ := msg["object_id"].(string)
+		result, err := h.world.ApplyInteract(npcName, objectID)
+		if err != nil {
+			client.WriteJSON(fiber.Map{
+				"type":      "error",
+				"error":     err.Error(),
+				"object_id": objectID,
+			})
+			break
+		}
+		log.Printf("🎁 %s", result["message"])
+		client.WriteJSON(result)
+
+	case "manual_decision":
+		// A player deciding for an NPC: {npc, action, target, message, ...}.
+		// The NPC stays human-controlled until release_control or until this
+		// client disconnects.
+		npcName, _ := msg["npc"].(string)
+		decision := make(map[string]interface{}, len(msg))
+		for k, v := range msg {
+			if k != "type" && k != "npc" {
+				decision[k] = v
+			}
+		}
+		result, err := h.world.ApplyManualDecision(npcName, decision)
+		if err != nil {
+			client.WriteJSON(fiber.Map{
+				"type":  "error",
+				"error": err.Error(),
+				"npc":   npcName,
+			})
+			break
+		}
+		h.takeControl(client, npcName)
+		npc := h.world.GetNPCByName(npcName)
+		h.observer.Audit("manual_decision", npcName, npc.Team, map[string]interface{}{
+			"action":   result.Action,
+			"feedback": result.Feedback,
+		})
+
+		applied := result.Decision
+		applied["type"] = "decision"
+		applied["npc_id"] = npc.ID
+		applied["manual"] = true
+		client.WriteJSON(applied)
+
+	case "release_control":
+		// Hand a human-controlled NPC back to the LLM
+		npcName, _ := msg["npc"].(string)
+		if err := h.world.SetHumanControlled(npcName, false); err != nil {
+			client.WriteJSON(fiber.Map{
+				"type":  "error",
+				"error": err.Error(),
+				"npc":   npcName,
+			})
+			break
+		}
+		h.controlMu.Lock()
+		delete(h.controllers, npcName)
+		h.controlMu.Unlock()
+
+	case "team_message":
+		// NPC sending message to teammate
+		fromNPC := msg["from"].(string)
+		message := msg["message"].(string)
+		npc := h.world.GetNPCByName(fromNPC)
+		if npc != nil {
+			teammate := h.world.Teams.GetTeammate(fromNPC)
+			h.world.SendMessage(fromNPC, teammate, message)
+			h.observer.AuditTeamMessage(fromNPC, npc.Team, message)
+
+			client.WriteJSON(fiber.Map{
+				"type":    "message_sent",
+				"from":    fromNPC,
+				"to":      teammate,
+				"message": message,
+			})
+		}
+
+	case "get_commentary":
+		// Client requesting live commentary
+		events := []map[string]interface{}{}
+		if evts, ok := msg["events"].([]interface{}); ok {
+			for _, e := range evts {
+				if em, ok := e.(map[string]interface{}); ok {
+					events = append(events, em)
+				}
+			}
+		}
+		scores := h.world.GetTeamScores()
+
+		// Forward the commentary as it's written, then the
+		// whole of it; a client that's gone stops the stream
+		streamCtx, cancel := context.WithTimeout(h.ctx, commentaryWait)
+		commentary, err := h.apiManager.StreamCommentary(streamCtx, events, scores, func(chunk string) {
+			if client.WriteJSON(fiber.Map{"type": "commentary_chunk", "text": chunk}) != nil {
+				cancel()
+			}
+		})
+		cancel()
+		if err != nil {
+			commentary = "The game continues..."
+		}
+
+		client.WriteJSON(fiber.Map{
+			"type":       "commentary_done",
+			"commentary": commentary,
+		})
+
+	case "check_zone_generation":
+		// Check if we should generate a new zone
+		trigger := h.zoneGen.CheckTriggers(h.world)
+		if trigger.ShouldGenerate {
+			generated, err := h.zoneGen.GenerateZone(h.world, trigger)
+			if errors.Is(err, game.ErrLLMUnavailable) {
+				log.Printf("🌍 Zone generation skipped (%s): no capable LLM, pausing triggers", trigger.Reason)
+			} else if err != nil {
+				log.Printf("Zone generation failed: %v", err)
+			} else {
+				h.zoneGen.ApplyGeneratedZone(h.world, generated)
+				h.observer.Audit("zone_generated", "", "", map[string]interface{}{
+					"zone_id":   generated.Zone.ID,
+					"zone_name": generated.Zone.Name,
+					"trigger":   trigger.Reason,
+				})
+
+				zones, gates := h.world.Zones.Snapshot()
+				client.WriteJSON(fiber.Map{
+					"type":  "zone_generated",
+					"zone":  generated.Zone,
+					"gate":  generated.Gate,
+					"zones": zones,
+					"gates": gates,
+				})
+			}
+		}
+
+	case "concede":
+		// Team forfeits the match (e.g. tournament orchestrator ending a lopsided game)
+		teamID, _ := msg["team"].(string)
+		result, err := h.world.Concede(teamID)
+		if err != nil {
+			log.Printf("⚠️ concede rejected: %v", err)
+			client.WriteJSON(fiber.Map{
+				"type":  "error",
+				"error": err.Error(),
+			})
+			break
+		}
+
+		log.Printf("🏳️ Team %s conceded, %s wins", teamID, result["winner"])
+		h.observer.Audit("match_over", "", teamID, result)
+
+		// Recap for the match_over message and /results
+		summary, err := h.apiManager.GenerateMatchSummary(h.world.ExportResults(), h.observer.KeyEvents(h.world.StartedAt, 40))
+		if err != nil {
+			log.Printf("⚠️ Match summary: %v (using template)", err)
+		}
+		h.world.SetMatchSummary(summary)
+		result["summary"] = summary
+		h.gameHub.Broadcast(result)
+
+	case "reset":
+		// Client restarted the match: clear per-match analytics
+		h.world.Actions.Reset()
+		log.Println("🔄 Match reset by client")
+
+	case "get_state":
+		// Client requesting current game state
+		client.WriteJSON(fiber.Map{
+			"type":  "game_state",
+			"state": h.world.GetGameState(),
+		})
+	}
+}
+
+// takeControl records that client is playing npcName, so the NPC goes back
+// to the LLM when that client disconnects
+func (h *messageHandler) takeControl(client *observability.HubClient, npcName string) {
+	h.controlMu.Lock()
+	defer h.controlMu.Unlock()
+	if h.controllers == nil {
+		h.controllers = make(map[string]*observability.HubClient)
+	}
+	h.controllers[npcName] = client
+}
+
+// releaseClient hands every NPC client was playing back to the LLM; the
+// /ws handler calls it when the connection closes
+func (h *messageHandler) releaseClient(client *observability.HubClient) {
+	h.controlMu.Lock()
+	var released []string
+	for npcName, controller := range h.controllers {
+		if controller == client {
+			released = append(released, npcName)
+			delete(h.controllers, npcName)
+		}
+	}
+	h.controlMu.Unlock()
+
+	for _, npcName := range released {
+		if err := h.world.SetHumanControlled(npcName, false); err != nil {
+			log.Printf("⚠️ Releasing %s: %v", npcName, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/observability"
+)
+
+// recordingConn stands in for a WebSocket connection, keeping what was sent
+// as the client would decode it
+type recordingConn struct {
+	sent []map[string]interface{}
+}
+
+func (c *recordingConn) WriteJSON(v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return err
+	}
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func TestHandleMessage_MalformedMessageGetsErrorReply(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	h := &messageHandler{world: game.NewWorld(config.Default())}
+	conn := &recordingConn{}
+	client := observability.NewHub().Register(conn)
+
+	// As the /ws handler does for each frame it reads
+	send := func(frame string) {
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(frame), &msg); err != nil {
+			t.Fatal(err)
+		}
+		observability.HandleMessage(client, msg, func(msg map[string]interface{}) {
+			h.handleMessage(client, msg)
+		})
+	}
+
+	send(`{"type": "decision_request", "observation": "not an object"}`)
+	if len(conn.sent) != 1 || conn.sent[0]["type"] != "error" {
+		t.Fatalf("malformed decision_request sent %v, want one error reply", conn.sent)
+	}
+
+	send(`{"type": "get_state"}`)
+	if len(conn.sent) != 2 || conn.sent[1]["type"] != "game_state" {
+		t.Errorf("get_state after the malformed message sent %v, want a game_state reply", conn.sent[1:])
+	}
+}
+
+func TestHandleMessage_ChallengeActiveHidesAnswers(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	cfg := config.Default()
+	cfg.Game.ChallengePools = map[string][]string{"gate_1_2": {"challenge_split_clue"}}
+	h := &messageHandler{world: game.NewWorld(cfg), observer: &observability.Observer{}}
+	conn := &recordingConn{}
+	client := observability.NewHub().Register(conn)
+
+	h.handleMessage(client, map[string]interface{}{"type": "challenge_start", "gate_id": "gate_1_2", "npc": "Explorer"})
+	if len(conn.sent) != 1 || conn.sent[0]["type"] != "challenge_active" {
+		t.Fatalf("challenge_start sent %v, want challenge_active", conn.sent)
+	}
+	challenge, _ := conn.sent[0]["challenge"].(map[string]interface{})
+	if challenge["name"] != "The Split Key" || challenge["solution"] != nil || challenge["participant_prompts"] != nil {
+		t.Errorf("challenge_active challenge = %v, want the solver's view", challenge)
+	}
+	if private, _ := conn.sent[0]["private"].(string); !strings.Contains(private, "begins 7 3") {
+		t.Errorf("private = %q, want Explorer's clue half", private)
+	}
+
+	state, err := json.Marshal(h.world.GetGameState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"7319", "begins 7 3", "ends 1 9"} {
+		if strings.Contains(string(state), secret) {
+			t.Errorf("game state broadcast leaks %q", secret)
+		}
+	}
+}
+
+func TestHandleMessage_ManualControlEndsWithTheClient(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	h := &messageHandler{world: game.NewWorld(config.Default()), observer: &observability.Observer{}}
+	hub := observability.NewHub()
+	player, other := &recordingConn{}, &recordingConn{}
+	playerClient, otherClient := hub.Register(player), hub.Register(other)
+
+	h.handleMessage(playerClient, map[string]interface{}{"type": "manual_decision", "npc": "Explorer", "action": "fly"})
+	if h.world.IsHumanControlled("Explorer") || player.sent[0]["type"] != "error" {
+		t.Fatalf("invalid manual decision: human controlled = %v, reply %v", h.world.IsHumanControlled("Explorer"), player.sent)
+	}
+
+	h.handleMessage(playerClient, map[string]interface{}{"type": "manual_decision", "npc": "Explorer", "action": "idle"})
+	if !h.world.IsHumanControlled("Explorer") {
+		t.Fatal("valid manual decision didn't take control")
+	}
+
+	h.releaseClient(otherClient) // Someone else leaving changes nothing
+	if !h.world.IsHumanControlled("Explorer") {
+		t.Error("another client's disconnect released Explorer")
+	}
+	h.releaseClient(playerClient)
+	if h.world.IsHumanControlled("Explorer") {
+		t.Error("Explorer still human-controlled after its player disconnected")
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/amit/npc/internal/api"
+	"github.com/amit/npc/internal/config"
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/observability"
+)
+
+// selftestTicks is how long the scripted match runs by default
+const selftestTicks = 20
+
+// selftestZone is the demo world builder's answer to a zone generation prompt
+const selftestZone = `{
+  "zone": {"name": "Selftest Vault", "theme": "crystal", "description": "A scripted zone.",
+           "x": 900, "y": 100, "width": 200, "height": 200, "rewards": 40},
+  "challenges": [{"type": "coordination", "name": "Vault Signal", "prompt": "Pick the same signal as your teammate",
+                  "options": ["LEFT", "RIGHT"], "difficulty": 2, "requires_teamwork": true, "token_reward": 30}],
+  "gate": {"from_zone": "zone_2", "position": [880, 200]}
+}`
+
+// selftestCheck is one subsystem's line in the selftest report
+type selftestCheck struct {
+	Subsystem string `json:"subsystem"`
+	Passed    bool   `json:"passed"`
+	Detail    string `json:"detail"`
+}
+
+// selftestReport is the machine-readable output of the selftest subcommand
+type selftestReport struct {
+	Passed bool            `json:"passed"`
+	Checks []selftestCheck `json:"checks"`
+}
+
+// runSelftest plays a short scripted match offline - demo mode, no API keys
+// - through the same game, challenge, api and observability code the server
+// uses: NPCs spawn, decide and tick, a team solves a challenge and unlocks
+// its gate, a zone is generated and the match ends with results and a recap.
+// Prints a pass/fail report per subsystem as JSON and returns the exit code.
+func runSelftest(cfg *config.Config, ticks int) int {
+	if ticks <= 0 {
+		ticks = selftestTicks
+	}
+
+	// Demo mode: no providers, and nothing that would keep subsystems off
+	cfg.SLMProviders, cfg.BrainProviders, cfg.NPCProviders = nil, nil, nil
+	cfg.Game.SafeMode = false
+	cfg.Game.ChallengePools = nil
+
+	observer := observability.GetObserver()
+	observer.Initialize(observability.ObserverConfig{Enabled: true}) // In memory only
+
+	world := game.NewWorld(cfg)
+	apiManager := api.NewManager(cfg)
+	world.Challenges.SetJudge(apiManager.JudgeChallenge)
+
+	report := selftestReport{Passed: true}
+	check := func(subsystem string, fn func() (string, error)) {
+		detail, err := fn()
+		c := selftestCheck{Subsystem: subsystem, Passed: err == nil, Detail: detail}
+		if err != nil {
+			c.Detail = err.Error()
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, c)
+	}
+
+	check("world", func() (string, error) {
+		if len(world.NPCs) == 0 || len(world.Zones.Zones) == 0 || len(world.Zones.Gates) == 0 {
+			return "", fmt.Errorf("%d NPCs, %d zones, %d gates", len(world.NPCs), len(world.Zones.Zones), len(world.Zones.Gates))
+		}
+		for _, npc := range world.NPCs {
+			if _, ok := world.Teams.Teams[npc.Team]; !ok {
+				return "", fmt.Errorf("%s is on unknown team %q", npc.Name, npc.Team)
+			}
+		}
+		return fmt.Sprintf("%d NPCs, %d zones, %d gates", len(world.NPCs), len(world.Zones.Zones), len(world.Zones.Gates)), nil
+	})
+
+	check("decisions", func() (string, error) {
+		for _, npc := range world.NPCs {
+			obs := world.Observation(npc)
+			world.SyncFromObservation(obs)
+			world.AnnotateObservation(obs)
+			decision, err := apiManager.GetEnhancedDecision(context.Background(), obs)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", npc.Name, err)
+			}
+			result := world.ApplyDecision(npc.Name, decision)
+			if _, ok := game.LookupAction(result.Action); !ok {
+				return "", fmt.Errorf("%s decided unknown action %q", npc.Name, result.Action)
+			}
+		}
+		return fmt.Sprintf("%d NPCs decided", len(world.NPCs)), nil
+	})
+
+	check("simulation", func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var stepped atomic.Int64
+		simulator := game.NewSimulator(100, func() {
+			if stepped.Load() < int64(ticks) {
+				world.Advance()
+				stepped.Add(1)
+			}
+		})
+		done := make(chan struct{})
+		go func() {
+			simulator.Run(ctx)
+			close(done)
+		}()
+		for stepped.Load() < int64(ticks) && ctx.Err() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		<-done
+
+		if n := stepped.Load(); n < int64(ticks) {
+			return "", fmt.Errorf("only %d of %d ticks ran", n, ticks)
+		}
+		if stats := simulator.Stats(); stats.Panics > 0 {
+			return "", fmt.Errorf("%d ticks panicked", stats.Panics)
+		}
+		return fmt.Sprintf("%d ticks", ticks), nil
+	})
+
+	check("challenge", func() (string, error) {
+		gate := world.Zones.Gates["gate_1_2"]
+		if gate == nil {
+			return "", errors.New("no gate_1_2 out of the start zone")
+		}
+		team := world.Teams.Teams[world.NPCs[0].Team]
+		active, err := world.Challenges.StartChallenge(gate.ID, gate.ChallengeID, team.Members[0], team.ID)
+		if err != nil {
+			return "", err
+		}
+		// Everyone gives the same answer: the solution, or the first option
+		answer := active.Challenge.Solution
+		if answer == "" && len(active.Options) > 0 {
+			answer = active.Options[0]
+		}
+		for _, member := range team.Members[:min(len(team.Members), active.RequiredParticipants())] {
+			world.Challenges.SubmitResponse(gate.ID, member, answer)
+		}
+		if !world.Challenges.ReadyToEvaluate(gate.ID) {
+			return "", errors.New("not ready to evaluate after every participant answered")
+		}
+		result := world.Challenges.EvaluateChallenge(gate.ID)
+		if result == nil || !result.Success {
+			return "", fmt.Errorf("%s failed: %+v", active.Challenge.ID, result)
+		}
+		observer.AuditChallengeComplete(team.Members[0], team.ID, gate.ID, true, result.TokensEarned)
+		world.Teams.RecordChallengeSolved(team.ID, result.TokensEarned)
+		return fmt.Sprintf("%s solved %s for %d tokens", team.Name, active.Challenge.ID, result.TokensEarned), nil
+	})
+
+	check("gate", func() (string, error) {
+		gate := world.Zones.Gates["gate_1_2"]
+		team := world.NPCs[0].Team
+		world.Zones.UnlockGate(gate.ID, team)
+		observer.AuditZoneUnlock(team, gate.ToZone, world.NPCs[0].Name)
+		if !gate.Unlocked || !world.Zones.Zones[gate.ToZone].Unlocked {
+			return "", fmt.Errorf("%s or %s still locked", gate.ID, gate.ToZone)
+		}
+		return fmt.Sprintf("%s opened %s", gate.ID, gate.ToZone), nil
+	})
+
+	check("zone_generation", func() (string, error) {
+		zoneGen := game.NewZoneGenerator()
+		zoneGen.SetRewardBounds(cfg.Game.GeneratedRewards)
+		zoneGen.SetLLMFunc(func(string) (string, error) { return selftestZone, nil })
+		generated, err := zoneGen.GenerateZone(world, game.TriggerResult{ShouldGenerate: true, Reason: "selftest"})
+		if err != nil {
+			return "", err
+		}
+		zoneGen.ApplyGeneratedZone(world, generated)
+		observer.Audit("zone_generated", "", "", map[string]interface{}{
+			"zone_id":   generated.Zone.ID,
+			"zone_name": generated.Zone.Name,
+		})
+
+		for id, gate := range world.Zones.Gates {
+			if gate.ToZone != generated.Zone.ID {
+				continue
+			}
+			if world.Challenges.GetChallenge(gate.ChallengeID) == nil {
+				return "", fmt.Errorf("%s's challenge %s isn't registered", id, gate.ChallengeID)
+			}
+			if world.Challenges.PreviewChallenge(id) == nil {
+				return "", fmt.Errorf("%s has no challenge preview", id)
+			}
+			return fmt.Sprintf("%s behind %s (%s)", generated.Zone.ID, id, gate.ChallengeID), nil
+		}
+		return "", fmt.Errorf("no gate leads to %s", generated.Zone.ID)
+	})
+
+	check("results", func() (string, error) {
+		loser := world.NPCs[len(world.NPCs)-1].Team
+		over, err := world.Concede(loser)
+		if err != nil {
+			return "", err
+		}
+		observer.Audit("match_over", "", loser, over)
+		results := world.ExportResults()
+		if !results.MatchOver || results.Winner == "" || len(results.Teams) == 0 {
+			return "", fmt.Errorf("incomplete results: %+v", results)
+		}
+		if _, err := results.CSV(); err != nil {
+			return "", fmt.Errorf("CSV: %w", err)
+		}
+		return fmt.Sprintf("%s wins after %d ticks", results.Winner, results.Ticks), nil
+	})
+
+	check("observability", func() (string, error) {
+		events := observer.KeyEvents(world.StartedAt, 40)
+		seen := make(map[string]bool)
+		for _, e := range events {
+			seen[e.Event] = true
+		}
+		for _, want := range []string{"challenge_complete", "zone_unlocked", "zone_generated", "match_over"} {
+			if !seen[want] {
+				return "", fmt.Errorf("no %s among %d key events", want, len(events))
+			}
+		}
+		summary, err := apiManager.GenerateMatchSummary(world.ExportResults(), events)
+		if err != nil || summary == "" {
+			return "", fmt.Errorf("match summary %q: %v", summary, err)
+		}
+		return fmt.Sprintf("%d key events; recap: %s", len(events), summary), nil
+	})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/amit/npc/internal/config"
+)
+
+// validateReport is the machine-readable output of the validate subcommand
+type validateReport struct {
+	Valid  bool           `json:"valid"`
+	Files  []string       `json:"files"`
+	Issues []config.Issue `json:"issues"`
+}
+
+// runValidate loads and merges the config files (expanding env vars, as the
+// server does), prints every issue as JSON and returns the exit code: 1 when
+// the server would refuse the config, 0 otherwise
+func runValidate(paths []string) int {
+	report := validateReport{Files: paths, Issues: []config.Issue{}}
+
+	cfg, err := config.LoadWithOverrides(paths...)
+	if err != nil {
+		report.Issues = append(report.Issues, config.Issue{
+			Severity: config.SeverityError,
+			Message:  err.Error(),
+		})
+	} else {
+		report.Issues = append(report.Issues, cfg.Check()...)
+	}
+	report.Valid = len(config.Errors(report.Issues)) == 0
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
@@ -1,14 +1,21 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/amit/npc/internal/observability"
+	"github.com/amit/npc/internal/storage"
 )
 
+// auditTimeFormat is the layout of AuditEntry.Timestamp (local time)
+const auditTimeFormat = "2006-01-02 15:04:05.000"
+
 // AuditEntry represents a single API call audit log entry
 type AuditEntry struct {
 	Timestamp string `json:"timestamp"`
@@ -17,6 +24,11 @@ type AuditEntry struct {
 	Model     string `json:"model"`
 	Prompt    string `json:"prompt"`
 	Response  string `json:"response"`
+
+	// Identify the full prompt even when its text isn't stored
+	PromptHash string `json:"prompt_hash,omitempty"`
+	PromptLen  int    `json:"prompt_len,omitempty"`
+
 	LatencyMs int64  `json:"latency_ms"`
 	Status    string `json:"status"` // "success" or "error"
 	Error     string `json:"error,omitempty"`
@@ -26,26 +38,42 @@ type AuditEntry struct {
 type AuditLog struct {
 	entries    []AuditEntry
 	maxEntries int
-	logFile    string
-	mu         sync.Mutex
+	logFile    string // Store key
+	store      storage.Store
+
+	includePrompts bool // false keeps only PromptHash/PromptLen
+	mu             sync.Mutex
 }
 
 var globalAuditLog *AuditLog
 
 // InitAuditLog initializes the global audit log
 func InitAuditLog() *AuditLog {
-	// Ensure logs directory exists
-	logsDir := "logs"
-	os.MkdirAll(logsDir, 0755)
-
 	globalAuditLog = &AuditLog{
 		entries:    make([]AuditEntry, 0),
 		maxEntries: 100, // Keep last 100 entries in memory
-		logFile:    filepath.Join(logsDir, "audit.log"),
+		logFile:    "logs/audit.log",
+		store:      storage.NewFileStore(""),
+
+		includePrompts: true,
 	}
 	return globalAuditLog
 }
 
+// SetStore replaces the filesystem store the log file is written to
+func (a *AuditLog) SetStore(store storage.Store) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.store = store
+}
+
+// SetIncludePrompts chooses between storing prompt text and only its hash and length
+func (a *AuditLog) SetIncludePrompts(include bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.includePrompts = include
+}
+
 // GetAuditLog returns the global audit log
 func GetAuditLog() *AuditLog {
 	if globalAuditLog == nil {
@@ -61,7 +89,14 @@ func (a *AuditLog) Log(entry AuditEntry) {
 
 	// Set timestamp if not set
 	if entry.Timestamp == "" {
-		entry.Timestamp = time.Now().Format("2006-01-02 15:04:05.000")
+		entry.Timestamp = time.Now().Format(auditTimeFormat)
+	}
+
+	if entry.Prompt != "" {
+		entry.PromptHash, entry.PromptLen = observability.PromptDigest(entry.Prompt), len(entry.Prompt)
+	}
+	if !a.includePrompts {
+		entry.Prompt = ""
 	}
 
 	// Truncate long prompts/responses for memory storage
@@ -104,6 +139,15 @@ func (a *AuditLog) LogError(npc, provider, model, prompt string, latencyMs int64
 	})
 }
 
+// LogWarning logs a provider-level warning that isn't tied to a single call
+func (a *AuditLog) LogWarning(provider, message string) {
+	a.Log(AuditEntry{
+		Provider: provider,
+		Status:   "warning",
+		Error:    message,
+	})
+}
+
 // GetEntries returns recent audit entries
 func (a *AuditLog) GetEntries(limit int) []AuditEntry {
 	a.mu.Lock()
@@ -121,6 +165,105 @@ func (a *AuditLog) GetEntries(limit int) []AuditEntry {
 	return result
 }
 
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Team        string   // Matches batch_<team> calls and TeamMembers
+	TeamMembers []string // NPC names on Team (the audit log doesn't know teams)
+	NPC         string
+	Status      string // success, error, warning
+	Provider    string
+	Since       time.Time
+	Until       time.Time
+	Limit       int  // Default 50
+	IncludeDisk bool // Also scan the log file for entries older than the buffer
+}
+
+// Matches reports whether an entry passes the filter
+func (f AuditFilter) Matches(e AuditEntry) bool {
+	if f.NPC != "" && e.NPC != f.NPC {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if f.Provider != "" && e.Provider != f.Provider {
+		return false
+	}
+	if f.Team != "" && e.NPC != "batch_"+f.Team && !containsString(f.TeamMembers, e.NPC) {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		ts, err := time.ParseInLocation(auditTimeFormat, e.Timestamp, time.Local)
+		if err != nil {
+			return false
+		}
+		if !f.Since.IsZero() && ts.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && ts.After(f.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetEntriesFiltered returns matching entries, most recent first. With
+// IncludeDisk, entries that have left the in-memory buffer are read from the
+// log file.
+func (a *AuditLog) GetEntriesFiltered(filter AuditFilter) []AuditEntry {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	a.mu.Lock()
+	source := make([]AuditEntry, len(a.entries))
+	copy(source, a.entries)
+	store := a.store
+	a.mu.Unlock()
+
+	if filter.IncludeDisk {
+		source = readCallLog(store, a.logFile)
+	}
+
+	result := []AuditEntry{}
+	for i := len(source) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		if filter.Matches(source[i]) {
+			result = append(result, source[i])
+		}
+	}
+	return result
+}
+
+// readCallLog loads every call entry from the log file. The file is shared with
+// the observer's game events, so lines without a timestamp/status are skipped.
+func readCallLog(store storage.Store, key string) []AuditEntry {
+	data, err := store.Get(key)
+	if err != nil {
+		return nil
+	}
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Timestamp == "" || e.Status == "" {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetStats returns summary statistics
 func (a *AuditLog) GetStats() map[string]interface{} {
 	a.mu.Lock()
@@ -161,15 +304,9 @@ func (a *AuditLog) GetStats() map[string]interface{} {
 
 // writeToFile appends an entry to the log file
 func (a *AuditLog) writeToFile(entry AuditEntry) {
-	f, err := os.OpenFile(a.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return
-	}
-	defer f.Close()
-
 	// Write as JSON line
 	jsonData, _ := json.Marshal(entry)
-	f.WriteString(string(jsonData) + "\n")
+	storage.Append(a.store, a.logFile, append(jsonData, '\n'))
 }
 
 func truncateStr(s string, maxLen int) string {
@@ -2,15 +2,16 @@ package api
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"log"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/amit/npc/internal/config"
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/observability"
 )
 
 // BatchDecisionSystem handles multi-NPC decisions in a single LLM call
@@ -21,11 +22,34 @@ type BatchDecisionSystem struct {
 	promptBuilder *PromptBuilder
 	mu            sync.RWMutex
 
+	// Reuse each NPC's last LLM decision when the batch call fails
+	staleFallback bool
+	lastDecisions map[string]map[string]interface{} // NPC name -> newest LLM decision
+
+	// Adaptive chunking: NPCs per LLM call, tuned by recent call latency
+	chunkSize   int
+	minChunk    int
+	maxChunk    int
+	fastLatency time.Duration
+	slowLatency time.Duration
+	avgLatency  time.Duration // Exponential moving average of batch calls
+
+	// Providers that keep returning unparseable batch JSON get per-NPC
+	// decisions instead for a cooldown after parseFailureLimit in a row
+	parseFailureLimit int
+	disableCooldown   time.Duration
+	parseFailures     map[string]int       // Consecutive failures per provider
+	batchDisabled     map[string]time.Time // Provider -> batching re-enabled at
+	now               func() time.Time
+
 	// Statistics
 	batchCalls     int
 	cachHits       int
 	fallbackUsed   int
+	staleServed    int
 	totalDecisions int
+	parseFailed    int // Batch responses with no usable JSON
+	perNPCDecided  int // Decisions made per-NPC while batching was disabled
 }
 
 // DecisionCache stores recent decisions to avoid redundant API calls
@@ -41,15 +65,56 @@ type CachedDecision struct {
 	Decision  map[string]interface{}
 	CreatedAt time.Time
 	HitCount  int
+	Stale     bool // Entry was past its TTL when returned
 }
 
 // NewBatchDecisionSystem creates a new batch decision system
-func NewBatchDecisionSystem(manager *Manager) *BatchDecisionSystem {
-	return &BatchDecisionSystem{
+func NewBatchDecisionSystem(manager *Manager, cfg *config.Config) *BatchDecisionSystem {
+	cacheSize, cacheTTL := cfg.Cache.MaxSize, time.Duration(cfg.Cache.TTLSeconds)*time.Second
+	if cacheSize <= 0 {
+		cacheSize = 100
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = 10 * time.Second
+	}
+
+	bds := &BatchDecisionSystem{
 		manager:       manager,
-		cache:         NewDecisionCache(100, 10*time.Second),
-		promptBuilder: &PromptBuilder{},
+		cache:         NewDecisionCache(cacheSize, cacheTTL),
+		promptBuilder: promptBuilder,
+		staleFallback: cfg.Batch.TimeoutFallback != "explore",
+		lastDecisions: make(map[string]map[string]interface{}),
+		minChunk:      cfg.Batch.MinChunk,
+		maxChunk:      cfg.Batch.MaxChunk,
+		fastLatency:   time.Duration(cfg.Batch.FastMs) * time.Millisecond,
+		slowLatency:   time.Duration(cfg.Batch.SlowMs) * time.Millisecond,
+
+		parseFailureLimit: cfg.Batch.ParseFailureLimit,
+		disableCooldown:   time.Duration(cfg.Batch.DisableCooldownSec) * time.Second,
+		parseFailures:     make(map[string]int),
+		batchDisabled:     make(map[string]time.Time),
+		now:               time.Now,
+	}
+	if bds.parseFailureLimit <= 0 {
+		bds.parseFailureLimit = 3
+	}
+	if bds.disableCooldown <= 0 {
+		bds.disableCooldown = 5 * time.Minute
+	}
+	if bds.minChunk <= 0 {
+		bds.minChunk = 2
+	}
+	if bds.maxChunk < bds.minChunk {
+		bds.maxChunk = max(bds.minChunk, 8)
+	}
+	if bds.fastLatency <= 0 {
+		bds.fastLatency = 2 * time.Second
 	}
+	if bds.slowLatency <= bds.fastLatency {
+		bds.slowLatency = 3 * bds.fastLatency
+	}
+	bds.chunkSize = bds.maxChunk
+	return bds
 }
 
 // NewDecisionCache creates a cache for NPC decisions
@@ -61,6 +126,13 @@ func NewDecisionCache(maxSize int, ttl time.Duration) *DecisionCache {
 	}
 }
 
+// Len returns the number of cached decisions, including expired ones not yet evicted
+func (c *DecisionCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
 // BatchDecisionRequest represents a request for multiple NPC decisions
 type BatchDecisionRequest struct {
 	Observations []map[string]interface{}
@@ -74,6 +146,14 @@ type BatchDecisionResponse struct {
 	Error     error
 }
 
+// DecisionResult is one NPC's decision delivered by GetBatchDecisionsStream
+type DecisionResult struct {
+	Index     int                    `json:"index"` // Position in the observations slice
+	NPC       string                 `json:"npc"`
+	Decision  map[string]interface{} `json:"decision"`
+	FromCache bool                   `json:"from_cache"`
+}
+
 // GetBatchDecisions gets decisions for ALL NPCs in a single optimized call
 // Auto-configures prompt based on number of NPCs - no manual changes needed!
 func (bds *BatchDecisionSystem) GetBatchDecisions(ctx context.Context, observations []map[string]interface{}) *BatchDecisionResponse {
@@ -81,57 +161,134 @@ func (bds *BatchDecisionSystem) GetBatchDecisions(ctx context.Context, observati
 		return &BatchDecisionResponse{Error: fmt.Errorf("no observations provided")}
 	}
 
-	bds.mu.Lock()
-	bds.totalDecisions += len(observations)
-	bds.mu.Unlock()
-
 	// Check context before proceeding
 	if ctx.Err() != nil {
 		return &BatchDecisionResponse{Error: ctx.Err()}
 	}
 
-	// Phase 1: Check cache for each NPC
 	response := &BatchDecisionResponse{
 		Decisions: make([]map[string]interface{}, len(observations)),
 		FromCache: make([]bool, len(observations)),
 	}
+	for result := range bds.GetBatchDecisionsStream(ctx, observations) {
+		response.Decisions[result.Index] = result.Decision
+		response.FromCache[result.Index] = result.FromCache
+	}
 
-	var uncachedIndices []int
-	var uncachedObs []map[string]interface{}
+	if ctx.Err() != nil {
+		return &BatchDecisionResponse{Error: ctx.Err()}
+	}
+	return response
+}
 
+// GetBatchDecisionsStream delivers decisions as they become available: cached
+// decisions immediately, then each chunk's decisions as soon as its LLM call
+// finishes. Chunks run concurrently, so a slow chunk doesn't hold up the rest.
+// The channel is closed once every NPC has a decision.
+func (bds *BatchDecisionSystem) GetBatchDecisionsStream(ctx context.Context, observations []map[string]interface{}) <-chan DecisionResult {
+	out := make(chan DecisionResult, len(observations)) // Never blocks a sender
+	if len(observations) == 0 {
+		close(out)
+		return out
+	}
+
+	bds.mu.Lock()
+	bds.totalDecisions += len(observations)
+	bds.mu.Unlock()
+
+	// Phase 1: Check cache for each NPC
+	var uncachedIndices []int
 	for i, obs := range observations {
 		hash := bds.hashObservation(obs)
 		if cached, ok := bds.cache.Get(hash); ok {
-			response.Decisions[i] = cached.Decision
-			response.FromCache[i] = true
+			out <- DecisionResult{Index: i, NPC: getString(obs, "name"), Decision: cached.Decision, FromCache: true}
 			bds.mu.Lock()
 			bds.cachHits++
 			bds.mu.Unlock()
 			log.Printf("📦 Cache hit for %s", getString(obs, "name"))
 		} else {
 			uncachedIndices = append(uncachedIndices, i)
-			uncachedObs = append(uncachedObs, obs)
 		}
 	}
 
-	// If all cached, return immediately
-	if len(uncachedObs) == 0 {
-		return response
+	// If all cached, we're done
+	if len(uncachedIndices) == 0 {
+		bds.auditBatch(ctx, observations, len(observations), "", false)
+		close(out)
+		return out
 	}
 
-	// Check context again before API call
+	// Phase 2: Order uncached NPCs stably, then split them into chunks sized
+	// by recent latency, each decided on its own goroutine
+	uncachedObs := orderByNPCName(uncachedIndices, observations)
+	chunkSize := bds.currentChunkSize()
+
+	go func() {
+		defer close(out)
+
+		var wg sync.WaitGroup
+		var resultMu sync.Mutex
+		providerName := ""
+		fallback := false
+
+		for start := 0; start < len(uncachedIndices); start += chunkSize {
+			end := start + chunkSize
+			if end > len(uncachedIndices) {
+				end = len(uncachedIndices)
+			}
+			chunkIndices := uncachedIndices[start:end]
+			chunkObs := uncachedObs[start:end]
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				decisions, name, ok := bds.decideChunk(ctx, observations, chunkIndices, chunkObs)
+				for i, idx := range chunkIndices {
+					out <- DecisionResult{Index: idx, NPC: getString(observations[idx], "name"), Decision: decisions[i]}
+				}
+
+				resultMu.Lock()
+				if ok {
+					providerName = name
+				} else {
+					fallback = true
+				}
+				resultMu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		bds.auditBatch(ctx, observations, len(observations)-len(uncachedIndices), providerName, fallback)
+	}()
+
+	return out
+}
+
+// decideChunk makes one LLM call for a chunk of uncached NPCs. Returns a
+// decision per index, the provider used, and false if it fell back.
+func (bds *BatchDecisionSystem) decideChunk(ctx context.Context, observations []map[string]interface{}, indices []int, chunkObs []map[string]interface{}) ([]map[string]interface{}, string, bool) {
+	decisions := make([]map[string]interface{}, len(indices))
+
 	if ctx.Err() != nil {
-		return &BatchDecisionResponse{Error: ctx.Err()}
+		for i, idx := range indices {
+			decisions[i] = bds.fallbackDecision(observations[idx])
+		}
+		return decisions, "", false
+	}
+
+	if primary := bds.manager.preferUnsaturated(bds.manager.roleProvider("movement", bds.manager.activeSLM)); primary != nil && bds.isBatchDisabled(primary.Name) {
+		return bds.decidePerNPC(ctx, chunkObs), primary.Name, true
 	}
 
-	// Phase 2: Build dynamic prompt for uncached NPCs
-	prompt := bds.buildFlexibleMultiNPCPrompt(uncachedObs)
+	prompt := bds.buildFlexibleMultiNPCPrompt(chunkObs)
 
 	// Phase 3: Call LLM with timeout context
-	callCtx, cancel := context.WithTimeout(ctx, 25*time.Second)
+	callCtx, cancel := context.WithTimeout(withNPCs(withRole(ctx, "batch"), chunkObs), bds.manager.requestTimeout)
 	defer cancel()
 
-	llmResponse, err := bds.callLLMWithFallback(callCtx, prompt, len(uncachedObs))
+	start := time.Now()
+	llmResponse, provider, err := bds.callLLMWithFallback(callCtx, prompt, len(chunkObs))
 	if err != nil {
 		// Fallback: Generate default decisions
 		log.Printf("⚠️ Batch LLM failed, using fallback: %v", err)
@@ -139,32 +296,211 @@ func (bds *BatchDecisionSystem) GetBatchDecisions(ctx context.Context, observati
 		bds.fallbackUsed++
 		bds.mu.Unlock()
 
-		for _, idx := range uncachedIndices {
-			response.Decisions[idx] = DefaultDecision(observations[idx])
+		for i, idx := range indices {
+			decisions[i] = bds.fallbackDecision(observations[idx])
 		}
-		return response
+		return decisions, "", false
 	}
+	bds.recordLatency(time.Since(start))
 
 	bds.mu.Lock()
 	bds.batchCalls++
 	bds.mu.Unlock()
 
 	// Phase 4: Parse and distribute decisions
-	decisions := bds.parseMultiNPCResponse(llmResponse, uncachedObs)
+	parsed, ok := bds.parseMultiNPCResponse(bds.manager.decoderFor(provider), llmResponse, chunkObs)
+	bds.recordParse(provider.Name, ok)
 
-	for i, idx := range uncachedIndices {
-		if i < len(decisions) {
-			response.Decisions[idx] = decisions[i]
+	for i, idx := range indices {
+		if i < len(parsed) && parsed[i] != nil {
+			decisions[i] = parsed[i]
 			// Cache this decision
 			hash := bds.hashObservation(observations[idx])
-			bds.cache.Set(hash, decisions[i])
+			bds.cache.Set(hash, parsed[i])
+			bds.rememberDecision(observations[idx], parsed[i])
 		} else {
-			// Not enough decisions returned, use fallback
-			response.Decisions[idx] = DefaultDecision(observations[idx])
+			// No decision returned for this NPC, use fallback
+			decisions[i] = bds.fallbackDecision(observations[idx])
 		}
 	}
 
-	return response
+	return decisions, provider.Name, true
+}
+
+// decidePerNPC asks for each NPC's decision with its own enhanced prompt,
+// concurrently; used while a provider's batch responses aren't parseable
+func (bds *BatchDecisionSystem) decidePerNPC(ctx context.Context, chunkObs []map[string]interface{}) []map[string]interface{} {
+	decisions := make([]map[string]interface{}, len(chunkObs))
+	var wg sync.WaitGroup
+	for i, obs := range chunkObs {
+		wg.Add(1)
+		go func(i int, obs map[string]interface{}) {
+			defer wg.Done()
+			decision, err := bds.manager.GetEnhancedDecision(ctx, obs)
+			if err != nil {
+				decision = bds.fallbackDecision(obs)
+			} else {
+				bds.rememberDecision(obs, decision)
+			}
+			decisions[i] = decision
+		}(i, obs)
+	}
+	wg.Wait()
+
+	bds.mu.Lock()
+	bds.perNPCDecided += len(chunkObs)
+	bds.mu.Unlock()
+	return decisions
+}
+
+// isBatchDisabled reports whether a provider is in per-NPC mode, re-enabling
+// batching once its cooldown has passed
+func (bds *BatchDecisionSystem) isBatchDisabled(provider string) bool {
+	bds.mu.Lock()
+	defer bds.mu.Unlock()
+
+	until, disabled := bds.batchDisabled[provider]
+	if !disabled {
+		return false
+	}
+	if bds.now().Before(until) {
+		return true
+	}
+	delete(bds.batchDisabled, provider)
+	bds.parseFailures[provider] = 0
+	log.Printf("📦 Re-enabling batch decisions for %s", provider)
+	return false
+}
+
+// recordParse counts consecutive unparseable batch responses per provider
+// and switches the provider to per-NPC decisions after parseFailureLimit
+func (bds *BatchDecisionSystem) recordParse(provider string, ok bool) {
+	bds.mu.Lock()
+	defer bds.mu.Unlock()
+
+	if ok {
+		bds.parseFailures[provider] = 0
+		return
+	}
+	bds.parseFailed++
+	bds.parseFailures[provider]++
+	if bds.parseFailures[provider] >= bds.parseFailureLimit {
+		bds.batchDisabled[provider] = bds.now().Add(bds.disableCooldown)
+		log.Printf("🔀 %s failed to produce batch JSON %d times in a row, switching to per-NPC decisions for %s",
+			provider, bds.parseFailures[provider], bds.disableCooldown)
+	}
+}
+
+// currentChunkSize returns how many NPCs to put in one LLM call
+func (bds *BatchDecisionSystem) currentChunkSize() int {
+	bds.mu.RLock()
+	defer bds.mu.RUnlock()
+	return bds.chunkSize
+}
+
+// recordLatency folds a successful call's latency into the moving average and
+// grows the chunk size when calls are fast or shrinks it when they are slow
+func (bds *BatchDecisionSystem) recordLatency(latency time.Duration) {
+	bds.mu.Lock()
+	defer bds.mu.Unlock()
+
+	if bds.avgLatency == 0 {
+		bds.avgLatency = latency
+	} else {
+		bds.avgLatency = (bds.avgLatency*7 + latency*3) / 10
+	}
+
+	previous := bds.chunkSize
+	switch {
+	case bds.avgLatency < bds.fastLatency && bds.chunkSize < bds.maxChunk:
+		bds.chunkSize++
+	case bds.avgLatency > bds.slowLatency && bds.chunkSize > bds.minChunk:
+		bds.chunkSize--
+	}
+	if bds.chunkSize != previous {
+		log.Printf("📐 Batch chunk size %d → %d (avg latency %dms)", previous, bds.chunkSize, bds.avgLatency.Milliseconds())
+	}
+}
+
+// auditBatch records how one batch request was served and for which NPCs,
+// tagged with its request ID
+func (bds *BatchDecisionSystem) auditBatch(ctx context.Context, observations []map[string]interface{}, cacheHits int, provider string, fallback bool) {
+	names := make([]string, len(observations))
+	for i, obs := range observations {
+		names[i] = getString(obs, "name")
+	}
+	observability.GetObserver().AuditRequest(ctx, "batch_decisions", "", "", map[string]interface{}{
+		"npcs":       len(observations),
+		"npc_names":  names,
+		"cache_hits": cacheHits,
+		"provider":   provider,
+		"fallback":   fallback,
+	})
+}
+
+// orderByNPCName sorts indices by NPC name (then npc_id) and returns the
+// matching observations, so the same NPCs always produce the same batch prompt
+// regardless of the order the client sent them in
+func orderByNPCName(indices []int, observations []map[string]interface{}) []map[string]interface{} {
+	sort.SliceStable(indices, func(a, b int) bool {
+		obsA, obsB := observations[indices[a]], observations[indices[b]]
+		nameA, nameB := getString(obsA, "name"), getString(obsB, "name")
+		if nameA != nameB {
+			return nameA < nameB
+		}
+		return getString(obsA, "npc_id") < getString(obsB, "npc_id")
+	})
+
+	ordered := make([]map[string]interface{}, len(indices))
+	for i, idx := range indices {
+		ordered[i] = observations[idx]
+	}
+	return ordered
+}
+
+// rememberDecision keeps an NPC's newest LLM decision for fallbackDecision
+func (bds *BatchDecisionSystem) rememberDecision(obs, decision map[string]interface{}) {
+	name := getString(obs, "name")
+	if name == "" {
+		return
+	}
+	bds.mu.Lock()
+	defer bds.mu.Unlock()
+	if bds.lastDecisions == nil {
+		bds.lastDecisions = make(map[string]map[string]interface{})
+	}
+	bds.lastDecisions[name] = decision
+}
+
+// fallbackDecision returns, when stale fallback is enabled, the NPC's last
+// LLM decision or failing that the cached decision for this observation even
+// if expired; otherwise (or if there's neither) the generic default decision.
+// Observations change every tick, so the NPC's last decision is looked up by
+// name first; it was made for an earlier observation and counts as stale.
+func (bds *BatchDecisionSystem) fallbackDecision(obs map[string]interface{}) map[string]interface{} {
+	if bds.staleFallback {
+		name := getString(obs, "name")
+		bds.mu.RLock()
+		last, ok := bds.lastDecisions[name]
+		bds.mu.RUnlock()
+		stale := ok
+		if !ok {
+			var cached *CachedDecision
+			if cached, ok = bds.cache.GetStaleOK(bds.hashObservation(obs)); ok {
+				last, stale = cached.Decision, cached.Stale
+			}
+		}
+		if ok {
+			if stale {
+				bds.mu.Lock()
+				bds.staleServed++
+				bds.mu.Unlock()
+				log.Printf("🕰️ Serving stale decision for %s", name)
+			}
+			return last
+		}
+	}
+	return DefaultDecision(obs)
 }
 
 // buildFlexibleMultiNPCPrompt creates a prompt that auto-configures based on NPC count
@@ -207,6 +543,16 @@ func (bds *BatchDecisionSystem) buildFlexibleMultiNPCPrompt(observations []map[s
 		sb.WriteString(fmt.Sprintf("### NPC %d: %s\n", i+1, name))
 		sb.WriteString(fmt.Sprintf("- Team: %s | Pos: (%.0f, %.0f) | Energy: %d%% | State: %s\n",
 			team, posX, posY, energy, state))
+		if getBool(obs, "out_of_bounds") {
+			sb.WriteString("- ⚠️ Drifted off the map and was pulled back to the edge: move back toward its zone\n")
+		}
+
+		if directive := getString(obs, "team_strategy"); directive != "" {
+			sb.WriteString(fmt.Sprintf("- Team orders: %s\n", directive))
+		}
+		if feedback := getString(obs, "last_feedback"); feedback != "" {
+			sb.WriteString(fmt.Sprintf("- Last move: %s\n", feedback))
+		}
 
 		// Nearby gates
 		nearbyGates := getArrayOfMaps(obs, "nearby_gates")
@@ -220,6 +566,10 @@ func (bds *BatchDecisionSystem) buildFlexibleMultiNPCPrompt(observations []map[s
 					if getBool(g, "requiresTeamwork") {
 						tw = " [2P]"
 					}
+					if preview, ok := g["challenge"].(map[string]interface{}); ok {
+						tw += fmt.Sprintf(" (%s d%d %dt)", getString(preview, "type"),
+							getInt(preview, "difficulty"), getInt(preview, "reward"))
+					}
 					gateInfo = append(gateInfo, fmt.Sprintf("%s:%.0fu%s", gateID, dist, tw))
 				}
 			}
@@ -227,6 +577,15 @@ func (bds *BatchDecisionSystem) buildFlexibleMultiNPCPrompt(observations []map[s
 				sb.WriteString(fmt.Sprintf("- Gates: %s\n", strings.Join(gateInfo, ", ")))
 			}
 		}
+		if objects := describeNearbyObjects(obs); len(objects) > 0 {
+			sb.WriteString(fmt.Sprintf("- Objects: %s\n", strings.Join(objects, ", ")))
+		}
+		if goal := getString(obs, "goal"); goal != "" {
+			sb.WriteString(fmt.Sprintf("- Goal: %s (keep pursuing unless done or impossible)\n", goal))
+		}
+		if messages := describeMessages(obs); len(messages) > 0 {
+			sb.WriteString(fmt.Sprintf("- Messages: %s\n", strings.Join(messages, " | ")))
+		}
 
 		// Nearby NPCs
 		nearbyNPCs := getArrayOfMaps(obs, "nearby_npcs")
@@ -240,7 +599,11 @@ func (bds *BatchDecisionSystem) buildFlexibleMultiNPCPrompt(observations []map[s
 				if isTeammate {
 					marker = "👥"
 				}
-				npcInfo = append(npcInfo, fmt.Sprintf("%s%s:%.0fu", marker, npcName, dist))
+				info := fmt.Sprintf("%s%s:%.0fu", marker, npcName, dist)
+				if trend := approachTrend(n); trend != "" {
+					info += " (" + trend + ")"
+				}
+				npcInfo = append(npcInfo, info)
 			}
 			sb.WriteString(fmt.Sprintf("- Nearby: %s\n", strings.Join(npcInfo, ", ")))
 		}
@@ -248,22 +611,22 @@ func (bds *BatchDecisionSystem) buildFlexibleMultiNPCPrompt(observations []map[s
 		sb.WriteString("\n")
 	}
 
+	radii := bds.promptBuilder.awarenessRadii()
+
 	// Actions section
-	sb.WriteString(`## AVAILABLE ACTIONS
-- move: {"action":"move","target":[x,y],"reason":"..."} - Move to coordinates
-- challenge: {"action":"challenge","target":"gate_id","reason":"..."} - Attempt gate (must be within 60 units!)
-- talk: {"action":"talk","target":"NPC_name","message":"..."} - Talk to nearby NPC
-- taunt: {"action":"taunt","target":"NPC_name","message":"..."} - Taunt opponent
-- wait: {"action":"wait","target":null,"reason":"..."} - Stay and wait
-- explore: {"action":"explore","target":null,"reason":"..."} - Random exploration
-
-## STRATEGY TIPS
-- Prioritize gates that are close (< 150 units)
-- If 2 teammates near a [2P] gate, coordinate!
-- Taunt opponents when you're winning
+	sb.WriteString("## AVAILABLE ACTIONS\n")
+	for _, spec := range game.ActionRegistry {
+		sb.WriteString(fmt.Sprintf("- %s: %s - %s\n", spec.Name, spec.Example, spec.Description))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`## STRATEGY TIPS
+- Prioritize gates that are close (< %.0f units)
+- If 2 teammates are within %.0f units of a [2P] gate, coordinate!
+- Taunt opponents within %.0f units when you're winning
 - Don't waste moves on already-unlocked gates
+- Add "goal":"reach gate_id" to a decision only to set or change that NPC's goal
 
-`)
+`, radii.Gate, radii.Teammate, radii.Opponent))
 
 	// Dynamic output format based on NPC count
 	sb.WriteString("## RESPOND WITH JSON ONLY\n")
@@ -288,13 +651,16 @@ func (bds *BatchDecisionSystem) buildFlexibleMultiNPCPrompt(observations []map[s
 	return sb.String()
 }
 
-// callLLMWithFallback tries primary provider, then falls back to others
-func (bds *BatchDecisionSystem) callLLMWithFallback(ctx context.Context, prompt string, expectedCount int) (string, error) {
-	// Try primary SLM provider
-	if bds.manager.activeSLM != nil {
-		response, err := bds.callWithContext(ctx, bds.manager.activeSLM, prompt)
+// callLLMWithFallback tries primary provider, then falls back to others,
+// skipping providers whose circuit is open. Returns the provider that
+// produced the response.
+func (bds *BatchDecisionSystem) callLLMWithFallback(ctx context.Context, prompt string, expectedCount int) (string, *Provider, error) {
+	// Try primary SLM provider (or the first one still under its quota threshold)
+	primary := bds.manager.preferUnsaturated(bds.manager.roleProvider("movement", bds.manager.activeSLM))
+	if primary != nil && !bds.manager.circuit.Open(primary.Name) {
+		response, err := bds.callWithContext(ctx, primary, prompt)
 		if err == nil {
-			return response, nil
+			return response, primary, nil
 		}
 		log.Printf("⚠️ Primary provider failed: %v", err)
 	}
@@ -302,25 +668,28 @@ func (bds *BatchDecisionSystem) callLLMWithFallback(ctx context.Context, prompt
 	// Try fallback providers
 	for i := range bds.manager.slmProviders {
 		p := &bds.manager.slmProviders[i]
-		if p.Name == bds.manager.activeSLM.Name {
+		if primary != nil && p.Name == primary.Name {
 			continue // Skip already-tried primary
 		}
+		if bds.manager.circuit.Open(p.Name) {
+			continue
+		}
 
 		select {
 		case <-ctx.Done():
-			return "", ctx.Err()
+			return "", nil, ctx.Err()
 		default:
 		}
 
 		response, err := bds.callWithContext(ctx, p, prompt)
 		if err == nil {
 			log.Printf("✅ Fallback to %s successful", p.Name)
-			return response, nil
+			return response, p, nil
 		}
 		log.Printf("⚠️ Fallback %s failed: %v", p.Name, err)
 	}
 
-	return "", fmt.Errorf("all providers failed")
+	return "", nil, fmt.Errorf("all providers failed")
 }
 
 // callWithContext wraps the API call with context cancellation
@@ -331,7 +700,7 @@ func (bds *BatchDecisionSystem) callWithContext(ctx context.Context, p *Provider
 	}, 1)
 
 	go func() {
-		resp, err := bds.manager.callProviderWithRetry(p, prompt, 2)
+		resp, err := bds.manager.callProviderWithRetry(ctx, p, prompt, bds.manager.maxRetries)
 		resultChan <- struct {
 			response string
 			err      error
@@ -346,27 +715,18 @@ func (bds *BatchDecisionSystem) callWithContext(ctx context.Context, p *Provider
 	}
 }
 
-// parseMultiNPCResponse extracts individual decisions from batch response
-func (bds *BatchDecisionSystem) parseMultiNPCResponse(response string, observations []map[string]interface{}) []map[string]interface{} {
-	// Try to find JSON in response
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-
-	if start < 0 || end < start {
-		log.Printf("⚠️ No JSON found in batch response")
-		return bds.generateDefaultDecisions(observations)
-	}
-
-	jsonStr := response[start : end+1]
-
+// parseMultiNPCResponse extracts individual decisions from batch response.
+// An NPC the response has no decision for gets nil; ok is false when the
+// response had no usable JSON at all.
+func (bds *BatchDecisionSystem) parseMultiNPCResponse(dec jsonDecoder, response string, observations []map[string]interface{}) ([]map[string]interface{}, bool) {
 	var parsed struct {
 		Decisions []map[string]interface{} `json:"decisions"`
 		Strategy  string                   `json:"strategy"`
 	}
 
-	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
-		log.Printf("⚠️ Failed to parse batch JSON: %v", err)
-		return bds.generateDefaultDecisions(observations)
+	if !dec.decode(response, &parsed) {
+		log.Printf("⚠️ No usable JSON in batch response")
+		return nil, false
 	}
 
 	// Map decisions back to NPCs by name or npc_id
@@ -377,72 +737,32 @@ func (bds *BatchDecisionSystem) parseMultiNPCResponse(response string, observati
 		npcName := getString(obs, "name")
 
 		// Find matching decision
-		found := false
 		for _, dec := range parsed.Decisions {
 			decNpcID := getString(dec, "npc_id")
 			decNpcName := getString(dec, "npc")
 
 			if decNpcID == npcID || decNpcName == npcName {
-				dec["npc_id"] = npcID // Ensure npc_id is set
+				// Attribute the decision to its NPC for whoever applies,
+				// traces or audits it
+				dec["npc_id"] = npcID
+				dec["npc"] = npcName
+				normalizeMoveTarget(dec, obs)
 				result[i] = dec
-				found = true
 				break
 			}
 		}
 
-		if !found {
-			log.Printf("⚠️ No decision found for %s, using default", npcName)
-			result[i] = DefaultDecision(obs)
+		if result[i] == nil {
+			log.Printf("⚠️ No decision found for %s, using fallback", npcName)
 		}
 	}
 
-	return result
-}
-
-// generateDefaultDecisions creates fallback decisions for all NPCs
-func (bds *BatchDecisionSystem) generateDefaultDecisions(observations []map[string]interface{}) []map[string]interface{} {
-	result := make([]map[string]interface{}, len(observations))
-	for i, obs := range observations {
-		result[i] = DefaultDecision(obs)
-	}
-	return result
+	return result, true
 }
 
-// hashObservation creates a cache key from observation
-// Only includes position (rounded) and nearby gates - things that actually matter for decisions
+// hashObservation creates a cache key from observation (see game.HashObservation)
 func (bds *BatchDecisionSystem) hashObservation(obs map[string]interface{}) string {
-	key := make(map[string]interface{})
-
-	// Round position to grid (reduces cache variations)
-	pos := getArray(obs, "pos")
-	if len(pos) >= 2 {
-		if x, ok := pos[0].(float64); ok {
-			key["x"] = int(x/50) * 50 // Round to 50-unit grid
-		}
-		if y, ok := pos[1].(float64); ok {
-			key["y"] = int(y/50) * 50
-		}
-	}
-
-	key["name"] = getString(obs, "name")
-
-	// Include only locked nearby gates
-	nearbyGates := getArrayOfMaps(obs, "nearby_gates")
-	var gateKeys []string
-	for _, g := range nearbyGates {
-		if !getBool(g, "unlocked") {
-			gateID := getString(g, "id")
-			dist := int(getFloat(g, "distance")/50) * 50 // Round distance
-			gateKeys = append(gateKeys, fmt.Sprintf("%s:%d", gateID, dist))
-		}
-	}
-	sort.Strings(gateKeys)
-	key["gates"] = strings.Join(gateKeys, ",")
-
-	// Hash the key
-	keyJSON, _ := json.Marshal(key)
-	hash := sha256.Sum256(keyJSON)
-	return hex.EncodeToString(hash[:8]) // Use first 8 bytes
+	return game.HashObservation(obs)
 }
 
 // Cache methods
@@ -465,6 +785,22 @@ func (c *DecisionCache) Get(key string) (*CachedDecision, bool) {
 	return entry, true
 }
 
+// GetStaleOK returns a cached entry even if it has expired.
+// The returned copy has Stale set when the entry is past its TTL.
+func (c *DecisionCache) GetStaleOK(key string) (*CachedDecision, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+
+	result := *entry
+	result.Stale = time.Since(entry.CreatedAt) > c.ttl
+	return &result, true
+}
+
 func (c *DecisionCache) Set(key string, decision map[string]interface{}) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -507,13 +843,27 @@ func (bds *BatchDecisionSystem) GetStats() map[string]interface{} {
 		cacheHitRate = float64(bds.cachHits) / float64(bds.totalDecisions) * 100
 	}
 
+	perNPC := make(map[string]string, len(bds.batchDisabled))
+	for provider, until := range bds.batchDisabled {
+		perNPC[provider] = until.Format(time.RFC3339)
+	}
+
 	return map[string]interface{}{
 		"batch_calls":     bds.batchCalls,
+		"parse_failures":  bds.parseFailed,
+		"per_npc_mode":    perNPC, // Provider -> when batching resumes
+		"per_npc_decided": bds.perNPCDecided,
 		"cache_hits":      bds.cachHits,
 		"total_decisions": bds.totalDecisions,
 		"cache_hit_rate":  fmt.Sprintf("%.1f%%", cacheHitRate),
 		"fallback_used":   bds.fallbackUsed,
+		"stale_served":    bds.staleServed,
+		"chunk_size":      bds.chunkSize,
+		"avg_latency_ms":  bds.avgLatency.Milliseconds(),
 		"cost_savings":    fmt.Sprintf("%.0f%%", (1-float64(bds.batchCalls)/float64(max(1, bds.totalDecisions)))*100),
+		"cache_size":      bds.cache.Len(),
+		"cache_max_size":  bds.cache.maxSize,
+		"cache_ttl_sec":   bds.cache.ttl.Seconds(),
 	}
 }
 
@@ -0,0 +1,389 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amit/npc/internal/config"
+	"github.com/amit/npc/internal/observability"
+)
+
+func testObservation(id, name, team string, x, y float64) map[string]interface{} {
+	return map[string]interface{}{
+		"npc_id": id,
+		"name":   name,
+		"team":   team,
+		"pos":    []interface{}{x, y},
+		"energy": 80.0,
+		"state":  "idle",
+	}
+}
+
+func TestBatchPrompt_StableUnderReordering(t *testing.T) {
+	explorer := testObservation("npc_0", "Explorer", "red", 150, 150)
+	scout := testObservation("npc_1", "Scout", "red", 250, 150)
+	wanderer := testObservation("npc_2", "Wanderer", "blue", 1050, 650)
+	seeker := testObservation("npc_3", "Seeker", "blue", 950, 650)
+
+	bds := &BatchDecisionSystem{}
+	orderings := [][]map[string]interface{}{
+		{explorer, scout, wanderer, seeker},
+		{seeker, wanderer, scout, explorer},
+		{wanderer, explorer, seeker, scout},
+	}
+
+	var want string
+	for n, observations := range orderings {
+		indices := make([]int, len(observations))
+		for i := range indices {
+			indices[i] = i
+		}
+
+		ordered := orderByNPCName(indices, observations)
+		for i, idx := range indices {
+			if getString(ordered[i], "name") != getString(observations[idx], "name") {
+				t.Fatalf("ordering %d: index %d does not point back to its observation", n, i)
+			}
+		}
+
+		prompt := bds.buildFlexibleMultiNPCPrompt(ordered)
+		if n == 0 {
+			want = prompt
+			continue
+		}
+		if prompt != want {
+			t.Errorf("ordering %d produced a different prompt:\n%s\n--- want ---\n%s", n, prompt, want)
+		}
+	}
+}
+
+func TestGetBatchDecisions_FailedCallServesEachNPCsLastDecision(t *testing.T) {
+	var down atomic.Bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if down.Load() {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"decisions\":[` +
+			`{\"npc\":\"Explorer\",\"action\":\"move\",\"target\":[400,300],\"reason\":\"gate\"},` +
+			`{\"npc\":\"Scout\",\"action\":\"talk\",\"target\":\"Explorer\",\"reason\":\"plan\"}]}"}}]}`))
+	}
+
+	cfg := config.Default()
+	m := newTestManagerWith(t, cfg, handler)
+	bds := NewBatchDecisionSystem(m, cfg)
+	ctx := context.Background()
+
+	first := bds.GetBatchDecisions(ctx, []map[string]interface{}{
+		testObservation("npc_0", "Explorer", "red", 150, 150),
+		testObservation("npc_1", "Scout", "red", 250, 150),
+	})
+	if first.Error != nil {
+		t.Fatal(first.Error)
+	}
+
+	// Everyone has moved, so no observation hash matches the first batch;
+	// the fallback still finds each NPC's own decision by name
+	down.Store(true)
+	second := bds.GetBatchDecisions(ctx, []map[string]interface{}{
+		testObservation("npc_1", "Scout", "red", 500, 400),
+		testObservation("npc_0", "Explorer", "red", 400, 300),
+		testObservation("npc_2", "Wanderer", "blue", 1050, 650),
+	})
+	wanderer := getString(DefaultDecision(testObservation("npc_2", "Wanderer", "blue", 1050, 650)), "action")
+	for i, want := range []string{"talk", "move", wanderer} {
+		if got := getString(second.Decisions[i], "action"); got != want {
+			t.Errorf("decision %d = %q after the failed call, want %q", i, got, want)
+		}
+	}
+	if served := bds.GetStats()["stale_served"]; served != 2 {
+		t.Errorf("stale_served = %v, want 2 (Wanderer had no earlier decision)", served)
+	}
+}
+
+func TestGetBatchDecisions_AttributesDecisionsToTheirNPCs(t *testing.T) {
+	// The model answers by npc_id only
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"decisions\":[` +
+			`{\"npc_id\":\"npc_7\",\"action\":\"wait\",\"target\":null},` +
+			`{\"npc_id\":\"npc_8\",\"action\":\"wait\",\"target\":null}]}"}}]}`))
+	}
+
+	cfg := config.Default()
+	m := newTestManagerWith(t, cfg, handler)
+	bds := NewBatchDecisionSystem(m, cfg)
+
+	result := bds.GetBatchDecisions(context.Background(), []map[string]interface{}{
+		testObservation("npc_7", "Attributed7", "red", 150, 150),
+		testObservation("npc_8", "Attributed8", "blue", 950, 650),
+	})
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	for i, want := range []string{"Attributed7", "Attributed8"} {
+		if got := getString(result.Decisions[i], "npc"); got != want {
+			t.Errorf("decision %d attributed to %q, want %q", i, got, want)
+		}
+		// The one batch call counts toward each NPC's latency
+		if calls := observability.GetObserver().NPCLatency(want, 0).Calls; calls != 1 {
+			t.Errorf("%s has %d traced calls, want the batch call", want, calls)
+		}
+	}
+}
+
+func TestGetBatchDecisions_UnparseableResponseServesStaleDecisions(t *testing.T) {
+	var garbled atomic.Bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if garbled.Load() {
+			w.Write([]byte(`{"choices":[{"message":{"content":"sorry, I can't decide right now"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"decisions\":[` +
+			`{\"npc\":\"Explorer\",\"action\":\"move\",\"target\":[400,300],\"reason\":\"gate\"}]}"}}]}`))
+	}
+
+	cfg := config.Default()
+	m := newTestManagerWith(t, cfg, handler)
+	bds := NewBatchDecisionSystem(m, cfg)
+	ctx := context.Background()
+
+	bds.GetBatchDecisions(ctx, []map[string]interface{}{testObservation("npc_0", "Explorer", "red", 150, 150)})
+
+	garbled.Store(true)
+	second := bds.GetBatchDecisions(ctx, []map[string]interface{}{testObservation("npc_0", "Explorer", "red", 400, 300)})
+	if got := getString(second.Decisions[0], "action"); got != "move" {
+		t.Errorf("decision after an unparseable response = %q, want Explorer's last move", got)
+	}
+	if served := bds.GetStats()["stale_served"]; served != 1 {
+		t.Errorf("stale_served = %v, want 1", served)
+	}
+}
+
+func TestFallbackDecision_ExpiredCacheEntryBeforeDefault(t *testing.T) {
+	quietLogs(t)
+
+	bds := &BatchDecisionSystem{cache: NewDecisionCache(10, 0), staleFallback: true}
+	obs := testObservation("npc_0", "Explorer", "red", 150, 150)
+	bds.cache.Set(bds.hashObservation(obs), map[string]interface{}{"action": "explore"})
+
+	if _, fresh := bds.cache.Get(bds.hashObservation(obs)); fresh {
+		t.Fatal("entry should be past its TTL")
+	}
+	cached, ok := bds.cache.GetStaleOK(bds.hashObservation(obs))
+	if !ok || !cached.Stale {
+		t.Fatalf("GetStaleOK = %+v, %v, want the expired entry flagged stale", cached, ok)
+	}
+	if got := getString(bds.fallbackDecision(obs), "action"); got != "explore" {
+		t.Errorf("fallback = %q, want the expired cached decision", got)
+	}
+
+	bds.staleFallback = false
+	if got := bds.fallbackDecision(obs); getString(got, "action") != getString(DefaultDecision(obs), "action") {
+		t.Errorf("explore fallback = %v, want the default decision", got)
+	}
+}
+
+// benchObservations builds n observations with nearby gates, NPCs and objects,
+// roughly what the client sends each decision tick
+func benchObservations(n int) []map[string]interface{} {
+	names := []string{"Explorer", "Scout", "Wanderer", "Seeker"}
+	observations := make([]map[string]interface{}, n)
+	for i := range observations {
+		team := "red"
+		if i%2 == 1 {
+			team = "blue"
+		}
+		obs := testObservation(fmt.Sprintf("npc_%d", i), fmt.Sprintf("%s%d", names[i%len(names)], i), team, float64(100+i*37), float64(100+i*23))
+		obs["nearby_gates"] = []interface{}{
+			map[string]interface{}{"id": "gate_1_2", "distance": 120.0, "unlocked": false, "requiresTeamwork": true,
+				"challenge": map[string]interface{}{"type": "spatial", "difficulty": 2, "reward": 40}},
+			map[string]interface{}{"id": "gate_2_4", "distance": 310.0, "unlocked": false},
+		}
+		obs["nearby_npcs"] = []interface{}{
+			map[string]interface{}{"name": "Scout", "distance": 90.0, "isTeammate": true},
+			map[string]interface{}{"name": "Wanderer", "distance": 140.0, "isTeammate": false},
+		}
+		obs["nearby_objects"] = []interface{}{
+			map[string]interface{}{"id": "obj_3", "type": "treasure", "behavior": "tokens", "distance": 45.0},
+		}
+		observations[i] = obs
+	}
+	return observations
+}
+
+// mockBatchManager returns a Manager whose only SLM provider is a local
+// OpenAI-compatible server answering every batch with a move per NPC
+func mockBatchManager(b *testing.B, observations []map[string]interface{}) *Manager {
+	var decisions []map[string]interface{}
+	for _, obs := range observations {
+		decisions = append(decisions, map[string]interface{}{
+			"npc_id": obs["npc_id"], "npc": obs["name"], "action": "move", "target": []float64{400, 300}, "reason": "gate",
+		})
+	}
+	content, _ := json.Marshal(map[string]interface{}{"decisions": decisions})
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{"message": map[string]interface{}{"content": string(content)}}},
+	})
+
+	return newTestManager(b, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write(body)
+	})
+}
+
+func BenchmarkGetBatchDecisions(b *testing.B) {
+	quietLogs(b)
+
+	observations := benchObservations(8)
+	bds := NewBatchDecisionSystem(mockBatchManager(b, observations), config.Default())
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bds.cache = NewDecisionCache(100, time.Minute) // Every NPC misses, so each iteration calls the provider
+		if resp := bds.GetBatchDecisions(ctx, observations); resp.Error != nil {
+			b.Fatal(resp.Error)
+		}
+	}
+}
+
+func BenchmarkHashObservation(b *testing.B) {
+	obs := benchObservations(1)[0]
+	bds := &BatchDecisionSystem{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bds.hashObservation(obs)
+	}
+}
+
+func BenchmarkBuildFlexiblePrompt(b *testing.B) {
+	observations := benchObservations(8)
+	bds := &BatchDecisionSystem{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bds.buildFlexibleMultiNPCPrompt(observations)
+	}
+}
+
+func TestRecordParse_DisablesBatchingUntilCooldown(t *testing.T) {
+	quietLogs(t)
+
+	cases := []struct {
+		name         string
+		parsed       []bool        // recordParse outcomes, in order
+		elapsed      time.Duration // Clock advance before checking
+		wantDisabled bool
+	}{
+		{"below the limit", []bool{false, false}, 0, false},
+		{"limit in a row", []bool{false, false, false}, 0, true},
+		{"success resets the count", []bool{false, false, true, false, false}, 0, false},
+		{"failures past the limit", []bool{false, false, false, false}, 0, true},
+		{"cooling down", []bool{false, false, false}, 4 * time.Minute, true},
+		{"cooldown over", []bool{false, false, false}, 5 * time.Minute, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Batch.ParseFailureLimit = 3
+			cfg.Batch.DisableCooldownSec = 300
+			bds := NewBatchDecisionSystem(nil, cfg)
+			now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			bds.now = func() time.Time { return now }
+
+			for _, ok := range tc.parsed {
+				bds.recordParse("groq", ok)
+			}
+			now = now.Add(tc.elapsed)
+			if got := bds.isBatchDisabled("groq"); got != tc.wantDisabled {
+				t.Errorf("isBatchDisabled = %v, want %v", got, tc.wantDisabled)
+			}
+			if bds.isBatchDisabled("openrouter") {
+				t.Error("another provider's batching was disabled")
+			}
+		})
+	}
+}
+
+func TestRecordParse_ReenabledProviderStartsCountingAgain(t *testing.T) {
+	quietLogs(t)
+
+	cfg := config.Default()
+	cfg.Batch.ParseFailureLimit = 2
+	cfg.Batch.DisableCooldownSec = 60
+	bds := NewBatchDecisionSystem(nil, cfg)
+	now := time.Now()
+	bds.now = func() time.Time { return now }
+
+	bds.recordParse("groq", false)
+	bds.recordParse("groq", false)
+	now = now.Add(time.Minute)
+	if bds.isBatchDisabled("groq") {
+		t.Fatal("batching still disabled after the cooldown")
+	}
+
+	// The failures before the cooldown don't count toward the next limit
+	bds.recordParse("groq", false)
+	if bds.isBatchDisabled("groq") {
+		t.Error("one failure after re-enabling disabled batching again")
+	}
+	bds.recordParse("groq", false)
+	if !bds.isBatchDisabled("groq") {
+		t.Error("limit reached again but batching stayed on")
+	}
+}
+
+func TestGetBatchDecisions_UnparseableProviderSwitchesToPerNPC(t *testing.T) {
+	var calls atomic.Int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		calls.Add(1)
+		w.Write([]byte(`{"choices":[{"message":{"content":"I think everyone should explore"}}]}`))
+	}
+
+	cfg := config.Default()
+	cfg.Batch.ParseFailureLimit = 2
+	m := newTestManagerWith(t, cfg, handler)
+	bds := NewBatchDecisionSystem(m, cfg)
+	ctx := context.Background()
+
+	// New positions every round, so nothing is served from the cache
+	batch := func(round int) {
+		x := float64(100 + round*200)
+		bds.GetBatchDecisions(ctx, []map[string]interface{}{
+			testObservation("npc_0", "Explorer", "red", x, 150),
+			testObservation("npc_1", "Scout", "red", x, 650),
+		})
+	}
+	batch(0)
+	batch(1)
+	stats := bds.GetStats()
+	if stats["parse_failures"] != 2 || stats["per_npc_decided"] != 0 {
+		t.Fatalf("after 2 unparseable batches stats = %v, want 2 parse failures and no per-NPC decisions", stats)
+	}
+	if _, disabled := stats["per_npc_mode"].(map[string]string)["mock"]; !disabled {
+		t.Fatalf("per_npc_mode = %v, want mock", stats["per_npc_mode"])
+	}
+
+	before := calls.Load()
+	batch(2)
+	if got := bds.GetStats()["per_npc_decided"]; got != 2 {
+		t.Errorf("per_npc_decided = %v, want both NPCs decided one by one", got)
+	}
+	if n := calls.Load() - before; n != 2 {
+		t.Errorf("%d provider calls for 2 NPCs in per-NPC mode, want one each", n)
+	}
+}
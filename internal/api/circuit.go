@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/amit/npc/internal/config"
+)
+
+// ErrCircuitOpen is returned for calls to a provider whose circuit breaker is
+// open, without contacting the provider
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Circuit breaker states
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+)
+
+// circuitState is one provider's breaker
+type circuitState struct {
+	state    string
+	failures []time.Time // Consecutive failures inside the window
+	openedAt time.Time
+	probing  bool // Half-open: the single probe call is in flight
+}
+
+// CircuitBreaker stops calling a provider after repeated failures. After
+// threshold consecutive failures within window its circuit opens and calls
+// are refused for cooldown; then it half-opens and lets one probe through,
+// closing again if the probe succeeds and reopening if it fails.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	circuits  map[string]*circuitState
+	threshold int // Failures that open the circuit; 0 = never open
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// NewCircuitBreaker creates a breaker from the llm.circuit_breaker config.
+// A negative failure_threshold disables it.
+func NewCircuitBreaker(cfg config.CircuitBreakerConfig) *CircuitBreaker {
+	threshold := cfg.FailureThreshold
+	if threshold == 0 {
+		threshold = 3
+	} else if threshold < 0 {
+		threshold = 0
+	}
+	window := time.Duration(cfg.WindowSec) * time.Second
+	if window <= 0 {
+		window = 60 * time.Second
+	}
+	cooldown := time.Duration(cfg.CooldownSec) * time.Second
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+
+	return &CircuitBreaker{
+		circuits:  make(map[string]*circuitState),
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// circuit returns the provider's breaker, creating it closed (caller holds mu)
+func (cb *CircuitBreaker) circuit(provider string) *circuitState {
+	c, ok := cb.circuits[provider]
+	if !ok {
+		c = &circuitState{state: circuitClosed}
+		cb.circuits[provider] = c
+	}
+	return c
+}
+
+// Open reports whether calls to the provider would be refused right now,
+// without claiming the half-open probe. Fallback loops use it to skip
+// providers.
+func (cb *CircuitBreaker) Open(provider string) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[provider]
+	if !ok {
+		return false
+	}
+	switch c.state {
+	case circuitOpen:
+		return cb.now().Sub(c.openedAt) < cb.cooldown
+	case circuitHalfOpen:
+		return c.probing
+	}
+	return false
+}
+
+// Allow reports whether a call to the provider may go ahead. Once an open
+// circuit's cooldown has elapsed the first caller gets through as the probe.
+func (cb *CircuitBreaker) Allow(provider string) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[provider]
+	if !ok {
+		return true
+	}
+	switch c.state {
+	case circuitOpen:
+		if cb.now().Sub(c.openedAt) < cb.cooldown {
+			return false
+		}
+		c.state = circuitHalfOpen
+		c.probing = true
+		log.Printf("🔌 [%s] Circuit half-open, probing", provider)
+		return true
+	case circuitHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	}
+	return true
+}
+
+// RecordSuccess closes the provider's circuit
+func (cb *CircuitBreaker) RecordSuccess(provider string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[provider]
+	if !ok {
+		return
+	}
+	if c.state != circuitClosed {
+		log.Printf("🔌 [%s] Circuit closed", provider)
+	}
+	c.state = circuitClosed
+	c.failures = nil
+	c.probing = false
+}
+
+// Release ends a call that says nothing about the provider's health (e.g. a
+// safety block), letting another caller probe a half-open circuit
+func (cb *CircuitBreaker) Release(provider string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c, ok := cb.circuits[provider]; ok {
+		c.probing = false
+	}
+}
+
+// RecordFailure counts a failed call, opening the circuit once threshold
+// failures fall inside the window. A failed probe reopens it immediately.
+func (cb *CircuitBreaker) RecordFailure(provider string) {
+	if cb.threshold == 0 {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := cb.now()
+	c := cb.circuit(provider)
+	if c.state == circuitHalfOpen {
+		c.state, c.openedAt, c.probing = circuitOpen, now, false
+		log.Printf("🔌 [%s] Probe failed, circuit open for %v", provider, cb.cooldown)
+		return
+	}
+	if c.state == circuitOpen {
+		return
+	}
+
+	recent := c.failures[:0]
+	for _, t := range c.failures {
+		if now.Sub(t) < cb.window {
+			recent = append(recent, t)
+		}
+	}
+	c.failures = append(recent, now)
+	if len(c.failures) >= cb.threshold {
+		c.state, c.openedAt, c.failures = circuitOpen, now, nil
+		log.Printf("🔌 [%s] %d failures in %v, circuit open for %v", provider, cb.threshold, cb.window, cb.cooldown)
+	}
+}
+
+// States returns each tracked provider's circuit state ("closed", "open" or
+// "half_open") for stats
+func (cb *CircuitBreaker) States() map[string]string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	states := make(map[string]string, len(cb.circuits))
+	for name, c := range cb.circuits {
+		state := c.state
+		if state == circuitOpen && cb.now().Sub(c.openedAt) >= cb.cooldown {
+			state = circuitHalfOpen // Next call probes
+		}
+		states[name] = state
+	}
+	return states
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetDecision_SkipsProviderWithOpenCircuit(t *testing.T) {
+	var downCalls atomic.Int32
+	var healthy atomic.Bool
+	m := newTestManager(t)
+	m.slmProviders = []Provider{
+		mockProvider(t, "sambanova", func(w http.ResponseWriter, r *http.Request) {
+			downCalls.Add(1)
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"choices":[{"message":{"content":"{\"action\":\"wait\"}"}}]}`))
+		}),
+		mockProvider(t, "groq", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"choices":[{"message":{"content":"{\"action\":\"explore\"}"}}]}`))
+		}),
+	}
+	m.npcProviders = map[string]*Provider{"Explorer": &m.slmProviders[0]}
+	m.roleProviders = nil
+	m.maxRetries, m.fallbackRetries = 0, 0
+	m.rateLimiter = NewRateLimiter(100, 100)
+	now := time.Now()
+	m.circuit.now = func() time.Time { return now }
+
+	obs := map[string]interface{}{"name": "Explorer"}
+	decide := func() string {
+		t.Helper()
+		decision, err := m.GetDecision(obs)
+		if err != nil {
+			t.Fatalf("GetDecision: %v", err)
+		}
+		action, _ := decision["action"].(string)
+		return action
+	}
+
+	for i := 0; i < 3; i++ {
+		if action := decide(); action != "explore" {
+			t.Fatalf("call %d: action = %q, want the fallback's explore", i, action)
+		}
+	}
+	if got := m.GetStats()["circuit"].(map[string]string)["sambanova"]; got != "open" {
+		t.Fatalf("after 3 failures circuit = %q, want open", got)
+	}
+
+	decide()
+	if downCalls.Load() != 3 {
+		t.Errorf("open provider called %d times, want it skipped after 3", downCalls.Load())
+	}
+
+	// Cooldown elapsed: one probe goes through, fails and reopens the circuit
+	now = now.Add(31 * time.Second)
+	decide()
+	if downCalls.Load() != 4 {
+		t.Errorf("provider called %d times after cooldown, want one probe", downCalls.Load())
+	}
+	decide()
+	if downCalls.Load() != 4 {
+		t.Errorf("failed probe didn't reopen the circuit (%d calls)", downCalls.Load())
+	}
+
+	// Recovered: the next probe succeeds and closes it
+	healthy.Store(true)
+	now = now.Add(31 * time.Second)
+	if action := decide(); action != "wait" {
+		t.Errorf("recovered provider's action = %q, want wait", action)
+	}
+	if got := m.GetStats()["circuit"].(map[string]string)["sambanova"]; got != "closed" {
+		t.Errorf("after a good probe circuit = %q, want closed", got)
+	}
+}
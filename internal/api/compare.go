@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderComparison is one provider's answer to a CompareProviders call
+type ProviderComparison struct {
+	Provider  string                 `json:"provider"`
+	Model     string                 `json:"model,omitempty"`
+	LatencyMs int64                  `json:"latency_ms"`
+	Decision  map[string]interface{} `json:"decision,omitempty"`
+	Raw       string                 `json:"raw,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// findProvider looks up an enabled SLM or brain provider by name
+func (m *Manager) findProvider(name string) *Provider {
+	for _, providers := range [][]Provider{m.slmProviders, m.brainProviders} {
+		for i := range providers {
+			if strings.EqualFold(providers[i].Name, name) {
+				return &providers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// CompareProviders asks each named provider for a decision on the same
+// observation, in parallel, with the movement prompt real decisions use. Each
+// provider is called once, without retries or fallbacks, so the answers
+// compare the models alone; calls are traced with the "compare" role and
+// count towards quotas. Results follow the order of names.
+func (m *Manager) CompareProviders(observation map[string]interface{}, names []string) []ProviderComparison {
+	ctx := withTools(withNPC(withRole(context.Background(), "compare"), observation), decideTool())
+	prompt := promptBuilder.BuildMovementPrompt(observation)
+
+	results := make([]ProviderComparison, len(names))
+	providers := make([]*Provider, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i].Provider = name
+		p := m.findProvider(name)
+		if p == nil {
+			results[i].Error = fmt.Sprintf("no enabled provider %q", name)
+			continue
+		}
+		target, err := m.usableModel(p)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		providers[i] = target
+		results[i].Model = target.Model
+
+		wg.Add(1)
+		go func(r *ProviderComparison, p *Provider) {
+			defer wg.Done()
+			start := time.Now()
+			response, usage, shared, err := m.callProvider(ctx, p, prompt, toolsFor(ctx, p)...)
+			latency := time.Since(start)
+			if !shared {
+				m.quota.Record(p.Name)
+				m.trace(ctx, p, prompt, response, usage, latency, err)
+			}
+
+			r.LatencyMs = latency.Milliseconds()
+			r.Raw = response
+			if err != nil {
+				r.Error = err.Error()
+			}
+		}(&results[i], target)
+	}
+	wg.Wait()
+
+	// Parsing reads the shared observation, so it happens after the calls
+	for i, p := range providers {
+		if p == nil || results[i].Error != "" {
+			continue
+		}
+		decision, err := parseActionResponse(m.decoderFor(p), results[i].Raw, observation)
+		if err != nil {
+			results[i].Error = "unparseable: " + err.Error()
+		}
+		results[i].Decision = decision
+	}
+	return results
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCompareProviders_SideBySide(t *testing.T) {
+	answer := func(content string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"choices":[{"message":{"content":` + content + `}}]}`))
+		}
+	}
+	m := newTestManager(t)
+	m.slmProviders = []Provider{
+		mockProvider(t, "mover", answer(`"{\"action\":\"move\",\"target\":[400,250]}"`)),
+		mockProvider(t, "waiter", answer(`"{\"action\":\"wait\"}"`)),
+	}
+	m.slmProviders[0].Model = "m1"
+
+	obs := testObservation("npc_0", "Explorer", "red", 300, 200)
+	results := m.CompareProviders(obs, []string{"waiter", "missing", "mover"})
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results[0]; r.Provider != "waiter" || r.Decision["action"] != "wait" || r.Raw == "" {
+		t.Errorf("waiter = %+v", r)
+	}
+	if r := results[1]; r.Error == "" || r.Decision != nil {
+		t.Errorf("unknown provider = %+v, want an error", r)
+	}
+	if r := results[2]; r.Model != "m1" || r.Decision["action"] != "move" {
+		t.Errorf("mover = %+v", r)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+// tokenUsage is the token counts a provider reported for one call (zero when
+// it reports none)
+type tokenUsage struct {
+	In  int
+	Out int
+}
+
+// ProviderUsage is a provider's cumulative token usage and cost
+type ProviderUsage struct {
+	Calls     int     `json:"calls"`
+	TokensIn  int     `json:"tokens_in"`
+	TokensOut int     `json:"tokens_out"`
+	CostUSD   float64 `json:"cost_usd"`
+}
+
+// cost prices a call's usage: input and output tokens at their own rates
+// when configured, otherwise both at CostPer1K
+func (p *Provider) cost(u tokenUsage) float64 {
+	in, out := p.CostPer1KIn, p.CostPer1KOut
+	if in <= 0 {
+		in = p.CostPer1K
+	}
+	if out <= 0 {
+		out = p.CostPer1K
+	}
+	return (float64(u.In)*in + float64(u.Out)*out) / 1000
+}
+
+// recordUsage adds a successful call's tokens and cost to the provider's totals
+func (m *Manager) recordUsage(p *Provider, u tokenUsage) {
+	cost := p.cost(u)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total, ok := m.usage[p.Name]
+	if !ok {
+		total = &ProviderUsage{}
+		m.usage[p.Name] = total
+	}
+	total.Calls++
+	total.TokensIn += u.In
+	total.TokensOut += u.Out
+	total.CostUSD += cost
+}
+
+// Usage returns each provider's token usage and cost so far, and the total cost
+func (m *Manager) Usage() (map[string]ProviderUsage, float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := make(map[string]ProviderUsage, len(m.usage))
+	total := 0.0
+	for name, u := range m.usage {
+		usage[name] = *u
+		total += u.CostUSD
+	}
+	return usage, total
+}
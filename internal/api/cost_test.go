@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallProviderWithRetry_RecordsUsageCost(t *testing.T) {
+	m := newTestManager(t)
+	split := mockProvider(t, "groq", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":1000,"completion_tokens":500}}`))
+	})
+	flat := split
+	split.CostPer1K, split.CostPer1KIn, split.CostPer1KOut = 9, 0.5, 1.5
+	flat.Name, flat.CostPer1K = "openrouter", 0.2
+
+	for _, p := range []*Provider{&split, &split, &flat} {
+		if _, err := m.callProviderWithRetry(context.Background(), p, "prompt for "+p.Name, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := m.GetStats()
+	usage := stats["usage"].(map[string]ProviderUsage)
+	// 1000 in at $0.50/1K + 500 out at $1.50/1K = $1.25 per call
+	if u := usage["groq"]; u.Calls != 2 || u.TokensIn != 2000 || u.TokensOut != 1000 || math.Abs(u.CostUSD-2.5) > 1e-9 {
+		t.Errorf("groq usage = %+v, want 2 calls, 2000/1000 tokens, $2.50", u)
+	}
+	// 1500 tokens at the flat $0.20/1K
+	if u := usage["openrouter"]; math.Abs(u.CostUSD-0.3) > 1e-9 {
+		t.Errorf("openrouter cost = %v, want $0.30", u.CostUSD)
+	}
+	if total := stats["total_cost_usd"].(float64); math.Abs(total-2.8) > 1e-9 {
+		t.Errorf("total_cost_usd = %v, want 2.80", total)
+	}
+}
+
+func TestCallProvider_SharedCallRecordsUsageOnce(t *testing.T) {
+	var calls atomic.Int32
+	m := newTestManager(t)
+	p := mockProvider(t, "groq", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond) // Long enough for the other callers to join
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":100,"completion_tokens":50}}`))
+	})
+	p.CostPer1K = 1
+
+	const callers = 4
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.callProviderWithRetry(context.Background(), &p, "same prompt", 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	hits := int(calls.Load())
+	if hits >= callers || m.deduped.Load() == 0 {
+		t.Fatalf("%d HTTP calls for %d callers, want them to share", hits, callers)
+	}
+	// Usage follows the HTTP calls actually made, not the callers
+	usage, total := m.Usage()
+	if u := usage["groq"]; u.Calls != hits || u.TokensIn != 100*hits || u.TokensOut != 50*hits || math.Abs(total-0.15*float64(hits)) > 1e-9 {
+		t.Errorf("usage = %+v (total $%v) for %d HTTP calls, want $0.15 and 150 tokens each", u, total, hits)
+	}
+	// So does the daily quota
+	if counted := m.quota.counts["groq"]; counted != hits {
+		t.Errorf("quota counted %d calls for %d HTTP calls", counted, hits)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import "sync"
+
+// flightGroup collapses concurrent calls with the same key into one: the
+// first caller runs fn, the others wait for and share its result. Unlike the
+// decision cache nothing is kept once the call returns.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done     chan struct{}
+	response string
+	usage    tokenUsage
+	err      error
+}
+
+// Do runs fn once per key at a time. shared is true for callers that reused
+// another caller's in-flight result; they get no usage, since the tokens were
+// only spent once.
+func (g *flightGroup) Do(key string, fn func() (string, tokenUsage, error)) (response string, usage tokenUsage, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.response, tokenUsage{}, call.err, true
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.response, call.usage, call.err = fn()
+	return call.response, call.usage, call.err, false
+}
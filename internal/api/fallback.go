@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"sort"
+)
+
+// orderedFallbacks returns the SLM providers to try after primary fails.
+// With llm.fallback_order set they follow that order (unlisted providers
+// last, in declaration order); otherwise the cheapest by cost_per_1k_tokens
+// come first, so retries don't escalate to an expensive model. Either way
+// healthy providers - under their quota threshold and with a usable model -
+// are tried before unhealthy ones.
+func (m *Manager) orderedFallbacks(primary *Provider) []*Provider {
+	rank := make(map[string]int, len(m.fallbackOrder))
+	for i, name := range m.fallbackOrder {
+		rank[name] = i
+	}
+	position := func(p *Provider) int {
+		if i, ok := rank[p.Name]; ok {
+			return i
+		}
+		return len(m.fallbackOrder)
+	}
+
+	var candidates []*Provider
+	healthy := make(map[string]bool)
+	for i := range m.slmProviders {
+		p := &m.slmProviders[i]
+		if p.Name == primary.Name {
+			continue
+		}
+		_, err := m.usableModel(p)
+		healthy[p.Name] = err == nil && !m.quota.Saturated(p.Name)
+		candidates = append(candidates, p)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if healthy[a.Name] != healthy[b.Name] {
+			return healthy[a.Name]
+		}
+		if len(m.fallbackOrder) > 0 {
+			return position(a) < position(b)
+		}
+		return a.CostPer1K < b.CostPer1K
+	})
+	return candidates
+}
+
+// relativeCost is p's price as a multiple of primary's, or 0 when either
+// price is unknown
+func relativeCost(p, primary *Provider) float64 {
+	if p.CostPer1K <= 0 || primary.CostPer1K <= 0 {
+		return 0
+	}
+	return p.CostPer1K / primary.CostPer1K
+}
+
+type traceFallbackKey struct{}
+
+type fallbackInfo struct {
+	rank         int // 1 = first fallback tried
+	relativeCost float64
+}
+
+// withFallback tags ctx as the rank-th fallback attempt for traces
+func withFallback(ctx context.Context, rank int, relativeCost float64) context.Context {
+	return context.WithValue(ctx, traceFallbackKey{}, fallbackInfo{rank: rank, relativeCost: relativeCost})
+}
@@ -0,0 +1,62 @@
+package api
+
+import "strings"
+
+// formatPromptFor applies a provider's prompt quirks to a finished prompt just
+// before it is sent: capping the leading role/rules section, stripping emoji,
+// and repeating the JSON instruction at the end. Providers without quirks get
+// the prompt unchanged.
+func (m *Manager) formatPromptFor(p *Provider, prompt string) string {
+	if p == nil {
+		return prompt
+	}
+	f := p.Format
+
+	if f.MaxSystemLen > 0 {
+		prompt = capSystemSection(prompt, f.MaxSystemLen)
+	}
+	if f.StripEmoji {
+		prompt = stripEmoji(prompt)
+	}
+	if f.JSONReminderSuffix != "" {
+		prompt = strings.TrimRight(prompt, "\n") + "\n\n" + f.JSONReminderSuffix
+	}
+	return prompt
+}
+
+// capSystemSection truncates the prompt's first section (everything before the
+// second top-level "# " heading) to maxLen bytes, leaving the game state and
+// output format intact
+func capSystemSection(prompt string, maxLen int) string {
+	if prompt == "" {
+		return prompt
+	}
+	end := strings.Index(prompt[1:], "\n# ")
+	if end < 0 {
+		return prompt
+	}
+	end++ // Index was relative to prompt[1:]
+
+	if end <= maxLen {
+		return prompt
+	}
+	cut := maxLen
+	for cut > 0 && (prompt[cut]&0xC0) == 0x80 { // Don't split a UTF-8 sequence
+		cut--
+	}
+	return strings.TrimRight(prompt[:cut], " \n") + "\n" + prompt[end:]
+}
+
+// stripEmoji removes pictographs, dingbats and their joiners/variation selectors
+func stripEmoji(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0x1F000 && r <= 0x1FAFF, // Emoji, pictographs, symbols
+			r >= 0x2600 && r <= 0x27BF, // Misc symbols, dingbats
+			r >= 0x2B00 && r <= 0x2BFF, // Arrows and shapes (⭐ ⬆)
+			r == 0x200D, r == 0xFE0F:   // Zero-width joiner, emoji presentation
+			return -1
+		}
+		return r
+	}, s)
+}
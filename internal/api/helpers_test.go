@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+// quietLogs discards log output until the test ends
+func quietLogs(t testing.TB) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+// mockProvider starts a server answering with handler, closed when the test
+// ends, and returns an enabled provider called name that calls it
+func mockProvider(t testing.TB, name string, handler http.HandlerFunc) Provider {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return Provider{Name: name, BaseURL: srv.URL, APIKey: "test", Model: "mock", Enabled: true}
+}
+
+// newTestManager returns a Manager on the default config with logs quieted,
+// no minimum interval between calls and an SLM provider per handler (mock,
+// mock2, ...) served by it; the first one is active
+func newTestManager(t testing.TB, handlers ...http.HandlerFunc) *Manager {
+	return newTestManagerWith(t, config.Default(), handlers...)
+}
+
+// newTestManagerWith is newTestManager on cfg
+func newTestManagerWith(t testing.TB, cfg *config.Config, handlers ...http.HandlerFunc) *Manager {
+	quietLogs(t)
+	m := NewManager(cfg)
+	m.minCallInterval = 0
+	m.slmProviders = nil
+	for i, handler := range handlers {
+		name := "mock"
+		if i > 0 {
+			name = fmt.Sprintf("mock%d", i+1)
+		}
+		m.slmProviders = append(m.slmProviders, mockProvider(t, name, handler))
+	}
+	if len(m.slmProviders) > 0 {
+		m.activeSLM = &m.slmProviders[0]
+	}
+	return m
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ParseCounts tallies how LLM JSON output was decoded
+type ParseCounts struct {
+	FirstPass int `json:"first_pass"` // Valid JSON as returned
+	Repaired  int `json:"repaired"`   // Valid only after repair
+	Failed    int `json:"failed"`     // Unusable JSON
+	Custom    int `json:"custom"`     // ...of which a registered ResponseParser rescued
+}
+
+// ParseStats tracks JSON parse outcomes keyed by "provider/model"
+type ParseStats struct {
+	mu     sync.Mutex
+	counts map[string]*ParseCounts
+}
+
+// NewParseStats creates empty parse telemetry
+func NewParseStats() *ParseStats {
+	return &ParseStats{counts: make(map[string]*ParseCounts)}
+}
+
+func (ps *ParseStats) record(key string, update func(*ParseCounts)) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	counts, ok := ps.counts[key]
+	if !ok {
+		counts = &ParseCounts{}
+		ps.counts[key] = counts
+	}
+	update(counts)
+}
+
+// Snapshot returns a copy of the counters with a failure rate per model
+func (ps *ParseStats) Snapshot() map[string]interface{} {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	result := make(map[string]interface{}, len(ps.counts))
+	for key, c := range ps.counts {
+		total := c.FirstPass + c.Repaired + c.Failed
+		failureRate := 0.0
+		if total > 0 {
+			failureRate = float64(c.Failed) / float64(total)
+		}
+		result[key] = map[string]interface{}{
+			"first_pass":   c.FirstPass,
+			"repaired":     c.Repaired,
+			"failed":       c.Failed,
+			"custom":       c.Custom,
+			"total":        total,
+			"failure_rate": failureRate,
+		}
+	}
+	return result
+}
+
+// jsonDecoder decodes JSON from one provider/model's output, repairing it
+// unless strict, and records the outcome. The zero value repairs and records nothing.
+type jsonDecoder struct {
+	stats  *ParseStats
+	key    string
+	strict bool
+	parser ResponseParser // Model's fallback for non-JSON decisions (may be nil)
+}
+
+// decoderFor returns a decoder that attributes parse outcomes to p
+func (m *Manager) decoderFor(p *Provider) jsonDecoder {
+	if p == nil {
+		return jsonDecoder{stats: m.parseStats, key: "unknown", strict: m.strictJSON}
+	}
+	return jsonDecoder{stats: m.parseStats, key: p.Name + "/" + p.Model, strict: m.strictJSON, parser: m.parserFor(p.Model)}
+}
+
+// decode extracts the JSON object in response into v. Returns false if no
+// usable object was found.
+func (d jsonDecoder) decode(response string, v interface{}) bool {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start >= 0 && end > start && json.Unmarshal([]byte(response[start:end+1]), v) == nil {
+		d.record(func(c *ParseCounts) { c.FirstPass++ })
+		return true
+	}
+
+	if !d.strict {
+		if fixed := repairJSON(response); fixed != "" && json.Unmarshal([]byte(fixed), v) == nil {
+			d.record(func(c *ParseCounts) { c.Repaired++ })
+			return true
+		}
+	}
+
+	d.record(func(c *ParseCounts) { c.Failed++ })
+	return false
+}
+
+func (d jsonDecoder) record(update func(*ParseCounts)) {
+	if d.stats != nil {
+		d.stats.record(d.key, update)
+	}
+}
+
+var trailingComma = regexp.MustCompile(`,\s*([}\]])`)
+
+// repairJSON fixes the common ways models mangle JSON: code fences, smart
+// quotes, single-quoted strings, unquoted keys, trailing commas, unquoted
+// coordinate expressions in targets and output truncated before the closing
+// braces.
+// Returns "" if there is no object to repair.
+func repairJSON(s string) string {
+	start := strings.Index(s, "{")
+	if start < 0 {
+		return ""
+	}
+	s = s[start:]
+	s = strings.NewReplacer("“", `"`, "”", `"`, "```", "").Replace(s)
+	s = quoteKeysAndStrings(s)
+	s = trailingComma.ReplaceAllString(s, "$1")
+	s = quoteTargetExpressions(s)
+
+	// Walk the text, dropping anything after the top-level object closes and
+	// closing whatever is still open at the end
+	var sb strings.Builder
+	var stack []byte
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		sb.WriteByte(c)
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				return sb.String()
+			}
+		}
+	}
+
+	if inString {
+		sb.WriteByte('"')
+	}
+	repaired := strings.TrimRight(sb.String(), ", \n\t")
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			repaired += "}"
+		} else {
+			repaired += "]"
+		}
+	}
+	return trailingComma.ReplaceAllString(repaired, "$1")
+}
+
+// quoteKeysAndStrings rewrites single-quoted strings as double-quoted ones
+// and quotes bare object keys ({action: "move"}), leaving double-quoted
+// strings and bare values (true, x+100) as they are
+func quoteKeysAndStrings(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := stringEnd(s, i)
+			sb.WriteString(s[i:end])
+			i = end - 1
+		case c == '\'':
+			end := stringEnd(s, i)
+			body := strings.TrimSuffix(s[i+1:end], "'")
+			body = strings.NewReplacer(`\'`, "'", `"`, `\"`).Replace(body)
+			sb.WriteString(`"` + body + `"`)
+			i = end - 1
+		case isKeyByte(c) && (c < '0' || c > '9'):
+			end := i
+			for end < len(s) && isKeyByte(s[end]) {
+				end++
+			}
+			if strings.HasPrefix(strings.TrimLeft(s[end:], " \t"), ":") {
+				sb.WriteString(`"` + s[i:end] + `"`)
+			} else {
+				sb.WriteString(s[i:end])
+			}
+			i = end - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// stringEnd returns the index just past the string opened by the quote at
+// s[start], or len(s) if it never closes
+func stringEnd(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isKeyByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
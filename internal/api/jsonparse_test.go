@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  map[string]interface{} // nil: nothing usable
+	}{
+		{
+			name:  "trailing commas",
+			input: `{"action": "move", "target": [400, 300,],}`,
+			want:  map[string]interface{}{"action": "move", "target": []interface{}{400.0, 300.0}},
+		},
+		{
+			name:  "single quotes",
+			input: `{'action': 'talk', 'message': 'Meet at the "north" gate, it\'s open'}`,
+			want:  map[string]interface{}{"action": "talk", "message": `Meet at the "north" gate, it's open`},
+		},
+		{
+			name:  "unquoted keys",
+			input: `{action: "wait", reason: "guarding: gate_1_2", urgent: true}`,
+			want:  map[string]interface{}{"action": "wait", "reason": "guarding: gate_1_2", "urgent": true},
+		},
+		{
+			name:  "code fence",
+			input: "```json\n{\"action\": \"explore\"}\n```",
+			want:  map[string]interface{}{"action": "explore"},
+		},
+		{
+			name:  "truncated",
+			input: `{"action": "move", "target": [400, 300], "reason": "head for the ga`,
+			want:  map[string]interface{}{"action": "move", "target": []interface{}{400.0, 300.0}, "reason": "head for the ga"},
+		},
+		{
+			name:  "all at once",
+			input: "Sure!\n```\n{action: 'move', target: [x+100, y-50],}\n```",
+			want:  map[string]interface{}{"action": "move", "target": []interface{}{"x+100", "y-50"}},
+		},
+		{
+			name:  "no object",
+			input: "I would move toward the gate.",
+		},
+		{
+			name:  "unrepairable",
+			input: `{"action" "move" : : }`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repaired := repairJSON(tc.input)
+			var got map[string]interface{}
+			err := json.Unmarshal([]byte(repaired), &got)
+			if tc.want == nil {
+				if err == nil {
+					t.Errorf("repairJSON(%q) = %q decoded to %v, want nothing usable", tc.input, repaired, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("repairJSON(%q) = %q: %v", tc.input, repaired, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("repairJSON(%q) decoded to %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
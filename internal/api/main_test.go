@@ -0,0 +1,22 @@
+package api
+
+import (
+	"os"
+	"testing"
+
+	"github.com/amit/npc/internal/storage"
+)
+
+// TestMain points the audit log at a temporary directory so test runs don't
+// write logs/audit.log into the package
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "npc-api-test")
+	if err != nil {
+		panic(err)
+	}
+	GetAuditLog().SetStore(storage.NewFileStore(dir))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -2,17 +2,26 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/amit/npc/internal/config"
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/llm"
+	"github.com/amit/npc/internal/observability"
 )
 
 // Manager handles multiple LLM API providers with rate limiting
@@ -23,20 +32,72 @@ type Manager struct {
 	activeBrain    *Provider
 	httpClient     *http.Client
 
+	// Retry budgets and per-request timeout (LLM_MAX_RETRIES, LLM_TIMEOUT_SEC)
+	maxRetries      int
+	fallbackRetries int
+	requestTimeout  time.Duration
+	judgeDeadline   time.Duration
+
 	// Per-NPC provider mapping
 	npcProviders  map[string]*Provider // npc_name -> provider
 	providerIndex int                  // for round-robin fallback
 
+	fallbackOrder []string // Explicit SLM fallback order; empty = cheapest first
+
+	roleProviders   map[string]*Provider // Resolved from model_roles (see resolveRoles)
+	challengeSolver string               // "npc" or "role" (see solverFor)
+
 	// Rate limiting
 	rateLimiter     *RateLimiter
 	lastCallTime    time.Time
 	minCallInterval time.Duration
 	mu              sync.Mutex
 
+	// Token usage and cost per provider, from the counts providers report
+	usage map[string]*ProviderUsage
+
 	// Audit logging
 	successCount map[string]int
 	errorCount   map[string]int
 	lastError    map[string]string
+
+	// Recent successful-call latency per provider (EWMA) and when it last
+	// succeeded or failed
+	avgLatency  map[string]time.Duration
+	lastSuccess map[string]time.Time
+	lastFailure map[string]time.Time
+
+	// Daily request quotas per provider
+	quota *QuotaTracker
+
+	// Providers skipped after repeated failures
+	circuit *CircuitBreaker
+
+	// JSON parse telemetry per provider/model
+	parseStats *ParseStats
+	strictJSON bool                      // Disable JSON repair
+	parsers    map[string]ResponseParser // Non-JSON fallback parsers by model name
+
+	// Safety filter blocks (Gemini)
+	safetyBlocked  map[string]int
+	blockedPrompts []string // Most recent blocked prompts (truncated)
+
+	// "provider/model" keys the provider reported as not found
+	badModels map[string]bool
+
+	// Identical concurrent provider calls share one request
+	inflight flightGroup
+	deduped  atomic.Int64
+
+	// Last good brain strategy per team, reused while the brain is failing
+	strategies  map[string]cachedStrategy
+	strategyTTL time.Duration
+}
+
+// cachedStrategy is a team's last successful brain strategy
+type cachedStrategy struct {
+	text string
+	at   time.Time
 }
 
 // RateLimiter implements token bucket rate limiting
@@ -46,6 +107,11 @@ type RateLimiter struct {
 	refillRate float64 // tokens per second
 	lastRefill time.Time
 	mu         sync.Mutex
+
+	// Counters are atomic so Stats never contends with Wait
+	calls     atomic.Int64
+	throttled atomic.Int64
+	waitNanos atomic.Int64
 }
 
 func NewRateLimiter(maxTokens, refillRate float64) *RateLimiter {
@@ -57,9 +123,17 @@ func NewRateLimiter(maxTokens, refillRate float64) *RateLimiter {
 	}
 }
 
+// Wait takes tokens from the bucket, sleeping until they refill if it runs
+// short. The time reported as waited runs from entry to return, so it
+// includes queueing behind callers already sleeping with the lock held.
 func (r *RateLimiter) Wait(tokens float64) {
+	start := time.Now()
+	r.calls.Add(1)
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer func() {
+		r.mu.Unlock()
+		r.waitNanos.Add(int64(time.Since(start)))
+	}()
 
 	now := time.Now()
 	elapsed := now.Sub(r.lastRefill).Seconds()
@@ -70,12 +144,35 @@ func (r *RateLimiter) Wait(tokens float64) {
 		waitTime := time.Duration((tokens - r.tokens) / r.refillRate * float64(time.Second))
 		log.Printf("⏳ Rate limiting: waiting %.1fs", waitTime.Seconds())
 		time.Sleep(waitTime)
+		r.throttled.Add(1)
 		r.tokens = 0
+		r.lastRefill = time.Now() // The sleep's refill went to this caller
 	} else {
 		r.tokens -= tokens
 	}
 }
 
+// RateLimiterStats shows how much time callers lose to rate limiting
+type RateLimiterStats struct {
+	Calls        int64   `json:"calls"`
+	Throttled    int64   `json:"throttled"` // Calls that found the bucket empty and waited
+	TotalWaitMs  int64   `json:"total_wait_ms"`
+	ThrottleRate float64 `json:"throttle_rate"` // Throttled / Calls
+}
+
+// Stats returns cumulative throttling counters without taking the limiter lock
+func (r *RateLimiter) Stats() RateLimiterStats {
+	stats := RateLimiterStats{
+		Calls:       r.calls.Load(),
+		Throttled:   r.throttled.Load(),
+		TotalWaitMs: time.Duration(r.waitNanos.Load()).Milliseconds(),
+	}
+	if stats.Calls > 0 {
+		stats.ThrottleRate = float64(stats.Throttled) / float64(stats.Calls)
+	}
+	return stats
+}
+
 // Provider represents an LLM API provider
 type Provider struct {
 	Name    string
@@ -83,20 +180,56 @@ type Provider struct {
 	APIKey  string
 	Model   string
 	Enabled bool
+
+	SafetySettings map[string]string // Gemini harm category -> threshold
+
+	Format config.PromptFormatConfig // Prompt post-processing for this model
+
+	FallbackModel string // Used instead of Model once Model is reported missing
+
+	CostPer1K float64 // USD per 1K tokens; orders cost-aware fallbacks
+
+	CostPer1KIn  float64 // Input and output token prices for usage costs;
+	CostPer1KOut float64 // 0 = CostPer1K (see cost)
+
+	Capabilities llm.Capabilities // Optional API features, e.g. tool calling
+
+	MaxTokens   int      // Completion params from the provider's model role; 0 = defaults
+	Temperature *float64 // nil = default (see completionParams)
 }
 
 // NewManager creates a new API manager with rate limiting
 func NewManager(cfg *config.Config) *Manager {
+	maxRetries, timeout := llmCallLimits(cfg.LLM)
+
 	m := &Manager{
-		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		httpClient:      &http.Client{Timeout: timeout},
+		maxRetries:      maxRetries,
+		fallbackRetries: maxRetries / 2,
+		requestTimeout:  timeout,
 		rateLimiter:     NewRateLimiter(5, 1.0),
 		minCallInterval: 500 * time.Millisecond,
 		npcProviders:    make(map[string]*Provider),
+		roleProviders:   make(map[string]*Provider),
+		usage:           make(map[string]*ProviderUsage),
 		successCount:    make(map[string]int),
 		errorCount:      make(map[string]int),
 		lastError:       make(map[string]string),
+		avgLatency:      make(map[string]time.Duration),
+		lastSuccess:     make(map[string]time.Time),
+		lastFailure:     make(map[string]time.Time),
+		safetyBlocked:   make(map[string]int),
+		badModels:       make(map[string]bool),
+		strategies:      make(map[string]cachedStrategy),
+		strategyTTL:     time.Duration(cfg.LLM.StrategyCacheTTLSec) * time.Second,
+		parseStats:      NewParseStats(),
+		strictJSON:      cfg.LLM.JSONStrictness == "strict",
+		fallbackOrder:   cfg.LLM.FallbackOrder,
+		challengeSolver: cfg.LLM.ChallengeSolver,
 	}
 
+	quotaLimits := make(map[string]int)
+
 	// Load SLM providers
 	for _, p := range cfg.SLMProviders {
 		if !p.Enabled {
@@ -106,19 +239,27 @@ func NewManager(cfg *config.Config) *Manager {
 		if apiKey == "" {
 			apiKey = getEnvKey(p.Name)
 		}
-		if apiKey == "" {
+		if apiKey == "" && p.KeyRequired() {
 			continue
 		}
 		model := getEnvModel(p.Name, p.Model)
 
 		provider := Provider{
-			Name:    p.Name,
-			BaseURL: p.BaseURL,
-			APIKey:  apiKey,
-			Model:   model,
-			Enabled: true,
+			Name:           p.Name,
+			BaseURL:        p.BaseURL,
+			APIKey:         apiKey,
+			Model:          model,
+			Enabled:        true,
+			SafetySettings: p.SafetySettings,
+			Format:         p.PromptFormat,
+			FallbackModel:  p.FallbackModel,
+			CostPer1K:      p.CostPer1KTokens,
+			CostPer1KIn:    p.CostPer1KInputTokens,
+			CostPer1KOut:   p.CostPer1KOutputTokens,
+			Capabilities:   providerCapabilities(p),
 		}
 		m.slmProviders = append(m.slmProviders, provider)
+		quotaLimits[p.Name] = p.DailyQuota
 		if m.activeSLM == nil {
 			m.activeSLM = &provider
 		}
@@ -133,55 +274,124 @@ func NewManager(cfg *config.Config) *Manager {
 		if apiKey == "" {
 			apiKey = getEnvKey(p.Name)
 		}
-		if apiKey == "" {
+		if apiKey == "" && p.KeyRequired() {
 			continue
 		}
 		model := getEnvModel(p.Name, p.Model)
 
 		provider := Provider{
-			Name:    p.Name,
-			BaseURL: p.BaseURL,
-			APIKey:  apiKey,
-			Model:   model,
-			Enabled: true,
+			Name:           p.Name,
+			BaseURL:        p.BaseURL,
+			APIKey:         apiKey,
+			Model:          model,
+			Enabled:        true,
+			SafetySettings: p.SafetySettings,
+			Format:         p.PromptFormat,
+			FallbackModel:  p.FallbackModel,
+			CostPer1K:      p.CostPer1KTokens,
+			CostPer1KIn:    p.CostPer1KInputTokens,
+			CostPer1KOut:   p.CostPer1KOutputTokens,
+			Capabilities:   providerCapabilities(p),
 		}
 		m.brainProviders = append(m.brainProviders, provider)
+		quotaLimits[p.Name] = p.DailyQuota
 		if m.activeBrain == nil {
 			m.activeBrain = &provider
 		}
 	}
 
-	// Load per-NPC provider and model assignments
-	npcNames := []string{"Explorer", "Scout", "Wanderer", "Seeker"}
-	for _, name := range npcNames {
-		providerEnv := fmt.Sprintf("NPC_%s_PROVIDER", strings.ToUpper(name))
-		modelEnv := fmt.Sprintf("NPC_%s_MODEL", strings.ToUpper(name))
+	m.quota = NewQuotaTracker(cfg.LLM.Quota, quotaLimits)
+	m.circuit = NewCircuitBreaker(cfg.LLM.CircuitBreaker)
+	m.resolveRoles(cfg.ModelRoles)
 
-		providerName := os.Getenv(providerEnv)
-		modelOverride := os.Getenv(modelEnv)
+	m.judgeDeadline = time.Duration(cfg.LLM.JudgeDeadlineSec) * time.Second
+	if m.judgeDeadline <= 0 {
+		m.judgeDeadline = 5 * time.Second
+	}
+	if m.strategyTTL <= 0 {
+		m.strategyTTL = 5 * time.Minute
+	}
 
-		if providerName != "" {
-			for i := range m.slmProviders {
-				if strings.EqualFold(m.slmProviders[i].Name, providerName) {
-					npcProvider := m.slmProviders[i]
-					if modelOverride != "" {
-						npcProvider.Model = modelOverride
-					}
-					m.npcProviders[name] = &npcProvider
-					log.Printf("📍 NPC %s → %s (%s)", name, npcProvider.Name, npcProvider.Model)
-					break
+	// Load per-NPC provider and model assignments: npc_providers in config,
+	// overridden by NPC_<NAME>_PROVIDER / NPC_<NAME>_MODEL env vars
+	for _, name := range configuredNPCNames(cfg) {
+		assignment := cfg.NPCProviders[name]
+		envName := npcEnvName(name)
+		if v := os.Getenv(fmt.Sprintf("NPC_%s_PROVIDER", envName)); v != "" {
+			assignment.Provider = v
+		}
+		if v := os.Getenv(fmt.Sprintf("NPC_%s_MODEL", envName)); v != "" {
+			assignment.Model = v
+		}
+		if assignment.Provider == "" {
+			continue
+		}
+
+		found := false
+		for i := range m.slmProviders {
+			if strings.EqualFold(m.slmProviders[i].Name, assignment.Provider) {
+				npcProvider := m.slmProviders[i]
+				if assignment.Model != "" {
+					npcProvider.Model = assignment.Model
 				}
+				m.npcProviders[name] = &npcProvider
+				log.Printf("📍 NPC %s → %s (%s)", name, npcProvider.Name, npcProvider.Model)
+				found = true
+				break
 			}
 		}
+		if !found {
+			log.Printf("⚠️ NPC %s assigned to provider %q, which isn't an enabled SLM provider", name, assignment.Provider)
+		}
 	}
 
 	return m
 }
 
+// configuredNPCNames lists every NPC named in the config: the roster, team
+// members and npc_providers keys
+func configuredNPCNames(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(list ...string) {
+		for _, name := range list {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	add(cfg.NPCs.Names...)
+	add(cfg.Teams.Red.Members...)
+	add(cfg.Teams.Blue.Members...)
+
+	extra := make([]string, 0, len(cfg.NPCProviders))
+	for name := range cfg.NPCProviders {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	add(extra...)
+	return names
+}
+
+// npcEnvName turns an NPC name into its env var form, e.g. "Old Sage" -> "OLD_SAGE"
+func npcEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
+
 // GetProviderForNPC returns the provider for a specific NPC
 func (m *Manager) GetProviderForNPC(npcName string) *Provider {
 	if provider, ok := m.npcProviders[npcName]; ok && provider != nil {
-		return provider
+		return m.preferUnsaturated(provider)
+	}
+
+	if provider := m.roleProviders["movement"]; provider != nil {
+		return m.preferUnsaturated(provider)
 	}
 
 	if len(m.slmProviders) == 0 {
@@ -193,7 +403,50 @@ func (m *Manager) GetProviderForNPC(npcName string) *Provider {
 	m.providerIndex++
 	m.mu.Unlock()
 
-	return provider
+	return m.preferUnsaturated(provider)
+}
+
+// preferUnsaturated swaps a provider past its quota threshold for the first
+// SLM provider still under it. Returns p unchanged if none is available.
+func (m *Manager) preferUnsaturated(p *Provider) *Provider {
+	if p == nil || !m.quota.Saturated(p.Name) {
+		return p
+	}
+	for i := range m.slmProviders {
+		alt := &m.slmProviders[i]
+		if alt.Name != p.Name && !m.quota.Saturated(alt.Name) {
+			log.Printf("📊 [%s] near daily quota, routing to %s", p.Name, alt.Name)
+			return alt
+		}
+	}
+	return p
+}
+
+// GetQuotaUsage returns per-provider daily quota usage
+func (m *Manager) GetQuotaUsage() map[string]interface{} {
+	return m.quota.Usage()
+}
+
+// llmCallLimits resolves the retry count and HTTP timeout from config,
+// letting LLM_MAX_RETRIES and LLM_TIMEOUT_SEC override it
+func llmCallLimits(cfg config.LLMConfig) (int, time.Duration) {
+	maxRetries := 2
+	if cfg.MaxRetries != nil && *cfg.MaxRetries >= 0 {
+		maxRetries = *cfg.MaxRetries
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_MAX_RETRIES")); err == nil && v >= 0 {
+		maxRetries = v
+	}
+
+	timeoutSec := cfg.TimeoutSec
+	if v, err := strconv.Atoi(os.Getenv("LLM_TIMEOUT_SEC")); err == nil && v > 0 {
+		timeoutSec = v
+	}
+	if timeoutSec <= 0 {
+		timeoutSec = 30
+	}
+
+	return maxRetries, time.Duration(timeoutSec) * time.Second
 }
 
 func getEnvKey(provider string) string {
@@ -220,6 +473,7 @@ func getEnvModel(provider, defaultModel string) string {
 		"nebius":      "NEBIUS_MODEL",
 		"gemini":      "GEMINI_MODEL",
 		"openai":      "OPENAI_MODEL",
+		"ollama":      "OLLAMA_MODEL",
 	}
 	if envName, ok := envMap[provider]; ok {
 		if model := os.Getenv(envName); model != "" {
@@ -245,12 +499,27 @@ func (m *Manager) GetActiveBrain() string {
 	return "none (demo mode)"
 }
 
+// RateLimiterStats returns time lost to the shared rate limiter
+func (m *Manager) RateLimiterStats() RateLimiterStats {
+	return m.rateLimiter.Stats()
+}
+
 // GetStats returns provider statistics
 func (m *Manager) GetStats() map[string]interface{} {
+	usage, cost := m.Usage()
 	return map[string]interface{}{
-		"success":   m.successCount,
-		"errors":    m.errorCount,
-		"lastError": m.lastError,
+		"usage":           usage,
+		"total_cost_usd":  cost,
+		"success":         m.successCount,
+		"errors":          m.errorCount,
+		"lastError":       m.lastError,
+		"safety_blocked":  m.safetyBlocked,
+		"bad_models":      m.badModelList(),
+		"rate_limiter":    m.rateLimiter.Stats(),
+		"blocked_prompts": m.blockedPrompts,
+		"json_parse":      m.parseStats.Snapshot(),
+		"deduped":         m.deduped.Load(),
+		"circuit":         m.circuit.States(),
 	}
 }
 
@@ -264,7 +533,9 @@ type ProviderTestResult struct {
 	Error    string `json:"error,omitempty"`
 }
 
-// TestProviders tests all configured providers and returns results
+// TestProviders tests all configured providers and returns results. Outcomes
+// count towards provider health, so a run at startup serves as the preflight
+// Readiness relies on before any game traffic arrives.
 func (m *Manager) TestProviders() []ProviderTestResult {
 	results := []ProviderTestResult{}
 	testPrompt := `Reply with exactly: {"action":"idle","reason":"test"}`
@@ -274,7 +545,7 @@ func (m *Manager) TestProviders() []ProviderTestResult {
 		p := &m.slmProviders[i]
 		startTime := time.Now()
 
-		resp, err := m.callProvider(p, testPrompt)
+		resp, _, _, err := m.callProvider(context.Background(), p, testPrompt)
 		latency := time.Since(startTime).Milliseconds()
 
 		result := ProviderTestResult{
@@ -284,10 +555,12 @@ func (m *Manager) TestProviders() []ProviderTestResult {
 		}
 
 		if err != nil {
+			m.recordError(p.Name, err)
 			result.Status = "❌ FAILED"
 			result.Error = err.Error()
 			log.Printf("❌ TEST %s (%s): %s", p.Name, p.Model, truncateError(err))
 		} else {
+			m.recordLatency(p.Name, time.Since(startTime))
 			result.Status = "✅ OK"
 			result.Response = truncateForLog(resp, 80)
 			log.Printf("✅ TEST %s (%s): %dms", p.Name, p.Model, latency)
@@ -304,9 +577,9 @@ func (m *Manager) TestProviders() []ProviderTestResult {
 		var err error
 
 		if p.Name == "gemini" {
-			resp, err = m.callGemini(p, "Say hello in 3 words")
+			resp, _, err = m.callGemini(context.Background(), p, "Say hello in 3 words")
 		} else {
-			resp, err = m.callOpenAICompatible(p, "Say hello in 3 words")
+			resp, _, err = m.callOpenAICompatible(context.Background(), p, "Say hello in 3 words")
 		}
 
 		latency := time.Since(startTime).Milliseconds()
@@ -318,10 +591,12 @@ func (m *Manager) TestProviders() []ProviderTestResult {
 		}
 
 		if err != nil {
+			m.recordError(p.Name, err)
 			result.Status = "❌ FAILED"
 			result.Error = err.Error()
 			log.Printf("❌ TEST %s brain (%s): %s", p.Name, p.Model, truncateError(err))
 		} else {
+			m.recordLatency(p.Name, time.Since(startTime))
 			result.Status = "✅ OK"
 			result.Response = truncateForLog(resp, 80)
 			log.Printf("✅ TEST %s brain (%s): %dms", p.Name, p.Model, latency)
@@ -332,6 +607,32 @@ func (m *Manager) TestProviders() []ProviderTestResult {
 	return results
 }
 
+// Readiness reports whether decisions can be served: true when any provider's
+// most recent call succeeded, or when none are configured (demo mode needs
+// none). The map gives each provider's state: ok, failing or untested.
+func (m *Manager) Readiness() (bool, map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make(map[string]string)
+	ready := len(m.slmProviders) == 0 && len(m.brainProviders) == 0
+	for _, providers := range [][]Provider{m.slmProviders, m.brainProviders} {
+		for _, p := range providers {
+			succeeded, failed := m.lastSuccess[p.Name], m.lastFailure[p.Name]
+			switch {
+			case succeeded.After(failed):
+				states[p.Name] = "ok"
+				ready = true
+			case !failed.IsZero():
+				states[p.Name] = "failing"
+			default:
+				states[p.Name] = "untested"
+			}
+		}
+	}
+	return ready, states
+}
+
 func truncateForLog(s string, maxLen int) string {
 	s = strings.ReplaceAll(s, "\n", " ")
 	if len(s) > maxLen {
@@ -359,16 +660,63 @@ func (m *Manager) recordSuccess(provider string) {
 	m.mu.Unlock()
 }
 
+// recordLatency folds a successful call's latency into the provider's average
+func (m *Manager) recordLatency(provider string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if avg, ok := m.avgLatency[provider]; ok {
+		m.avgLatency[provider] = (avg*7 + latency*3) / 10
+	} else {
+		m.avgLatency[provider] = latency
+	}
+	m.lastSuccess[provider] = time.Now()
+}
+
+// fastestBrain returns the brain provider with the lowest average latency
+// among those that succeeded in the last 10 minutes, or activeBrain if none have
+func (m *Manager) fastestBrain() *Provider {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	best := m.activeBrain
+	bestLatency := time.Duration(-1)
+	for i := range m.brainProviders {
+		p := &m.brainProviders[i]
+		if time.Since(m.lastSuccess[p.Name]) > 10*time.Minute || m.quota.Saturated(p.Name) {
+			continue
+		}
+		if latency := m.avgLatency[p.Name]; bestLatency < 0 || latency < bestLatency {
+			best, bestLatency = p, latency
+		}
+	}
+	return best
+}
+
 // recordError logs a failed API call
 func (m *Manager) recordError(provider string, err error) {
 	m.mu.Lock()
 	m.errorCount[provider]++
 	m.lastError[provider] = err.Error()
+	m.lastFailure[provider] = time.Now()
+	m.mu.Unlock()
+}
+
+// recordSafetyBlock tracks a prompt refused by a provider's safety filter
+func (m *Manager) recordSafetyBlock(provider, prompt string) {
+	m.mu.Lock()
+	m.safetyBlocked[provider]++
+	m.blockedPrompts = append(m.blockedPrompts, truncateForLog(prompt, 120))
+	if len(m.blockedPrompts) > 10 {
+		m.blockedPrompts = m.blockedPrompts[1:]
+	}
 	m.mu.Unlock()
 }
 
 // GetDecision gets an action decision from the SLM with rate limiting
 func (m *Manager) GetDecision(observation map[string]interface{}) (map[string]interface{}, error) {
+	ctx := withTools(withNPC(withRole(context.Background(), "movement"), observation), decideTool())
+
 	npcName := ""
 	if name, ok := observation["name"].(string); ok {
 		npcName = name
@@ -385,33 +733,51 @@ func (m *Manager) GetDecision(observation map[string]interface{}) (map[string]in
 	prompt := buildActionPrompt(observation)
 	startTime := time.Now()
 
-	response, err := m.callProviderWithRetry(provider, prompt, 2)
+	response, err := m.callProviderWithRetry(ctx, provider, prompt, m.maxRetries)
 	latency := time.Since(startTime).Milliseconds()
 
 	audit := GetAuditLog()
 
-	if err != nil {
-		log.Printf("❌ %s [%s] FAILED: %s", npcName, provider.Name, truncateError(err))
+	if errors.Is(err, llm.ErrSafetyBlocked) {
 		m.recordError(provider.Name, err)
 		audit.LogError(npcName, provider.Name, provider.Model, prompt, latency, err)
+		return cannedTaunt(observation), nil
+	}
 
-		// Try fallback providers
-		for i, p := range m.slmProviders {
-			if p.Name != provider.Name {
-				startTime = time.Now()
-				response, err = m.callProviderWithRetry(&m.slmProviders[i], prompt, 1)
-				latency = time.Since(startTime).Milliseconds()
+	if err != nil {
+		if errors.Is(err, ErrCircuitOpen) {
+			log.Printf("🔌 %s [%s] skipped: circuit open", npcName, provider.Name)
+		} else {
+			log.Printf("❌ %s [%s] FAILED: %s", npcName, provider.Name, truncateError(err))
+			m.recordError(provider.Name, err)
+			audit.LogError(npcName, provider.Name, provider.Model, prompt, latency, err)
+		}
 
-				if err == nil {
-					log.Printf("✅ %s switched to backup: %s", npcName, p.Name)
-					m.recordSuccess(p.Name)
-					audit.LogSuccess(npcName, p.Name, p.Model, prompt, response, latency)
-					break
+		// Try fallback providers, cheapest healthy ones first, skipping any
+		// whose circuit is open
+		primary := provider
+		for i, p := range m.orderedFallbacks(primary) {
+			if m.circuit.Open(p.Name) {
+				continue
+			}
+			cost := relativeCost(p, primary)
+			startTime = time.Now()
+			response, err = m.callProviderWithRetry(withFallback(ctx, i+1, cost), p, prompt, m.fallbackRetries)
+			latency = time.Since(startTime).Milliseconds()
+
+			if err == nil {
+				provider = p
+				if cost > 0 {
+					log.Printf("✅ %s switched to backup: %s (%.2fx cost)", npcName, p.Name, cost)
 				} else {
-					m.recordError(p.Name, err)
-					audit.LogError(npcName, p.Name, p.Model, prompt, latency, err)
+					log.Printf("✅ %s switched to backup: %s", npcName, p.Name)
 				}
+				m.recordSuccess(p.Name)
+				audit.LogSuccess(npcName, p.Name, p.Model, prompt, response, latency)
+				break
 			}
+			m.recordError(p.Name, err)
+			audit.LogError(npcName, p.Name, p.Model, prompt, latency, err)
 		}
 		if err != nil {
 			return DefaultDecision(observation), err
@@ -421,13 +787,22 @@ func (m *Manager) GetDecision(observation map[string]interface{}) (map[string]in
 		audit.LogSuccess(npcName, provider.Name, provider.Model, prompt, response, latency)
 	}
 
-	return parseActionResponse(response, observation)
+	return parseActionResponse(m.decoderFor(provider), response, observation)
 }
 
-// GetStrategy gets strategic advice from the brain LLM
-func (m *Manager) GetStrategy(summary string) (string, error) {
-	if m.activeBrain == nil {
-		return "Continue exploring systematically.", nil
+// defaultStrategy is the advice given when the brain has nothing better
+const defaultStrategy = "Continue exploring systematically."
+
+// GetStrategy gets strategic advice for a team from the brain LLM. When the
+// brain is failing or rate-limited, the team's last good strategy (if younger
+// than the strategy cache TTL) is returned instead with stale set, so team
+// coordination doesn't reset to the generic default on every hiccup.
+func (m *Manager) GetStrategy(team, summary string) (strategy string, stale bool, err error) {
+	ctx := withRole(context.Background(), "strategy")
+
+	brain := m.roleProvider("strategy", m.activeBrain)
+	if brain == nil {
+		return defaultStrategy, false, nil
 	}
 
 	m.rateLimiter.Wait(1)
@@ -435,22 +810,66 @@ func (m *Manager) GetStrategy(summary string) (string, error) {
 
 	prompt := buildStrategyPrompt(summary)
 
+	var response string
+	if brain.Name == "gemini" {
+		response, err = m.callGeminiWithRetry(ctx, brain, prompt, m.maxRetries)
+	} else {
+		response, err = m.callProviderWithRetry(ctx, brain, prompt, m.maxRetries)
+	}
+
+	if err != nil {
+		log.Printf("❌ Brain [%s] FAILED: %s", brain.Name, truncateError(err))
+		m.recordError(brain.Name, err)
+
+		m.mu.Lock()
+		cached, ok := m.strategies[team]
+		m.mu.Unlock()
+		if ok && time.Since(cached.at) < m.strategyTTL {
+			log.Printf("🧠 Reusing team %q strategy from %s ago", team, time.Since(cached.at).Round(time.Second))
+			return cached.text, true, nil
+		}
+		return defaultStrategy, false, err
+	}
+
+	m.recordSuccess(brain.Name)
+	m.mu.Lock()
+	m.strategies[team] = cachedStrategy{text: response, at: time.Now()}
+	m.mu.Unlock()
+	return response, false, nil
+}
+
+// ErrNoBrain is returned when a task needs the brain LLM and none is configured
+var ErrNoBrain = errors.New("no brain provider configured")
+
+// GenerateContent sends a raw prompt to the brain LLM (used for zone
+// generation). Unlike GetStrategy it never substitutes a canned reply.
+func (m *Manager) GenerateContent(prompt string) (string, error) {
+	ctx := withRole(context.Background(), "zone_gen")
+
+	brain := m.roleProvider("zone_generator", m.activeBrain)
+	if brain == nil {
+		return "", ErrNoBrain
+	}
+
+	m.rateLimiter.Wait(1)
+	m.throttle()
+
 	var response string
 	var err error
 
-	if m.activeBrain.Name == "gemini" {
-		response, err = m.callGeminiWithRetry(m.activeBrain, prompt, 2)
+	if brain.Name == "gemini" {
+		response, err = m.callGeminiWithRetry(ctx, brain, prompt, m.maxRetries)
 	} else {
-		response, err = m.callProviderWithRetry(m.activeBrain, prompt, 2)
+		response, err = m.callProviderWithRetry(ctx, brain, prompt, m.maxRetries)
 	}
 
 	if err != nil {
-		log.Printf("❌ Brain [%s] FAILED: %s", m.activeBrain.Name, truncateError(err))
-		m.recordError(m.activeBrain.Name, err)
-		return "Continue exploring systematically.", err
+		log.Printf("❌ Brain [%s] generation FAILED: %s", brain.Name, truncateError(err))
+		m.recordError(brain.Name, err)
+		return "", err
 	}
 
-	m.recordSuccess(m.activeBrain.Name)
+	m.recordSuccess(brain.Name)
 	return response, nil
 }
 
@@ -465,10 +884,18 @@ func buildActionPrompt(obs map[string]interface{}) string {
 		compact["near"] = nearby
 	}
 
+	actions := make([]string, len(game.ActionRegistry))
+	for i, spec := range game.ActionRegistry {
+		actions[i] = spec.Name
+		if spec.Target != game.TargetNone {
+			actions[i] += "(target)"
+		}
+	}
+
 	obsJSON, _ := json.Marshal(compact)
 	return fmt.Sprintf(`NPC decision. State: %s
-Actions: move(target), explore, interact(target), idle
-Reply JSON only: {"action":"...", "target":"...", "reason":"..."}`, string(obsJSON))
+Actions: %s
+Reply JSON only: {"action":"...", "target":"...", "reason":"..."}`, string(obsJSON), strings.Join(actions, ", "))
 }
 
 func buildStrategyPrompt(summary string) string {
@@ -485,53 +912,157 @@ func truncateError(err error) string {
 	return s
 }
 
-// callProviderWithRetry calls the provider with exponential backoff retry
-func (m *Manager) callProviderWithRetry(p *Provider, prompt string, maxRetries int) (string, error) {
-	var lastErr error
-	for i := 0; i <= maxRetries; i++ {
-		if i > 0 {
-			backoff := time.Duration(1<<uint(i-1)) * time.Second
-			log.Printf("🔄 [%s] Retry %d/%d after %v", p.Name, i, maxRetries, backoff)
-			time.Sleep(backoff)
-		}
+type traceRoleKey struct{}
 
-		response, err := m.callProvider(p, prompt)
-		if err == nil {
-			return response, nil
-		}
-		lastErr = err
+// withRole tags ctx with the LLM task for traces (movement, judge, ...)
+func withRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, traceRoleKey{}, role)
+}
 
-		if !isRetryableError(err) {
-			return "", err
+type traceNPCKey struct{}
+
+type traceNPC struct{ name, team string }
+
+// withNPC tags ctx with the NPC a call decides for, so traces can be
+// filtered (and latency tracked) per NPC
+func withNPC(ctx context.Context, observation map[string]interface{}) context.Context {
+	name, _ := observation["name"].(string)
+	team, _ := observation["team"].(string)
+	return context.WithValue(ctx, traceNPCKey{}, traceNPC{name, team})
+}
+
+type traceNPCsKey struct{}
+
+// withNPCs tags ctx with the NPCs a batch call decides for, so each of them
+// is credited with the call
+func withNPCs(ctx context.Context, observations []map[string]interface{}) context.Context {
+	names := make([]string, 0, len(observations))
+	for _, obs := range observations {
+		if name, _ := obs["name"].(string); name != "" {
+			names = append(names, name)
 		}
 	}
-	return "", lastErr
+	return context.WithValue(ctx, traceNPCsKey{}, names)
 }
 
-// callGeminiWithRetry calls Gemini with exponential backoff retry
-func (m *Manager) callGeminiWithRetry(p *Provider, prompt string, maxRetries int) (string, error) {
+// trace records one provider attempt, tagged with the request ID, role and NPC(s) in ctx
+func (m *Manager) trace(ctx context.Context, p *Provider, prompt, response string, usage tokenUsage, latency time.Duration, err error) {
+	role, _ := ctx.Value(traceRoleKey{}).(string)
+	npc, _ := ctx.Value(traceNPCKey{}).(traceNPC)
+	npcs, _ := ctx.Value(traceNPCsKey{}).([]string)
+	entry := observability.TraceEntry{
+		RequestID: observability.RequestID(ctx),
+		Role:      role,
+		NPC:       npc.name,
+		Team:      npc.team,
+		NPCs:      npcs,
+		Provider:  p.Name,
+		Model:     p.Model,
+		Prompt:    truncateStr(prompt, 500),
+		Response:  truncateStr(response, 500),
+		LatencyMs: latency.Milliseconds(),
+		Success:   err == nil,
+
+		PromptHash: observability.PromptDigest(prompt),
+		PromptLen:  len(prompt),
+
+		TokensIn:  usage.In,
+		TokensOut: usage.Out,
+		CostUSD:   p.cost(usage),
+	}
+	if fb, ok := ctx.Value(traceFallbackKey{}).(fallbackInfo); ok {
+		entry.Fallback = fb.rank
+		entry.RelativeCost = fb.relativeCost
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	observability.GetObserver().TraceCall(entry)
+}
+
+// callProviderWithRetry calls the provider with exponential backoff retry,
+// unless its circuit breaker is open. The outcome after retries feeds the
+// breaker; safety blocks and cancelled calls say nothing about the provider's
+// health and don't count.
+func (m *Manager) callProviderWithRetry(ctx context.Context, p *Provider, prompt string, maxRetries int) (string, error) {
+	if !m.circuit.Allow(p.Name) {
+		return "", fmt.Errorf("%s: %w", p.Name, ErrCircuitOpen)
+	}
+	response, err := m.retryProvider(ctx, p, prompt, maxRetries)
+	switch {
+	case err == nil:
+		m.circuit.RecordSuccess(p.Name)
+	case errors.Is(err, llm.ErrSafetyBlocked), errors.Is(err, context.Canceled):
+		m.circuit.Release(p.Name)
+	default:
+		m.circuit.RecordFailure(p.Name)
+	}
+	return response, err
+}
+
+func (m *Manager) retryProvider(ctx context.Context, p *Provider, prompt string, maxRetries int) (string, error) {
 	var lastErr error
 	for i := 0; i <= maxRetries; i++ {
 		if i > 0 {
 			backoff := time.Duration(1<<uint(i-1)) * time.Second
 			log.Printf("🔄 [%s] Retry %d/%d after %v", p.Name, i, maxRetries, backoff)
-			time.Sleep(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
 		}
 
-		response, err := m.callGemini(p, prompt)
+		target, err := m.usableModel(p)
+		if err != nil {
+			return "", err
+		}
+
+		start := time.Now()
+		var response string
+		var usage tokenUsage
+		var shared bool
+		if onChunk := streamFor(ctx, target); onChunk != nil {
+			response, usage, err = m.streamProvider(ctx, target, prompt, onChunk)
+		} else {
+			response, usage, shared, err = m.callProvider(ctx, target, prompt, toolsFor(ctx, target)...)
+		}
+		if !shared {
+			// A shared result was counted and traced by the caller that made it
+			m.quota.Record(p.Name)
+			m.trace(ctx, target, prompt, response, usage, time.Since(start), err)
+		}
 		if err == nil {
+			if !shared {
+				m.recordLatency(p.Name, time.Since(start))
+			}
 			return response, nil
 		}
 		lastErr = err
 
-		if !isRetryableError(err) {
+		if errors.Is(err, llm.ErrModelNotFound) {
+			m.markBadModel(target, err)
+			if target == p && p.FallbackModel != "" && p.FallbackModel != p.Model {
+				return m.retryProvider(ctx, p, prompt, maxRetries-i) // Now uses the fallback
+			}
+			return "", err
+		}
+		if !isRetryableError(err) || errors.Is(err, errStreamInterrupted) {
 			return "", err
 		}
 	}
 	return "", lastErr
 }
 
+// callGeminiWithRetry calls Gemini with exponential backoff retry
+func (m *Manager) callGeminiWithRetry(ctx context.Context, p *Provider, prompt string, maxRetries int) (string, error) {
+	return m.callProviderWithRetry(ctx, p, prompt, maxRetries)
+}
+
 func isRetryableError(err error) bool {
+	if errors.Is(err, llm.ErrSafetyBlocked) {
+		return false
+	}
 	errStr := strings.ToLower(err.Error())
 	return strings.Contains(errStr, "429") ||
 		strings.Contains(errStr, "rate") ||
@@ -541,79 +1072,196 @@ func isRetryableError(err error) bool {
 		strings.Contains(errStr, "502")
 }
 
-// callProvider routes to the correct provider-specific implementation
-func (m *Manager) callProvider(p *Provider, prompt string) (string, error) {
+// callProvider sends prompt to p, offering tools if any, until ctx is done.
+// Identical prompts already in flight to the same provider and model share
+// that call's result (and its first caller's ctx) instead of making another.
+// Usage is recorded once per actual call; callers sharing a result get zero
+// usage back and shared set, so they don't count or trace the call again.
+func (m *Manager) callProvider(ctx context.Context, p *Provider, prompt string, tools ...llm.Tool) (response string, usage tokenUsage, shared bool, err error) {
+	maxTokens, temperature := p.completionParams()
+	key := fmt.Sprintf("%s/%s/%d/%g/%s", p.Name, p.Model, maxTokens, temperature, observability.PromptDigest(prompt))
+	if len(tools) > 0 {
+		key += "/tools"
+	}
+	response, usage, err, shared = m.inflight.Do(key, func() (string, tokenUsage, error) {
+		response, usage, err := m.dispatch(ctx, p, prompt, tools...)
+		if err == nil {
+			m.recordUsage(p, usage)
+		}
+		return response, usage, err
+	})
+	if shared {
+		m.deduped.Add(1)
+	}
+	return response, usage, shared, err
+}
+
+// dispatch routes to the correct provider-specific implementation. When a
+// model answers with a tool call, its JSON arguments are the response.
+func (m *Manager) dispatch(ctx context.Context, p *Provider, prompt string, tools ...llm.Tool) (string, tokenUsage, error) {
 	switch p.Name {
+	case "gemini":
+		return m.callGemini(ctx, p, prompt, tools...)
 	case "huggingface":
-		return m.callHuggingFace(p, prompt)
+		return m.callHuggingFace(ctx, p, prompt, tools...)
+	case "ollama":
+		return m.callOllama(ctx, p, prompt)
 	case "groq", "openrouter", "sambanova", "nebius":
-		return m.callOpenAICompatible(p, prompt)
+		return m.callOpenAICompatible(ctx, p, prompt, tools...)
 	default:
-		return m.callOpenAICompatible(p, prompt)
+		return m.callOpenAICompatible(ctx, p, prompt, tools...)
 	}
 }
 
 // callOpenAICompatible calls OpenAI-compatible APIs (Groq, OpenRouter, SambaNova, OpenAI)
-func (m *Manager) callOpenAICompatible(p *Provider, prompt string) (string, error) {
+func (m *Manager) callOpenAICompatible(ctx context.Context, p *Provider, prompt string, tools ...llm.Tool) (string, tokenUsage, error) {
+	prompt = m.formatPromptFor(p, prompt)
+	maxTokens, temperature := p.completionParams()
 	reqBody := map[string]interface{}{
 		"model": p.Model,
 		"messages": []map[string]string{
 			{"role": "user", "content": prompt},
 		},
-		"temperature": 0.7,
-		"max_tokens":  100,
+		"temperature": temperature,
+		"max_tokens":  maxTokens,
+	}
+	for k, v := range llm.OpenAIToolFields(tools) {
+		reqBody[k] = v
 	}
 
 	body, _ := json.Marshal(reqBody)
 	url := p.BaseURL + "/chat/completions"
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("request creation failed: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("request creation failed: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("network error: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("network error: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("[%s] HTTP %d: %s", p.Name, resp.StatusCode, truncateError(fmt.Errorf(string(respBody))))
+		return "", tokenUsage{}, httpError(p.Name, resp.StatusCode, respBody)
 	}
 
 	var result struct {
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content   string               `json:"content"`
+				ToolCalls []llm.OpenAIToolCall `json:"tool_calls"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
 		Error struct {
 			Message string `json:"message"`
 		} `json:"error"`
 	}
 
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("[%s] JSON parse error: %w", p.Name, err)
+		return "", tokenUsage{}, fmt.Errorf("[%s] JSON parse error: %w", p.Name, err)
 	}
 
 	if result.Error.Message != "" {
-		return "", fmt.Errorf("[%s] API error: %s", p.Name, result.Error.Message)
+		return "", tokenUsage{}, fmt.Errorf("[%s] API error: %s", p.Name, result.Error.Message)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("[%s] no response choices returned", p.Name)
+		return "", tokenUsage{}, fmt.Errorf("[%s] no response choices returned", p.Name)
 	}
 
-	return result.Choices[0].Message.Content, nil
+	usage := tokenUsage{In: result.Usage.PromptTokens, Out: result.Usage.CompletionTokens}
+	if calls := result.Choices[0].Message.ToolCalls; len(calls) > 0 {
+		if call := calls[0].ToolCall(); call != nil {
+			return string(call.Arguments), usage, nil
+		}
+	}
+	return result.Choices[0].Message.Content, usage, nil
+}
+
+// callOllama calls a local Ollama server's chat API with streaming off, so
+// the arena can run offline. Keyless servers get no Authorization header.
+// Tools aren't offered; decisions come back as text.
+func (m *Manager) callOllama(ctx context.Context, p *Provider, prompt string) (string, tokenUsage, error) {
+	prompt = m.formatPromptFor(p, prompt)
+	maxTokens, temperature := p.completionParams()
+
+	baseURL := strings.TrimSuffix(p.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+
+	reqBody := map[string]interface{}{
+		"model": p.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"stream": false,
+		"options": map[string]interface{}{
+			"temperature": temperature,
+			"num_predict": maxTokens,
+		},
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return "", tokenUsage{}, fmt.Errorf("request creation failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", tokenUsage{}, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != 200 {
+		return "", tokenUsage{}, httpError(p.Name, resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Done            bool   `json:"done"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+		Error           string `json:"error"`
+	}
+
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", tokenUsage{}, fmt.Errorf("[%s] JSON parse error: %w", p.Name, err)
+	}
+
+	if result.Error != "" {
+		return "", tokenUsage{}, fmt.Errorf("[%s] API error: %s", p.Name, result.Error)
+	}
+
+	if result.Message.Content == "" {
+		return "", tokenUsage{}, fmt.Errorf("[%s] empty response", p.Name)
+	}
+	return result.Message.Content, tokenUsage{In: result.PromptEvalCount, Out: result.EvalCount}, nil
 }
 
 // callHuggingFace calls HuggingFace Router API with correct format
-func (m *Manager) callHuggingFace(p *Provider, prompt string) (string, error) {
+func (m *Manager) callHuggingFace(ctx context.Context, p *Provider, prompt string, tools ...llm.Tool) (string, tokenUsage, error) {
+	prompt = m.formatPromptFor(p, prompt)
+	maxTokens, temperature := p.completionParams()
 	// HuggingFace Router API - model goes in the body, not URL
 	url := "https://router.huggingface.co/v1/chat/completions"
 
@@ -623,61 +1271,77 @@ func (m *Manager) callHuggingFace(p *Provider, prompt string) (string, error) {
 		"messages": []map[string]string{
 			{"role": "user", "content": prompt},
 		},
-		"max_tokens":  100,
-		"temperature": 0.7,
+		"max_tokens":  maxTokens,
+		"temperature": temperature,
 		"stream":      false,
 	}
+	for k, v := range llm.OpenAIToolFields(tools) {
+		reqBody[k] = v
+	}
 
 	body, _ := json.Marshal(reqBody)
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("request creation failed: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("request creation failed: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("network error: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("network error: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("[huggingface] HTTP %d: %s", resp.StatusCode, truncateError(fmt.Errorf(string(respBody))))
+		return "", tokenUsage{}, httpError("huggingface", resp.StatusCode, respBody)
 	}
 
 	// Parse OpenAI-compatible response
 	var result struct {
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content   string               `json:"content"`
+				ToolCalls []llm.OpenAIToolCall `json:"tool_calls"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
 		Error struct {
 			Message string `json:"message"`
 		} `json:"error"`
 	}
 
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("[huggingface] JSON parse error: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("[huggingface] JSON parse error: %w", err)
 	}
 
 	if result.Error.Message != "" {
-		return "", fmt.Errorf("[huggingface] API error: %s", result.Error.Message)
+		return "", tokenUsage{}, fmt.Errorf("[huggingface] API error: %s", result.Error.Message)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("[huggingface] no response returned")
+		return "", tokenUsage{}, fmt.Errorf("[huggingface] no response returned")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	usage := tokenUsage{In: result.Usage.PromptTokens, Out: result.Usage.CompletionTokens}
+	if calls := result.Choices[0].Message.ToolCalls; len(calls) > 0 {
+		if call := calls[0].ToolCall(); call != nil {
+			return string(call.Arguments), usage, nil
+		}
+	}
+	return result.Choices[0].Message.Content, usage, nil
 }
 
 // callGemini calls Google's Gemini API
-func (m *Manager) callGemini(p *Provider, prompt string) (string, error) {
+func (m *Manager) callGemini(ctx context.Context, p *Provider, prompt string, tools ...llm.Tool) (string, tokenUsage, error) {
+	prompt = m.formatPromptFor(p, prompt)
+	maxTokens, temperature := p.completionParams()
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
 		p.Model, p.APIKey)
 
@@ -690,96 +1354,99 @@ func (m *Manager) callGemini(p *Provider, prompt string) (string, error) {
 			},
 		},
 		"generationConfig": map[string]interface{}{
-			"temperature":     0.7,
-			"maxOutputTokens": 100,
+			"temperature":     temperature,
+			"maxOutputTokens": maxTokens,
 		},
+		"safetySettings": llm.GeminiSafetySettings(p.SafetySettings),
+	}
+	for k, v := range llm.GeminiToolFields(tools) {
+		reqBody[k] = v
 	}
 
 	body, _ := json.Marshal(reqBody)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("request creation failed: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("request creation failed: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("network error: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("network error: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("[gemini] HTTP %d: %s", resp.StatusCode, truncateError(fmt.Errorf(string(respBody))))
+		return "", tokenUsage{}, httpError("gemini", resp.StatusCode, respBody)
 	}
 
 	var result struct {
 		Candidates []struct {
 			Content struct {
 				Parts []struct {
-					Text string `json:"text"`
+					Text         string                  `json:"text"`
+					FunctionCall *llm.GeminiFunctionCall `json:"functionCall"`
 				} `json:"parts"`
 			} `json:"content"`
+			FinishReason string `json:"finishReason"`
 		} `json:"candidates"`
+		PromptFeedback struct {
+			BlockReason string `json:"blockReason"`
+		} `json:"promptFeedback"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
 		Error struct {
 			Message string `json:"message"`
 		} `json:"error"`
 	}
 
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("[gemini] JSON parse error: %w", err)
+		return "", tokenUsage{}, fmt.Errorf("[gemini] JSON parse error: %w", err)
 	}
 
 	if result.Error.Message != "" {
-		return "", fmt.Errorf("[gemini] API error: %s", result.Error.Message)
+		return "", tokenUsage{}, fmt.Errorf("[gemini] API error: %s", result.Error.Message)
+	}
+
+	if result.PromptFeedback.BlockReason != "" ||
+		(len(result.Candidates) > 0 && result.Candidates[0].FinishReason == "SAFETY") {
+		m.recordSafetyBlock(p.Name, prompt)
+		return "", tokenUsage{}, fmt.Errorf("[gemini] %w", llm.ErrSafetyBlocked)
 	}
 
 	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("[gemini] no response returned")
+		return "", tokenUsage{}, fmt.Errorf("[gemini] no response returned")
 	}
 
-	return result.Candidates[0].Content.Parts[0].Text, nil
+	usage := tokenUsage{In: result.UsageMetadata.PromptTokenCount, Out: result.UsageMetadata.CandidatesTokenCount}
+	for _, part := range result.Candidates[0].Content.Parts {
+		if call := part.FunctionCall.ToolCall(); call != nil {
+			return string(call.Arguments), usage, nil
+		}
+	}
+	return result.Candidates[0].Content.Parts[0].Text, usage, nil
 }
 
-func parseActionResponse(response string, obs map[string]interface{}) (map[string]interface{}, error) {
+func parseActionResponse(dec jsonDecoder, response string, obs map[string]interface{}) (map[string]interface{}, error) {
 	var action map[string]interface{}
 
-	start := -1
-	end := -1
-	for i, c := range response {
-		if c == '{' && start == -1 {
-			start = i
-		}
-		if c == '}' {
-			end = i + 1
-		}
+	if dec.decode(response, &action) && action != nil {
+		action["npc_id"] = obs["npc_id"]
+		normalizeMoveTarget(action, obs)
+		// Targets are validated by the world's decision validators (game.ApplyDecision)
+		return action, nil
 	}
 
-	if start >= 0 && end > start {
-		jsonStr := response[start:end]
-		if err := json.Unmarshal([]byte(jsonStr), &action); err == nil {
+	// A model-specific parser gets the next look at non-JSON output
+	if dec.parser != nil {
+		if action, ok := dec.parser.Parse(response, obs); ok && action != nil {
+			dec.record(func(c *ParseCounts) { c.Custom++ })
 			action["npc_id"] = obs["npc_id"]
-
-			// VALIDATION: Fix self-targeting for talk/taunt actions (industry best practice)
-			actionType, _ := action["action"].(string)
-			if actionType == "talk" || actionType == "taunt" {
-				target, _ := action["target"].(string)
-				npcName, _ := obs["name"].(string)
-
-				// Check if targeting self - this is invalid
-				if target == npcName || target == "" {
-					// Auto-correct to first nearby NPC
-					if nearbyNPCs, ok := obs["nearby_npcs"].([]interface{}); ok && len(nearbyNPCs) > 0 {
-						if firstNPC, ok := nearbyNPCs[0].(map[string]interface{}); ok {
-							if validTarget, ok := firstNPC["name"].(string); ok {
-								action["target"] = validTarget
-							}
-						}
-					}
-				}
-			}
-
+			normalizeMoveTarget(action, obs)
 			return action, nil
 		}
 	}
@@ -824,6 +1491,32 @@ func DefaultDecision(obs map[string]interface{}) map[string]interface{} {
 	}
 }
 
+var cannedTaunts = []string{
+	"You'll never catch me!",
+	"Is that the best you've got?",
+	"Too slow!",
+	"Watch and learn!",
+}
+
+// cannedTaunt returns a safe taunt used when a provider's safety filter blocks the real one
+func cannedTaunt(obs map[string]interface{}) map[string]interface{} {
+	target := "opponent"
+	if nearbyNPCs, ok := obs["nearby_npcs"].([]interface{}); ok && len(nearbyNPCs) > 0 {
+		if firstNPC, ok := nearbyNPCs[0].(map[string]interface{}); ok {
+			if name, ok := firstNPC["name"].(string); ok {
+				target = name
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"npc_id":  obs["npc_id"],
+		"action":  "taunt",
+		"target":  target,
+		"message": cannedTaunts[rand.Intn(len(cannedTaunts))],
+	}
+}
+
 func min(a, b float64) float64 {
 	if a < b {
 		return a
@@ -836,7 +1529,9 @@ func min(a, b float64) float64 {
 var promptBuilder = &PromptBuilder{}
 
 // GetEnhancedDecision uses the new context-rich prompts
-func (m *Manager) GetEnhancedDecision(observation map[string]interface{}) (map[string]interface{}, error) {
+func (m *Manager) GetEnhancedDecision(ctx context.Context, observation map[string]interface{}) (map[string]interface{}, error) {
+	ctx = withTools(withNPC(withRole(ctx, "movement"), observation), decideTool())
+
 	npcName := ""
 	if name, ok := observation["name"].(string); ok {
 		npcName = name
@@ -854,7 +1549,7 @@ func (m *Manager) GetEnhancedDecision(observation map[string]interface{}) (map[s
 	prompt := promptBuilder.BuildMovementPrompt(observation)
 	startTime := time.Now()
 
-	response, err := m.callProviderWithRetry(provider, prompt, 2)
+	response, err := m.callProviderWithRetry(ctx, provider, prompt, m.maxRetries)
 	latency := time.Since(startTime).Milliseconds()
 
 	audit := GetAuditLog()
@@ -863,18 +1558,23 @@ func (m *Manager) GetEnhancedDecision(observation map[string]interface{}) (map[s
 		log.Printf("❌ %s [%s] FAILED: %s", npcName, provider.Name, truncateError(err))
 		m.recordError(provider.Name, err)
 		audit.LogError(npcName, provider.Name, provider.Model, "enhanced_prompt", latency, err)
+		if errors.Is(err, llm.ErrSafetyBlocked) {
+			return cannedTaunt(observation), nil
+		}
 		return DefaultDecision(observation), err
 	}
 
 	m.recordSuccess(provider.Name)
 	audit.LogSuccess(npcName, provider.Name, provider.Model, "enhanced_prompt", response, latency)
 
-	return parseActionResponse(response, observation)
+	return parseActionResponse(m.decoderFor(provider), response, observation)
 }
 
 // GetBatchDecision makes a single LLM call for multiple NPCs on the same team
 // This reduces API calls from 4 per tick to 2 per tick
 func (m *Manager) GetBatchDecision(observations []map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx := withRole(context.Background(), "team_batch")
+
 	if len(observations) == 0 {
 		return nil, nil
 	}
@@ -908,7 +1608,7 @@ func (m *Manager) GetBatchDecision(observations []map[string]interface{}) ([]map
 	prompt := promptBuilder.BuildBatchPrompt(observations)
 	startTime := time.Now()
 
-	response, err := m.callProviderWithRetry(provider, prompt, 2)
+	response, err := m.callProviderWithRetry(ctx, provider, prompt, m.maxRetries)
 	latency := time.Since(startTime).Milliseconds()
 
 	audit := GetAuditLog()
@@ -930,48 +1630,75 @@ func (m *Manager) GetBatchDecision(observations []map[string]interface{}) ([]map
 	m.recordSuccess(provider.Name)
 	audit.LogSuccess("batch_"+teamName, provider.Name, provider.Model, "batch_prompt", response, latency)
 
-	return parseBatchResponse(response, observations)
+	return parseBatchResponse(m.decoderFor(provider), response, observations)
 }
 
-// JudgeChallenge uses Gemini to evaluate challenge responses
+// JudgeChallenge evaluates challenge responses with the fastest recently
+// successful brain provider. If judging misses the judge deadline, the
+// rule-based simpleJudge decides instead so the gate result isn't held up.
+// Debates have no rule-based judgment: without a usable brain verdict the
+// result is nil and the challenge manager's own heuristic decides.
 func (m *Manager) JudgeChallenge(challenge, responses map[string]interface{}) (map[string]interface{}, error) {
-	if m.activeBrain == nil {
+	// The deadline also cancels the call itself, so a slow judge doesn't
+	// keep its connection and retries going after the simple judge answered
+	ctx, cancel := context.WithTimeout(withRole(context.Background(), "judge"), m.judgeDeadline)
+	defer cancel()
+
+	provider := m.roleProvider("judge", m.fastestBrain())
+	if provider == nil {
 		// Fallback to simple matching
 		return simpleJudge(challenge, responses), nil
 	}
 
-	m.rateLimiter.Wait(1)
-	m.throttle()
-
-	prompt := promptBuilder.BuildJudgePrompt(challenge, responses)
+	type judgment struct {
+		response string
+		err      error
+	}
+	done := make(chan judgment, 1)
 	startTime := time.Now()
 
-	var response string
-	var err error
+	go func() {
+		m.rateLimiter.Wait(1)
+		m.throttle()
 
-	if m.activeBrain.Name == "gemini" {
-		response, err = m.callGeminiWithRetry(m.activeBrain, prompt, 2)
-	} else {
-		response, err = m.callProviderWithRetry(m.activeBrain, prompt, 2)
+		prompt := promptBuilder.BuildJudgePrompt(challenge, responses)
+		var j judgment
+		if provider.Name == "gemini" {
+			j.response, j.err = m.callGeminiWithRetry(ctx, provider, prompt, m.maxRetries)
+		} else {
+			j.response, j.err = m.callProviderWithRetry(ctx, provider, prompt, m.maxRetries)
+		}
+		done <- j
+	}()
+
+	var j judgment
+	select {
+	case j = <-done:
+	case <-ctx.Done():
+		log.Printf("⏱️ Judge [%s] missed %v deadline, using simple judge", provider.Name, m.judgeDeadline)
+		return simpleJudge(challenge, responses), fmt.Errorf("judge deadline %v exceeded", m.judgeDeadline)
 	}
 
 	latency := time.Since(startTime).Milliseconds()
 
-	if err != nil {
-		log.Printf("❌ Judge [%s] FAILED: %s", m.activeBrain.Name, truncateError(err))
-		m.recordError(m.activeBrain.Name, err)
-		return simpleJudge(challenge, responses), err
+	if j.err != nil {
+		log.Printf("❌ Judge [%s] FAILED: %s", provider.Name, truncateError(j.err))
+		m.recordError(provider.Name, j.err)
+		return simpleJudge(challenge, responses), j.err
 	}
 
-	log.Printf("✅ Judge [%s] OK in %dms", m.activeBrain.Name, latency)
-	m.recordSuccess(m.activeBrain.Name)
+	log.Printf("✅ Judge [%s] OK in %dms", provider.Name, latency)
+	m.recordSuccess(provider.Name)
 
-	return parseJudgeResponse(response, challenge, responses)
+	return parseJudgeResponse(m.decoderFor(provider), j.response, challenge, responses)
 }
 
 // GetCommentary generates exciting play-by-play commentary
 func (m *Manager) GetCommentary(events []map[string]interface{}, scores map[string]int) (string, error) {
-	if m.activeBrain == nil {
+	ctx := withRole(context.Background(), "commentary")
+
+	brain := m.roleProvider("commentary", m.activeBrain)
+	if brain == nil {
 		return "The game continues...", nil
 	}
 
@@ -983,10 +1710,10 @@ func (m *Manager) GetCommentary(events []map[string]interface{}, scores map[stri
 	var response string
 	var err error
 
-	if m.activeBrain.Name == "gemini" {
-		response, err = m.callGeminiWithRetry(m.activeBrain, prompt, 1)
+	if brain.Name == "gemini" {
+		response, err = m.callGeminiWithRetry(ctx, brain, prompt, m.fallbackRetries)
 	} else {
-		response, err = m.callProviderWithRetry(m.activeBrain, prompt, 1)
+		response, err = m.callProviderWithRetry(ctx, brain, prompt, m.fallbackRetries)
 	}
 
 	if err != nil {
@@ -1001,55 +1728,51 @@ func (m *Manager) GetCommentary(events []map[string]interface{}, scores map[stri
 }
 
 // parseBatchResponse extracts individual decisions from a batch LLM response
-func parseBatchResponse(response string, observations []map[string]interface{}) ([]map[string]interface{}, error) {
-	// Try to find JSON in response
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-
-	if start >= 0 && end > start {
-		jsonStr := response[start : end+1]
-
-		var parsed struct {
-			Decisions []struct {
-				NPC    string      `json:"npc"`
-				Action string      `json:"action"`
-				Target interface{} `json:"target"`
-				Reason string      `json:"reason"`
-			} `json:"decisions"`
-			Strategy string `json:"strategy"`
-		}
-
-		if err := json.Unmarshal([]byte(jsonStr), &parsed); err == nil {
-			results := make([]map[string]interface{}, len(observations))
+func parseBatchResponse(dec jsonDecoder, response string, observations []map[string]interface{}) ([]map[string]interface{}, error) {
+	var parsed struct {
+		Decisions []struct {
+			NPC    string      `json:"npc"`
+			Action string      `json:"action"`
+			Target interface{} `json:"target"`
+			Reason string      `json:"reason"`
+			Goal   string      `json:"goal"`
+		} `json:"decisions"`
+		Strategy string `json:"strategy"`
+	}
+
+	if dec.decode(response, &parsed) {
+		results := make([]map[string]interface{}, len(observations))
 
-			for i, obs := range observations {
-				npcName := ""
-				if name, ok := obs["name"].(string); ok {
-					npcName = name
-				}
+		for i, obs := range observations {
+			npcName := ""
+			if name, ok := obs["name"].(string); ok {
+				npcName = name
+			}
 
-				// Find matching decision
-				found := false
-				for _, dec := range parsed.Decisions {
-					if dec.NPC == npcName {
-						results[i] = map[string]interface{}{
-							"npc_id": obs["npc_id"],
-							"action": dec.Action,
-							"target": dec.Target,
-							"reason": dec.Reason,
-						}
-						found = true
-						break
+			// Find matching decision
+			found := false
+			for _, decision := range parsed.Decisions {
+				if decision.NPC == npcName {
+					results[i] = map[string]interface{}{
+						"npc_id": obs["npc_id"],
+						"action": decision.Action,
+						"target": decision.Target,
+						"reason": decision.Reason,
 					}
-				}
-
-				if !found {
-					results[i] = DefaultDecision(obs)
+					if decision.Goal != "" {
+						results[i]["goal"] = decision.Goal
+					}
+					found = true
+					break
 				}
 			}
 
-			return results, nil
+			if !found {
+				results[i] = DefaultDecision(obs)
+			}
 		}
+
+		return results, nil
 	}
 
 	// Fallback to defaults
@@ -1061,26 +1784,24 @@ func parseBatchResponse(response string, observations []map[string]interface{})
 }
 
 // parseJudgeResponse extracts judgment from LLM response
-func parseJudgeResponse(response string, challenge, responses map[string]interface{}) (map[string]interface{}, error) {
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-
-	if start >= 0 && end > start {
-		jsonStr := response[start : end+1]
-
-		var parsed struct {
-			Correct  bool    `json:"correct"`
-			Feedback string  `json:"feedback"`
-			Score    float64 `json:"score"`
+func parseJudgeResponse(dec jsonDecoder, response string, challenge, responses map[string]interface{}) (map[string]interface{}, error) {
+	var parsed struct {
+		Correct  bool    `json:"correct"`
+		Feedback string  `json:"feedback"`
+		Score    float64 `json:"score"`
+		Winner   string  `json:"winner"` // Debates only
+	}
+
+	if dec.decode(response, &parsed) {
+		judgment := map[string]interface{}{
+			"correct":  parsed.Correct,
+			"feedback": parsed.Feedback,
+			"score":    parsed.Score,
 		}
-
-		if err := json.Unmarshal([]byte(jsonStr), &parsed); err == nil {
-			return map[string]interface{}{
-				"correct":  parsed.Correct,
-				"feedback": parsed.Feedback,
-				"score":    parsed.Score,
-			}, nil
+		if parsed.Winner != "" {
+			judgment["winner"] = parsed.Winner
 		}
+		return judgment, nil
 	}
 
 	// Fallback to simple judge
@@ -1134,6 +1855,10 @@ func simpleJudge(challenge, responses map[string]interface{}) map[string]interfa
 			"feedback": "Incorrect answer",
 			"score":    0.0,
 		}
+
+	case "debate":
+		// Arguments can't be matched; the challenge manager scores them
+		return nil
 	}
 
 	// Default: success
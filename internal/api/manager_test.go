@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestReadiness_FollowsLatestProviderOutcome(t *testing.T) {
+	m := NewManager(config.Default())
+	m.slmProviders = []Provider{{Name: "a"}, {Name: "b"}}
+	m.brainProviders = nil
+
+	if ready, states := m.Readiness(); ready || states["a"] != "untested" {
+		t.Fatalf("before any call: ready = %v, states = %v", ready, states)
+	}
+
+	m.recordError("a", errors.New("503"))
+	m.recordLatency("b", time.Second)
+	if ready, states := m.Readiness(); !ready || states["a"] != "failing" || states["b"] != "ok" {
+		t.Errorf("one provider up: ready = %v, states = %v", ready, states)
+	}
+
+	m.recordError("b", errors.New("timeout"))
+	if ready, states := m.Readiness(); ready || states["b"] != "failing" {
+		t.Errorf("all failing: ready = %v, states = %v", ready, states)
+	}
+
+	// Demo mode has nothing to wait for
+	m.slmProviders = nil
+	if ready, _ := m.Readiness(); !ready {
+		t.Error("demo mode not ready")
+	}
+}
+
+func TestJudgeChallenge_DeadlineCancelsTheCall(t *testing.T) {
+	canceled := make(chan struct{})
+	m := newTestManager(t)
+	m.judgeDeadline = 50 * time.Millisecond
+	m.brainProviders = []Provider{mockProvider(t, "slow", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done() // Never answers; returns once the client gives up
+		close(canceled)
+	})}
+	m.activeBrain = &m.brainProviders[0]
+
+	challenge := map[string]interface{}{"type": "coordination", "prompt": "Pick a color"}
+	responses := map[string]interface{}{"Explorer": "RED", "Scout": "RED"}
+	if _, err := m.JudgeChallenge(challenge, responses); err == nil {
+		t.Fatal("JudgeChallenge past its deadline returned no error")
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Error("the judge call was still open after the deadline")
+	}
+}
+
+func TestRateLimiter_WaitCountsQueueingBehindSleepers(t *testing.T) {
+	quietLogs(t)
+
+	// One token, refilled every 100ms: the first caller goes straight
+	// through, the second sleeps 100ms and the third queues behind it
+	// before sleeping its own 100ms
+	limiter := NewRateLimiter(1, 10)
+	limiter.Wait(1)
+	done := make(chan struct{})
+	go func() {
+		limiter.Wait(1)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond) // Let the second caller take the lock
+	limiter.Wait(1)
+	<-done
+
+	stats := limiter.Stats()
+	if stats.Calls != 3 || stats.Throttled != 2 {
+		t.Errorf("stats = %+v, want 3 calls, 2 throttled", stats)
+	}
+	if stats.TotalWaitMs < 250 {
+		t.Errorf("total wait = %dms, want the third caller's queueing counted (about 280ms)", stats.TotalWaitMs)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/amit/npc/internal/llm"
+)
+
+// isModelNotFound reports whether an HTTP error response means the configured
+// model name is wrong or retired, going by each provider's error shape:
+//   - OpenAI-compatible (Groq, OpenRouter, ...): 404 or 400 with
+//     "model_not_found", "does not exist", "not a valid model" or "decommissioned"
+//   - HuggingFace router: 400/404 "model ... not supported" or "not found"
+//   - Gemini: 404 NOT_FOUND "models/<name> is not found"
+func isModelNotFound(provider string, status int, body string) bool {
+	if status != 400 && status != 404 {
+		return false
+	}
+	body = strings.ToLower(body)
+	if strings.Contains(body, "model_not_found") {
+		return true
+	}
+	if !strings.Contains(body, "model") {
+		return false
+	}
+
+	switch provider {
+	case "gemini":
+		return status == 404 || strings.Contains(body, "not_found") || strings.Contains(body, "is not found")
+	case "huggingface":
+		return strings.Contains(body, "not supported") || strings.Contains(body, "not found") ||
+			strings.Contains(body, "does not exist")
+	default:
+		return strings.Contains(body, "does not exist") || strings.Contains(body, "not found") ||
+			strings.Contains(body, "decommissioned") || strings.Contains(body, "invalid model") ||
+			strings.Contains(body, "not a valid model")
+	}
+}
+
+// httpError builds the error for a non-200 provider response, wrapping
+// llm.ErrModelNotFound when the model name is the problem
+func httpError(provider string, status int, body []byte) error {
+	detail := truncateError(errors.New(string(body)))
+	if isModelNotFound(provider, status, string(body)) {
+		return fmt.Errorf("[%s] HTTP %d: %w: %s", provider, status, llm.ErrModelNotFound, detail)
+	}
+	return fmt.Errorf("[%s] HTTP %d: %s", provider, status, detail)
+}
+
+// markBadModel records that p's model doesn't exist, logging a warning the
+// first time only
+func (m *Manager) markBadModel(p *Provider, err error) {
+	key := p.Name + "/" + p.Model
+
+	m.mu.Lock()
+	seen := m.badModels[key]
+	m.badModels[key] = true
+	m.mu.Unlock()
+
+	if seen {
+		return
+	}
+	log.Printf("🚨🚨 [%s] MODEL %q NOT FOUND - check the model name in config.yaml (%v)", p.Name, p.Model, err)
+	if p.FallbackModel != "" && p.FallbackModel != p.Model {
+		log.Printf("🚨 [%s] Falling back to %q for the rest of this run", p.Name, p.FallbackModel)
+	}
+}
+
+// badModelList returns the "provider/model" keys reported as not found
+func (m *Manager) badModelList() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	models := make([]string, 0, len(m.badModels))
+	for key := range m.badModels {
+		models = append(models, key)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// usableModel returns the provider to call for p: p itself, or a copy using
+// its fallback model once p's model is known to be bad. Returns an error
+// without calling the API when the model is bad and there is no fallback.
+func (m *Manager) usableModel(p *Provider) (*Provider, error) {
+	m.mu.Lock()
+	bad := m.badModels[p.Name+"/"+p.Model]
+	m.mu.Unlock()
+
+	if !bad {
+		return p, nil
+	}
+	if p.FallbackModel == "" || p.FallbackModel == p.Model {
+		return nil, fmt.Errorf("[%s] %w: %s", p.Name, llm.ErrModelNotFound, p.Model)
+	}
+	fallback := *p
+	fallback.Model = p.FallbackModel
+
+	m.mu.Lock()
+	bad = m.badModels[fallback.Name+"/"+fallback.Model]
+	m.mu.Unlock()
+	if bad {
+		return nil, fmt.Errorf("[%s] %w: %s (and fallback %s)", p.Name, llm.ErrModelNotFound, p.Model, fallback.Model)
+	}
+	return &fallback, nil
+}
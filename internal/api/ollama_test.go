@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestNewManager_LoadsKeylessLocalProvider(t *testing.T) {
+	quietLogs(t)
+	t.Setenv("GROQ_API_KEY", "")
+
+	var auth string
+	var req struct {
+		Model   string                 `json:"model"`
+		Stream  bool                   `json:"stream"`
+		Options map[string]interface{} `json:"options"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"ok"},"done":true}`))
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.SLMProviders = []config.ProviderConfig{
+		{Name: "groq", Enabled: true, BaseURL: "https://api.groq.com/openai/v1", Model: "llama-3.1-8b-instant"},
+		{Name: "ollama", Enabled: true, BaseURL: server.URL, Model: "llama3.2"},
+	}
+	m := NewManager(cfg)
+
+	if len(m.slmProviders) != 1 || m.slmProviders[0].Name != "ollama" {
+		t.Fatalf("loaded %+v, want only the keyless local provider", m.slmProviders)
+	}
+	response, _, _, err := m.callProvider(context.Background(), &m.slmProviders[0], "Say ok")
+	if err != nil || response != "ok" {
+		t.Fatalf("callProvider = %q, %v", response, err)
+	}
+	if auth != "" {
+		t.Errorf("Authorization = %q, want none for a keyless provider", auth)
+	}
+	if req.Model != "llama3.2" || req.Stream || req.Options["num_predict"] != float64(defaultMaxTokens) {
+		t.Errorf("request = %+v", req)
+	}
+}
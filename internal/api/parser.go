@@ -0,0 +1,87 @@
+package api
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ResponseParser turns a completion that isn't usable JSON into a decision,
+// for models that never reliably produce JSON. Parse returns false when it
+// can't make sense of the response either.
+type ResponseParser interface {
+	Parse(response string, obs map[string]interface{}) (map[string]interface{}, bool)
+}
+
+// ResponseParserFunc adapts a function to ResponseParser
+type ResponseParserFunc func(response string, obs map[string]interface{}) (map[string]interface{}, bool)
+
+// Parse calls f
+func (f ResponseParserFunc) Parse(response string, obs map[string]interface{}) (map[string]interface{}, bool) {
+	return f(response, obs)
+}
+
+// RegisterResponseParser sets the parser used for model's decisions when JSON
+// decoding fails (nil removes it). Without one the usual text fallbacks apply.
+func (m *Manager) RegisterResponseParser(model string, p ResponseParser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p == nil {
+		delete(m.parsers, model)
+		return
+	}
+	if m.parsers == nil {
+		m.parsers = make(map[string]ResponseParser)
+	}
+	m.parsers[model] = p
+}
+
+// parserFor returns the parser registered for a model, if any
+func (m *Manager) parserFor(model string) ResponseParser {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.parsers[model]
+}
+
+// RegexRule maps completions matching Pattern to Action. The named groups
+// "target", "message", "x" and "y" fill in the decision's target ([x, y]
+// when both coordinates match) and message.
+type RegexRule struct {
+	Pattern *regexp.Regexp
+	Action  string
+}
+
+// RegexParser is a ResponseParser that tries its rules in order and uses the
+// first match, e.g. `(?i)move to \(?(?P<x>\d+),\s*(?P<y>\d+)` for "move".
+type RegexParser []RegexRule
+
+// Parse implements ResponseParser
+func (rp RegexParser) Parse(response string, obs map[string]interface{}) (map[string]interface{}, bool) {
+	for _, rule := range rp {
+		m := rule.Pattern.FindStringSubmatch(response)
+		if m == nil {
+			continue
+		}
+		groups := make(map[string]string)
+		for i, name := range rule.Pattern.SubexpNames() {
+			if name != "" && m[i] != "" {
+				groups[name] = strings.TrimSpace(m[i])
+			}
+		}
+
+		decision := map[string]interface{}{"action": rule.Action, "reason": "parsed from text"}
+		x, errX := strconv.ParseFloat(groups["x"], 64)
+		y, errY := strconv.ParseFloat(groups["y"], 64)
+		switch {
+		case errX == nil && errY == nil:
+			decision["target"] = []interface{}{x, y}
+		case groups["target"] != "":
+			decision["target"] = groups["target"]
+		}
+		if msg := groups["message"]; msg != "" {
+			decision["message"] = msg
+		}
+		return decision, true
+	}
+	return nil, false
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestParseActionResponse_RegisteredParser(t *testing.T) {
+	obs := testObservation("npc_0", "Explorer", "red", 300, 200)
+	parser := RegexParser{
+		{Pattern: regexp.MustCompile(`(?i)move to \(?(?P<x>\d+),\s*(?P<y>\d+)`), Action: "move"},
+		{Pattern: regexp.MustCompile(`(?i)open (?P<target>gate_\w+)`), Action: "challenge"},
+	}
+	dec := jsonDecoder{stats: NewParseStats(), key: "tiny/tiny-1b", parser: parser}
+
+	decision, _ := parseActionResponse(dec, "I will move to (420, 260) now", obs)
+	if target, _ := decision["target"].([]interface{}); decision["action"] != "move" || len(target) != 2 || target[0] != 420.0 || target[1] != 260.0 {
+		t.Errorf("move: got %v", decision)
+	}
+	decision, _ = parseActionResponse(dec, "Let's open gate_1_2!", obs)
+	if decision["action"] != "challenge" || decision["target"] != "gate_1_2" || decision["npc_id"] != "npc_0" {
+		t.Errorf("challenge: got %v", decision)
+	}
+
+	// JSON still wins, and unmatched text falls through to the usual fallback
+	decision, _ = parseActionResponse(dec, `{"action":"wait","reason":"resting"}`, obs)
+	if decision["action"] != "wait" {
+		t.Errorf("JSON response: got %v", decision)
+	}
+	decision, _ = parseActionResponse(dec, "You'll never catch me!", obs)
+	if decision["action"] != "taunt" {
+		t.Errorf("unmatched text: action = %v, want the taunt fallback", decision["action"])
+	}
+
+	counts := dec.stats.Snapshot()["tiny/tiny-1b"].(map[string]interface{})
+	if counts["custom"] != 2 {
+		t.Errorf("custom parses = %v, want 2", counts["custom"])
+	}
+}
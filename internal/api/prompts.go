@@ -4,6 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/observability"
 )
 
 // PromptRole defines the type of LLM task
@@ -17,11 +22,56 @@ const (
 	RoleStrategy   PromptRole = "strategy"
 )
 
-// PromptBuilder creates well-structured prompts using proven techniques
-type PromptBuilder struct{}
+// PromptBuilder creates well-structured prompts using proven techniques.
+// Any role with a loaded template (see LoadPromptTemplates) uses it instead.
+type PromptBuilder struct {
+	templates map[PromptRole]*template.Template
+	radii     PromptRadii
+}
+
+// PromptRadii are the distances at which prompts call out nearby NPCs and gates
+type PromptRadii struct {
+	Opponent float64 // Opponent close enough to react to (taunt/talk)
+	Teammate float64 // Teammate close enough to coordinate with
+	Gate     float64 // Gates worth prioritizing in batch tips
+}
+
+// DefaultPromptRadii are used for any radius left unset
+var DefaultPromptRadii = PromptRadii{Opponent: 80, Teammate: 100, Gate: 150}
+
+// withDefaults fills unset radii from DefaultPromptRadii
+func (r PromptRadii) withDefaults() PromptRadii {
+	if r.Opponent <= 0 {
+		r.Opponent = DefaultPromptRadii.Opponent
+	}
+	if r.Teammate <= 0 {
+		r.Teammate = DefaultPromptRadii.Teammate
+	}
+	if r.Gate <= 0 {
+		r.Gate = DefaultPromptRadii.Gate
+	}
+	return r
+}
+
+// awarenessRadii returns the builder's radii with defaults filled in
+func (pb *PromptBuilder) awarenessRadii() PromptRadii {
+	if pb == nil {
+		return DefaultPromptRadii
+	}
+	return pb.radii.withDefaults()
+}
+
+// SetPromptRadii sets the shared prompt builder's awareness radii
+func SetPromptRadii(r PromptRadii) {
+	promptBuilder.radii = r.withDefaults()
+}
 
 // BuildMovementPrompt creates a context-rich prompt for NPC movement decisions
 func (pb *PromptBuilder) BuildMovementPrompt(obs map[string]interface{}) string {
+	if out, ok := pb.render(RoleMovement, obs); ok {
+		return out
+	}
+
 	name := getString(obs, "name")
 	team := getString(obs, "team")
 	pos := getArray(obs, "pos")
@@ -34,6 +84,8 @@ func (pb *PromptBuilder) BuildMovementPrompt(obs map[string]interface{}) string
 	nearbyNPCs := getArrayOfMaps(obs, "nearby_npcs")
 	nearbyGates := getArrayOfMaps(obs, "nearby_gates")
 
+	radii := pb.awarenessRadii()
+
 	var sb strings.Builder
 
 	// PERSONALITY based on name
@@ -54,6 +106,9 @@ Personality: %s
 
 YOUR POSITION: (%d, %d), Energy: %d%%
 `, name, strings.ToUpper(team), myPersonality, myX, myY, energy))
+	if getBool(obs, "out_of_bounds") {
+		sb.WriteString("⚠️ You drifted off the map and were pulled back to the edge - move toward the middle of your zone.\n")
+	}
 
 	// Find teammates and opponents
 	var teammate map[string]interface{}
@@ -63,12 +118,12 @@ YOUR POSITION: (%d, %d), Energy: %d%%
 	for _, npc := range nearbyNPCs {
 		if getBool(npc, "isTeammate") {
 			teammate = npc
-			if getFloat(npc, "distance") < 100 {
+			if getFloat(npc, "distance") < radii.Teammate {
 				teammateNear = true
 			}
 		} else {
 			opponents = append(opponents, npc)
-			if getFloat(npc, "distance") < 80 {
+			if getFloat(npc, "distance") < radii.Opponent {
 				opponentNear = true
 			}
 		}
@@ -93,10 +148,10 @@ YOUR POSITION: (%d, %d), Energy: %d%%
 			oppName := getString(opp, "name")
 			oppDist := getFloat(opp, "distance")
 			oppState := getString(opp, "state")
-			if oppDist < 80 {
-				sb.WriteString(fmt.Sprintf("� %s is RIGHT NEXT TO YOU (%.0f units, %s) - SAY SOMETHING!\n", oppName, oppDist, oppState))
+			if oppDist < radii.Opponent {
+				sb.WriteString(fmt.Sprintf("� %s is RIGHT NEXT TO YOU (%.0f units, %s)%s - SAY SOMETHING!\n", oppName, oppDist, oppState, describeApproach(opp)))
 			} else {
-				sb.WriteString(fmt.Sprintf("- %s: %.0f units away, %s\n", oppName, oppDist, oppState))
+				sb.WriteString(fmt.Sprintf("- %s: %.0f units away, %s%s\n", oppName, oppDist, oppState, describeApproach(opp)))
 			}
 		}
 	}
@@ -129,10 +184,32 @@ YOUR POSITION: (%d, %d), Energy: %d%%
 			if getBool(gate, "requiresTeamwork") {
 				tw = " [2-PLAYER]"
 			}
-			sb.WriteString(fmt.Sprintf("- %s: %.0f units%s\n", gateID, dist, tw))
+			sb.WriteString(fmt.Sprintf("- %s: %.0f units%s%s\n", gateID, dist, tw, describeChallengePreview(gate)))
+		}
+	}
+
+	// OBJECTS
+	if objects := describeNearbyObjects(obs); len(objects) > 0 {
+		sb.WriteString("\n## NEARBY OBJECTS (interact within 60 units)\n")
+		for _, obj := range objects {
+			sb.WriteString("- " + obj + "\n")
 		}
 	}
 
+	if feedback := getString(obs, "last_feedback"); feedback != "" {
+		sb.WriteString(fmt.Sprintf("\n⚠️ LAST MOVE: %s\n", feedback))
+	}
+
+	if goal := getString(obs, "goal"); goal != "" {
+		sb.WriteString(fmt.Sprintf("\n🧭 YOUR CURRENT GOAL: %s\nStick with it unless it's done or impossible - don't switch targets every turn.\n", goal))
+	} else {
+		sb.WriteString("\n🧭 No current goal. Pick one and add \"goal\" to your JSON (e.g. \"reach gate_1_2\") so you stay on course.\n")
+	}
+
+	if directive := getString(obs, "team_strategy"); directive != "" {
+		sb.WriteString(fmt.Sprintf("\n🎯 TEAM ORDERS: %s\n", directive))
+	}
+
 	// DECISION GUIDANCE
 	sb.WriteString("\n## WHAT SHOULD YOU DO?\n")
 
@@ -147,7 +224,7 @@ OPTIONS:
 	}
 
 	// Priority 2: Teammate coordination
-	if teammateNear && closestGate != nil && needsTeamwork && closestDist < 100 {
+	if teammateNear && closestGate != nil && needsTeamwork && closestDist < radii.Teammate {
 		gateID := getString(closestGate, "id")
 		sb.WriteString(fmt.Sprintf(`
 👥 Your teammate is here and gate %s needs 2 players!
@@ -159,12 +236,23 @@ Talk to your teammate or start the challenge together!
 	if closestGate != nil && closestDist < 60 {
 		gateID := getString(closestGate, "id")
 		sb.WriteString(fmt.Sprintf("🔒 You're at gate %s! Attempt the challenge.\n", gateID))
+		if secs := getInt(closestGate, "seconds_remaining"); secs > 0 {
+			sb.WriteString(fmt.Sprintf("⏳ A challenge attempt here ends in %ds - join it now or it's lost!\n", secs))
+		}
 	} else if closestGate != nil {
 		gateID := getString(closestGate, "id")
 		sb.WriteString(fmt.Sprintf("→ Move toward gate %s (%.0f units)\n", gateID, closestDist))
 	}
 
-	sb.WriteString(fmt.Sprintf("\nYour secret code: %s (for memory challenges)\n", memoryCode))
+	if memoryCode != "" {
+		sb.WriteString(fmt.Sprintf("\nYour secret code: %s (for memory challenges)\n", memoryCode))
+	}
+	if messages := describeMessages(obs); len(messages) > 0 {
+		sb.WriteString("\n📨 RECENT MESSAGES:\n")
+		for _, m := range messages {
+			sb.WriteString(fmt.Sprintf("- %s\n", m))
+		}
+	}
 
 	// Build explicit valid targets list (industry best practice: constrained generation)
 	var validTargets []string
@@ -181,19 +269,17 @@ Talk to your teammate or start the challenge together!
 	}
 
 	// OUTPUT FORMAT with social actions
-	sb.WriteString(`
-## OUTPUT (JSON only)
-EXAMPLES:
-{"action": "move", "target": [400, 200], "reason": "heading to gate"}
-{"action": "challenge", "target": "gate_1_2", "reason": "solving puzzle"}
-{"action": "talk", "target": "Scout", "message": "Let's team up!"}
-{"action": "taunt", "target": "Wanderer", "message": "You're too slow!"}
-{"action": "wait", "target": null, "reason": "waiting for teammate"}
+	sb.WriteString("\n## OUTPUT (JSON only)\nEXAMPLES:\n")
+	for _, spec := range game.ActionRegistry {
+		sb.WriteString(spec.Example + "\n")
+	}
+	sb.WriteString(`{"action":"move","target":[400,200],"goal":"reach gate_1_2","reason":"heading to gate"}
 
 RULES:
 - Use REAL numbers in target, NOT expressions like [x+100, y-50]
 - For talk/taunt, target must be someone ELSE - never yourself!
 - Keep messages short and punchy
+- Only include "goal" when setting or changing your goal deliberately
 `)
 
 	return sb.String()
@@ -201,6 +287,10 @@ RULES:
 
 // BuildChallengePrompt creates a prompt for solving a challenge
 func (pb *PromptBuilder) BuildChallengePrompt(challenge map[string]interface{}, npcContext map[string]interface{}) string {
+	if out, ok := pb.render(RoleChallenge, map[string]interface{}{"challenge": challenge, "npc": npcContext}); ok {
+		return out
+	}
+
 	challengeType := getString(challenge, "type")
 	prompt := getString(challenge, "prompt")
 	options := getStringArray(challenge, "options")
@@ -224,13 +314,41 @@ You are %s (Team %s), attempting to solve a challenge.
 
 `, strings.ToUpper(challengeType), prompt))
 
+	// What only this NPC was shown (a clue half, an encoding role)
+	if private := getString(challenge, "private"); private != "" {
+		sb.WriteString(fmt.Sprintf(`# ONLY YOU KNOW
+%s
+
+`, private))
+	}
+
+	if secs := getInt(challenge, "seconds_remaining"); secs > 0 {
+		sb.WriteString(fmt.Sprintf(`# TIME LEFT: %d seconds
+Answer before time runs out: a quick answer beats a perfect one that's too late.
+
+`, secs))
+	}
+
 	// Add memory hint for memory challenges
-	if challengeType == "memory" {
+	if challengeType == "memory" && memoryCode != "" {
 		sb.WriteString(fmt.Sprintf(`# HINT
 You were given a code earlier: %s
 Use this to solve the challenge.
 
 `, memoryCode))
+	} else if challengeType == "memory" {
+		sb.WriteString(`# HINT
+You were sent a secret code at the start of the game. Check your messages and recall it exactly.
+
+`)
+	}
+
+	if messages := describeMessages(npcContext); len(messages) > 0 {
+		sb.WriteString("# YOUR MESSAGES\n")
+		for _, m := range messages {
+			sb.WriteString(fmt.Sprintf("- %s\n", m))
+		}
+		sb.WriteString("\n")
 	}
 
 	// Options if available
@@ -258,6 +376,10 @@ Think step by step:
 
 // BuildJudgePrompt creates a prompt for evaluating challenge responses
 func (pb *PromptBuilder) BuildJudgePrompt(challenge, responses map[string]interface{}) string {
+	if out, ok := pb.render(RoleJudge, map[string]interface{}{"challenge": challenge, "responses": responses}); ok {
+		return out
+	}
+
 	challengeType := getString(challenge, "type")
 	prompt := getString(challenge, "prompt")
 	solution := getString(challenge, "solution")
@@ -304,6 +426,20 @@ Challenge: %s
 
 	sb.WriteString(fmt.Sprintf("Requires Teamwork: %v\n\n", requiresTeamwork))
 
+	debate := challengeType == "debate"
+	if debate {
+		rubric := getString(challenge, "rubric")
+		if rubric == "" {
+			rubric = "Clear position, sound reasoning, supporting evidence"
+		}
+		sb.WriteString(fmt.Sprintf(`# DEBATE RUBRIC
+Score each argument against: %s
+"correct" is true if the arguments are convincing overall; "score" is the
+team's average; "winner" is the name of the participant who argued best.
+
+`, rubric))
+	}
+
 	// Responses
 	sb.WriteString("## Responses Received\n")
 	responsesJSON, _ := json.MarshalIndent(responses, "", "  ")
@@ -311,6 +447,12 @@ Challenge: %s
 	sb.WriteString("\n\n")
 
 	// OUTPUT
+	if debate {
+		sb.WriteString(`# OUTPUT FORMAT (JSON only)
+{"correct": true/false, "feedback": "brief explanation", "score": 0.0-1.0, "winner": "participant name"}
+`)
+		return sb.String()
+	}
 	sb.WriteString(`# OUTPUT FORMAT (JSON only)
 {"correct": true/false, "feedback": "brief explanation", "score": 0.0-1.0}
 `)
@@ -320,6 +462,10 @@ Challenge: %s
 
 // BuildCommentaryPrompt creates a prompt for generating play-by-play commentary
 func (pb *PromptBuilder) BuildCommentaryPrompt(events []map[string]interface{}, scores map[string]int) string {
+	if out, ok := pb.render(RoleCommentary, map[string]interface{}{"events": events, "scores": scores}); ok {
+		return out
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString(`# ROLE
@@ -352,11 +498,54 @@ Example outputs:
 	return sb.String()
 }
 
+// BuildMatchSummaryPrompt creates a prompt for the post-match recap from the
+// final results and the match's key events (oldest first)
+func (pb *PromptBuilder) BuildMatchSummaryPrompt(results game.MatchResults, events []observability.AuditEntry) string {
+	if out, ok := pb.render(RoleMatchSummary, map[string]interface{}{"results": results, "events": events}); ok {
+		return out
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(`# ROLE
+You are a sports journalist writing the recap of an AI arena match,
+where teams of NPCs solve challenges to unlock gates and claim zones.
+
+`)
+
+	sb.WriteString("# FINAL RESULT\n")
+	sb.WriteString(describeOutcome(results) + "\n")
+	for _, team := range results.Teams {
+		sb.WriteString(fmt.Sprintf("- %s: %d points, %d challenges solved, %d failed, %d zones unlocked, best streak %d\n",
+			team.Name, team.Score, team.ChallengesSolved, team.ChallengesFailed, team.ZonesUnlocked, team.BestStreak))
+	}
+	sb.WriteString(fmt.Sprintf("Match length: %s\n", formatMatchTime(time.Duration(results.DurationSec*float64(time.Second)))))
+
+	sb.WriteString("\n# KEY EVENTS\n")
+	if len(events) == 0 {
+		sb.WriteString("(none recorded)\n")
+	}
+	for _, e := range events {
+		sb.WriteString("- " + describeEvent(e, results) + "\n")
+	}
+
+	sb.WriteString(`
+# TASK
+Write a recap of 3-5 sentences: who won and how, the turning points, and
+the standout NPCs. Only use facts from above. Plain text, no headings.
+`)
+
+	return sb.String()
+}
+
 // BuildBatchPrompt creates a single prompt for multiple NPCs on the same team
 func (pb *PromptBuilder) BuildBatchPrompt(observations []map[string]interface{}) string {
 	if len(observations) == 0 {
 		return ""
 	}
+	if out, ok := pb.render(RoleBatch, map[string]interface{}{"observations": observations}); ok {
+		return out
+	}
 
 	team := getString(observations[0], "team")
 
@@ -367,6 +556,10 @@ You are the strategist for Team %s, making decisions for BOTH team members.
 
 `, strings.ToUpper(team)))
 
+	if directive := getString(observations[0], "team_strategy"); directive != "" {
+		sb.WriteString(fmt.Sprintf("# TEAM ORDERS\n%s\n\n", directive))
+	}
+
 	sb.WriteString("# TEAM MEMBERS\n\n")
 
 	for i, obs := range observations {
@@ -412,6 +605,94 @@ Coordinate both team members efficiently:
 
 // Helper functions for safe type extraction
 
+// approachThreshold is how far (in units) an NPC's distance has to change
+// over its observation history to count as approaching or retreating, and
+// fastApproach how quickly (units per second) it has to close to count as
+// closing in fast. Rates are per second so they hold at any tick rate.
+const (
+	approachThreshold = 30
+	fastApproach      = 60
+)
+
+// approachTrend classifies a nearby NPC's distance_change over
+// history_seconds (see observation_history): "CLOSING IN FAST",
+// "approaching", "moving away" or "keeping its distance". Empty without
+// history.
+func approachTrend(npc map[string]interface{}) string {
+	change, ok := npc["distance_change"].(float64)
+	seconds := getFloat(npc, "history_seconds")
+	if !ok || seconds <= 0 {
+		return ""
+	}
+	switch {
+	case change <= -approachThreshold && -change/seconds >= fastApproach:
+		return "CLOSING IN FAST"
+	case change <= -approachThreshold:
+		return "approaching"
+	case change >= approachThreshold:
+		return "moving away"
+	}
+	return "keeping its distance"
+}
+
+// describeApproach turns approachTrend into ", closing in fast (90 units
+// closer in 1.5s)" and the like; empty without history
+func describeApproach(npc map[string]interface{}) string {
+	trend := approachTrend(npc)
+	change, seconds := getFloat(npc, "distance_change"), getFloat(npc, "history_seconds")
+	switch trend {
+	case "":
+		return ""
+	case "CLOSING IN FAST", "approaching":
+		return fmt.Sprintf(", %s (%.0f units closer in %.1fs)", trend, -change, seconds)
+	case "moving away":
+		return fmt.Sprintf(", %s (%.0f units further in %.1fs)", trend, change, seconds)
+	}
+	return ", " + trend
+}
+
+// describeChallengePreview formats a gate's challenge preview, e.g.
+// " - hard spatial challenge, worth 50 tokens". Empty if the gate has none.
+func describeChallengePreview(gate map[string]interface{}) string {
+	preview, ok := gate["challenge"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	difficulty := "medium"
+	switch d := getInt(preview, "difficulty"); {
+	case d <= 2:
+		difficulty = "easy"
+	case d >= 4:
+		difficulty = "hard"
+	}
+	return fmt.Sprintf(" - %s %s challenge, worth %d tokens",
+		difficulty, getString(preview, "type"), getInt(preview, "reward"))
+}
+
+// describeNearbyObjects lists unused nearby_objects as "obj_1 treasure (tokens) 45u"
+func describeNearbyObjects(obs map[string]interface{}) []string {
+	var objects []string
+	for _, obj := range getArrayOfMaps(obs, "nearby_objects") {
+		if getBool(obj, "used") {
+			continue
+		}
+		objects = append(objects, fmt.Sprintf("%s %s (%s) %.0fu", getString(obj, "id"),
+			getString(obj, "type"), getString(obj, "behavior"), getFloat(obj, "distance")))
+	}
+	return objects
+}
+
+// describeMessages renders an NPC's recent messages, oldest first. Prompts
+// carry no conversation history, so this is all the NPC recalls of them.
+func describeMessages(m map[string]interface{}) []string {
+	var messages []string
+	for _, msg := range getArrayOfMaps(m, "messages") {
+		messages = append(messages, fmt.Sprintf("%s: %s", getString(msg, "from"), getString(msg, "content")))
+	}
+	return messages
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if v, ok := m[key]; ok {
 		if s, ok := v.(string); ok {
@@ -462,6 +743,9 @@ func getArray(m map[string]interface{}, key string) []interface{} {
 
 func getStringArray(m map[string]interface{}, key string) []string {
 	if v, ok := m[key]; ok {
+		if arr, ok := v.([]string); ok {
+			return arr
+		}
 		if arr, ok := v.([]interface{}); ok {
 			result := make([]string, len(arr))
 			for i, item := range arr {
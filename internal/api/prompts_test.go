@@ -0,0 +1,90 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrompts_ReflectConfiguredRadii(t *testing.T) {
+	obs := testObservation("npc_0", "Explorer", "red", 300, 200)
+	obs["nearby_npcs"] = []interface{}{
+		map[string]interface{}{"name": "Wanderer", "distance": 120.0, "isTeammate": false, "state": "idle"},
+		map[string]interface{}{"name": "Scout", "distance": 130.0, "isTeammate": true},
+	}
+
+	defaults := &PromptBuilder{}
+	prompt := defaults.BuildMovementPrompt(obs)
+	if strings.Contains(prompt, "RIGHT NEXT TO YOU") || strings.Contains(prompt, "is right here") {
+		t.Errorf("default radii (80/100) should not flag NPCs at 120/130 units:\n%s", prompt)
+	}
+
+	wide := &PromptBuilder{radii: PromptRadii{Opponent: 150, Teammate: 200, Gate: 250}}
+	prompt = wide.BuildMovementPrompt(obs)
+	if !strings.Contains(prompt, "Wanderer is RIGHT NEXT TO YOU") {
+		t.Errorf("opponent radius 150 should flag Wanderer at 120 units:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "TEAMMATE Scout is right here") {
+		t.Errorf("social radius 200 should flag Scout at 130 units:\n%s", prompt)
+	}
+
+	bds := &BatchDecisionSystem{promptBuilder: wide}
+	batch := bds.buildFlexibleMultiNPCPrompt([]map[string]interface{}{obs})
+	for _, want := range []string{"(< 250 units)", "within 200 units of a [2P] gate", "within 150 units when"} {
+		if !strings.Contains(batch, want) {
+			t.Errorf("batch tips missing %q", want)
+		}
+	}
+}
+
+func TestPrompts_CarryMessageHistory(t *testing.T) {
+	reveal := map[string]interface{}{"from": "Game Master", "content": "Your secret code is A749."}
+	obs := testObservation("npc_0", "Explorer", "red", 300, 200)
+	obs["messages"] = []interface{}{reveal}
+
+	pb := &PromptBuilder{}
+	if prompt := pb.BuildMovementPrompt(obs); !strings.Contains(prompt, "Game Master: Your secret code is A749.") {
+		t.Errorf("movement prompt missing message history:\n%s", prompt)
+	}
+	bds := &BatchDecisionSystem{promptBuilder: pb}
+	if batch := bds.buildFlexibleMultiNPCPrompt([]map[string]interface{}{obs}); !strings.Contains(batch, "A749") {
+		t.Errorf("batch prompt missing message history:\n%s", batch)
+	}
+
+	challenge := map[string]interface{}{"type": "memory", "prompt": "What was your code?"}
+	solve := pb.BuildChallengePrompt(challenge, map[string]interface{}{"name": "Explorer", "messages": []interface{}{reveal}})
+	if !strings.Contains(solve, "A749") || !strings.Contains(solve, "Check your messages") {
+		t.Errorf("strict solve prompt should point at message history:\n%s", solve)
+	}
+}
+
+func TestApproachTrend_PerSecondAtAnyTickRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  float64
+		seconds float64
+		want    string
+	}{
+		{"fast", -90, 1.5, "CLOSING IN FAST"},
+		{"slow", -45, 1.5, "approaching"},
+		{"away", 40, 1.5, "moving away"},
+		{"steady", -10, 1.5, "keeping its distance"},
+	}
+	for _, tt := range tests {
+		npc := map[string]interface{}{"name": "Wanderer", "distance": 120.0, "distance_change": tt.change, "history_seconds": tt.seconds}
+		if got := approachTrend(npc); got != tt.want {
+			t.Errorf("%s: trend = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	obs := testObservation("npc_0", "Explorer", "red", 300, 200)
+	obs["nearby_npcs"] = []interface{}{
+		map[string]interface{}{"name": "Wanderer", "distance": 120.0, "distance_change": -90.0, "history_ticks": 89, "history_seconds": 1.5},
+	}
+	if prompt := (&PromptBuilder{}).BuildMovementPrompt(obs); !strings.Contains(prompt, "CLOSING IN FAST (90 units closer in 1.5s)") {
+		t.Errorf("movement prompt missing the approach:\n%s", prompt)
+	}
+	bds := &BatchDecisionSystem{promptBuilder: &PromptBuilder{}}
+	if batch := bds.buildFlexibleMultiNPCPrompt([]map[string]interface{}{obs}); !strings.Contains(batch, "Wanderer:120u (CLOSING IN FAST)") {
+		t.Errorf("batch prompt missing the approach:\n%s", batch)
+	}
+}
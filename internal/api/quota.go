@@ -0,0 +1,131 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/amit/npc/internal/config"
+)
+
+// QuotaTracker counts requests per provider against a daily quota and warns
+// once a provider crosses the warning threshold
+type QuotaTracker struct {
+	mu        sync.Mutex
+	limits    map[string]int // provider -> daily request quota (0 = unlimited)
+	counts    map[string]int
+	warned    map[string]bool
+	threshold float64
+	reroute   bool
+	resetHour int
+	nextReset time.Time
+	now       func() time.Time
+}
+
+// NewQuotaTracker creates a tracker for the given per-provider daily quotas
+func NewQuotaTracker(cfg config.QuotaConfig, limits map[string]int) *QuotaTracker {
+	threshold := cfg.WarnThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.8
+	}
+	resetHour := cfg.ResetHourUTC
+	if resetHour < 0 || resetHour > 23 {
+		resetHour = 0
+	}
+
+	q := &QuotaTracker{
+		limits:    limits,
+		counts:    make(map[string]int),
+		warned:    make(map[string]bool),
+		threshold: threshold,
+		reroute:   cfg.Reroute,
+		resetHour: resetHour,
+		now:       time.Now,
+	}
+	q.nextReset = q.resetAfter(q.now())
+	return q
+}
+
+// resetAfter returns the first reset time (resetHour:00 UTC) after t
+func (q *QuotaTracker) resetAfter(t time.Time) time.Time {
+	t = t.UTC()
+	reset := time.Date(t.Year(), t.Month(), t.Day(), q.resetHour, 0, 0, 0, time.UTC)
+	if !reset.After(t) {
+		reset = reset.AddDate(0, 0, 1)
+	}
+	return reset
+}
+
+// rollover clears counters once the reset time has passed (caller holds mu)
+func (q *QuotaTracker) rollover() {
+	now := q.now()
+	if now.Before(q.nextReset) {
+		return
+	}
+	q.counts = make(map[string]int)
+	q.warned = make(map[string]bool)
+	q.nextReset = q.resetAfter(now)
+	log.Printf("🔄 Provider quotas reset (next reset %s)", q.nextReset.Format(time.RFC3339))
+}
+
+// Record counts one request against a provider's quota and writes a warning
+// audit entry the first time the provider crosses the threshold each day
+func (q *QuotaTracker) Record(provider string) {
+	q.mu.Lock()
+	q.rollover()
+	q.counts[provider]++
+	count := q.counts[provider]
+	limit := q.limits[provider]
+	crossed := limit > 0 && !q.warned[provider] && float64(count) >= q.threshold*float64(limit)
+	if crossed {
+		q.warned[provider] = true
+	}
+	q.mu.Unlock()
+
+	if crossed {
+		msg := fmt.Sprintf("daily quota %d/%d (%.0f%%) used", count, limit, 100*float64(count)/float64(limit))
+		log.Printf("⚠️ [%s] %s", provider, msg)
+		GetAuditLog().LogWarning(provider, msg)
+	}
+}
+
+// Saturated reports whether new traffic should be routed away from a provider
+func (q *QuotaTracker) Saturated(provider string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+
+	limit := q.limits[provider]
+	if !q.reroute || limit <= 0 {
+		return false
+	}
+	return float64(q.counts[provider]) >= q.threshold*float64(limit)
+}
+
+// Usage returns per-provider quota usage for the health endpoint
+func (q *QuotaTracker) Usage() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+
+	providers := make(map[string]interface{})
+	for name, limit := range q.limits {
+		usage := map[string]interface{}{
+			"used":  q.counts[name],
+			"limit": limit,
+		}
+		if limit > 0 {
+			usage["percent"] = 100 * float64(q.counts[name]) / float64(limit)
+			usage["warning"] = q.warned[name]
+		}
+		providers[name] = usage
+	}
+
+	return map[string]interface{}{
+		"providers":      providers,
+		"warn_threshold": q.threshold,
+		"reroute":        q.reroute,
+		"next_reset":     q.nextReset.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"log"
+
+	"github.com/amit/npc/internal/config"
+)
+
+// Completion params used when a provider's role doesn't set them
+const (
+	defaultMaxTokens   = 100
+	defaultTemperature = 0.7
+)
+
+// resolveRoles gives each model role its own provider: the one its tier
+// picks, else the one it names (with its model, if set). Either way the
+// role's max_tokens and temperature go along with it. Roles with neither
+// keep the default routing and completion params. The challenge role's
+// provider is only used with llm.challenge_solver "role".
+func (m *Manager) resolveRoles(roles config.ModelRolesConfig) {
+	for role, rc := range map[string]config.RoleConfig{
+		"movement":       roles.Movement,
+		"challenge":      roles.Challenge,
+		"judge":          roles.Judge,
+		"zone_generator": roles.ZoneGen,
+		"commentary":     roles.Commentary,
+		"strategy":       roles.Strategy,
+	} {
+		var base *Provider
+		switch {
+		case rc.Tier != "":
+			base = m.resolveTier(rc.Tier)
+		case rc.Provider != "":
+			if base = m.findProvider(rc.Provider); base == nil {
+				log.Printf("⚠️ model_roles.%s provider %q isn't enabled; using the default routing", role, rc.Provider)
+			}
+		}
+		if base == nil {
+			continue
+		}
+
+		dedicated := *base
+		if rc.Tier == "" && rc.Model != "" {
+			dedicated.Model = rc.Model
+		}
+		dedicated.MaxTokens = rc.MaxTokens
+		dedicated.Temperature = rc.Temperature
+		m.roleProviders[role] = &dedicated
+
+		if rc.Tier != "" {
+			log.Printf("🎚️ Role %s (%s tier) → %s (%s)", role, rc.Tier, dedicated.Name, dedicated.Model)
+		} else {
+			log.Printf("🎚️ Role %s → %s (%s)", role, dedicated.Name, dedicated.Model)
+		}
+	}
+}
+
+// roleProvider returns the provider resolved for a role, or fallback when
+// the role has none
+func (m *Manager) roleProvider(role string, fallback *Provider) *Provider {
+	if p := m.roleProviders[role]; p != nil {
+		return p
+	}
+	return fallback
+}
+
+// completionParams returns the max_tokens and temperature to request from
+// p: its role's, or the defaults when unset. An explicit temperature of 0 is
+// kept; only a missing or negative one gets the default.
+func (p *Provider) completionParams() (maxTokens int, temperature float64) {
+	maxTokens, temperature = p.MaxTokens, defaultTemperature
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	if p.Temperature != nil && *p.Temperature >= 0 {
+		temperature = *p.Temperature
+	}
+	return maxTokens, temperature
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestResolveRoles_CompletionParamsReachRequest(t *testing.T) {
+	type request struct {
+		Model       string  `json:"model"`
+		MaxTokens   int     `json:"max_tokens"`
+		Temperature float64 `json:"temperature"`
+	}
+	var mu sync.Mutex
+	received := make(map[string]request) // Server name -> last request body
+	server := func(name, content string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var req request
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			received[name] = req
+			mu.Unlock()
+			reply, _ := json.Marshal(content)
+			w.Write([]byte(`{"choices":[{"message":{"content":` + string(reply) + `}}]}`))
+		}
+	}
+
+	m := newTestManager(t)
+	m.brainProviders = []Provider{
+		mockProvider(t, "brain", server("brain", "Hold the bridge.")),
+		mockProvider(t, "strict", server("judge", `{"success": true, "reason": "same answer"}`)),
+	}
+	m.brainProviders[0].Model, m.brainProviders[1].Model = "b", "s"
+	m.activeBrain = &m.brainProviders[0]
+	m.roleProviders = make(map[string]*Provider)
+
+	var roles config.ModelRolesConfig
+	judgeTemperature := 0.1
+	roles.Judge = config.RoleConfig{Provider: "strict", Model: "s-large", MaxTokens: 64, Temperature: &judgeTemperature}
+	roles.Commentary = config.RoleConfig{Provider: "missing", MaxTokens: 30}
+	m.resolveRoles(roles)
+
+	if p := m.roleProvider("commentary", nil); p != nil {
+		t.Errorf("commentary resolved to %s; a provider that isn't enabled should keep the default", p.Name)
+	}
+
+	challenge := map[string]interface{}{"type": "coordination", "prompt": "Pick a color"}
+	responses := map[string]interface{}{"Explorer": "RED", "Scout": "RED"}
+	if _, err := m.JudgeChallenge(challenge, responses); err != nil {
+		t.Fatalf("JudgeChallenge: %v", err)
+	}
+	if _, _, err := m.GetStrategy("red", "score 1-0"); err != nil {
+		t.Fatalf("GetStrategy: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := received["judge"], (request{Model: "s-large", MaxTokens: 64, Temperature: 0.1}); got != want {
+		t.Errorf("judge request = %+v, want %+v", got, want)
+	}
+	if got, want := received["brain"], (request{Model: "b", MaxTokens: defaultMaxTokens, Temperature: defaultTemperature}); got != want {
+		t.Errorf("strategy request without a role = %+v, want the defaults %+v", got, want)
+	}
+}
+
+func TestCompletionParams_ExplicitZeroTemperatureKept(t *testing.T) {
+	zero, negative := 0.0, -1.0
+	tests := []struct {
+		name        string
+		temperature *float64
+		want        float64
+	}{
+		{"unset", nil, defaultTemperature},
+		{"zero", &zero, 0},
+		{"negative", &negative, defaultTemperature},
+	}
+	for _, tt := range tests {
+		p := &Provider{Temperature: tt.temperature}
+		if _, got := p.completionParams(); got != tt.want {
+			t.Errorf("%s: temperature = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrNoSolver is returned when no provider can answer a challenge
+var ErrNoSolver = errors.New("no provider to solve challenges")
+
+// solverFor is the provider that answers npcName's challenges: the NPC's own
+// (sticky, like its movement) unless a dedicated challenge role is configured
+func (m *Manager) solverFor(npcName string) *Provider {
+	if m.challengeSolver == "role" {
+		if p := m.roleProviders["challenge"]; p != nil {
+			return m.preferUnsaturated(p)
+		}
+	}
+	return m.GetProviderForNPC(npcName)
+}
+
+// SolveChallenge asks npcName's solver for its answer to a challenge (as
+// shown by ChallengeManager.SolverView). npcContext carries the NPC's name,
+// team and any memory code. A reply without an "answer" field is used
+// verbatim.
+func (m *Manager) SolveChallenge(npcName string, challenge, npcContext map[string]interface{}) (string, error) {
+	ctx := withNPC(withRole(context.Background(), "challenge"), npcContext)
+
+	provider := m.solverFor(npcName)
+	if provider == nil {
+		return "", ErrNoSolver
+	}
+
+	m.rateLimiter.Wait(1)
+	m.throttle()
+
+	prompt := promptBuilder.BuildChallengePrompt(challenge, npcContext)
+	start := time.Now()
+	response, err := m.callProviderWithRetry(ctx, provider, prompt, m.maxRetries)
+	if err != nil {
+		log.Printf("❌ %s solving [%s] FAILED: %s", npcName, provider.Name, truncateError(err))
+		m.recordError(provider.Name, err)
+		return "", err
+	}
+	m.recordSuccess(provider.Name)
+	log.Printf("🧩 %s answered via %s in %dms", npcName, provider.Name, time.Since(start).Milliseconds())
+
+	var out struct {
+		Answer interface{} `json:"answer"`
+	}
+	if m.decoderFor(provider).decode(response, &out) && out.Answer != nil {
+		return fmt.Sprint(out.Answer), nil
+	}
+	return strings.TrimSpace(response), nil
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSolveChallenge_StickyToNPCProvider(t *testing.T) {
+	answering := func(answer string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"choices":[{"message":{"content":"{\"thinking\":\"...\",\"answer\":\"` + answer + `\"}"}}]}`))
+		}
+	}
+
+	m := newTestManager(t)
+	m.slmProviders = []Provider{mockProvider(t, "own", answering("RED")), mockProvider(t, "dedicated", answering("BLUE"))}
+	m.npcProviders["Explorer"] = &m.slmProviders[0]
+	m.roleProviders["challenge"] = &m.slmProviders[1]
+
+	challenge := map[string]interface{}{"type": "coordination", "prompt": "Pick a color", "options": []string{"RED", "BLUE"}}
+	npc := map[string]interface{}{"name": "Explorer", "team": "red"}
+
+	if answer, err := m.SolveChallenge("Explorer", challenge, npc); err != nil || answer != "RED" {
+		t.Errorf("npc solver answered %q, %v; want the NPC's provider (RED)", answer, err)
+	}
+	m.challengeSolver = "role"
+	if answer, err := m.SolveChallenge("Explorer", challenge, npc); err != nil || answer != "BLUE" {
+		t.Errorf("role solver answered %q, %v; want the challenge role (BLUE)", answer, err)
+	}
+}
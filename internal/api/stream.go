@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/amit/npc/internal/llm"
+)
+
+// StreamCommentary is GetCommentary, handing the commentary to onChunk
+// piece by piece as the brain writes it; the cleaned-up whole is returned.
+// OpenAI-compatible providers stream; others (Gemini, Ollama) deliver the
+// commentary as one chunk. The call goes through callProviderWithRetry like
+// any other, so the circuit breaker and retries apply; cancelling ctx stops
+// the stream mid-flight.
+func (m *Manager) StreamCommentary(ctx context.Context, events []map[string]interface{}, scores map[string]int, onChunk func(chunk string)) (string, error) {
+	brain := m.roleProvider("commentary", m.activeBrain)
+	if brain == nil || !canStream(brain) {
+		commentary, err := m.GetCommentary(events, scores)
+		onChunk(commentary)
+		return commentary, err
+	}
+	ctx = withStream(withRole(ctx, "commentary"), onChunk)
+
+	m.rateLimiter.Wait(1)
+	m.throttle()
+
+	prompt := promptBuilder.BuildCommentaryPrompt(events, scores)
+	response, err := m.callProviderWithRetry(ctx, brain, prompt, m.fallbackRetries)
+	if err != nil {
+		return "The game continues...", err
+	}
+	return strings.Trim(strings.TrimSpace(response), "\""), nil
+}
+
+// errStreamInterrupted marks a stream that failed after some chunks were
+// already handed on; retrying would repeat them, so it isn't retried
+var errStreamInterrupted = errors.New("stream interrupted")
+
+type streamKey struct{}
+
+// withStream asks the calls made with ctx to stream their response to
+// onChunk. Only providers that canStream do; the rest answer as usual.
+func withStream(ctx context.Context, onChunk func(chunk string)) context.Context {
+	return context.WithValue(ctx, streamKey{}, onChunk)
+}
+
+// streamFor returns the chunk handler in ctx if p can stream to it
+func streamFor(ctx context.Context, p *Provider) func(chunk string) {
+	onChunk, _ := ctx.Value(streamKey{}).(func(chunk string))
+	if onChunk == nil || !canStream(p) {
+		return nil
+	}
+	return onChunk
+}
+
+// streamProvider is callProvider for a streamed call. Each caller gets its
+// own chunks, so streamed calls aren't shared with identical ones in flight.
+func (m *Manager) streamProvider(ctx context.Context, p *Provider, prompt string, onChunk func(chunk string)) (string, tokenUsage, error) {
+	maxTokens, temperature := p.completionParams()
+	adapter := llm.NewOpenAIAdapter(llm.ProviderConfig{
+		Name:    p.Name,
+		BaseURL: p.BaseURL,
+		APIKey:  p.APIKey,
+		Model:   p.Model,
+	})
+
+	streamed := false
+	result, err := adapter.CompleteStream(ctx, m.formatPromptFor(p, prompt), llm.CompletionOpts{MaxTokens: maxTokens, Temperature: temperature}, func(chunk string) {
+		streamed = true
+		onChunk(chunk)
+	})
+	if err != nil {
+		if streamed {
+			err = fmt.Errorf("%w: %w", errStreamInterrupted, err)
+		}
+		return "", tokenUsage{}, err
+	}
+	usage := tokenUsage{In: result.TokensIn, Out: result.TokensOut}
+	m.recordUsage(p, usage)
+	return result.Content, usage, nil
+}
+
+// canStream reports whether p speaks the OpenAI-compatible streaming API
+func canStream(p *Provider) bool {
+	switch p.Name {
+	case "gemini", "ollama":
+		return false
+	}
+	return p.BaseURL != ""
+}
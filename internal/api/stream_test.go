@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStreamCommentary_ForwardsChunks(t *testing.T) {
+	m := newTestManager(t)
+	m.brainProviders = []Provider{mockProvider(t, "groq", func(w http.ResponseWriter, r *http.Request) {
+		for _, c := range []string{`"Red `, `storms `, `ahead!"`} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})}
+	m.activeBrain = &m.brainProviders[0]
+	m.roleProviders = make(map[string]*Provider)
+
+	var chunks []string
+	commentary, err := m.StreamCommentary(context.Background(), nil, map[string]int{"red": 3}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil || commentary != "Red storms ahead!" {
+		t.Errorf("commentary = %q, %v", commentary, err)
+	}
+	if len(chunks) != 3 {
+		t.Errorf("chunks = %q, want the 3 streamed pieces", chunks)
+	}
+}
+
+func TestStreamCommentary_OpenCircuitSkipsProvider(t *testing.T) {
+	var hits atomic.Int32
+	m := newTestManager(t)
+	m.brainProviders = []Provider{mockProvider(t, "groq", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})}
+	m.activeBrain = &m.brainProviders[0]
+	m.roleProviders = make(map[string]*Provider)
+
+	var err error
+	for i := 0; i < 5; i++ {
+		_, err = m.StreamCommentary(context.Background(), nil, nil, func(string) {
+			t.Error("chunk from a failing provider")
+		})
+	}
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v, want ErrCircuitOpen once failures pass the threshold", err)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Errorf("provider hit %d times, want 3 (the default failure_threshold)", n)
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/observability"
+)
+
+// GenerateMatchSummary asks the brain (or the commentary role's provider)
+// for a narrative recap of a finished match: who won, the turning points
+// and the standout NPCs. keyEvents are the match's notable events, oldest
+// first (see Observer.KeyEvents). Without a brain, or when the call fails,
+// the recap is templated from the results instead; a failure is returned
+// alongside it.
+func (m *Manager) GenerateMatchSummary(results game.MatchResults, keyEvents []observability.AuditEntry) (string, error) {
+	ctx := withRole(context.Background(), "match_summary")
+
+	brain := m.roleProvider("commentary", m.activeBrain)
+	if brain == nil {
+		return templatedMatchSummary(results, keyEvents), nil
+	}
+
+	m.rateLimiter.Wait(1)
+	m.throttle()
+
+	prompt := promptBuilder.BuildMatchSummaryPrompt(results, keyEvents)
+
+	var response string
+	var err error
+
+	if brain.Name == "gemini" {
+		response, err = m.callGeminiWithRetry(ctx, brain, prompt, m.fallbackRetries)
+	} else {
+		response, err = m.callProviderWithRetry(ctx, brain, prompt, m.fallbackRetries)
+	}
+
+	if err != nil {
+		log.Printf("❌ Match summary [%s] FAILED: %s", brain.Name, truncateError(err))
+		m.recordError(brain.Name, err)
+		return templatedMatchSummary(results, keyEvents), err
+	}
+	m.recordSuccess(brain.Name)
+
+	summary := strings.Trim(strings.TrimSpace(response), "\"")
+	if summary == "" {
+		return templatedMatchSummary(results, keyEvents), nil
+	}
+	return summary, nil
+}
+
+// templatedMatchSummary is the recap used when the brain can't write one
+func templatedMatchSummary(results game.MatchResults, events []observability.AuditEntry) string {
+	sentences := []string{describeOutcome(results)}
+
+	var lines []string
+	for _, team := range results.Teams {
+		lines = append(lines, fmt.Sprintf("%s solved %s and unlocked %s",
+			team.Name, plural(team.ChallengesSolved, "challenge"), plural(team.ZonesUnlocked, "zone")))
+	}
+	if len(lines) > 0 {
+		sentences = append(sentences, strings.Join(lines, "; ")+".")
+	}
+
+	leadChanges := 0
+	solved := make(map[string]int)
+	for _, e := range events {
+		switch e.Event {
+		case "lead_change":
+			leadChanges++
+		case "challenge_complete":
+			if e.NPC != "" {
+				solved[e.NPC]++
+			}
+		}
+	}
+	switch leadChanges {
+	case 0:
+	case 1:
+		sentences = append(sentences, "The lead changed hands once.")
+	default:
+		sentences = append(sentences, fmt.Sprintf("The lead changed hands %d times.", leadChanges))
+	}
+
+	if len(solved) > 0 {
+		names := make([]string, 0, len(solved))
+		for name := range solved {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if solved[names[i]] != solved[names[j]] {
+				return solved[names[i]] > solved[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		sentences = append(sentences, fmt.Sprintf("Standout: %s, with %s solved.",
+			names[0], plural(solved[names[0]], "challenge")))
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+// describeOutcome says who won and by how much
+func describeOutcome(results game.MatchResults) string {
+	if len(results.Teams) < 2 {
+		return "The match ended."
+	}
+	winner, loser := results.Teams[0], results.Teams[1]
+	for i, team := range results.Teams {
+		if team.ID == results.Winner {
+			winner = team
+			loser = results.Teams[(i+1)%len(results.Teams)]
+		}
+	}
+
+	switch {
+	case loser.Forfeited:
+		return fmt.Sprintf("%s won, %d to %d, after %s forfeited.", winner.Name, winner.Score, loser.Score, loser.Name)
+	case results.Winner == "" && winner.Score == loser.Score:
+		return fmt.Sprintf("%s and %s finished level on %d.", winner.Name, loser.Name, winner.Score)
+	default:
+		return fmt.Sprintf("%s beat %s, %d to %d.", winner.Name, loser.Name, winner.Score, loser.Score)
+	}
+}
+
+// describeEvent is one key event as a recap prompt line, timed from the
+// start of the match
+func describeEvent(e observability.AuditEntry, results game.MatchResults) string {
+	team := e.Team
+	for _, t := range results.Teams {
+		if t.ID == e.Team {
+			team = t.Name
+		}
+	}
+
+	var what string
+	switch e.Event {
+	case "challenge_complete":
+		what = fmt.Sprintf("%s (%s) solved the challenge at %v for %v tokens", e.NPC, team, e.Data["gate_id"], e.Data["tokens_earned"])
+	case "zone_unlocked":
+		what = fmt.Sprintf("%s unlocked %v", team, e.Data["zone_id"])
+		if e.NPC != "" {
+			what += " (" + e.NPC + ")"
+		}
+	case "zone_generated":
+		what = fmt.Sprintf("a new zone appeared: %v", e.Data["zone_name"])
+	case "lead_change":
+		what = fmt.Sprintf("%s took the lead", team)
+	case "match_over":
+		what = "match over"
+		if reason, ok := e.Data["reason"].(string); ok {
+			what += " (" + reason + ")"
+		}
+	default:
+		what = e.Event
+	}
+
+	if results.StartedAt.IsZero() {
+		return what
+	}
+	return fmt.Sprintf("[%s] %s", formatMatchTime(e.Timestamp.Sub(results.StartedAt)), what)
+}
+
+// formatMatchTime renders a match clock as m:ss
+func formatMatchTime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	secs := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// plural renders "1 challenge" or "3 challenges"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/observability"
+)
+
+func TestTemplatedMatchSummary_WinnerTurningPointsAndStandout(t *testing.T) {
+	start := time.Now()
+	results := game.MatchResults{
+		StartedAt: start,
+		MatchOver: true,
+		Winner:    "blue",
+		Teams: []game.TeamResult{
+			{ID: "red", Name: "Team Red", Score: 120, ChallengesSolved: 3, Forfeited: true},
+			{ID: "blue", Name: "Team Blue", Score: 90, ChallengesSolved: 2},
+		},
+	}
+	events := []observability.AuditEntry{
+		{Timestamp: start.Add(30 * time.Second), Event: "challenge_complete", NPC: "Scout", Team: "red"},
+		{Timestamp: start.Add(40 * time.Second), Event: "lead_change", Team: "red"},
+		{Timestamp: start.Add(90 * time.Second), Event: "challenge_complete", NPC: "Seeker", Team: "blue",
+			Data: map[string]interface{}{"gate_id": "gate_2_4", "tokens_earned": 50}},
+		{Timestamp: start.Add(95 * time.Second), Event: "challenge_complete", NPC: "Seeker", Team: "blue"},
+		{Timestamp: start.Add(99 * time.Second), Event: "lead_change", Team: "blue"},
+	}
+
+	summary := templatedMatchSummary(results, events)
+	for _, want := range []string{"Team Blue won, 90 to 120, after Team Red forfeited.", "changed hands 2 times", "Standout: Seeker, with 2 challenges"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+
+	if line := describeEvent(events[2], results); line != "[1:30] Seeker (Team Blue) solved the challenge at gate_2_4 for 50 tokens" {
+		t.Errorf("event line = %q", line)
+	}
+}
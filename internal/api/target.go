@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Models sometimes answer with relative coordinates despite the prompt
+// ("target": [x+100, y-50]). These helpers quote such arrays so the JSON
+// parses, then evaluate them against the NPC's position.
+
+// bareTargetArray matches a target array holding unquoted non-numeric values
+var bareTargetArray = regexp.MustCompile(`("target"\s*:\s*)\[([^\]"]*[A-Za-z][^\]"]*)\]`)
+
+// quoteTargetExpressions turns "target": [x+100, y-50] into
+// "target": ["x+100", "y-50"] so it survives JSON decoding
+func quoteTargetExpressions(s string) string {
+	return bareTargetArray.ReplaceAllStringFunc(s, func(m string) string {
+		parts := bareTargetArray.FindStringSubmatch(m)
+		elems := strings.Split(parts[2], ",")
+		for i, e := range elems {
+			elems[i] = strconv.Quote(strings.TrimSpace(e))
+		}
+		return parts[1] + "[" + strings.Join(elems, ", ") + "]"
+	})
+}
+
+// resolveTargetExpression reads a move target as absolute coordinates. It
+// accepts numeric pairs as well as simple relative expressions such as
+// ["x+100", "y-50"] or "[x+100, y-50]", evaluated against pos. Returns false
+// if the target can't be read.
+func resolveTargetExpression(target interface{}, pos [2]float64) ([2]float64, bool) {
+	var elems []interface{}
+	switch t := target.(type) {
+	case []interface{}:
+		elems = t
+	case []float64:
+		for _, v := range t {
+			elems = append(elems, v)
+		}
+	case string:
+		inner := strings.Trim(strings.TrimSpace(t), "[]()")
+		for _, e := range strings.Split(inner, ",") {
+			elems = append(elems, e)
+		}
+	default:
+		return [2]float64{}, false
+	}
+	if len(elems) != 2 {
+		return [2]float64{}, false
+	}
+
+	var result [2]float64
+	for i, e := range elems {
+		switch v := e.(type) {
+		case float64:
+			result[i] = v
+		case string:
+			value, err := evalCoordinate(v, pos)
+			if err != nil {
+				return [2]float64{}, false
+			}
+			result[i] = value
+		default:
+			return [2]float64{}, false
+		}
+	}
+	return result, true
+}
+
+// evalCoordinate evaluates a sum of numbers and the variables x and y,
+// e.g. "x+100", "y - 50" or "250"
+func evalCoordinate(expr string, pos [2]float64) (float64, error) {
+	expr = strings.ToLower(strings.ReplaceAll(expr, " ", ""))
+	if expr == "" || strings.HasSuffix(expr, "+") || strings.HasSuffix(expr, "-") {
+		return 0, fmt.Errorf("incomplete coordinate %q", expr)
+	}
+
+	total, sign := 0.0, 1.0
+	for len(expr) > 0 {
+		switch expr[0] {
+		case '+':
+			expr = expr[1:]
+			continue
+		case '-':
+			sign = -sign
+			expr = expr[1:]
+			continue
+		}
+
+		end := strings.IndexAny(expr, "+-")
+		if end < 0 {
+			end = len(expr)
+		}
+		term := expr[:end]
+		expr = expr[end:]
+
+		var value float64
+		switch term {
+		case "x":
+			value = pos[0]
+		case "y":
+			value = pos[1]
+		default:
+			n, err := strconv.ParseFloat(term, 64)
+			if err != nil {
+				return 0, fmt.Errorf("unsupported term %q", term)
+			}
+			value = n
+		}
+		total += sign * value
+		sign = 1
+	}
+	return total, nil
+}
+
+// normalizeMoveTarget rewrites a move decision's target as numbers, or turns
+// the decision into a wait when the target can't be read
+func normalizeMoveTarget(decision, obs map[string]interface{}) {
+	if action, _ := decision["action"].(string); action != "move" {
+		return
+	}
+
+	var pos [2]float64
+	if p := getArray(obs, "pos"); len(p) >= 2 {
+		pos[0], _ = p[0].(float64)
+		pos[1], _ = p[1].(float64)
+	}
+
+	if target, ok := resolveTargetExpression(decision["target"], pos); ok {
+		decision["target"] = []interface{}{target[0], target[1]}
+		return
+	}
+	decision["action"] = "wait"
+	decision["reason"] = fmt.Sprintf("couldn't read move target %v", decision["target"])
+	decision["target"] = nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestResolveTargetExpression(t *testing.T) {
+	pos := [2]float64{300, 200}
+	tests := []struct {
+		name   string
+		target interface{}
+		want   [2]float64
+		ok     bool
+	}{
+		{"numbers", []interface{}{400.0, 250.0}, [2]float64{400, 250}, true},
+		{"relative strings", []interface{}{"x+100", "y-50"}, [2]float64{400, 150}, true},
+		{"spaced", []interface{}{"x + 100", "y - 50"}, [2]float64{400, 150}, true},
+		{"mixed", []interface{}{"x+100", 250.0}, [2]float64{400, 250}, true},
+		{"whole string", "[x+100, y-50]", [2]float64{400, 150}, true},
+		{"parenthesized", "(x-20, y+20)", [2]float64{280, 220}, true},
+		{"numeric strings", []interface{}{"400", "250"}, [2]float64{400, 250}, true},
+		{"uppercase", []interface{}{"X+10", "Y"}, [2]float64{310, 200}, true},
+		{"unknown variable", []interface{}{"z+100", "y"}, [2]float64{}, false},
+		{"multiplication", []interface{}{"x*2", "y"}, [2]float64{}, false},
+		{"dangling operator", []interface{}{"x+", "y"}, [2]float64{}, false},
+		{"one coordinate", []interface{}{"x+100"}, [2]float64{}, false},
+		{"gate id", "gate_1_2", [2]float64{}, false},
+		{"null", nil, [2]float64{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := resolveTargetExpression(tt.target, pos)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: resolveTargetExpression(%v) = %v, %v; want %v, %v", tt.name, tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseActionResponse_CoordinateExpressions(t *testing.T) {
+	obs := testObservation("npc_0", "Explorer", "red", 300, 200)
+
+	tests := []struct {
+		response   string
+		wantAction string
+		wantTarget []interface{}
+	}{
+		// The exact format the movement prompt warns against
+		{`{"action": "move", "target": [x+100, y-50], "reason": "heading to gate"}`, "move", []interface{}{400.0, 150.0}},
+		{`{"action": "move", "target": ["x+100", "y-50"], "reason": "heading to gate"}`, "move", []interface{}{400.0, 150.0}},
+		{`{"action": "move", "target": "[x+100, y-50]", "reason": "heading to gate"}`, "move", []interface{}{400.0, 150.0}},
+		{`{"action": "move", "target": [400, 200], "reason": "heading to gate"}`, "move", []interface{}{400.0, 200.0}},
+		{`{"action": "move", "target": [x*2, y/2], "reason": "heading to gate"}`, "wait", nil},
+		{`{"action": "move", "target": "somewhere east", "reason": "heading to gate"}`, "wait", nil},
+	}
+
+	for _, tt := range tests {
+		decision, err := parseActionResponse(jsonDecoder{}, tt.response, obs)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.response, err)
+		}
+		if decision["action"] != tt.wantAction {
+			t.Errorf("%s: action = %v, want %s", tt.response, decision["action"], tt.wantAction)
+			continue
+		}
+		if tt.wantTarget == nil {
+			if decision["target"] != nil {
+				t.Errorf("%s: target = %v, want nil", tt.response, decision["target"])
+			}
+			continue
+		}
+		target, _ := decision["target"].([]interface{})
+		if len(target) != 2 || target[0] != tt.wantTarget[0] || target[1] != tt.wantTarget[1] {
+			t.Errorf("%s: target = %v, want %v", tt.response, decision["target"], tt.wantTarget)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// RoleBatch selects the team batch prompt template (batch.tmpl)
+const RoleBatch PromptRole = "batch"
+
+// RoleMatchSummary selects the post-match recap template (match_summary.tmpl)
+const RoleMatchSummary PromptRole = "match_summary"
+
+// templateRoles are the prompts that can be overridden by <role>.tmpl files
+var templateRoles = []PromptRole{RoleMovement, RoleChallenge, RoleJudge, RoleCommentary, RoleBatch, RoleMatchSummary}
+
+// templateFuncs exposes the observation helpers to prompt templates
+var templateFuncs = template.FuncMap{
+	"getString":      getString,
+	"getInt":         getInt,
+	"getFloat":       getFloat,
+	"getBool":        getBool,
+	"getArray":       getArray,
+	"getStringArray": getStringArray,
+	"getArrayOfMaps": getArrayOfMaps,
+	"upper":          strings.ToUpper,
+	"join":           strings.Join,
+	"json": func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	},
+}
+
+// LoadPromptTemplates parses <role>.tmpl files from dir into the shared prompt
+// builder. Roles without a template keep the built-in prompt. An empty dir
+// disables templates; a template that fails to parse is a startup error.
+func LoadPromptTemplates(dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	templates := make(map[PromptRole]*template.Template)
+	for _, role := range templateRoles {
+		path := filepath.Join(dir, string(role)+".tmpl")
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("prompt template %s: %w", path, err)
+		}
+
+		tmpl, err := template.New(string(role)).Funcs(templateFuncs).Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("prompt template %s: %w", path, err)
+		}
+		templates[role] = tmpl
+		log.Printf("📝 Loaded %s prompt template from %s", role, path)
+	}
+
+	promptBuilder.templates = templates
+	return nil
+}
+
+// render executes the template for a role. ok is false when no template is
+// loaded or execution fails, so callers fall back to the built-in prompt.
+func (pb *PromptBuilder) render(role PromptRole, data interface{}) (string, bool) {
+	tmpl, exists := pb.templates[role]
+	if !exists {
+		return "", false
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		log.Printf("⚠️ %s prompt template failed, using built-in: %v", role, err)
+		return "", false
+	}
+	return sb.String(), true
+}
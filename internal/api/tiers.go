@@ -0,0 +1,57 @@
+package api
+
+import "sort"
+
+// Cost tiers a model role can ask for instead of naming a provider
+const (
+	TierCheap    = "cheap"
+	TierBalanced = "balanced"
+	TierQuality  = "quality"
+)
+
+// resolveTier picks a provider for a cost tier from every enabled provider,
+// ranked by cost_per_1k_tokens as a stand-in for quality: cheap takes the
+// cheapest, quality the priciest and balanced the one in between. Providers
+// without a cost (0 is unknown, not free) rank after every priced one and are
+// only picked when none is priced. Ties keep config order, SLM providers
+// first. Returns nil for no tier or no providers.
+func (m *Manager) resolveTier(tier string) *Provider {
+	var ranked []*Provider
+	seen := make(map[string]bool)
+	for _, providers := range [][]Provider{m.slmProviders, m.brainProviders} {
+		for i := range providers {
+			p := &providers[i]
+			if key := p.Name + "/" + p.Model; !seen[key] {
+				seen[key] = true
+				ranked = append(ranked, p)
+			}
+		}
+	}
+	if len(ranked) == 0 {
+		return nil
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		ci, cj := ranked[i].CostPer1K, ranked[j].CostPer1K
+		if ci <= 0 || cj <= 0 {
+			return ci > 0 && cj <= 0 // Unknown cost last
+		}
+		return ci < cj
+	})
+	priced := 0
+	for priced < len(ranked) && ranked[priced].CostPer1K > 0 {
+		priced++
+	}
+	if priced > 0 {
+		ranked = ranked[:priced]
+	}
+
+	switch tier {
+	case TierCheap:
+		return ranked[0]
+	case TierBalanced:
+		return ranked[(len(ranked)-1)/2]
+	case TierQuality:
+		return ranked[len(ranked)-1]
+	}
+	return nil
+}
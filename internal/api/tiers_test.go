@@ -0,0 +1,69 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestResolveRoleTiers_RanksByCost(t *testing.T) {
+	m := NewManager(config.Default())
+	m.slmProviders = []Provider{
+		{Name: "mid", Model: "m", CostPer1K: 0.5},
+		{Name: "cheap", Model: "c", CostPer1K: 0.1},
+	}
+	m.brainProviders = []Provider{
+		{Name: "pricey", Model: "p", CostPer1K: 2},
+		{Name: "mid", Model: "m", CostPer1K: 0.5}, // Same provider in both lists counts once
+	}
+	m.activeBrain = &m.brainProviders[0]
+
+	var roles config.ModelRolesConfig
+	roles.Movement.Tier = TierCheap
+	roles.Judge.Tier = TierQuality
+	roles.Commentary.Tier = TierBalanced
+	m.resolveRoles(roles)
+
+	if p := m.GetProviderForNPC("Explorer"); p == nil || p.Name != "cheap" {
+		t.Errorf("movement = %v, want cheap", p)
+	}
+	for role, want := range map[string]string{"judge": "pricey", "commentary": "mid"} {
+		if p := m.roleProvider(role, nil); p == nil || p.Name != want {
+			t.Errorf("%s = %v, want %s", role, p, want)
+		}
+	}
+	if p := m.roleProvider("zone_generator", m.activeBrain); p != m.activeBrain {
+		t.Errorf("untiered role = %v, want the default brain", p)
+	}
+}
+
+func TestResolveTier_UnknownCostRanksLast(t *testing.T) {
+	m := NewManager(config.Default())
+	m.slmProviders = []Provider{
+		{Name: "unpriced", Model: "u"}, // No cost_per_1k_tokens
+		{Name: "mid", Model: "m", CostPer1K: 0.5},
+		{Name: "cheap", Model: "c", CostPer1K: 0.1},
+	}
+	m.brainProviders = []Provider{{Name: "pricey", Model: "p", CostPer1K: 2}}
+
+	for tier, want := range map[string]string{TierCheap: "cheap", TierBalanced: "mid", TierQuality: "pricey"} {
+		if p := m.resolveTier(tier); p == nil || p.Name != want {
+			t.Errorf("%s = %v, want %s", tier, p, want)
+		}
+	}
+}
+
+func TestResolveTier_AllUnknownCostKeepsConfigOrder(t *testing.T) {
+	m := NewManager(config.Default())
+	m.slmProviders = []Provider{{Name: "first", Model: "a"}, {Name: "second", Model: "b"}}
+	m.brainProviders = []Provider{{Name: "third", Model: "c"}}
+
+	for tier, want := range map[string]string{TierCheap: "first", TierBalanced: "second", TierQuality: "third"} {
+		if p := m.resolveTier(tier); p == nil || p.Name != want {
+			t.Errorf("%s = %v, want %s", tier, p, want)
+		}
+	}
+	if p := m.resolveTier("unknown"); p != nil {
+		t.Errorf("unknown tier = %v, want nil", p)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/amit/npc/internal/config"
+	"github.com/amit/npc/internal/game"
+	"github.com/amit/npc/internal/llm"
+)
+
+// decideToolName is the function tool-calling providers answer decisions with
+const decideToolName = "decide"
+
+// decideTool describes a decision as a function call. Its arguments are a
+// decision object, so a call's arguments parse like any JSON decision; move
+// targets are given as "x,y" since not every provider's schema dialect
+// allows a string-or-array field.
+func decideTool() llm.Tool {
+	var actions []string
+	for _, spec := range game.ActionRegistry {
+		actions = append(actions, fmt.Sprintf("%s (%s)", spec.Name, spec.Description))
+	}
+	return llm.Tool{
+		Name:        decideToolName,
+		Description: "Choose this NPC's next action. Actions: " + strings.Join(actions, "; "),
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"action": map[string]interface{}{
+					"type": "string",
+					"enum": game.ActionNames(),
+				},
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": `Coordinates as "x,y" for move; gate ID for challenge; NPC name for talk/taunt; object ID for interact; empty otherwise`,
+				},
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "What to say, for talk and taunt",
+				},
+				"goal": map[string]interface{}{
+					"type":        "string",
+					"description": `Only when setting or changing this NPC's goal, e.g. "reach gate_1_2"; omit otherwise`,
+				},
+				"reason": map[string]interface{}{
+					"type":        "string",
+					"description": "A few words on why",
+				},
+			},
+			"required": []string{"action", "target"},
+		},
+	}
+}
+
+type toolsKey struct{}
+
+// withTools offers tools to the calls made with ctx. Only providers with
+// ToolCalling capability send them; the rest keep answering in text.
+func withTools(ctx context.Context, tools ...llm.Tool) context.Context {
+	return context.WithValue(ctx, toolsKey{}, tools)
+}
+
+// toolsFor returns the tools in ctx that p can be sent
+func toolsFor(ctx context.Context, p *Provider) []llm.Tool {
+	if !p.Capabilities.ToolCalling {
+		return nil
+	}
+	tools, _ := ctx.Value(toolsKey{}).([]llm.Tool)
+	return tools
+}
+
+// providerCapabilities is the capability descriptor for a configured provider
+func providerCapabilities(cfg config.ProviderConfig) llm.Capabilities {
+	return llm.Capabilities{ToolCalling: cfg.ToolCalling}
+}
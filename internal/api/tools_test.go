@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/amit/npc/internal/llm"
+)
+
+func TestGetEnhancedDecision_ToolCalling(t *testing.T) {
+	var offered []interface{}
+	m := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		offered, _ = req["tools"].([]interface{})
+		if len(offered) == 0 {
+			w.Write([]byte(`{"choices":[{"message":{"content":"{\"action\":\"wait\",\"target\":null}"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":null,"tool_calls":[{"type":"function",
+			"function":{"name":"decide","arguments":"{\"action\":\"move\",\"target\":\"400,250\",\"goal\":\"reach gate_1_2\",\"reason\":\"gate\"}"}}]}}]}`))
+	})
+	m.slmProviders[0].Capabilities = llm.Capabilities{ToolCalling: true}
+
+	obs := testObservation("npc_0", "Explorer", "red", 300, 200)
+	decision, err := m.GetEnhancedDecision(context.Background(), obs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offered) != 1 {
+		t.Fatalf("tools offered = %v, want the decide function", offered)
+	}
+	target, _ := decision["target"].([]interface{})
+	if decision["action"] != "move" || len(target) != 2 || target[0] != 400.0 || target[1] != 250.0 {
+		t.Errorf("decision from tool call = %v", decision)
+	}
+	if decision["goal"] != "reach gate_1_2" {
+		t.Errorf("goal from tool call = %v, want it carried through", decision["goal"])
+	}
+
+	// Providers without the capability get the plain text prompt
+	m.slmProviders[0].Capabilities = llm.Capabilities{}
+	decision, err = m.GetEnhancedDecision(context.Background(), testObservation("npc_1", "Scout", "red", 250, 150))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offered != nil || decision["action"] != "wait" {
+		t.Errorf("text provider: tools = %v, decision = %v", offered, decision)
+	}
+}
@@ -0,0 +1,112 @@
+package challenge
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// refreshParticipantData rebuilds what each participant alone is shown: their
+// ParticipantPrompts entry by join order and, for encoding decoders, the
+// encoder's message once sent. Callers hold cm.mu.
+func (a *ActiveChallenge) refreshParticipantData() {
+	prompts := a.Challenge.ParticipantPrompts
+	if len(prompts) == 0 {
+		return
+	}
+	a.ParticipantData = make(map[string]string, len(a.Participants))
+	for i, npc := range a.Participants {
+		if i < len(prompts) {
+			a.ParticipantData[npc] = prompts[i]
+		}
+	}
+
+	if a.Challenge.Type != TypeEncoding {
+		return
+	}
+	encoder := a.Participants[0]
+	message, sent := a.Responses[encoder]
+	if !sent {
+		return
+	}
+	for _, npc := range a.Participants[1:] {
+		a.ParticipantData[npc] = strings.TrimSpace(a.ParticipantData[npc] + "\nEncoded message from " + encoder + ": " + message)
+	}
+}
+
+// judgeEncoding checks that the encoder (first participant) kept the secret
+// word out of their message and that every decoder recovered it
+func judgeEncoding(solution string, participants []string, responses map[string]string) *ChallengeResult {
+	result := &ChallengeResult{}
+	if len(participants) < 2 {
+		result.Feedback = "Encoding needs an encoder and a decoder"
+		return result
+	}
+
+	encoder := participants[0]
+	if givesAway(responses[encoder], solution) {
+		result.Feedback = fmt.Sprintf("%s gave the word away instead of encoding it", encoder)
+		return result
+	}
+	for _, decoder := range participants[1:] {
+		if !answers(responses[decoder], solution) {
+			result.Feedback = fmt.Sprintf("%s decoded %q - the word was something else", decoder, responses[decoder])
+			return result
+		}
+	}
+
+	result.Success = true
+	result.PartialCredit = 1.0
+	result.Feedback = fmt.Sprintf("Message received! %s's code was cracked", encoder)
+	return result
+}
+
+// judgeInfoAsymmetry succeeds when every participant answers with the
+// combined solution. If only some do, they earn half credit per share: the
+// clues were combined, but not shared.
+func judgeInfoAsymmetry(solution string, participants []string, responses map[string]string) *ChallengeResult {
+	result := &ChallengeResult{}
+	var solved []string
+	for _, npc := range participants {
+		if answers(responses[npc], solution) {
+			solved = append(solved, npc)
+		}
+	}
+
+	switch {
+	case len(participants) > 0 && len(solved) == len(participants):
+		result.Success = true
+		result.PartialCredit = 1.0
+		result.Feedback = "Clues combined! Everyone had the full answer"
+	case len(solved) > 0:
+		result.PartialCredit = float64(len(solved)) / float64(len(participants)) / 2
+		result.Feedback = fmt.Sprintf("Only %s combined the clues", strings.Join(solved, " and "))
+	default:
+		result.Feedback = "Nobody combined the clues correctly"
+	}
+	return result
+}
+
+// answers reports whether a response is the answer, ignoring case, spaces
+// and punctuation (so "L-A-N-T-E-R-N" and "7 3 1 9" count, but a list of
+// guesses that happens to include it doesn't)
+func answers(response, answer string) bool {
+	answer = alphanumeric(answer)
+	return answer != "" && alphanumeric(response) == answer
+}
+
+// givesAway reports whether an encoder's message spells out the answer
+// anywhere, however it's spaced or punctuated
+func givesAway(message, answer string) bool {
+	answer = alphanumeric(answer)
+	return answer != "" && strings.Contains(alphanumeric(message), answer)
+}
+
+func alphanumeric(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, s)
+}
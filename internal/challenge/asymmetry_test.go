@@ -0,0 +1,88 @@
+package challenge
+
+import (
+	"strings"
+	"testing"
+)
+
+func joinPair(t *testing.T, cm *ChallengeManager, challengeID string) {
+	t.Helper()
+	for _, npc := range []string{"Explorer", "Scout"} {
+		if _, err := cm.StartChallenge("gate_1", challengeID, npc, "red"); err != nil {
+			t.Fatalf("%s joining: %v", npc, err)
+		}
+	}
+}
+
+func TestEncoding_DecoderGetsMessageAndScores(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		decoded  string
+		success  bool
+		feedback string
+	}{
+		{"decoded", "I light the way at night and hang from a hook", "Lantern.", true, "cracked"},
+		{"wrong word", "I light the way at night", "candle", false, "something else"},
+		{"guessed a list", "I light the way at night", "candle, torch or lantern", false, "something else"},
+		{"word given away", "The word is L-A-N-T-E-R-N", "lantern", false, "gave the word away"},
+	}
+	for _, tt := range tests {
+		cm := NewChallengeManager()
+		joinPair(t, cm, "challenge_encoding")
+
+		// Only the encoder is told the word
+		if private := cm.SolverView("gate_1", "Explorer")["private"]; !strings.Contains(private.(string), "LANTERN") {
+			t.Fatalf("%s: encoder sees %q", tt.name, private)
+		}
+		if private := cm.SolverView("gate_1", "Scout")["private"].(string); strings.Contains(private, "LANTERN") {
+			t.Fatalf("%s: decoder was shown the word: %q", tt.name, private)
+		}
+
+		if ok, _ := cm.SubmitResponse("gate_1", "Scout", "lantern"); ok {
+			t.Errorf("%s: decoder answered before the message arrived", tt.name)
+		}
+		cm.SubmitResponse("gate_1", "Explorer", tt.message)
+		if private := cm.SolverView("gate_1", "Scout")["private"].(string); !strings.Contains(private, tt.message) {
+			t.Errorf("%s: decoder's view %q lacks the message", tt.name, private)
+		}
+		cm.SubmitResponse("gate_1", "Scout", tt.decoded)
+
+		result := cm.EvaluateChallenge("gate_1")
+		if result == nil || result.Success != tt.success || !strings.Contains(result.Feedback, tt.feedback) {
+			t.Errorf("%s: result = %+v, want success=%v with %q", tt.name, result, tt.success, tt.feedback)
+		}
+	}
+}
+
+func TestInfoAsymmetry_SplitCluesAndScoring(t *testing.T) {
+	tests := []struct {
+		name     string
+		explorer string
+		scout    string
+		success  bool
+		tokens   int
+	}{
+		{"both combined", "7319", "7-3-1-9", true, 40},
+		{"guessed around it", "7319", "1234 7319 5678", false, 10},
+		{"one combined", "7319", "73", false, 10},
+		{"nobody", "73", "19", false, 0},
+	}
+	for _, tt := range tests {
+		cm := NewChallengeManager()
+		joinPair(t, cm, "challenge_split_clue")
+
+		first := cm.SolverView("gate_1", "Explorer")["private"]
+		second := cm.SolverView("gate_1", "Scout")["private"]
+		if first == nil || second == nil || first == second {
+			t.Fatalf("%s: clue halves = %v / %v, want a different one each", tt.name, first, second)
+		}
+
+		cm.SubmitResponse("gate_1", "Explorer", tt.explorer)
+		cm.SubmitResponse("gate_1", "Scout", tt.scout)
+		result := cm.EvaluateChallenge("gate_1")
+		if result == nil || result.Success != tt.success || result.TokensEarned != tt.tokens {
+			t.Errorf("%s: result = %+v, want success=%v and %d tokens", tt.name, result, tt.success, tt.tokens)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package challenge
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// debatePassMark is the average argument score a team needs to win a debate
+// judged by the heuristic, high enough that one strong argument can't carry a
+// one-line teammate
+const debatePassMark = 0.6
+
+// reasoningMarker matches words that signal an argument gives reasons,
+// evidence or a rebuttal rather than just a position
+var reasoningMarker = regexp.MustCompile(`\b(because|since|therefore|thus|so that|which means|for example|for instance|evidence|shows|proves|however|although|whereas|otherwise|if)\b`)
+
+// judgeDebate scores the participants' arguments with the judge against the
+// challenge's rubric. The judge may name a "winner"; if it names nobody who
+// argued, the strongest argument by the heuristic wins. Without a judge (or
+// when it returns nothing) the length/keyword heuristic decides outright.
+func (cm *ChallengeManager) judgeDebate(challenge *Challenge, responses map[string]string) *ChallengeResult {
+	heuristic := judgeDebateHeuristic(responses)
+	if cm.judge == nil {
+		return heuristic
+	}
+
+	judged, _ := cm.judge(challenge.judgeView(), toInterfaceMap(responses))
+	if judged == nil {
+		return heuristic
+	}
+
+	result := &ChallengeResult{Winner: heuristic.Winner}
+	result.Success, _ = judged["correct"].(bool)
+	result.Feedback, _ = judged["feedback"].(string)
+	result.PartialCredit, _ = judged["score"].(float64)
+	if result.Success && result.PartialCredit == 0 {
+		result.PartialCredit = 1.0 // Judge said correct but gave no score
+	}
+	if winner, _ := judged["winner"].(string); winner != "" {
+		if _, argued := responses[winner]; argued {
+			result.Winner = winner
+		}
+	}
+	return result
+}
+
+// judgeDebateHeuristic scores each argument on length (full marks at 30
+// words) and reasoning (full marks for 3 distinct reasoning markers such as
+// "because" or "for example"), weighted 40/60. Partial credit is the team's
+// average and passes at debatePassMark; the best argument wins.
+func judgeDebateHeuristic(responses map[string]string) *ChallengeResult {
+	result := &ChallengeResult{}
+	npcs := make([]string, 0, len(responses))
+	for npc := range responses {
+		npcs = append(npcs, npc)
+	}
+	sort.Strings(npcs) // Ties go to the same NPC every time
+
+	total, best := 0.0, -1.0
+	for _, npc := range npcs {
+		score := argumentScore(responses[npc])
+		total += score
+		if score > best {
+			best, result.Winner = score, npc
+		}
+	}
+	if len(npcs) == 0 || best == 0 {
+		result.Winner = ""
+		result.Feedback = "No argument given"
+		return result
+	}
+
+	result.PartialCredit = total / float64(len(npcs))
+	result.Success = result.PartialCredit >= debatePassMark
+	if result.Success {
+		result.Feedback = fmt.Sprintf("Convincing case! %s argued best", result.Winner)
+	} else {
+		result.Feedback = fmt.Sprintf("Arguments too thin - give reasons and examples (%s argued best)", result.Winner)
+	}
+	return result
+}
+
+// argumentScore rates one argument from 0 to 1
+func argumentScore(argument string) float64 {
+	length := math.Min(float64(len(strings.Fields(argument)))/30, 1)
+
+	markers := make(map[string]bool)
+	for _, m := range reasoningMarker.FindAllString(strings.ToLower(argument), -1) {
+		markers[m] = true
+	}
+	reasoning := math.Min(float64(len(markers))/3, 1)
+
+	return 0.4*length + 0.6*reasoning
+}
@@ -0,0 +1,82 @@
+package challenge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	strongArgument = "We should stay together because a split team loses fights near contested gates. " +
+		"For example, last round Scout was caught alone and we lost the bridge. " +
+		"However, if we scout ahead in pairs we still cover ground."
+	weakArgument = "Split up."
+)
+
+func startDebate(t *testing.T, cm *ChallengeManager, explorer, scout string) *ChallengeResult {
+	t.Helper()
+	cm.SetShuffleOptions(false)
+	for _, npc := range []string{"Explorer", "Scout"} {
+		if _, err := cm.StartChallenge("gate_1", "challenge_debate", npc, "red"); err != nil {
+			t.Fatalf("%s joining: %v", npc, err)
+		}
+	}
+	cm.SubmitResponse("gate_1", "Explorer", explorer)
+	cm.SubmitResponse("gate_1", "Scout", scout)
+	result := cm.EvaluateChallenge("gate_1")
+	if result == nil {
+		t.Fatal("debate not evaluated")
+	}
+	return result
+}
+
+func TestDebate_JudgedByLLMWithRubric(t *testing.T) {
+	cm := NewChallengeManager()
+	var seen map[string]interface{}
+	cm.SetJudge(func(challenge, responses map[string]interface{}) (map[string]interface{}, error) {
+		seen = challenge
+		return map[string]interface{}{"correct": true, "feedback": "Scout's case was sharper", "score": 0.75, "winner": "Scout"}, nil
+	})
+
+	result := startDebate(t, cm, weakArgument, strongArgument)
+	if rubric, _ := seen["rubric"].(string); !strings.Contains(rubric, "reason") {
+		t.Errorf("judge got rubric %q", rubric)
+	}
+	if !result.Success || result.Winner != "Scout" || result.PartialCredit != 0.75 || result.TokensEarned != 30 {
+		t.Errorf("judged debate = %+v, want Scout winning with 0.75 credit and 30 tokens", result)
+	}
+
+	// A winner who didn't argue is replaced by the heuristic's pick
+	cm = NewChallengeManager()
+	cm.SetJudge(func(challenge, responses map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"correct": false, "feedback": "meh", "score": 0.2, "winner": "Wanderer"}, nil
+	})
+	if result := startDebate(t, cm, strongArgument, weakArgument); result.Success || result.Winner != "Explorer" {
+		t.Errorf("unknown judge winner = %+v, want a failure won by Explorer", result)
+	}
+}
+
+func TestDebate_HeuristicWithoutBrain(t *testing.T) {
+	// No judge at all
+	result := startDebate(t, NewChallengeManager(), strongArgument, strongArgument+" Therefore we stay.")
+	if !result.Success || result.PartialCredit != 1 || result.Winner != "Explorer" {
+		t.Errorf("two reasoned arguments = %+v, want full credit, tie to Explorer", result)
+	}
+
+	// A judge with no brain behind it returns nothing
+	cm := NewChallengeManager()
+	cm.SetJudge(func(challenge, responses map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("no brain")
+	})
+	result = startDebate(t, cm, weakArgument, strongArgument)
+	if result.Success || result.Winner != "Scout" {
+		t.Errorf("one thin argument = %+v, want a failure won by Scout", result)
+	}
+	if result.PartialCredit <= 0 || result.PartialCredit >= debatePassMark {
+		t.Errorf("partial credit = %v, want between 0 and %v", result.PartialCredit, debatePassMark)
+	}
+
+	if result := judgeDebateHeuristic(map[string]string{"Explorer": "", "Scout": "  "}); result.Success || result.Winner != "" {
+		t.Errorf("empty arguments = %+v, want no winner", result)
+	}
+}
@@ -0,0 +1,166 @@
+package challenge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoGenerator is returned by RegenerateChallenge when no LLM is set
+var ErrNoGenerator = errors.New("no challenge generator configured")
+
+// ErrAttemptInProgress is returned when a gate's challenge is replaced mid-attempt
+var ErrAttemptInProgress = errors.New("a challenge attempt is in progress at this gate")
+
+// regenerableTypes can be judged without extra state: coordination by rules,
+// the rest by the judge. Memory needs the NPCs' codes and spatial a grid.
+var regenerableTypes = map[ChallengeType]bool{
+	TypeCoordination:  true,
+	TypeEncoding:      true,
+	TypeInfoAsymmetry: true,
+	TypeDebate:        true,
+}
+
+// generatedChallenge is the JSON the LLM is asked for
+type generatedChallenge struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Prompt   string   `json:"prompt"`
+	Options  []string `json:"options"`
+	Solution string   `json:"solution"`
+	Hints    []string `json:"hints"`
+}
+
+// SetGenerator sets the LLM call RegenerateChallenge uses
+func (cm *ChallengeManager) SetGenerator(fn func(prompt string) (string, error)) {
+	cm.genFunc = fn
+}
+
+// RegenerateChallenge asks the LLM for a fresh challenge of the given
+// difficulty (clamped to 1-5) and puts it behind gateID in place of the
+// current one, which is left registered for anything else using it. The
+// teamwork requirement carries over, since the gate enforces it; reward, time
+// limit and hint cost scale with difficulty.
+func (cm *ChallengeManager) RegenerateChallenge(gateID string, difficulty int) (*Challenge, error) {
+	if cm.genFunc == nil {
+		return nil, ErrNoGenerator
+	}
+	if cm.gateChallenge == nil || cm.assignGate == nil {
+		return nil, fmt.Errorf("gates aren't linked to challenges")
+	}
+	current := cm.GetChallenge(cm.gateChallenge(gateID))
+	if current == nil {
+		return nil, fmt.Errorf("gate %s has no challenge", gateID)
+	}
+	if cm.GetActiveChallenge(gateID) != nil {
+		return nil, ErrAttemptInProgress
+	}
+	if difficulty < 1 {
+		difficulty = 1
+	} else if difficulty > 5 {
+		difficulty = 5
+	}
+
+	response, err := cm.genFunc(buildRegeneratePrompt(current, difficulty))
+	if err != nil {
+		return nil, fmt.Errorf("generating challenge: %w", err)
+	}
+	generated, err := parseGeneratedChallenge(response)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.mu.Lock()
+	if _, busy := cm.ActiveChallenges[gateID]; busy {
+		cm.mu.Unlock()
+		return nil, ErrAttemptInProgress // Someone started while the LLM was thinking
+	}
+	cm.regenerated++
+	fresh := &Challenge{
+		ID:               fmt.Sprintf("challenge_%s_r%d", gateID, cm.regenerated),
+		Type:             ChallengeType(generated.Type),
+		Name:             generated.Name,
+		Description:      fmt.Sprintf("Regenerated at difficulty %d", difficulty),
+		Difficulty:       difficulty,
+		Prompt:           generated.Prompt,
+		Options:          generated.Options,
+		Solution:         generated.Solution,
+		RequiresTeamwork: current.RequiresTeamwork,
+		MinParticipants:  current.MinParticipants,
+		TimeLimit:        time.Duration(20+10*difficulty) * time.Second,
+		TokenReward:      15 + 8*difficulty,
+		Hints:            generated.Hints,
+		HintCost:         2 * difficulty,
+	}
+	cm.Challenges[fresh.ID] = fresh
+	cm.mu.Unlock()
+
+	cm.assignGate(gateID, fresh.ID)
+	return fresh, nil
+}
+
+func buildRegeneratePrompt(current *Challenge, difficulty int) string {
+	return fmt.Sprintf(`# ROLE
+You design puzzles for a competitive AI arena game. Two AI teammates answer
+each challenge; the gate opens when they succeed.
+
+# TASK
+Replace this challenge, which players found too hard or too stale:
+- Name: %s
+- Type: %s
+- Prompt: %s
+
+Write ONE new challenge at difficulty %d on a 1 (trivial) to 5 (expert) scale.
+
+Types:
+- coordination: teammates must pick the SAME option without talking; give 3-4 options, no solution
+- encoding: decode or transform a short message; give the exact solution
+- info_asymmetry: a riddle or logic puzzle with one clear answer; give the solution
+- debate: argue for a position; the solution describes what a strong answer covers
+
+# OUTPUT FORMAT (JSON only)
+{
+  "type": "coordination|encoding|info_asymmetry|debate",
+  "name": "Short Evocative Name",
+  "prompt": "What the players see",
+  "options": ["A", "B", "C"],
+  "solution": "expected answer (empty for coordination)",
+  "hints": ["gentle hint", "stronger hint"]
+}
+`, current.Name, current.Type, current.Prompt, difficulty)
+}
+
+// parseGeneratedChallenge reads and checks the LLM's challenge JSON. Unknown
+// types fall back to coordination, which then needs at least two options.
+func parseGeneratedChallenge(response string) (*generatedChallenge, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("no JSON found in generated challenge")
+	}
+
+	var generated generatedChallenge
+	if err := json.Unmarshal([]byte(response[start:end+1]), &generated); err != nil {
+		return nil, fmt.Errorf("generated challenge JSON: %w", err)
+	}
+	if strings.TrimSpace(generated.Prompt) == "" {
+		return nil, fmt.Errorf("generated challenge has no prompt")
+	}
+	if generated.Name == "" {
+		generated.Name = "The Unknown"
+	}
+	if !regenerableTypes[ChallengeType(generated.Type)] {
+		generated.Type = string(TypeCoordination)
+	}
+	if generated.Type == string(TypeCoordination) {
+		if len(generated.Options) < 2 {
+			return nil, fmt.Errorf("generated coordination challenge needs at least 2 options")
+		}
+		generated.Solution = ""
+	} else if strings.TrimSpace(generated.Solution) == "" {
+		return nil, fmt.Errorf("generated %s challenge has no solution", generated.Type)
+	}
+	return &generated, nil
+}
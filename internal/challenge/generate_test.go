@@ -0,0 +1,44 @@
+package challenge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegenerateChallenge_ReplacesGateChallenge(t *testing.T) {
+	cm := NewChallengeManager()
+	gates := map[string]string{"gate_1": "challenge_teamwork"}
+	cm.SetGateResolver(func(gateID string) string { return gates[gateID] })
+	cm.SetGateAssigner(func(gateID, challengeID string) { gates[gateID] = challengeID })
+
+	var prompt string
+	cm.SetGenerator(func(p string) (string, error) {
+		prompt = p
+		return "Here you go:\n" + `{"type":"riddle","name":"Twin Lanterns","prompt":"Pick the same lantern.","options":["AMBER","JADE","ONYX"],"solution":"JADE"}`, nil
+	})
+
+	fresh, err := cm.RegenerateChallenge("gate_1", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "The Bond Test") || !strings.Contains(prompt, "difficulty 5") {
+		t.Errorf("prompt doesn't describe the old challenge and clamped difficulty:\n%s", prompt)
+	}
+	if gates["gate_1"] != fresh.ID || cm.GetChallenge(fresh.ID) != fresh {
+		t.Errorf("gate points at %q, want registered %q", gates["gate_1"], fresh.ID)
+	}
+	if fresh.Type != TypeCoordination || fresh.Solution != "" || !fresh.RequiresTeamwork || fresh.Difficulty != 5 {
+		t.Errorf("fresh challenge = %+v", fresh)
+	}
+	if cm.GetChallenge("challenge_teamwork") == nil {
+		t.Error("the replaced challenge should stay registered")
+	}
+
+	if _, err := cm.StartChallenge("gate_1", fresh.ID, "Explorer", "red"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.RegenerateChallenge("gate_1", 2); !errors.Is(err, ErrAttemptInProgress) {
+		t.Errorf("regenerating mid-attempt: err = %v, want ErrAttemptInProgress", err)
+	}
+}
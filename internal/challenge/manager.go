@@ -1,6 +1,15 @@
 package challenge
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +32,7 @@ const (
 	StatusPending   ChallengeStatus = "pending"
 	StatusActive    ChallengeStatus = "active"
 	StatusWaiting   ChallengeStatus = "waiting" // Waiting for second teammate
+	StatusJudging   ChallengeStatus = "judging" // Responses are with the judge
 	StatusCompleted ChallengeStatus = "completed"
 	StatusFailed    ChallengeStatus = "failed"
 	StatusExpired   ChallengeStatus = "expired"
@@ -38,11 +48,23 @@ type Challenge struct {
 
 	// The actual challenge content
 	Prompt   string   `json:"prompt"`
-	Options  []string `json:"options,omitempty"`  // For multi-choice
-	Solution string   `json:"solution,omitempty"` // Expected answer (for auto-validation)
+	Options  []string `json:"-"` // For multi-choice; clients get ActiveChallenge.Options, in the shown order
+	Solution string   `json:"-"` // Expected answer (for auto-validation); never sent to clients
+
+	// Spatial challenges with a grid are judged by walking the route on it
+	SpatialGrid *SpatialGrid `json:"spatial_grid,omitempty"`
+
+	// Debate challenges: what the judge scores each argument on
+	Rubric string `json:"rubric,omitempty"`
+
+	// Instructions only one participant sees, by join order: the first to
+	// join gets ParticipantPrompts[0] and so on (info_asymmetry clue halves,
+	// encoding encoder/decoder roles). Kept out of JSON: see SolverView.
+	ParticipantPrompts []string `json:"-"`
 
 	// Requirements
 	RequiresTeamwork bool          `json:"requires_teamwork"`
+	MinParticipants  int           `json:"min_participants,omitempty"` // Teamwork responses needed to judge (default 2)
 	TimeLimit        time.Duration `json:"time_limit"`
 
 	// Rewards
@@ -62,20 +84,87 @@ type ActiveChallenge struct {
 	// Participants
 	Participants []string `json:"participants"` // NPC names
 	TeamID       string   `json:"team_id"`
+	MinimumTeam  int      `json:"minimum_team,omitempty"` // Raises Challenge.RequiredParticipants for this attempt
+
+	// Options in the order shown for this attempt (shuffled when enabled);
+	// coordination answers are matched against these labels
+	Options []string `json:"options,omitempty"`
 
 	// Responses
 	Responses map[string]string `json:"responses"` // NPC name -> response
 
+	// What each participant alone is shown: their ParticipantPrompts entry,
+	// plus for encoding decoders the message the encoder sent. Kept out of
+	// JSON so game state broadcasts don't show it to everyone.
+	ParticipantData map[string]string `json:"-"`
+
 	// Timing
 	StartedAt   time.Time  `json:"started_at"`
 	ExpiresAt   time.Time  `json:"expires_at"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Remaining   int        `json:"seconds_remaining"` // As of the ActiveSnapshot it came from
 
 	// Result
 	Success      bool   `json:"success"`
 	Feedback     string `json:"feedback"`
 	TokensEarned int    `json:"tokens_earned"`
 	HintsUsed    int    `json:"hints_used"`
+	HintPenalty  int    `json:"hint_penalty"` // Sum of escalating hint costs
+}
+
+// SecondsRemaining returns the whole seconds left before the attempt expires
+// (rounded up, 0 once it has)
+func (a *ActiveChallenge) SecondsRemaining() int {
+	return a.secondsRemainingAt(time.Now())
+}
+
+func (a *ActiveChallenge) secondsRemainingAt(now time.Time) int {
+	left := a.ExpiresAt.Sub(now)
+	if left <= 0 {
+		return 0
+	}
+	return int(math.Ceil(left.Seconds()))
+}
+
+// Errors returned when an NPC can't join a gate's in-flight challenge
+var (
+	ErrWrongTeam     = errors.New("challenge belongs to another team")
+	ErrChallengeFull = errors.New("challenge already has all its participants")
+)
+
+// RequiredParticipants is how many NPCs this attempt takes, and how many
+// responses it needs before judging: the challenge's own count, raised to
+// MinimumTeam when the attempt was started with one
+func (ac *ActiveChallenge) RequiredParticipants() int {
+	return max(ac.Challenge.RequiredParticipants(), ac.MinimumTeam)
+}
+
+// hasParticipant reports whether npcName joined this attempt
+func (ac *ActiveChallenge) hasParticipant(npcName string) bool {
+	for _, p := range ac.Participants {
+		if p == npcName {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredParticipants is how many NPCs an attempt takes, and how many
+// responses it needs before judging: MinParticipants (default 2) for
+// teamwork, else 1
+func (c *Challenge) RequiredParticipants() int {
+	if !c.RequiresTeamwork {
+		return 1
+	}
+	if c.MinParticipants > 0 {
+		return c.MinParticipants
+	}
+	return 2
+}
+
+// NextHintCost returns the cost of the next hint: HintCost * (HintsUsed+1)
+func (ac *ActiveChallenge) NextHintCost() int {
+	return ac.Challenge.HintCost * (ac.HintsUsed + 1)
 }
 
 // ChallengeResult is returned after validating a challenge attempt
@@ -83,13 +172,47 @@ type ChallengeResult struct {
 	Success       bool    `json:"success"`
 	Feedback      string  `json:"feedback"`
 	TokensEarned  int     `json:"tokens_earned"`
-	PartialCredit float64 `json:"partial_credit"` // 0.0 to 1.0
+	PartialCredit float64 `json:"partial_credit"`   // 0.0 to 1.0
+	Winner        string  `json:"winner,omitempty"` // Debate: participant with the strongest argument
+	Contested     bool    `json:"contested"`        // Opponent was near the gate
+	Refunded      bool    `json:"refunded"`         // Practice mode: the failure doesn't count and the attempt reopened
 }
 
-// ChallengeManager handles all challenge operations
+// ChallengeManager handles all challenge operations. It is safe for concurrent
+// use by WebSocket handlers.
 type ChallengeManager struct {
+	mu sync.RWMutex
+
 	Challenges       map[string]*Challenge       `json:"challenges"`
 	ActiveChallenges map[string]*ActiveChallenge `json:"active_challenges"` // gate_id -> active
+
+	// Contest bonus: multiplier applied when contestCheck reports an opponent nearby
+	contestBonus float64
+	contestCheck func(gateID, teamID string) bool
+
+	// Resolves which challenge guards a gate, and points a gate at another
+	// challenge (both set by World)
+	gateChallenge func(gateID string) string
+	assignGate    func(gateID, challengeID string)
+
+	// Challenges a gate's attempts are drawn from (set by World)
+	gatePool func(gateID string) []string
+
+	// LLM call used to regenerate a gate's challenge, and how many have been
+	// regenerated (for unique IDs)
+	genFunc     func(prompt string) (string, error)
+	regenerated int
+
+	// Shuffles multi-choice options per attempt; seeded for reproducible games
+	rng            *rand.Rand
+	shuffleOptions bool
+
+	// Practice mode: hints are free and failed attempts are refunded
+	practice bool
+
+	// Scores challenge types that can't be auto-validated (e.g. an LLM judge).
+	// Returns "correct", "feedback" and "score" (0.0-1.0).
+	judge func(challenge, responses map[string]interface{}) (map[string]interface{}, error)
 }
 
 // NewChallengeManager creates a manager with default challenges
@@ -97,6 +220,8 @@ func NewChallengeManager() *ChallengeManager {
 	cm := &ChallengeManager{
 		Challenges:       make(map[string]*Challenge),
 		ActiveChallenges: make(map[string]*ActiveChallenge),
+		rng:              rand.New(rand.NewSource(time.Now().UnixNano())),
+		shuffleOptions:   true,
 	}
 
 	// Create default challenges
@@ -120,7 +245,7 @@ Choose wisely - you only get one chance.`,
 		RequiresTeamwork: false, // Can attempt solo, but coordination version is harder
 		TimeLimit:        30 * time.Second,
 		TokenReward:      25,
-		Hints:            []string{"Think about what's most 'default' or 'first'", "Consider alphabetical order"},
+		Hints:            []string{"The options are shuffled every attempt - which one would your teammate call the obvious pick?", "Agree on a rule you'd both follow, not a fixed word"},
 		HintCost:         5,
 	}
 
@@ -138,7 +263,7 @@ The gate will only open if you think alike.`,
 		RequiresTeamwork: true,
 		TimeLimit:        45 * time.Second,
 		TokenReward:      40,
-		Hints:            []string{"Your team has a color...", "Think about team identity"},
+		Hints:            []string{"Your team has a color...", "Think about team identity, not where it appears in the list"},
 		HintCost:         8,
 	}
 
@@ -159,6 +284,11 @@ What was it? Enter the exact code to proceed.`,
 	}
 
 	// Challenge 4: Spatial Navigation
+	grid := &SpatialGrid{
+		Width: 6, Height: 5,
+		Start: [2]int{0, 0}, Goal: [2]int{5, 4},
+		Obstacles: [][2]int{{3, 0}, {1, 1}, {3, 1}, {1, 2}, {5, 2}, {1, 3}, {2, 3}, {3, 3}, {3, 4}},
+	}
 	cm.Challenges["challenge_spatial"] = &Challenge{
 		ID:          "challenge_spatial",
 		Type:        TypeSpatial,
@@ -166,48 +296,198 @@ What was it? Enter the exact code to proceed.`,
 		Description: "Find the optimal path avoiding obstacles",
 		Difficulty:  4,
 		Prompt: `You are at position A. Target is at position B.
-Obstacles block direct paths. 
+Obstacles (#) block the way; you can't leave the grid.
+
+` + grid.Render() + `
 Describe the optimal route (e.g., "right 2, down 3, right 1").`,
+		SpatialGrid:      grid,
 		RequiresTeamwork: true,
 		TimeLimit:        60 * time.Second,
 		TokenReward:      50,
 		Hints:            []string{"Draw it out mentally", "Sometimes going around is faster"},
 		HintCost:         10,
 	}
+
+	// Challenge 5: Debate
+	cm.Challenges["challenge_debate"] = &Challenge{
+		ID:          "challenge_debate",
+		Type:        TypeDebate,
+		Name:        "The Council",
+		Description: "Each teammate argues a side; the gate opens for convincing reasoning",
+		Difficulty:  3,
+		Prompt: `The gatekeeper asks: "Should a team split up to explore, or stay together?"
+Argue for one side in 2-4 sentences. Give a reason and back it up.`,
+		Rubric:           "Clear position, at least one concrete reason, supporting evidence or example, and addressing the other side",
+		RequiresTeamwork: true,
+		TimeLimit:        45 * time.Second,
+		TokenReward:      40,
+		Hints:            []string{"State your side in the first sentence", "Say why, then give an example"},
+		HintCost:         8,
+	}
+
+	// Challenge 6: Encoding
+	cm.Challenges["challenge_encoding"] = &Challenge{
+		ID:          "challenge_encoding",
+		Type:        TypeEncoding,
+		Name:        "The Cipher",
+		Description: "One teammate encodes a secret word, the other decodes it",
+		Difficulty:  4,
+		Prompt: `One of you knows a secret word and must pass it on in code; the other must decode it.
+Writing the word itself, in any spelling, fails the challenge.`,
+		Solution: "LANTERN",
+		ParticipantPrompts: []string{
+			"You are the ENCODER. The secret word is LANTERN. Reply with a coded message (cipher, riddle or clue) your teammate can decode - never the word itself.",
+			"You are the DECODER. Your teammate's coded message appears below once sent. Reply with the word it hides.",
+		},
+		RequiresTeamwork: true,
+		TimeLimit:        60 * time.Second,
+		TokenReward:      45,
+		Hints:            []string{"Encoder: a riddle about what the word does works well", "Decoder: the word is a single noun"},
+		HintCost:         9,
+	}
+
+	// Challenge 7: Split Clue
+	cm.Challenges["challenge_split_clue"] = &Challenge{
+		ID:          "challenge_split_clue",
+		Type:        TypeInfoAsymmetry,
+		Name:        "The Split Key",
+		Description: "Each teammate holds half the vault code; both must answer with all of it",
+		Difficulty:  3,
+		Prompt: `Each of you holds half of a 4-digit vault code.
+Share your halves, then BOTH answer with the full code.`,
+		Solution: "7319",
+		ParticipantPrompts: []string{
+			"Your half of the clue: the vault code begins 7 3.",
+			"Your half of the clue: the vault code ends 1 9.",
+		},
+		RequiresTeamwork: true,
+		TimeLimit:        60 * time.Second,
+		TokenReward:      40,
+		Hints:            []string{"Your half is the first or last two digits", "Message your teammate before answering"},
+		HintCost:         8,
+	}
+}
+
+// SetContestCheck sets the function used to detect contested gates and the
+// reward multiplier applied when a contested challenge is solved
+func (cm *ChallengeManager) SetContestCheck(fn func(gateID, teamID string) bool, bonus float64) {
+	cm.contestCheck = fn
+	cm.contestBonus = bonus
+}
+
+// SetGateResolver sets the function that maps a gate to its challenge ID
+func (cm *ChallengeManager) SetGateResolver(fn func(gateID string) string) {
+	cm.gateChallenge = fn
+}
+
+// SetGateAssigner sets the function that makes a gate use another challenge
+func (cm *ChallengeManager) SetGateAssigner(fn func(gateID, challengeID string)) {
+	cm.assignGate = fn
+}
+
+// SetPoolResolver sets the function that lists the challenges a gate's
+// attempts are drawn from
+func (cm *ChallengeManager) SetPoolResolver(fn func(gateID string) []string) {
+	cm.gatePool = fn
+}
+
+// SetJudge sets the function that scores spatial, logic and other free-form
+// challenges. Its "score" becomes the result's partial credit.
+func (cm *ChallengeManager) SetJudge(fn func(challenge, responses map[string]interface{}) (map[string]interface{}, error)) {
+	cm.judge = fn
+}
+
+// PreviewChallenge returns what an NPC can know about a gate's challenge before
+// attempting it: type, difficulty, reward and teamwork requirement. Solutions and
+// hints are never included. Returns nil if the gate has no known challenge.
+func (cm *ChallengeManager) PreviewChallenge(gateID string) map[string]interface{} {
+	if cm.gateChallenge == nil {
+		return nil
+	}
+	challengeID := cm.gateChallenge(gateID)
+
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	challenge := cm.Challenges[challengeID]
+	if challenge == nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"type":              string(challenge.Type),
+		"difficulty":        challenge.Difficulty,
+		"reward":            challenge.TokenReward,
+		"requires_teamwork": challenge.RequiresTeamwork,
+		"participants":      challenge.RequiredParticipants(),
+	}
 }
 
 // GetChallenge returns a challenge by ID
 func (cm *ChallengeManager) GetChallenge(id string) *Challenge {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
 	return cm.Challenges[id]
 }
 
-// StartChallenge initiates a challenge attempt
+// AddChallenge registers a challenge (e.g. one from a generated zone),
+// replacing any with the same ID
+func (cm *ChallengeManager) AddChallenge(c *Challenge) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.Challenges[c.ID] = c
+}
+
+// StartChallenge initiates a challenge attempt, or joins the one in progress.
+// If the gate has a challenge pool, a new attempt draws its challenge from it
+// instead of challengeID and the gate is pointed at the one drawn.
 func (cm *ChallengeManager) StartChallenge(gateID, challengeID, npcName, teamID string) (*ActiveChallenge, error) {
-	challenge := cm.GetChallenge(challengeID)
-	if challenge == nil {
-		return nil, nil
+	return cm.StartTeamChallenge(gateID, challengeID, npcName, teamID, 0)
+}
+
+// StartTeamChallenge is StartChallenge for an attempt that needs at least
+// minimumTeam participants, even if its challenge could be played solo
+func (cm *ChallengeManager) StartTeamChallenge(gateID, challengeID, npcName, teamID string, minimumTeam int) (*ActiveChallenge, error) {
+	active, drawn, err := cm.startChallenge(gateID, challengeID, npcName, teamID, minimumTeam)
+	if drawn != "" && drawn != challengeID && cm.assignGate != nil {
+		cm.assignGate(gateID, drawn)
 	}
+	return active, err
+}
+
+func (cm *ChallengeManager) startChallenge(gateID, challengeID, npcName, teamID string, minimumTeam int) (*ActiveChallenge, string, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	challenge := cm.Challenges[challengeID]
 
 	// Check if already active
-	if active, exists := cm.ActiveChallenges[gateID]; exists {
-		if active.Status == StatusActive || active.Status == StatusWaiting {
-			// Add participant if it's a teamwork challenge
-			if challenge.RequiresTeamwork {
-				found := false
-				for _, p := range active.Participants {
-					if p == npcName {
-						found = true
-						break
-					}
-				}
-				if !found {
-					active.Participants = append(active.Participants, npcName)
-				}
+	previous, exists := cm.ActiveChallenges[gateID]
+	if exists {
+		if previous.Status == StatusActive || previous.Status == StatusWaiting {
+			// Only the owning team may join, up to the required participant count
+			if teamID != previous.TeamID {
+				return nil, "", fmt.Errorf("%s can't join %s: %w", npcName, gateID, ErrWrongTeam)
+			}
+			if previous.hasParticipant(npcName) {
+				return previous, "", nil
+			}
+			if len(previous.Participants) >= previous.RequiredParticipants() {
+				return nil, "", fmt.Errorf("%s can't join %s: %w", npcName, gateID, ErrChallengeFull)
 			}
-			return active, nil
+			previous.Participants = append(previous.Participants, npcName)
+			previous.refreshParticipantData()
+			return previous, "", nil
 		}
 	}
 
+	if drawn := cm.drawFromPool(gateID, previous); drawn != nil {
+		challenge = drawn
+	}
+	if challenge == nil {
+		return nil, "", nil
+	}
+
 	// Create new active challenge
 	now := time.Now()
 	active := &ActiveChallenge{
@@ -216,60 +496,304 @@ func (cm *ChallengeManager) StartChallenge(gateID, challengeID, npcName, teamID
 		Status:       StatusActive,
 		Participants: []string{npcName},
 		TeamID:       teamID,
+		MinimumTeam:  minimumTeam,
+		Options:      cm.attemptOptions(challenge),
 		Responses:    make(map[string]string),
 		StartedAt:    now,
 		ExpiresAt:    now.Add(challenge.TimeLimit),
 	}
+	active.refreshParticipantData()
 
-	if challenge.RequiresTeamwork {
+	if active.RequiredParticipants() > 1 {
 		active.Status = StatusWaiting // Waiting for teammate
 	}
 
 	cm.ActiveChallenges[gateID] = active
-	return active, nil
+	return active, challenge.ID, nil
+}
+
+// drawFromPool picks a new attempt's challenge at random from the gate's
+// pool, avoiding the one the last attempt faced so retries aren't rote.
+// Returns nil if the gate has no pool. Callers hold cm.mu.
+func (cm *ChallengeManager) drawFromPool(gateID string, previous *ActiveChallenge) *Challenge {
+	if cm.gatePool == nil {
+		return nil
+	}
+	var candidates []*Challenge
+	for _, id := range cm.gatePool(gateID) {
+		if c := cm.Challenges[id]; c != nil {
+			candidates = append(candidates, c)
+		}
+	}
+	if previous != nil && len(candidates) > 1 {
+		for i, c := range candidates {
+			if c == previous.Challenge {
+				candidates = append(candidates[:i], candidates[i+1:]...)
+				break
+			}
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil
+	case 1:
+		return candidates[0] // Leave the seeded shuffle sequence untouched
+	}
+	return candidates[cm.rng.Intn(len(candidates))]
+}
+
+// SetSeed reseeds the option shuffler so a game can be replayed exactly
+func (cm *ChallengeManager) SetSeed(seed int64) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.rng = rand.New(rand.NewSource(seed))
+}
+
+// SetShuffleOptions enables or disables per-attempt option shuffling
+func (cm *ChallengeManager) SetShuffleOptions(enabled bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.shuffleOptions = enabled
+}
+
+// SetPracticeMode makes hints free and reopens failed attempts for an
+// immediate retry instead of ending them
+func (cm *ChallengeManager) SetPracticeMode(enabled bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.practice = enabled
+}
+
+// attemptOptions returns the challenge's options in the order for a new
+// attempt. Callers hold cm.mu.
+func (cm *ChallengeManager) attemptOptions(challenge *Challenge) []string {
+	if len(challenge.Options) == 0 {
+		return nil
+	}
+	options := append([]string(nil), challenge.Options...)
+	if cm.shuffleOptions {
+		cm.rng.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+	}
+	return options
 }
 
 // SubmitResponse records an NPC's response to a challenge
 func (cm *ChallengeManager) SubmitResponse(gateID, npcName, response string) (bool, string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	active, exists := cm.ActiveChallenges[gateID]
 	if !exists {
 		return false, "No active challenge at this gate"
 	}
 
+	if active.Status != StatusActive && active.Status != StatusWaiting {
+		return false, "Challenge already " + string(active.Status)
+	}
+
 	if time.Now().After(active.ExpiresAt) {
 		active.Status = StatusExpired
 		return false, "Challenge expired"
 	}
 
+	if !active.hasParticipant(npcName) {
+		return false, npcName + " is not part of this challenge"
+	}
+
+	// Store the canonical option for chatty coordination answers; anything
+	// unmatched is kept as-is so judging can report it
+	if active.Challenge.Type == TypeCoordination && len(active.Options) > 0 {
+		if label, ok := matchOption(active.Options, response); ok {
+			response = label
+		}
+	}
+
+	// Encoding: the first participant encodes and teammates decode what
+	// they're sent, so they can't answer before it arrives
+	if active.Challenge.Type == TypeEncoding {
+		encoder := active.Participants[0]
+		if _, sent := active.Responses[encoder]; npcName != encoder && !sent {
+			return false, "Wait for " + encoder + "'s encoded message"
+		}
+	}
 	active.Responses[npcName] = response
+	active.refreshParticipantData()
 
 	// Check if all required responses are in
-	challenge := active.Challenge
-	if challenge.RequiresTeamwork {
-		if len(active.Responses) < 2 {
-			return true, "Response recorded. Waiting for teammate..."
-		}
+	if len(active.Responses) < active.RequiredParticipants() {
+		return true, "Response recorded. Waiting for teammate..."
 	}
 
 	return true, "Response recorded"
 }
 
-// EvaluateChallenge checks if the challenge was solved
+// ReadyToEvaluate reports whether every required response is in for the
+// challenge at a gate
+func (cm *ChallengeManager) ReadyToEvaluate(gateID string) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	active, exists := cm.ActiveChallenges[gateID]
+	if !exists || (active.Status != StatusActive && active.Status != StatusWaiting) {
+		return false
+	}
+	return len(active.Responses) >= active.RequiredParticipants()
+}
+
+// EvaluateChallenge checks if the challenge was solved. Only the first call
+// for an attempt gets a result; later calls (e.g. a teammate whose response
+// arrived at the same moment) get nil, so rewards and unlocks fire once.
+// Tokens are the reward scaled by partial credit, so a near-miss on a judged
+// challenge still earns something.
 func (cm *ChallengeManager) EvaluateChallenge(gateID string) *ChallengeResult {
+	cm.mu.Lock()
 	active, exists := cm.ActiveChallenges[gateID]
-	if !exists {
+	if !exists || (active.Status != StatusActive && active.Status != StatusWaiting) {
+		cm.mu.Unlock()
+		return nil
+	}
+	// A practice attempt may have been reopened since the caller checked
+	// ReadyToEvaluate; don't judge its empty responses
+	if len(active.Responses) < active.RequiredParticipants() {
+		cm.mu.Unlock()
 		return nil
 	}
 
+	// Claim the attempt, then judge without holding the lock
+	active.Status = StatusJudging
 	challenge := active.Challenge
+	teamID := active.TeamID
+	options := active.Options
+	participants := append([]string(nil), active.Participants...)
+	responses := make(map[string]string, len(active.Responses))
+	for npc, resp := range active.Responses {
+		responses[npc] = resp
+	}
+	cm.mu.Unlock()
+
+	result := cm.judgeResponses(challenge, options, participants, responses)
+	result.PartialCredit = math.Max(0, math.Min(1, result.PartialCredit))
+	result.TokensEarned = int(float64(challenge.TokenReward) * result.PartialCredit)
+
+	// Apply contest bonus before hint penalty
+	if result.Success && cm.contestCheck != nil && cm.contestCheck(gateID, teamID) {
+		result.Contested = true
+		result.TokensEarned = int(float64(result.TokensEarned) * cm.contestBonus)
+		result.Feedback += " Contested gate bonus!"
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	// Apply escalating hint penalty
+	result.TokensEarned = max(0, result.TokensEarned-active.HintPenalty)
+
+	// In practice a failure is refunded: the same participants get a fresh
+	// attempt straight away
+	if !result.Success && cm.practice {
+		result.Refunded = true
+		result.Feedback += " (Practice: try again)"
+		cm.reopen(active)
+		return result
+	}
+
+	// Update active challenge status
+	if result.Success {
+		active.Status = StatusCompleted
+		active.Success = true
+	} else {
+		active.Status = StatusFailed
+		active.Success = false
+	}
+	now := time.Now()
+	active.CompletedAt = &now
+	active.Feedback = result.Feedback
+	active.TokensEarned = result.TokensEarned
+
+	return result
+}
+
+// reopen restarts a failed attempt in place: responses cleared, options
+// reshuffled and the timer reset. Callers hold cm.mu.
+func (cm *ChallengeManager) reopen(active *ActiveChallenge) {
+	now := time.Now()
+	active.Status = StatusActive
+	active.Options = cm.attemptOptions(active.Challenge)
+	active.Responses = make(map[string]string)
+	active.refreshParticipantData()
+	active.StartedAt = now
+	active.ExpiresAt = now.Add(active.Challenge.TimeLimit)
+	active.Feedback = ""
+	active.TokensEarned = 0
+}
+
+// ForceResolve completes the challenge at a gate with the given outcome,
+// bypassing evaluation (a referee override for stuck attempts). Like
+// EvaluateChallenge it claims the attempt, so only an active or waiting
+// challenge can be resolved and a later evaluation returns nil. A success
+// earns the full reward less hint penalties.
+func (cm *ChallengeManager) ForceResolve(gateID string, success bool) (*ChallengeResult, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	active, exists := cm.ActiveChallenges[gateID]
+	if !exists {
+		return nil, fmt.Errorf("no active challenge at %s", gateID)
+	}
+	if active.Status != StatusActive && active.Status != StatusWaiting {
+		return nil, fmt.Errorf("challenge at %s is already %s", gateID, active.Status)
+	}
+
+	result := &ChallengeResult{Success: success, Feedback: "Resolved by referee: failed"}
+	if success {
+		result.Feedback = "Resolved by referee: passed"
+		result.PartialCredit = 1.0
+		result.TokensEarned = max(0, active.Challenge.TokenReward-active.HintPenalty)
+		active.Status = StatusCompleted
+	} else {
+		active.Status = StatusFailed
+	}
+	now := time.Now()
+	active.Success = success
+	active.CompletedAt = &now
+	active.Feedback = result.Feedback
+	active.TokensEarned = result.TokensEarned
+
+	return result, nil
+}
+
+// judgeResponses decides success, feedback and partial credit for a set of
+// responses. options is the attempt's (possibly shuffled) option order and
+// participants its join order.
+func (cm *ChallengeManager) judgeResponses(challenge *Challenge, options, participants []string, responses map[string]string) *ChallengeResult {
+	if challenge.Type == TypeSpatial && challenge.SpatialGrid != nil {
+		return judgeSpatial(challenge.SpatialGrid, responses)
+	}
+	// Encoding and split clues check against the solution; generated ones
+	// without one go to the judge
+	if challenge.Type == TypeEncoding && challenge.Solution != "" {
+		return judgeEncoding(challenge.Solution, participants, responses)
+	}
+	if challenge.Type == TypeInfoAsymmetry && challenge.Solution != "" {
+		return judgeInfoAsymmetry(challenge.Solution, participants, responses)
+	}
+
 	result := &ChallengeResult{}
 
 	switch challenge.Type {
 	case TypeCoordination:
-		// All responses must match
+		// All responses must name the same option
 		var firstResponse string
 		allMatch := true
-		for _, resp := range active.Responses {
+		for npc, resp := range responses {
+			if len(options) > 0 {
+				label, ok := matchOption(options, resp)
+				if !ok {
+					result.Feedback = fmt.Sprintf("%s's answer %q isn't one of the options", npc, resp)
+					return result
+				}
+				resp = label
+			}
 			if firstResponse == "" {
 				firstResponse = resp
 			} else if resp != firstResponse {
@@ -279,19 +803,26 @@ func (cm *ChallengeManager) EvaluateChallenge(gateID string) *ChallengeResult {
 		}
 		result.Success = allMatch && firstResponse != ""
 		if result.Success {
-			result.Feedback = "Perfect coordination! Both chose: " + firstResponse
-			result.TokensEarned = challenge.TokenReward
+			who := "Both"
+			if len(responses) > 2 {
+				who = fmt.Sprintf("All %d", len(responses))
+			}
+			result.Feedback = fmt.Sprintf("Perfect coordination! %s chose: %s", who, firstResponse)
+			result.PartialCredit = 1.0
 		} else {
 			result.Feedback = "Coordination failed - different choices"
 		}
 
+	case TypeDebate:
+		return cm.judgeDebate(challenge, responses)
+
 	case TypeMemory:
 		// Check if any response matches the solution
-		for _, resp := range active.Responses {
+		for _, resp := range responses {
 			if resp == challenge.Solution {
 				result.Success = true
 				result.Feedback = "Correct! You remembered the code."
-				result.TokensEarned = challenge.TokenReward
+				result.PartialCredit = 1.0
 				break
 			}
 		}
@@ -300,51 +831,254 @@ func (cm *ChallengeManager) EvaluateChallenge(gateID string) *ChallengeResult {
 		}
 
 	default:
-		// For other types, might need LLM judging
-		result.Feedback = "Challenge evaluation pending..."
+		// Free-form challenges need the judge
+		if cm.judge == nil {
+			result.Feedback = "Challenge evaluation pending..."
+			break
+		}
+
+		judged, err := cm.judge(challenge.judgeView(), toInterfaceMap(responses))
+		if judged == nil {
+			result.Feedback = fmt.Sprintf("Judge unavailable: %v", err)
+			break
+		}
+		result.Success, _ = judged["correct"].(bool)
+		result.Feedback, _ = judged["feedback"].(string)
+		result.PartialCredit, _ = judged["score"].(float64)
+		if result.Success && result.PartialCredit == 0 {
+			result.PartialCredit = 1.0 // Judge said correct but gave no score
+		}
 	}
 
-	// Apply hint penalty
-	hintPenalty := active.HintsUsed * challenge.HintCost
-	result.TokensEarned = max(0, result.TokensEarned-hintPenalty)
+	return result
+}
 
-	// Update active challenge status
-	if result.Success {
-		active.Status = StatusCompleted
-		active.Success = true
-	} else {
-		active.Status = StatusFailed
-		active.Success = false
+// matchOption resolves a response to one of the attempt's option labels: by
+// name (case-insensitive), by 1-based position in the shown order ("2",
+// "option 2"), or, for chatty answers like "I choose blue because...", by the
+// option the text names. When several options are named, the first one after
+// the last choice word ("choose", "pick", ...) wins, else the first named.
+func matchOption(options []string, response string) (string, bool) {
+	response = strings.TrimSpace(response)
+	bare := strings.Trim(response, " \t\n.!?\"'`*")
+	for _, opt := range options {
+		if strings.EqualFold(opt, response) || strings.EqualFold(opt, bare) {
+			return opt, true
+		}
+	}
+	if n, err := strconv.Atoi(bare); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1], true
+	}
+	if m := optionNumber.FindStringSubmatch(response); m != nil {
+		if n, _ := strconv.Atoi(m[1]); n >= 1 && n <= len(options) {
+			return options[n-1], true
+		}
 	}
-	now := time.Now()
-	active.CompletedAt = &now
-	active.Feedback = result.Feedback
-	active.TokensEarned = result.TokensEarned
 
-	return result
+	// Where each option is first named in the text
+	lower := strings.ToLower(response)
+	type mention struct {
+		option string
+		at     int
+	}
+	var mentions []mention
+	for _, opt := range options {
+		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(strings.ToLower(opt)) + `\b`)
+		if loc := pattern.FindStringIndex(lower); loc != nil {
+			mentions = append(mentions, mention{opt, loc[0]})
+		}
+	}
+	switch len(mentions) {
+	case 0:
+		return "", false
+	case 1:
+		return mentions[0].option, true
+	}
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].at < mentions[j].at })
+
+	cues := choiceCue.FindAllStringIndex(lower, -1)
+	for c := len(cues) - 1; c >= 0; c-- {
+		for _, m := range mentions {
+			if m.at >= cues[c][1] {
+				return m.option, true
+			}
+		}
+	}
+	return mentions[0].option, true
+}
+
+var (
+	optionNumber = regexp.MustCompile(`(?i)\boption\s*#?(\d+)\b`)
+	choiceCue    = regexp.MustCompile(`\b(choose|chose|choosing|pick|picking|select|selecting|go with|going with|vote for|answer is|my choice)\b`)
+)
+
+// SolverView is the attempt at a gate as npcName's solve prompt sees it: the
+// challenge without its solution, options in this attempt's order, the time
+// left and, under "private", what only npcName was shown. Returns nil if the
+// gate has no attempt.
+func (cm *ChallengeManager) SolverView(gateID, npcName string) map[string]interface{} {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	a := cm.ActiveChallenges[gateID]
+	if a == nil {
+		return nil
+	}
+	view := map[string]interface{}{
+		"id":                a.Challenge.ID,
+		"type":              string(a.Challenge.Type),
+		"name":              a.Challenge.Name,
+		"prompt":            a.Challenge.Prompt,
+		"options":           a.Options,
+		"requires_teamwork": a.RequiredParticipants() > 1,
+		"seconds_remaining": a.SecondsRemaining(),
+	}
+	if private := a.ParticipantData[npcName]; private != "" {
+		view["private"] = private
+	}
+	return view
+}
+
+// judgeView is the challenge as the judge sees it
+func (c *Challenge) judgeView() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                c.ID,
+		"type":              string(c.Type),
+		"name":              c.Name,
+		"prompt":            c.Prompt,
+		"options":           c.Options,
+		"solution":          c.Solution,
+		"spatial_grid":      c.SpatialGrid,
+		"rubric":            c.Rubric,
+		"requires_teamwork": c.RequiresTeamwork,
+		"participants":      c.RequiredParticipants(),
+	}
 }
 
-// UseHint provides a hint and deducts from potential reward
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// UseHint dispenses the next unused hint and deducts its escalating cost from
+// the potential reward (nothing in practice mode). Hints are strictly sequential: hintIndex must equal the
+// number of hints already used (or be negative to mean "next").
 func (cm *ChallengeManager) UseHint(gateID string, hintIndex int) (string, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	active, exists := cm.ActiveChallenges[gateID]
 	if !exists {
 		return "", false
 	}
 
 	hints := active.Challenge.Hints
-	if hintIndex >= len(hints) {
+	if active.HintsUsed >= len(hints) {
 		return "No more hints available", false
 	}
+	if hintIndex >= 0 && hintIndex != active.HintsUsed {
+		return "Hints must be taken in order", false
+	}
 
+	hint := hints[active.HintsUsed]
+	if !cm.practice {
+		active.HintPenalty += active.NextHintCost()
+	}
 	active.HintsUsed++
-	return hints[hintIndex], true
+	return hint, true
+}
+
+// ReleaseTeam abandons any in-progress challenges held by a team so their
+// gates can be attempted again. Returns the released gate IDs.
+func (cm *ChallengeManager) ReleaseTeam(teamID string) []string {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var released []string
+	for gateID, active := range cm.ActiveChallenges {
+		if active.TeamID != teamID {
+			continue
+		}
+		if active.Status == StatusActive || active.Status == StatusWaiting {
+			delete(cm.ActiveChallenges, gateID)
+			released = append(released, gateID)
+		}
+	}
+	return released
+}
+
+// SweepExpired marks in-progress challenges past their time limit as expired
+// and frees their gates. Returns the swept gate IDs.
+func (cm *ChallengeManager) SweepExpired(now time.Time) []string {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var swept []string
+	for gateID, active := range cm.ActiveChallenges {
+		if active.Status != StatusActive && active.Status != StatusWaiting {
+			continue
+		}
+		if now.After(active.ExpiresAt) {
+			active.Status = StatusExpired
+			delete(cm.ActiveChallenges, gateID)
+			swept = append(swept, gateID)
+		}
+	}
+	return swept
+}
+
+// Countdowns returns the seconds left on each in-progress challenge that
+// expires within the given window, keyed by gate ID
+func (cm *ChallengeManager) Countdowns(now time.Time, within time.Duration) map[string]int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	countdowns := make(map[string]int)
+	for gateID, active := range cm.ActiveChallenges {
+		if active.Status != StatusActive && active.Status != StatusWaiting {
+			continue
+		}
+		if left := active.ExpiresAt.Sub(now); left > 0 && left <= within {
+			countdowns[gateID] = active.secondsRemainingAt(now)
+		}
+	}
+	return countdowns
 }
 
 // GetActiveChallenge returns the active challenge at a gate
 func (cm *ChallengeManager) GetActiveChallenge(gateID string) *ActiveChallenge {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
 	return cm.ActiveChallenges[gateID]
 }
 
+// ActiveSnapshot returns a copy of the active challenges for serialization
+func (cm *ChallengeManager) ActiveSnapshot() map[string]ActiveChallenge {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	now := time.Now()
+	snapshot := make(map[string]ActiveChallenge, len(cm.ActiveChallenges))
+	for gateID, active := range cm.ActiveChallenges {
+		copied := *active
+		copied.Remaining = active.secondsRemainingAt(now)
+		copied.Participants = append([]string(nil), active.Participants...)
+		copied.Responses = make(map[string]string, len(active.Responses))
+		for npc, resp := range active.Responses {
+			copied.Responses[npc] = resp
+		}
+		copied.ParticipantData = make(map[string]string, len(active.ParticipantData))
+		for npc, data := range active.ParticipantData {
+			copied.ParticipantData[npc] = data
+		}
+		snapshot[gateID] = copied
+	}
+	return snapshot
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
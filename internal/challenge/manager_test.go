@@ -0,0 +1,245 @@
+package challenge
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentTeamworkSubmissions(t *testing.T) {
+	for round := 0; round < 50; round++ {
+		cm := NewChallengeManager()
+		cm.StartChallenge("gate_1", "challenge_teamwork", "Explorer", "red")
+		cm.StartChallenge("gate_1", "challenge_teamwork", "Scout", "red")
+
+		var results int32
+		var wg sync.WaitGroup
+		for _, npc := range []string{"Explorer", "Scout"} {
+			wg.Add(1)
+			go func(npc string) {
+				defer wg.Done()
+				if ok, _ := cm.SubmitResponse("gate_1", npc, "RED"); !ok {
+					t.Errorf("%s: response rejected", npc)
+					return
+				}
+				if cm.ReadyToEvaluate("gate_1") {
+					if result := cm.EvaluateChallenge("gate_1"); result != nil {
+						atomic.AddInt32(&results, 1)
+						if !result.Success {
+							t.Errorf("matching responses failed: %s", result.Feedback)
+						}
+					}
+				}
+			}(npc)
+		}
+		wg.Wait()
+
+		if results != 1 {
+			t.Fatalf("round %d: evaluated %d times, want exactly once", round, results)
+		}
+		if active := cm.GetActiveChallenge("gate_1"); active.Status != StatusCompleted {
+			t.Fatalf("round %d: status %s, want completed", round, active.Status)
+		}
+	}
+}
+
+func TestStartChallenge_RejectsCrossTeamAndOverflow(t *testing.T) {
+	cm := NewChallengeManager()
+	if _, err := cm.StartChallenge("gate_1", "challenge_teamwork", "Explorer", "red"); err != nil {
+		t.Fatalf("first participant: %v", err)
+	}
+
+	// An opponent can't join and can't answer
+	if _, err := cm.StartChallenge("gate_1", "challenge_teamwork", "Wanderer", "blue"); !errors.Is(err, ErrWrongTeam) {
+		t.Errorf("opponent join: err = %v, want ErrWrongTeam", err)
+	}
+	if ok, _ := cm.SubmitResponse("gate_1", "Wanderer", "BLUE"); ok {
+		t.Error("opponent response was accepted")
+	}
+
+	// Teammate fills the second slot; nobody else fits
+	if _, err := cm.StartChallenge("gate_1", "challenge_teamwork", "Scout", "red"); err != nil {
+		t.Fatalf("teammate join: %v", err)
+	}
+	if _, err := cm.StartChallenge("gate_1", "challenge_teamwork", "Rookie", "red"); !errors.Is(err, ErrChallengeFull) {
+		t.Errorf("third join: err = %v, want ErrChallengeFull", err)
+	}
+	// Rejoining is a no-op
+	if _, err := cm.StartChallenge("gate_1", "challenge_teamwork", "Scout", "red"); err != nil {
+		t.Errorf("rejoin: %v", err)
+	}
+
+	active := cm.GetActiveChallenge("gate_1")
+	if len(active.Participants) != 2 {
+		t.Errorf("participants = %v, want Explorer and Scout", active.Participants)
+	}
+	if _, ok := active.Responses["Wanderer"]; ok {
+		t.Error("opponent response recorded")
+	}
+}
+
+func TestMatchOption_ChattyResponses(t *testing.T) {
+	options := []string{"RED", "BLUE", "GREEN"}
+	cases := []struct {
+		response string
+		want     string
+	}{
+		{"BLUE", "BLUE"},
+		{"  green. ", "GREEN"},
+		{"2", "BLUE"},
+		{"Option 3", "GREEN"},
+		{"I choose blue because it's calm", "BLUE"},
+		{"I choose blue because red is too obvious", "BLUE"},
+		{"Red is tempting, but I'll go with GREEN.", "GREEN"},
+		{"Not red. I pick blue!", "BLUE"},
+		{"**RED** - our team color", "RED"},
+		{"Definitely red, it's our team color", "RED"},
+	}
+	for _, c := range cases {
+		got, ok := matchOption(options, c.response)
+		if !ok || got != c.want {
+			t.Errorf("matchOption(%q) = %q, %v; want %q", c.response, got, ok, c.want)
+		}
+	}
+
+	for _, response := range []string{"purple", "I'm not sure", "redundant", "9"} {
+		if got, ok := matchOption(options, response); ok {
+			t.Errorf("matchOption(%q) = %q, want no match", response, got)
+		}
+	}
+}
+
+func TestSubmitResponse_StoresCanonicalOption(t *testing.T) {
+	cm := NewChallengeManager()
+	cm.StartChallenge("gate_1", "challenge_teamwork", "Explorer", "red")
+	cm.StartChallenge("gate_1", "challenge_teamwork", "Scout", "red")
+
+	cm.SubmitResponse("gate_1", "Explorer", "I choose red because we're Team Red")
+	cm.SubmitResponse("gate_1", "Scout", "Going with RED, obviously!")
+
+	if got := cm.GetActiveChallenge("gate_1").Responses["Explorer"]; got != "RED" {
+		t.Errorf("stored response = %q, want canonical RED", got)
+	}
+	if result := cm.EvaluateChallenge("gate_1"); result == nil || !result.Success {
+		t.Errorf("chatty matching answers failed: %+v", result)
+	}
+}
+
+func TestCountdowns_OnlyChallengesNearExpiry(t *testing.T) {
+	cm := NewChallengeManager()
+	soon, err := cm.StartChallenge("gate_1", "challenge_teamwork", "Explorer", "red")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.StartChallenge("gate_2", "challenge_teamwork", "Wanderer", "blue"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	soon.ExpiresAt = now.Add(7500 * time.Millisecond)
+	countdowns := cm.Countdowns(now, 10*time.Second)
+	if len(countdowns) != 1 || countdowns["gate_1"] != 8 {
+		t.Errorf("countdowns = %v, want gate_1 at 8s (rounded up)", countdowns)
+	}
+	if got := cm.ActiveSnapshot()["gate_2"].Remaining; got < 44 || got > 45 {
+		t.Errorf("snapshot seconds_remaining = %d, want ~45", got)
+	}
+
+	soon.ExpiresAt = now.Add(-time.Second)
+	if left := soon.SecondsRemaining(); left != 0 {
+		t.Errorf("expired SecondsRemaining = %d, want 0", left)
+	}
+}
+
+func TestPracticeMode_FreeHintsAndRefundedFailures(t *testing.T) {
+	cm := NewChallengeManager()
+	cm.SetPracticeMode(true)
+	cm.StartChallenge("gate_1", "challenge_teamwork", "Explorer", "red")
+	cm.StartChallenge("gate_1", "challenge_teamwork", "Scout", "red")
+
+	if _, ok := cm.UseHint("gate_1", -1); !ok {
+		t.Fatal("hint refused")
+	}
+	if penalty := cm.GetActiveChallenge("gate_1").HintPenalty; penalty != 0 {
+		t.Errorf("hint penalty = %d, want 0 in practice", penalty)
+	}
+
+	cm.SubmitResponse("gate_1", "Explorer", "RED")
+	cm.SubmitResponse("gate_1", "Scout", "BLUE")
+	result := cm.EvaluateChallenge("gate_1")
+	if result == nil || result.Success || !result.Refunded {
+		t.Fatalf("mismatched answers = %+v, want a refunded failure", result)
+	}
+
+	// The attempt reopens for the same pair, with nothing to judge yet
+	active := cm.GetActiveChallenge("gate_1")
+	if active.Status != StatusActive || len(active.Responses) != 0 || len(active.Participants) != 2 {
+		t.Errorf("reopened attempt = %s with %d responses and %d participants", active.Status, len(active.Responses), len(active.Participants))
+	}
+	if result := cm.EvaluateChallenge("gate_1"); result != nil {
+		t.Errorf("reopened attempt judged before responses: %+v", result)
+	}
+
+	cm.SubmitResponse("gate_1", "Explorer", "RED")
+	cm.SubmitResponse("gate_1", "Scout", "RED")
+	if result := cm.EvaluateChallenge("gate_1"); result == nil || !result.Success || result.TokensEarned != 40 {
+		t.Errorf("retry = %+v, want success with the full 40 tokens", result)
+	}
+}
+
+func TestMinParticipants_WaitsForEveryResponse(t *testing.T) {
+	cm := NewChallengeManager()
+	trio := *cm.Challenges["challenge_teamwork"]
+	trio.ID, trio.MinParticipants = "challenge_trio", 3
+	cm.Challenges[trio.ID] = &trio
+
+	for _, npc := range []string{"Explorer", "Scout", "Ranger"} {
+		if _, err := cm.StartChallenge("gate_1", trio.ID, npc, "red"); err != nil {
+			t.Fatalf("%s joining: %v", npc, err)
+		}
+	}
+	if _, err := cm.StartChallenge("gate_1", trio.ID, "Medic", "red"); !errors.Is(err, ErrChallengeFull) {
+		t.Errorf("fourth participant: err = %v, want ErrChallengeFull", err)
+	}
+
+	cm.SubmitResponse("gate_1", "Explorer", "RED")
+	cm.SubmitResponse("gate_1", "Scout", "RED")
+	if cm.ReadyToEvaluate("gate_1") {
+		t.Fatal("ready with 2 of 3 responses")
+	}
+	cm.SubmitResponse("gate_1", "Ranger", "RED")
+	if !cm.ReadyToEvaluate("gate_1") {
+		t.Fatal("not ready with all 3 responses")
+	}
+	if result := cm.EvaluateChallenge("gate_1"); result == nil || !result.Success {
+		t.Errorf("three matching answers = %+v", result)
+	}
+}
+
+func TestShuffledOptions_OnlyShownOrderSerialized(t *testing.T) {
+	cm := NewChallengeManager()
+	cm.SetSeed(7)
+	active, _ := cm.StartChallenge("gate_1", "challenge_teamwork", "Explorer", "red")
+
+	raw, err := json.Marshal(cm.ActiveSnapshot()["gate_1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Options   []string               `json:"options"`
+		Challenge map[string]interface{} `json:"challenge"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, leaked := decoded.Challenge["options"]; leaked {
+		t.Errorf("serialized challenge carries the unshuffled options: %v", decoded.Challenge["options"])
+	}
+	if !slices.Equal(decoded.Options, active.Options) {
+		t.Errorf("options = %v, want the shown order %v", decoded.Options, active.Options)
+	}
+}
@@ -0,0 +1,196 @@
+package challenge
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SpatialGrid is the map for a spatial challenge. Cells are [x, y] with
+// [0, 0] at the top left; "down" increases y.
+type SpatialGrid struct {
+	Width     int      `json:"width"`
+	Height    int      `json:"height"`
+	Start     [2]int   `json:"start"`
+	Goal      [2]int   `json:"goal"`
+	Obstacles [][2]int `json:"obstacles"`
+}
+
+// blocked reports whether a cell is an obstacle or off the grid
+func (g *SpatialGrid) blocked(cell [2]int) bool {
+	if cell[0] < 0 || cell[1] < 0 || cell[0] >= g.Width || cell[1] >= g.Height {
+		return true
+	}
+	for _, o := range g.Obstacles {
+		if o == cell {
+			return true
+		}
+	}
+	return false
+}
+
+// Render draws the grid for prompts: A start, B goal, # obstacle, . open
+func (g *SpatialGrid) Render() string {
+	var sb strings.Builder
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			cell := [2]int{x, y}
+			switch {
+			case cell == g.Start:
+				sb.WriteString("A")
+			case cell == g.Goal:
+				sb.WriteString("B")
+			case g.blocked(cell):
+				sb.WriteString("#")
+			default:
+				sb.WriteString(".")
+			}
+			if x < g.Width-1 {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ShortestPath returns the fewest moves from Start to Goal, or -1 if the goal
+// can't be reached
+func (g *SpatialGrid) ShortestPath() int {
+	dist := map[[2]int]int{g.Start: 0}
+	queue := [][2]int{g.Start}
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+		if cell == g.Goal {
+			return dist[cell]
+		}
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			next := [2]int{cell[0] + d[0], cell[1] + d[1]}
+			if _, seen := dist[next]; seen || g.blocked(next) {
+				continue
+			}
+			dist[next] = dist[cell] + 1
+			queue = append(queue, next)
+		}
+	}
+	return -1
+}
+
+// moveStep matches one move such as "right 2", "down", "u3" or "east 1"
+var moveStep = regexp.MustCompile(`(?i)\b(up|down|left|right|north|south|east|west|u|d|l|r)\s*(\d+)?\b`)
+
+// contraction matches words like "I'd" or "don't", removed before parsing so
+// their letters aren't read as single-letter moves
+var contraction = regexp.MustCompile(`\w+['’]\w+`)
+
+var moveDirections = map[string][2]int{
+	"up": {0, -1}, "north": {0, -1}, "u": {0, -1},
+	"down": {0, 1}, "south": {0, 1}, "d": {0, 1},
+	"left": {-1, 0}, "west": {-1, 0}, "l": {-1, 0},
+	"right": {1, 0}, "east": {1, 0}, "r": {1, 0},
+}
+
+// SpatialOutcome is the result of walking a route on a grid
+type SpatialOutcome struct {
+	Reached  bool    // Stepped onto the goal
+	Steps    int     // Moves taken before reaching the goal or stopping
+	End      [2]int  // Last cell reached
+	HitCell  *[2]int // Obstacle or off-grid cell the route ran into
+	Progress float64 // 0-1: how much closer End is to the goal than Start
+}
+
+// Walk parses a route like "right 2, down 3" and simulates it from Start,
+// stopping on reaching the goal or on the first move into an obstacle or
+// off the grid
+func (g *SpatialGrid) Walk(route string) SpatialOutcome {
+	out := SpatialOutcome{End: g.Start}
+	pos := g.Start
+
+	route = contraction.ReplaceAllString(route, " ")
+walk:
+	for _, m := range moveStep.FindAllStringSubmatch(route, -1) {
+		dir := moveDirections[strings.ToLower(m[1])]
+		count := 1
+		if m[2] != "" {
+			count, _ = strconv.Atoi(m[2])
+		}
+		for i := 0; i < count; i++ {
+			next := [2]int{pos[0] + dir[0], pos[1] + dir[1]}
+			if g.blocked(next) {
+				out.HitCell = &next
+				break walk
+			}
+			pos = next
+			out.Steps++
+			if pos == g.Goal {
+				out.Reached = true
+				break walk
+			}
+		}
+	}
+
+	out.End = pos
+	start := manhattan(g.Start, g.Goal)
+	if out.Reached || start == 0 {
+		out.Progress = 1
+	} else {
+		out.Progress = float64(start-manhattan(pos, g.Goal)) / float64(start)
+		if out.Progress < 0 {
+			out.Progress = 0
+		}
+	}
+	return out
+}
+
+func manhattan(a, b [2]int) int {
+	dx, dy := a[0]-b[0], a[1]-b[1]
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx + dy
+}
+
+// judgeSpatial walks each response on the challenge's grid and scores the
+// best one. Reaching the goal succeeds; otherwise partial credit is half the
+// progress made toward the goal, so a route that crashes early earns little.
+func judgeSpatial(grid *SpatialGrid, responses map[string]string) *ChallengeResult {
+	result := &ChallengeResult{}
+	npcs := make([]string, 0, len(responses))
+	for npc := range responses {
+		npcs = append(npcs, npc)
+	}
+	sort.Strings(npcs) // Ties go to the same NPC every time
+
+	best := SpatialOutcome{Progress: -1}
+	bestNPC := ""
+	for _, npc := range npcs {
+		out := grid.Walk(responses[npc])
+		if out.Reached && (!best.Reached || out.Steps < best.Steps) || !best.Reached && out.Progress > best.Progress {
+			best, bestNPC = out, npc
+		}
+	}
+	if bestNPC == "" {
+		result.Feedback = "No route given"
+		return result
+	}
+
+	switch {
+	case best.Reached:
+		result.Success = true
+		result.PartialCredit = 1.0
+		result.Feedback = fmt.Sprintf("%s reached the target in %d moves (shortest: %d)", bestNPC, best.Steps, grid.ShortestPath())
+	case best.HitCell != nil:
+		result.PartialCredit = best.Progress / 2
+		result.Feedback = fmt.Sprintf("%s's route hit an obstacle at %v after %d moves", bestNPC, *best.HitCell, best.Steps)
+	default:
+		result.PartialCredit = best.Progress / 2
+		result.Feedback = fmt.Sprintf("%s's route stopped at %v, short of the target at %v", bestNPC, best.End, grid.Goal)
+	}
+	return result
+}
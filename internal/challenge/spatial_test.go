@@ -0,0 +1,40 @@
+package challenge
+
+import "testing"
+
+func TestJudgeSpatial(t *testing.T) {
+	grid := NewChallengeManager().GetChallenge("challenge_spatial").SpatialGrid
+	if n := grid.ShortestPath(); n != 9 {
+		t.Fatalf("ShortestPath() = %d, want 9", n)
+	}
+
+	tests := []struct {
+		name    string
+		route   string
+		success bool
+		credit  float64
+	}{
+		{"optimal", "right 2, down 2, right 2, down 2, right 1", true, 1},
+		{"abbreviated", "R2 D2 R2 D2 R1", true, 1},
+		{"compass words", "east 2 then south 2, east 2, south 2, east", true, 1},
+		{"into obstacle", "right 3", false, 2.0 / 9 / 2},
+		{"off the grid", "up 1", false, 0},
+		{"stops short", "right 2, down 2", false, 4.0 / 9 / 2},
+		{"no moves", "I'm not sure", false, 0},
+		{"contractions", "I'd go right 2, then I'll head down 2 - don't stop - right 2, down 2, right 1", true, 1},
+		{"only contractions", "I'd say we'd better not", false, 0},
+	}
+	for _, tt := range tests {
+		result := judgeSpatial(grid, map[string]string{"Explorer": tt.route})
+		if result.Success != tt.success || result.PartialCredit != tt.credit {
+			t.Errorf("%s: success=%v credit=%.3f (%s); want %v, %.3f",
+				tt.name, result.Success, result.PartialCredit, result.Feedback, tt.success, tt.credit)
+		}
+	}
+
+	// Teammates: the best route counts
+	result := judgeSpatial(grid, map[string]string{"Explorer": "up", "Scout": "R2 D2 R2 D2 R1"})
+	if !result.Success {
+		t.Errorf("teammate's correct route should succeed: %s", result.Feedback)
+	}
+}
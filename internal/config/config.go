@@ -1,30 +1,171 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Game           GameConfig          `yaml:"game"`
-	NPCs           NPCConfig           `yaml:"npcs"`
-	Teams          TeamsConfig         `yaml:"teams"`
-	SLMProviders   []ProviderConfig    `yaml:"slm_providers"`
-	BrainProviders []ProviderConfig    `yaml:"brain_providers"`
-	ModelRoles     ModelRolesConfig    `yaml:"model_roles"`
-	Observability  ObservabilityConfig `yaml:"observability"`
-	Server         ServerConfig        `yaml:"server"`
+	Game           GameConfig                   `yaml:"game"`
+	NPCs           NPCConfig                    `yaml:"npcs"`
+	NPCProviders   map[string]NPCProviderConfig `yaml:"npc_providers"` // Keyed by NPC name
+	Teams          TeamsConfig                  `yaml:"teams"`
+	SLMProviders   []ProviderConfig             `yaml:"slm_providers"`
+	BrainProviders []ProviderConfig             `yaml:"brain_providers"`
+	ModelRoles     ModelRolesConfig             `yaml:"model_roles"`
+	Batch          BatchConfig                  `yaml:"batch"`
+	Cache          CacheConfig                  `yaml:"cache"`
+	LLM            LLMConfig                    `yaml:"llm"`
+	Observability  ObservabilityConfig          `yaml:"observability"`
+	Storage        StorageConfig                `yaml:"storage"`
+	Server         ServerConfig                 `yaml:"server"`
 }
 
 type GameConfig struct {
+	// World ticks per second. Settings counted in ticks (zone income
+	// interval, taunt cooldown, respawn delays, observation history, move
+	// speed) are written for 2 ticks/sec and rescaled to this rate, so
+	// "10 ticks" stays ~5s.
 	TickRate       int `yaml:"tick_rate"`
 	DecisionRate   int `yaml:"decision_rate"`
+	BroadcastRate  int `yaml:"broadcast_rate"` // State deltas sent to game clients per second (at most tick_rate)
 	WorldWidth     int `yaml:"world_width"`
 	WorldHeight    int `yaml:"world_height"`
 	StartingTokens int `yaml:"starting_tokens"`
 	HintCost       int `yaml:"hint_cost"`
 	SkipCost       int `yaml:"skip_cost"`
+
+	// Contested gates: reward multiplier when an opponent is within ContestRadius
+	ContestBonus  float64 `yaml:"contest_bonus"`
+	ContestRadius float64 `yaml:"contest_radius"`
+
+	// Challenges with this many seconds or fewer left get a
+	// challenge_countdown broadcast every second (default 10)
+	ChallengeCountdownSec int `yaml:"challenge_countdown_sec"`
+
+	ZoneIncome ZoneIncomeConfig `yaml:"zone_income"`
+
+	Respawn RespawnConfig `yaml:"respawn"`
+
+	GeneratedRewards GeneratedRewardsConfig `yaml:"generated_rewards"`
+
+	// Prompt awareness radii: an opponent within OpponentRadius prompts a
+	// social reaction, a teammate within SocialRadius prompts coordination,
+	// and gates within GateFocusRadius are flagged as priorities in batch tips
+	OpponentRadius  float64 `yaml:"opponent_radius"`
+	SocialRadius    float64 `yaml:"social_radius"`
+	GateFocusRadius float64 `yaml:"gate_focus_radius"`
+
+	// Safe mode confines NPCs to the start zone for tuning movement/social
+	// behavior: gates stay locked, zone generation and challenges are disabled
+	SafeMode bool `yaml:"safe_mode"`
+
+	// Practice mode is for exercising challenge content: hints are free and a
+	// failed attempt is refunded and reopened for an immediate retry. Results
+	// are exported as non-competitive.
+	PracticeMode bool `yaml:"practice_mode"`
+
+	// Strict memory: each NPC's memory code is sent once at game start as a
+	// Game Master message and left out of observations, so memory challenges
+	// test whether the NPC finds it in its recent messages. Only the last 5
+	// messages are kept: once chatter pushes the reveal out, the code is gone
+	// unless the NPC carried it forward (e.g. in its goal).
+	MemoryStrict bool `yaml:"memory_strict"`
+
+	// Loose bounds passes client-reported positions through unchanged; by
+	// default an off-map position is clamped to the world and flagged
+	// out_of_bounds in the observation so the NPC can walk back
+	LooseBounds bool `yaml:"loose_bounds"`
+
+	// Decision validators applied in order to every decision (default: all of
+	// known_action, self_target, unlocked_gate, taunt_cooldown, world_bounds,
+	// locked_zone)
+	DecisionValidators []string `yaml:"decision_validators"`
+	TauntCooldownTicks int      `yaml:"taunt_cooldown_ticks"` // Default 10 (~5s)
+
+	// What an NPC with full energy, no reachable locked gate and nobody nearby
+	// does instead of exploring at random; the first that applies wins
+	// (default: guard, regroup, patrol)
+	IdleBehaviors []string `yaml:"idle_behaviors"`
+
+	// Ticks (at 2/sec, rescaled to tick_rate) of recent positions included
+	// for each nearby NPC in observations, with how its distance changed, so
+	// NPCs can tell an opponent closing in from one retreating (0 = off; adds
+	// to every movement and batch prompt)
+	ObservationHistory int `yaml:"observation_history"`
+
+	// At most this many NPC decisions start per world tick (0 = unlimited);
+	// the rest wait their turn for a later tick
+	MaxDecisionsPerTick int `yaml:"max_decisions_per_tick"`
+
+	// Times in a row an NPC whose observation hasn't changed (same grid cell,
+	// same locked gates nearby) continues its last decision instead of asking
+	// the LLM again (default 3; negative always asks)
+	DecisionReuse int `yaml:"decision_reuse"`
+
+	// The server moves NPCs toward their targets every world tick and asks
+	// the batch system for their decisions DecisionRate times a second,
+	// instead of waiting for clients to send observations (for headless
+	// matches and spectator-only clients). The tick loop itself always runs;
+	// only this half is opt-in, because the bundled web clients still move
+	// NPCs and request decisions themselves: with both on, every NPC would
+	// have two movers and every decision would be paid for twice.
+	ServerDriven bool `yaml:"server_driven"`
+
+	// Distance a server-driven NPC covers per tick at 2 ticks/sec (default 10)
+	MoveSpeed float64 `yaml:"move_speed"`
+
+	// Challenges each gate's attempts are drawn from, by gate ID; a retry
+	// never faces the same challenge as the attempt before it. Gates not
+	// listed keep their single challenge.
+	ChallengePools map[string][]string `yaml:"challenge_pools"`
+
+	// Shuffle multi-choice challenge options per attempt, from Seed (0 = random)
+	ShuffleChallengeOptions bool  `yaml:"shuffle_challenge_options"`
+	Seed                    int64 `yaml:"seed"`
+
+	// World object types by name; entries override or extend the built-in
+	// treasure, resource, mystery and landmark types
+	ObjectTypes map[string]ObjectTypeConfig `yaml:"object_types"`
+}
+
+// ObjectTypeConfig defines what interacting with an object type does.
+// Behavior is one of tokens, energy, challenge or waypoint.
+type ObjectTypeConfig struct {
+	Behavior    string `yaml:"behavior"`
+	Amount      int    `yaml:"amount"`       // Tokens or energy granted
+	ChallengeID string `yaml:"challenge_id"` // Mini-challenge for the challenge behavior
+	Teammates   int    `yaml:"teammates"`    // NPCs the mini-challenge needs, at least
+}
+
+// ZoneIncomeConfig controls passive token income from controlled zones:
+// every IntervalTicks, each team earns Zone.Rewards / Divisor per zone it holds
+type ZoneIncomeConfig struct {
+	Enabled       bool `yaml:"enabled"` // Off unless a config opts in
+	Divisor       int  `yaml:"divisor"`
+	IntervalTicks int  `yaml:"interval_ticks"`
+}
+
+// RespawnConfig scales respawn delay by the score gap between teams so the
+// trailing team gets back into play sooner: a team ScoreGapForMax points
+// behind respawns after MinTicks, one that far ahead after MaxTicks
+type RespawnConfig struct {
+	BaseTicks      int `yaml:"base_ticks"`        // Delay with scores level (default 10, ~5s)
+	MinTicks       int `yaml:"min_ticks"`         // Default BaseTicks / 2
+	MaxTicks       int `yaml:"max_ticks"`         // Default BaseTicks * 2
+	ScoreGapForMax int `yaml:"score_gap_for_max"` // Gap at which the bounds are reached (default 100)
+}
+
+// GeneratedRewardsConfig bounds the rewards of LLM-generated zones; values
+// outside are clamped (defaults: zones 20-60, challenges 20-50 tokens)
+type GeneratedRewardsConfig struct {
+	ZoneMin      int `yaml:"zone_min"`
+	ZoneMax      int `yaml:"zone_max"`
+	ChallengeMin int `yaml:"challenge_min"`
+	ChallengeMax int `yaml:"challenge_max"`
 }
 
 type NPCConfig struct {
@@ -32,15 +173,23 @@ type NPCConfig struct {
 	Names []string `yaml:"names"`
 }
 
+// NPCProviderConfig pins one NPC to an SLM provider and optionally a model.
+// NPC_<NAME>_PROVIDER / NPC_<NAME>_MODEL env vars take precedence.
+type NPCProviderConfig struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`
+}
+
 type TeamsConfig struct {
 	Red  TeamConfig `yaml:"red"`
 	Blue TeamConfig `yaml:"blue"`
 }
 
 type TeamConfig struct {
-	Name    string   `yaml:"name"`
-	Color   string   `yaml:"color"`
-	Members []string `yaml:"members"`
+	Name     string   `yaml:"name"`
+	Color    string   `yaml:"color"`
+	Members  []string `yaml:"members"`
+	Strategy string   `yaml:"strategy"` // aggressive, objective or balanced (default)
 }
 
 type ProviderConfig struct {
@@ -49,6 +198,53 @@ type ProviderConfig struct {
 	APIKey  string `yaml:"api_key"`
 	BaseURL string `yaml:"base_url"`
 	Model   string `yaml:"model"`
+
+	// Known-good model to switch to if Model is reported as not found
+	FallbackModel string `yaml:"fallback_model"`
+
+	// Price in USD per 1K tokens; without an explicit llm.fallback_order,
+	// fallbacks try the cheapest provider first (0 = unknown, sorts first)
+	CostPer1KTokens float64 `yaml:"cost_per_1k_tokens"`
+
+	// Separate input (prompt) and output (completion) prices for usage
+	// costs; either left at 0 uses cost_per_1k_tokens
+	CostPer1KInputTokens  float64 `yaml:"cost_per_1k_input_tokens"`
+	CostPer1KOutputTokens float64 `yaml:"cost_per_1k_output_tokens"`
+
+	// Requests per day before the provider's free tier runs out (0 = unlimited)
+	DailyQuota int `yaml:"daily_quota"`
+
+	// Send decisions as a "decide" function definition and read the model's
+	// structured call instead of parsing text (OpenAI-compatible and Gemini
+	// APIs; the model must support tool calling)
+	ToolCalling bool `yaml:"tool_calling"`
+
+	// Gemini only: harm category -> block threshold
+	SafetySettings map[string]string `yaml:"safety_settings"`
+
+	PromptFormat PromptFormatConfig `yaml:"prompt_format"`
+
+	// RequiresKey says whether the provider is skipped without an API key.
+	// Nil means yes, except for local servers (see KeyRequired).
+	RequiresKey *bool `yaml:"requires_key"`
+}
+
+// KeyRequired reports whether the provider needs an API key to be loaded:
+// RequiresKey when set, else true for all but local servers (ollama, or a
+// localhost base_url)
+func (p ProviderConfig) KeyRequired() bool {
+	if p.RequiresKey != nil {
+		return *p.RequiresKey
+	}
+	local := p.Name == "ollama" || strings.Contains(p.BaseURL, "localhost") || strings.Contains(p.BaseURL, "127.0.0.1")
+	return !local
+}
+
+// PromptFormatConfig adapts final prompts to a provider's model quirks
+type PromptFormatConfig struct {
+	StripEmoji         bool   `yaml:"strip_emoji"`          // For models that choke on emoji
+	JSONReminderSuffix string `yaml:"json_reminder_suffix"` // Appended so the JSON instruction comes last
+	MaxSystemLen       int    `yaml:"max_system_len"`       // Cap on the leading role/rules section (0 = no cap)
 }
 
 type ModelRolesConfig struct {
@@ -57,25 +253,163 @@ type ModelRolesConfig struct {
 	Judge      RoleConfig `yaml:"judge"`
 	ZoneGen    RoleConfig `yaml:"zone_generator"`
 	Commentary RoleConfig `yaml:"commentary"`
+	Strategy   RoleConfig `yaml:"strategy"`
 }
 
 type RoleConfig struct {
-	Provider    string  `yaml:"provider"`
-	Model       string  `yaml:"model"`
-	MaxTokens   int     `yaml:"max_tokens"`
-	Temperature float64 `yaml:"temperature"`
+	// Tier (cheap, balanced or quality) picks the role's provider at startup
+	// from the enabled providers by cost_per_1k_tokens, instead of the
+	// default routing. Empty keeps the default.
+	Tier string `yaml:"tier"`
+
+	Provider    string   `yaml:"provider"`
+	Model       string   `yaml:"model"`
+	MaxTokens   int      `yaml:"max_tokens"`
+	Temperature *float64 `yaml:"temperature"` // Unset = the default; 0 is a valid (greedy) setting
+}
+
+// temperature is a RoleConfig.Temperature literal
+func temperature(t float64) *float64 { return &t }
+
+type BatchConfig struct {
+	// TimeoutFallback selects what uncached NPCs get when the batch call fails:
+	// "stale" (default) reuses the NPC's last LLM decision (or an expired cache entry),
+	// "explore" always falls back to the generic default decision.
+	TimeoutFallback string `yaml:"timeout_fallback"`
+
+	// Adaptive chunking: NPCs per LLM call start at MaxChunk, grow while the
+	// average call is faster than FastMs and shrink while slower than SlowMs
+	MinChunk int `yaml:"min_chunk"`
+	MaxChunk int `yaml:"max_chunk"`
+	FastMs   int `yaml:"fast_ms"`
+	SlowMs   int `yaml:"slow_ms"`
+
+	// After ParseFailureLimit consecutive unparseable batch responses from a
+	// provider (default 3), its NPCs get per-NPC decisions for
+	// DisableCooldownSec (default 300) before batching is retried
+	ParseFailureLimit  int `yaml:"parse_failure_limit"`
+	DisableCooldownSec int `yaml:"disable_cooldown_sec"`
+}
+
+// CacheConfig sizes the batch decision cache (defaults: 100 entries, 10s TTL)
+type CacheConfig struct {
+	MaxSize    int `yaml:"max_size"`
+	TTLSeconds int `yaml:"ttl_seconds"`
+}
+
+type LLMConfig struct {
+	// MaxRetries is the retry budget for primary calls; fallback and
+	// commentary calls get half of it. Nil means the default (2).
+	// LLM_MAX_RETRIES overrides it.
+	MaxRetries *int `yaml:"max_retries"`
+	// TimeoutSec is the per-request HTTP timeout. LLM_TIMEOUT_SEC overrides it.
+	TimeoutSec int `yaml:"timeout_sec"`
+
+	// PromptDir holds optional <role>.tmpl overrides (movement, challenge,
+	// judge, commentary, batch). Empty uses the built-in prompts.
+	PromptDir string `yaml:"prompt_dir"`
+
+	// JSONStrictness: "lenient" (default) repairs malformed model JSON,
+	// "strict" treats anything unparseable as a failure
+	JSONStrictness string `yaml:"json_strictness"`
+
+	// JudgeDeadlineSec bounds how long challenge judging may take before the
+	// simple rule-based judge is used instead (default 5)
+	JudgeDeadlineSec int `yaml:"judge_deadline_sec"`
+
+	// StrategyCacheTTLSec is how long a team's last good brain strategy is
+	// reused while the brain is failing (default 300)
+	StrategyCacheTTLSec int `yaml:"strategy_cache_ttl_sec"`
+
+	// FallbackOrder lists SLM provider names to try, in order, when an NPC's
+	// provider fails. Empty means cheapest first by cost_per_1k_tokens.
+	FallbackOrder []string `yaml:"fallback_order"`
+
+	// ChallengeSolver picks who answers challenges: "npc" (default) uses the
+	// NPC's own provider, as for its movement, so its model's personality
+	// carries over; "role" uses model_roles.challenge (its tier, else its
+	// provider and model) for every NPC
+	ChallengeSolver string `yaml:"challenge_solver"`
+
+	// SkipPreflight turns off the test call per provider at startup (repeated
+	// every 30s while none answers) that lets /readyz report ready before any
+	// game traffic arrives
+	SkipPreflight bool `yaml:"skip_preflight"`
+
+	Quota QuotaConfig `yaml:"quota"`
+
+	AutoPause AutoPauseConfig `yaml:"auto_pause"`
+
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+}
+
+// AutoPauseConfig pauses LLM decision requests (NPCs get default decisions)
+// while the rolling error rate is high, resuming once it subsides
+type AutoPauseConfig struct {
+	Enabled    bool    `yaml:"enabled"`
+	ErrorRate  float64 `yaml:"error_rate"`  // Pause at or above this fraction of failed calls (default 0.8)
+	ResumeRate float64 `yaml:"resume_rate"` // Resume below this (default error_rate / 2)
+	WindowSec  int     `yaml:"window_sec"`  // Rolling window (default 60)
+	MinCalls   int     `yaml:"min_calls"`   // Calls needed in the window before pausing (default 10)
+}
+
+// CircuitBreakerConfig stops calling a provider that keeps failing: after
+// FailureThreshold consecutive failures within WindowSec it is skipped for
+// CooldownSec, then one probe call decides whether it's back
+type CircuitBreakerConfig struct {
+	FailureThreshold int `yaml:"failure_threshold"` // Default 3; negative disables the breaker
+	WindowSec        int `yaml:"window_sec"`        // Default 60
+	CooldownSec      int `yaml:"cooldown_sec"`      // Default 30
+}
+
+type QuotaConfig struct {
+	WarnThreshold float64 `yaml:"warn_threshold"` // Fraction of daily_quota that triggers a warning (default 0.8)
+	Reroute       bool    `yaml:"reroute"`        // Route new traffic away from providers past the threshold
+	ResetHourUTC  int     `yaml:"reset_hour_utc"` // Hour of day (UTC) when counters reset
 }
 
 type ObservabilityConfig struct {
 	TraceEnabled  bool   `yaml:"trace_enabled"`
-	TracePath     string `yaml:"trace_path"`
+	TracePath     string `yaml:"trace_path"` // A .gz suffix gzip-compresses the trace log
+	TraceFlushMs  int    `yaml:"trace_flush_ms"`
 	AuditEnabled  bool   `yaml:"audit_enabled"`
 	AuditPath     string `yaml:"audit_path"`
 	ReplayEnabled bool   `yaml:"replay_enabled"`
+
+	// Lossless per-tick event log (every decision and state change) in
+	// TickLogPath (default logs/ticks.jsonl); heavier than replay snapshots
+	TickLogEnabled bool   `yaml:"tick_log_enabled"`
+	TickLogPath    string `yaml:"tick_log_path"`
+
+	// Store prompt text in traces and the audit log; false keeps only a hash and length
+	IncludePrompts bool `yaml:"include_prompts"`
+
+	// NPCs whose decision p95 latency exceeds this are flagged slow by
+	// /npc/:name/latency (default 3000)
+	SlowNPCP95Ms int `yaml:"slow_npc_p95_ms"`
+
+	Webhook WebhookConfig `yaml:"webhook"`
+}
+
+// WebhookConfig posts significant game events to an external URL (disabled when URL is empty)
+type WebhookConfig struct {
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"` // HMAC-SHA256 signing key for X-NPC-Arena-Signature
+	Events []string `yaml:"events"` // Default: match_over, lead_change, zone_generated
+}
+
+// StorageConfig selects where replays and logs are persisted. Backend "file"
+// (the default) stores keys as files under Path (default: working directory).
+type StorageConfig struct {
+	Backend string `yaml:"backend"`
+	Path    string `yaml:"path"`
 }
 
 type ServerConfig struct {
 	Port int `yaml:"port"`
+
+	// Debug enables the /debug endpoints (referee overrides etc.)
+	Debug bool `yaml:"debug"`
 }
 
 func Load(path string) (*Config, error) {
@@ -95,16 +429,38 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// Validate rejects configurations the game can't run with: the first
+// error-severity issue from Check. Warnings are left to the caller.
+func (c *Config) Validate() error {
+	if errs := Errors(c.Check()); len(errs) > 0 {
+		return fmt.Errorf("%s", errs[0])
+	}
+	return nil
+}
+
 func Default() *Config {
 	return &Config{
 		Game: GameConfig{
 			TickRate:       60,
 			DecisionRate:   2,
+			BroadcastRate:  10,
 			WorldWidth:     1200,
 			WorldHeight:    800,
 			StartingTokens: 50,
 			HintCost:       5,
 			SkipCost:       20,
+			ContestBonus:   1.5,
+			ContestRadius:  150,
+
+			OpponentRadius:  80,
+			SocialRadius:    100,
+			GateFocusRadius: 150,
+
+			ShuffleChallengeOptions: true,
+			ZoneIncome: ZoneIncomeConfig{
+				Divisor:       10,
+				IntervalTicks: 20,
+			},
 		},
 		NPCs: NPCConfig{
 			Count: 4,
@@ -112,14 +468,16 @@ func Default() *Config {
 		},
 		Teams: TeamsConfig{
 			Red: TeamConfig{
-				Name:    "Team Red",
-				Color:   "#ef4444",
-				Members: []string{"Explorer", "Scout"},
+				Name:     "Team Red",
+				Color:    "#ef4444",
+				Members:  []string{"Explorer", "Scout"},
+				Strategy: "balanced",
 			},
 			Blue: TeamConfig{
-				Name:    "Team Blue",
-				Color:   "#3b82f6",
-				Members: []string{"Wanderer", "Seeker"},
+				Name:     "Team Blue",
+				Color:    "#3b82f6",
+				Members:  []string{"Wanderer", "Seeker"},
+				Strategy: "balanced",
 			},
 		},
 		SLMProviders: []ProviderConfig{
@@ -129,11 +487,26 @@ func Default() *Config {
 			{Name: "gemini", Enabled: true, Model: "gemini-2.0-flash"},
 		},
 		ModelRoles: ModelRolesConfig{
-			Movement:   RoleConfig{Provider: "groq", Model: "llama-3.1-8b-instant", MaxTokens: 50, Temperature: 0.3},
-			Challenge:  RoleConfig{Provider: "groq", Model: "llama-3.1-8b-instant", MaxTokens: 200, Temperature: 0.7},
-			Judge:      RoleConfig{Provider: "gemini", Model: "gemini-2.0-flash", MaxTokens: 100, Temperature: 0.1},
-			ZoneGen:    RoleConfig{Provider: "gemini", Model: "gemini-2.0-flash", MaxTokens: 500, Temperature: 0.9},
-			Commentary: RoleConfig{Provider: "groq", Model: "llama-3.1-8b-instant", MaxTokens: 30, Temperature: 0.8},
+			Movement:   RoleConfig{Provider: "groq", Model: "llama-3.1-8b-instant", MaxTokens: 50, Temperature: temperature(0.3)},
+			Challenge:  RoleConfig{Provider: "groq", Model: "llama-3.1-8b-instant", MaxTokens: 200, Temperature: temperature(0.7)},
+			Judge:      RoleConfig{Provider: "gemini", Model: "gemini-2.0-flash", MaxTokens: 100, Temperature: temperature(0.1)},
+			ZoneGen:    RoleConfig{Provider: "gemini", Model: "gemini-2.0-flash", MaxTokens: 500, Temperature: temperature(0.9)},
+			Commentary: RoleConfig{Provider: "groq", Model: "llama-3.1-8b-instant", MaxTokens: 30, Temperature: temperature(0.8)},
+			Strategy:   RoleConfig{Provider: "gemini", Model: "gemini-2.0-flash", MaxTokens: 100, Temperature: temperature(0.7)},
+		},
+		Batch: BatchConfig{
+			TimeoutFallback: "stale",
+			MinChunk:        2,
+			MaxChunk:        8,
+			FastMs:          2000,
+			SlowMs:          6000,
+		},
+		Cache: CacheConfig{MaxSize: 100, TTLSeconds: 10},
+		LLM: LLMConfig{
+			TimeoutSec:       30,
+			JSONStrictness:   "lenient",
+			JudgeDeadlineSec: 5,
+			Quota:            QuotaConfig{WarnThreshold: 0.8},
 		},
 		Observability: ObservabilityConfig{
 			TraceEnabled:  true,
@@ -141,7 +514,10 @@ func Default() *Config {
 			AuditEnabled:  true,
 			AuditPath:     "./logs/audit.log",
 			ReplayEnabled: true,
+
+			IncludePrompts: true,
 		},
-		Server: ServerConfig{Port: 8080},
+		Storage: StorageConfig{Backend: "file"},
+		Server:  ServerConfig{Port: 8080},
 	}
 }
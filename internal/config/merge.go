@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadWithOverrides loads a base config followed by override files, each
+// later file overriding fields of the earlier ones. Environment variables are
+// expanded in every file, so they apply throughout the merged result.
+//
+// Merge semantics:
+//   - mappings merge key by key, recursively
+//   - scalars and other lists are replaced wholesale
+//   - slm_providers and brain_providers merge by provider name: an override
+//     entry with a known name updates only the fields it sets, new names are
+//     appended, and base providers it doesn't mention are kept (set
+//     enabled: false to drop one)
+func LoadWithOverrides(paths ...string) (*Config, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config files given")
+	}
+
+	merged := map[string]interface{}{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var layer map[string]interface{}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &layer); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		merged = mergeMaps(merged, layer)
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// namedLists are the lists merged entry by entry on their "name" field
+var namedLists = map[string]bool{"slm_providers": true, "brain_providers": true}
+
+// mergeMaps deep-merges override into base
+func mergeMaps(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		if existing, ok := base[key].(map[string]interface{}); ok {
+			if layer, ok := value.(map[string]interface{}); ok {
+				base[key] = mergeMaps(existing, layer)
+				continue
+			}
+		}
+		if namedLists[key] {
+			if existing, ok := base[key].([]interface{}); ok {
+				if layer, ok := value.([]interface{}); ok {
+					base[key] = mergeNamed(existing, layer)
+					continue
+				}
+			}
+		}
+		base[key] = value
+	}
+	return base
+}
+
+// mergeNamed merges list entries that share a "name", appending the rest
+func mergeNamed(base, override []interface{}) []interface{} {
+	index := make(map[string]int, len(base))
+	for i, entry := range base {
+		if m, ok := entry.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				index[name] = i
+			}
+		}
+	}
+
+	for _, entry := range override {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			base = append(base, entry)
+			continue
+		}
+		name, _ := m["name"].(string)
+		if i, found := index[name]; found && name != "" {
+			if existing, ok := base[i].(map[string]interface{}); ok {
+				base[i] = mergeMaps(existing, m)
+				continue
+			}
+		}
+		index[name] = len(base)
+		base = append(base, entry)
+	}
+	return base
+}
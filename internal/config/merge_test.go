@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadWithOverrides_MergesOverriddenAndKeepsUntouched(t *testing.T) {
+	base := writeConfig(t, "config.yaml", `
+game:
+  tick_rate: 60
+  world_width: 1200
+  world_height: 800
+slm_providers:
+  - name: groq
+    model: llama-3.1-8b-instant
+    enabled: true
+  - name: ollama
+    model: llama3
+    enabled: true
+server:
+  port: 8080
+`)
+	override := writeConfig(t, "prod.yaml", `
+game:
+  world_width: 1600
+slm_providers:
+  - name: groq
+    model: llama-3.3-70b
+  - name: nebius
+    model: qwen
+    enabled: true
+`)
+
+	cfg, err := LoadWithOverrides(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Game.WorldWidth != 1600 {
+		t.Errorf("world_width = %d, want the override's 1600", cfg.Game.WorldWidth)
+	}
+	if cfg.Game.TickRate != 60 || cfg.Game.WorldHeight != 800 || cfg.Server.Port != 8080 {
+		t.Errorf("untouched fields changed: tick_rate %d, world_height %d, port %d",
+			cfg.Game.TickRate, cfg.Game.WorldHeight, cfg.Server.Port)
+	}
+
+	providers := cfg.SLMProviders
+	if len(providers) != 3 {
+		t.Fatalf("slm_providers = %+v, want groq, ollama and nebius", providers)
+	}
+	if providers[0].Name != "groq" || providers[0].Model != "llama-3.3-70b" || !providers[0].Enabled {
+		t.Errorf("groq = %+v, want the new model and still enabled", providers[0])
+	}
+	if providers[1].Name != "ollama" || providers[1].Model != "llama3" {
+		t.Errorf("ollama = %+v, want it kept as is", providers[1])
+	}
+	if providers[2].Name != "nebius" {
+		t.Errorf("new provider = %+v, want nebius appended", providers[2])
+	}
+}
+
+func TestLoadWithOverrides_BadOverrideIsAnError(t *testing.T) {
+	base := writeConfig(t, "config.yaml", "game:\n  tick_rate: 60\n")
+
+	_, err := LoadWithOverrides(base, filepath.Join(t.TempDir(), "missing.yaml"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing override: err = %v, want not-exist", err)
+	}
+
+	broken := writeConfig(t, "broken.yaml", "game: [unclosed\n")
+	if _, err := LoadWithOverrides(base, broken); err == nil {
+		t.Error("unparseable override loaded without error")
+	}
+}
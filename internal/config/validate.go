@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"   // The server refuses to start
+	SeverityWarning = "warning" // Runs, but probably not as intended
+)
+
+// Issue is one problem found by Check
+type Issue struct {
+	Severity string `json:"severity"`
+	Field    string `json:"field"` // YAML path, e.g. "llm.fallback_order"
+	Message  string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Field, i.Message)
+}
+
+// Check lists every problem in the config, errors and warnings alike, so a
+// config can be vetted in CI without starting the server
+func (c *Config) Check() []Issue {
+	var issues []Issue
+	errorf := func(field, format string, args ...interface{}) {
+		issues = append(issues, Issue{SeverityError, field, fmt.Sprintf(format, args...)})
+	}
+	warnf := func(field, format string, args ...interface{}) {
+		issues = append(issues, Issue{SeverityWarning, field, fmt.Sprintf(format, args...)})
+	}
+
+	// Zones are laid out as quadrants of the world; a zero-sized world has no
+	// usable zones
+	if c.Game.WorldWidth <= 0 || c.Game.WorldHeight <= 0 {
+		errorf("game.world_width", "world_width and world_height must be positive (got %dx%d): the world would have no zones",
+			c.Game.WorldWidth, c.Game.WorldHeight)
+	}
+	if c.Game.TickRate < 0 {
+		errorf("game.tick_rate", "must not be negative (got %d)", c.Game.TickRate)
+	}
+	if r := c.Game.Respawn; r.MinTicks > 0 && r.MaxTicks > 0 && r.MinTicks > r.MaxTicks {
+		errorf("game.respawn", "min_ticks (%d) is above max_ticks (%d)", r.MinTicks, r.MaxTicks)
+	}
+
+	for _, team := range []struct {
+		field    string
+		strategy string
+	}{{"teams.red", c.Teams.Red.Strategy}, {"teams.blue", c.Teams.Blue.Strategy}} {
+		switch team.strategy {
+		case "", "aggressive", "objective", "balanced":
+		default:
+			warnf(team.field+".strategy", "unknown strategy %q, balanced will be used", team.strategy)
+		}
+	}
+
+	// Providers: unique names, keys present once env vars are expanded
+	slm := checkProviders("slm_providers", c.SLMProviders, errorf, warnf)
+	brain := checkProviders("brain_providers", c.BrainProviders, errorf, warnf)
+	if len(slm) == 0 {
+		warnf("slm_providers", "no enabled providers: every NPC decision will be a default decision")
+	}
+	known := func(name string) bool { return slm[name] || brain[name] }
+
+	for _, name := range c.LLM.FallbackOrder {
+		if !slm[name] {
+			warnf("llm.fallback_order", "%q is not an enabled SLM provider", name)
+		}
+	}
+	npcs := make([]string, 0, len(c.NPCProviders))
+	for npc := range c.NPCProviders {
+		npcs = append(npcs, npc)
+	}
+	sort.Strings(npcs)
+	for _, npc := range npcs {
+		if p := c.NPCProviders[npc].Provider; p != "" && !known(p) {
+			warnf("npc_providers."+npc, "provider %q is not enabled", p)
+		}
+	}
+	roles := []struct {
+		name string
+		RoleConfig
+	}{
+		{"movement", c.ModelRoles.Movement},
+		{"challenge", c.ModelRoles.Challenge},
+		{"judge", c.ModelRoles.Judge},
+		{"zone_generator", c.ModelRoles.ZoneGen},
+		{"commentary", c.ModelRoles.Commentary},
+		{"strategy", c.ModelRoles.Strategy},
+	}
+	for _, r := range roles {
+		if r.Provider != "" && !known(r.Provider) {
+			warnf("model_roles."+r.name, "provider %q is not enabled", r.Provider)
+		}
+		switch r.Tier {
+		case "", "cheap", "balanced", "quality":
+		default:
+			errorf("model_roles."+r.name+".tier", "must be cheap, balanced or quality (got %q)", r.Tier)
+		}
+	}
+
+	switch c.Batch.TimeoutFallback {
+	case "", "stale", "explore":
+	default:
+		errorf("batch.timeout_fallback", "must be stale or explore (got %q)", c.Batch.TimeoutFallback)
+	}
+	if c.Batch.MinChunk > 0 && c.Batch.MaxChunk > 0 && c.Batch.MinChunk > c.Batch.MaxChunk {
+		errorf("batch.min_chunk", "min_chunk (%d) is above max_chunk (%d)", c.Batch.MinChunk, c.Batch.MaxChunk)
+	}
+
+	switch c.LLM.ChallengeSolver {
+	case "", "npc", "role":
+	default:
+		errorf("llm.challenge_solver", "must be npc or role (got %q)", c.LLM.ChallengeSolver)
+	}
+
+	switch c.LLM.JSONStrictness {
+	case "", "lenient", "strict":
+	default:
+		errorf("llm.json_strictness", "must be lenient or strict (got %q)", c.LLM.JSONStrictness)
+	}
+	if q := c.LLM.Quota; q.ResetHourUTC < 0 || q.ResetHourUTC > 23 {
+		errorf("llm.quota.reset_hour_utc", "must be 0-23 (got %d)", q.ResetHourUTC)
+	}
+	if q := c.LLM.Quota; q.WarnThreshold < 0 || q.WarnThreshold > 1 {
+		warnf("llm.quota.warn_threshold", "is a fraction of daily_quota and should be 0-1 (got %g)", q.WarnThreshold)
+	}
+	if a := c.LLM.AutoPause; a.Enabled && a.ErrorRate > 0 && a.ResumeRate > a.ErrorRate {
+		errorf("llm.auto_pause.resume_rate", "resume_rate (%g) is above error_rate (%g)", a.ResumeRate, a.ErrorRate)
+	}
+
+	switch c.Storage.Backend {
+	case "", "file":
+	default:
+		errorf("storage.backend", "unknown storage backend %q", c.Storage.Backend)
+	}
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
+		errorf("server.port", "must be 0-65535 (got %d)", c.Server.Port)
+	}
+	if c.Server.Debug {
+		warnf("server.debug", "debug endpoints (referee overrides) are enabled")
+	}
+	return issues
+}
+
+// checkProviders reports unnamed and duplicate providers and missing keys,
+// returning the names of enabled providers
+func checkProviders(field string, providers []ProviderConfig, errorf, warnf func(field, format string, args ...interface{})) map[string]bool {
+	enabled := make(map[string]bool)
+	seen := make(map[string]bool)
+	for i, p := range providers {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if p.Name == "" {
+			errorf(path, "provider has no name")
+			continue
+		}
+		if seen[p.Name] {
+			errorf(path, "duplicate provider %q", p.Name)
+		}
+		seen[p.Name] = true
+		if !p.Enabled {
+			continue
+		}
+		enabled[p.Name] = true
+		if p.APIKey == "" && p.KeyRequired() {
+			warnf(path+".api_key", "%s is enabled but has no API key (is its env var set?)", p.Name)
+		}
+	}
+	return enabled
+}
+
+// Errors returns only the error-severity issues
+func Errors(issues []Issue) []Issue {
+	var errs []Issue
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
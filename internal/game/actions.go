@@ -0,0 +1,80 @@
+package game
+
+import "sync"
+
+// ActionStats counts decision actions per NPC and per team over a match
+type ActionStats struct {
+	mu     sync.Mutex
+	byNPC  map[string]map[string]int // npc name -> action -> count
+	byTeam map[string]map[string]int // team ID -> action -> count
+	total  map[string]int
+}
+
+// NewActionStats creates an empty action histogram
+func NewActionStats() *ActionStats {
+	as := &ActionStats{}
+	as.Reset()
+	return as
+}
+
+// Record counts one decision
+func (as *ActionStats) Record(npcName, teamID, action string) {
+	if action == "" {
+		action = "unknown"
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.byNPC[npcName] == nil {
+		as.byNPC[npcName] = make(map[string]int)
+	}
+	as.byNPC[npcName][action]++
+
+	if teamID != "" {
+		if as.byTeam[teamID] == nil {
+			as.byTeam[teamID] = make(map[string]int)
+		}
+		as.byTeam[teamID][action]++
+	}
+
+	as.total[action]++
+}
+
+// Reset clears all counters (e.g. on match reset)
+func (as *ActionStats) Reset() {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.byNPC = make(map[string]map[string]int)
+	as.byTeam = make(map[string]map[string]int)
+	as.total = make(map[string]int)
+}
+
+// Snapshot returns a copy of the histograms for the stats endpoint
+func (as *ActionStats) Snapshot() map[string]interface{} {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	return map[string]interface{}{
+		"by_npc":  copyHistograms(as.byNPC),
+		"by_team": copyHistograms(as.byTeam),
+		"total":   copyCounts(as.total),
+	}
+}
+
+func copyHistograms(src map[string]map[string]int) map[string]map[string]int {
+	dst := make(map[string]map[string]int, len(src))
+	for key, counts := range src {
+		dst[key] = copyCounts(counts)
+	}
+	return dst
+}
+
+func copyCounts(src map[string]int) map[string]int {
+	dst := make(map[string]int, len(src))
+	for action, n := range src {
+		dst[action] = n
+	}
+	return dst
+}
@@ -0,0 +1,230 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"strings"
+)
+
+// DecisionResult describes how a decision was applied to the world
+type DecisionResult struct {
+	Action   string      `json:"action"`
+	Target   *[2]float64 `json:"target,omitempty"`  // Final move target after validation
+	Clamped  bool        `json:"clamped,omitempty"` // Move target was pulled back from a locked zone
+	Feedback string      `json:"feedback,omitempty"`
+
+	// The decision as applied: validated, with any feedback. A copy, so a
+	// cached or shared decision map is never modified.
+	Decision map[string]interface{} `json:"-"`
+}
+
+// ApplyDecision runs a copy of a decision through the world's validator chain
+// and records it. Validators may rewrite the copy (e.g. clamping a move
+// target short of a locked zone), which is returned as result.Decision;
+// their notes are logged, returned as feedback and kept on the NPC so its
+// next observation can learn from them. An explore decision for an NPC with
+// nothing to do becomes its IdleBehavior.
+func (w *World) ApplyDecision(npcName string, decision map[string]interface{}) DecisionResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.applyDecision(npcName, decision)
+}
+
+func (w *World) applyDecision(npcName string, decision map[string]interface{}) DecisionResult {
+	decision = maps.Clone(decision) // The caller's map may be cached and sent concurrently
+	npc := w.GetNPCByName(npcName)
+	if npc == nil {
+		w.RecordAction(npcName, decision)
+		w.decisionApplied(npcName, decision)
+		action, _ := decision["action"].(string)
+		return DecisionResult{Action: action, Decision: decision}
+	}
+
+	result := DecisionResult{Decision: decision}
+	var notes []string
+	if action, _ := decision["action"].(string); action == "explore" && w.isIdle(npc) {
+		if idle := w.IdleBehavior(npc); idle != nil {
+			delete(decision, "target")
+			for k, v := range idle {
+				decision[k] = v
+			}
+		}
+	}
+	for _, v := range w.validators {
+		if note := v.Validate(w, npc, decision); note != "" {
+			log.Printf("🛡️ [%s] %s: %s", v.Name(), npcName, note)
+			notes = append(notes, note)
+			if _, ok := v.(lockedZoneValidator); ok {
+				result.Clamped = true
+			}
+		}
+	}
+
+	action, _ := decision["action"].(string)
+	result.Action = action
+	w.RecordAction(npcName, decision)
+
+	if goal, ok := decision["goal"].(string); ok {
+		if err := w.SetGoal(npcName, goal); err != nil {
+			notes = append(notes, "goal rejected: "+err.Error())
+		}
+	}
+
+	if action == "move" || action == "explore" {
+		if target, ok := parseTarget(decision["target"]); ok {
+			result.Target = &target
+			decision["target"] = []float64{target[0], target[1]}
+			npc.Target = &target
+		}
+	}
+
+	if action == "interact" {
+		objectID, _ := decision["target"].(string)
+		interaction, err := w.applyInteract(npcName, objectID)
+		if err != nil {
+			notes = append(notes, "interact failed: "+err.Error())
+		} else {
+			log.Printf("🎁 %s", interaction["message"])
+			decision["interaction"] = interaction
+		}
+	}
+
+	result.Feedback = strings.Join(notes, "; ")
+	npc.LastFeedback = result.Feedback
+	if result.Feedback != "" {
+		decision["feedback"] = result.Feedback
+	} else {
+		delete(decision, "feedback") // Not a note the decision arrived with
+	}
+	w.decisionApplied(npcName, decision)
+	return result
+}
+
+// SetDecisionHook sets a function called with every decision after it has
+// been validated and applied (e.g. the TickRecorder)
+func (w *World) SetDecisionHook(fn func(tick int, npcName string, decision map[string]interface{})) {
+	w.onDecision = fn
+}
+
+func (w *World) decisionApplied(npcName string, decision map[string]interface{}) {
+	if w.onDecision != nil {
+		w.onDecision(w.Tick, npcName, decision)
+	}
+}
+
+// validateMoveTarget clamps a move target that lies in a zone the NPC's team
+// can't enter
+func (w *World) validateMoveTarget(npc *NPC, target [2]float64, result *DecisionResult) {
+	result.Target = &target
+
+	zone := w.Zones.GetZoneAt(target[0], target[1])
+	if zone == nil || w.Zones.CanAccessZone(zone.ID, npc.Team) {
+		return
+	}
+
+	clamped := w.lockedGatePosition(npc, zone.ID)
+	if clamped == nil {
+		clamped = w.lastReachablePoint(npc, target)
+	}
+	result.Target = clamped
+	result.Clamped = true
+	result.Feedback = fmt.Sprintf("%s is locked for your team - stopped at (%.0f, %.0f). Solve its gate challenge first.",
+		zone.Name, clamped[0], clamped[1])
+}
+
+// lockedGatePosition returns the nearest locked gate into zoneID, if any
+func (w *World) lockedGatePosition(npc *NPC, zoneID string) *[2]float64 {
+	var best *[2]float64
+	bestDist := math.MaxFloat64
+	for _, gate := range w.Zones.AllGates() {
+		if gate.ToZone != zoneID || gate.Unlocked {
+			continue
+		}
+		dist := math.Hypot(gate.Position[0]-npc.Pos[0], gate.Position[1]-npc.Pos[1])
+		if dist < bestDist {
+			pos := gate.Position
+			best = &pos
+			bestDist = dist
+		}
+	}
+	return best
+}
+
+// lastReachablePoint walks from the NPC toward target and returns the last
+// point that is still in an accessible zone
+func (w *World) lastReachablePoint(npc *NPC, target [2]float64) *[2]float64 {
+	const step = 5.0
+	dx := target[0] - npc.Pos[0]
+	dy := target[1] - npc.Pos[1]
+	dist := math.Hypot(dx, dy)
+
+	last := npc.Pos
+	for d := step; d < dist; d += step {
+		p := [2]float64{npc.Pos[0] + dx*d/dist, npc.Pos[1] + dy*d/dist}
+		if zone := w.Zones.GetZoneAt(p[0], p[1]); zone != nil && !w.Zones.CanAccessZone(zone.ID, npc.Team) {
+			break
+		}
+		last = p
+	}
+	return &last
+}
+
+// parseTarget reads an [x, y] target from a decision
+func parseTarget(v interface{}) ([2]float64, bool) {
+	switch t := v.(type) {
+	case []interface{}:
+		if len(t) >= 2 {
+			x, okX := t[0].(float64)
+			y, okY := t[1].(float64)
+			return [2]float64{x, y}, okX && okY
+		}
+	case []float64:
+		if len(t) >= 2 {
+			return [2]float64{t[0], t[1]}, true
+		}
+	case [2]float64:
+		return t, true
+	}
+	return [2]float64{}, false
+}
+
+// EnqueueDecision stores a decision for the NPC's next tick. Decisions arrive
+// from the LLM asynchronously, so only the newest one is kept: a decision
+// still waiting when a newer one arrives is superseded and discarded.
+// Returns true if a waiting decision was discarded.
+func (w *World) EnqueueDecision(npcName string, decision map[string]interface{}) bool {
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+
+	if w.queue == nil {
+		w.queue = make(map[string]map[string]interface{})
+	}
+	_, superseded := w.queue[npcName]
+	w.queue[npcName] = decision
+	return superseded
+}
+
+// DequeueDecision takes the NPC's waiting decision, if any. When the queue is
+// empty the NPC simply carries on with its previous action.
+func (w *World) DequeueDecision(npcName string) (map[string]interface{}, bool) {
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+
+	decision, ok := w.queue[npcName]
+	if ok {
+		delete(w.queue, npcName)
+	}
+	return decision, ok
+}
+
+// applyQueuedDecisions applies each NPC's waiting decision without blocking
+// on the LLM; NPCs with nothing queued keep their current target
+func (w *World) applyQueuedDecisions() {
+	for _, npc := range w.NPCs {
+		if decision, ok := w.DequeueDecision(npc.Name); ok {
+			w.applyDecision(npc.Name, decision)
+		}
+	}
+}
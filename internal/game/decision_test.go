@@ -0,0 +1,229 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestApplyDecision_ValidatorChain(t *testing.T) {
+	world := NewWorld(config.Default())
+	explorer := world.GetNPCByName("Explorer")
+	explorer.Pos = [2]float64{150, 150}
+
+	t.Run("self target", func(t *testing.T) {
+		// Scout (Explorer's teammate) is nearest, but the correction goes to a rival
+		world.GetNPCByName("Scout").Pos = [2]float64{160, 150}
+		decision := map[string]interface{}{"action": "talk", "target": "Explorer"}
+		applied := world.ApplyDecision("Explorer", decision).Decision
+		target, _ := applied["target"].(string)
+		if rival := world.GetNPCByName(target); rival == nil || rival.Team == explorer.Team {
+			t.Errorf("self-target corrected to %q, want an NPC on another team", target)
+		}
+		if decision["target"] != "Explorer" {
+			t.Errorf("caller's decision was modified: %v", decision)
+		}
+	})
+
+	t.Run("unlocked gate", func(t *testing.T) {
+		world.Zones.Gates["gate_1_2"].Unlocked = true
+		defer func() { world.Zones.Gates["gate_1_2"].Unlocked = false }()
+
+		decision := map[string]interface{}{"action": "challenge", "target": "gate_1_2"}
+		result := world.ApplyDecision("Explorer", decision)
+		if result.Action != "wait" || result.Feedback == "" {
+			t.Errorf("challenge on open gate: %+v", result)
+		}
+	})
+
+	t.Run("world bounds", func(t *testing.T) {
+		decision := map[string]interface{}{"action": "move", "target": []interface{}{-50.0, 100.0}}
+		result := world.ApplyDecision("Explorer", decision)
+		if result.Target == nil || result.Target[0] != 0 || result.Target[1] != 100 {
+			t.Errorf("target not clamped to the map: %+v", result.Target)
+		}
+	})
+
+	t.Run("locked zone", func(t *testing.T) {
+		decision := map[string]interface{}{"action": "move", "target": []interface{}{900.0, 200.0}}
+		result := world.ApplyDecision("Explorer", decision)
+		if !result.Clamped {
+			t.Errorf("move into locked zone not clamped: %+v", result)
+		}
+	})
+
+	t.Run("taunt cooldown", func(t *testing.T) {
+		first := world.ApplyDecision("Explorer", map[string]interface{}{"action": "taunt", "target": "Wanderer"})
+		second := world.ApplyDecision("Explorer", map[string]interface{}{"action": "taunt", "target": "Wanderer"})
+		if first.Action != "taunt" || second.Action != "wait" {
+			t.Errorf("taunt cooldown: first %q, second %q", first.Action, second.Action)
+		}
+	})
+}
+
+func TestAnnotateObservation_ClampsOffMapPosition(t *testing.T) {
+	world := NewWorld(config.Default())
+
+	obs := map[string]interface{}{"name": "Scout", "pos": []interface{}{1350.0, -40.0}}
+	world.SyncFromObservation(obs)
+	world.AnnotateObservation(obs)
+
+	pos, _ := obs["pos"].([]interface{})
+	if len(pos) != 2 || pos[0] != float64(world.Width) || pos[1] != 0.0 {
+		t.Errorf("pos = %v, want clamped to (%d, 0)", obs["pos"], world.Width)
+	}
+	if obs["out_of_bounds"] != true || obs["current_zone"] != "zone_2" {
+		t.Errorf("out_of_bounds = %v, current_zone = %v", obs["out_of_bounds"], obs["current_zone"])
+	}
+	if scout := world.GetNPCByName("Scout"); scout.Pos != [2]float64{float64(world.Width), 0} {
+		t.Errorf("NPC position not pulled back: %v", scout.Pos)
+	}
+
+	inside := map[string]interface{}{"name": "Scout", "pos": []interface{}{100.0, 100.0}}
+	world.SyncFromObservation(inside)
+	world.AnnotateObservation(inside)
+	if _, flagged := inside["out_of_bounds"]; flagged || inside["current_zone"] != "start" {
+		t.Errorf("in-bounds observation: %v", inside)
+	}
+}
+
+func TestAnnotateObservation_NearbyNPCHistory(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.TickRate = baseTickRate
+	cfg.Game.ObservationHistory = 3
+	world := NewWorld(cfg)
+	explorer, wanderer := world.GetNPCByName("Explorer"), world.GetNPCByName("Wanderer")
+
+	explorer.Pos = [2]float64{100, 100}
+	for _, x := range []float64{600, 500, 400, 300} { // Oldest falls out of the ring
+		wanderer.Pos = [2]float64{x, 100}
+		world.Advance()
+	}
+
+	opponent := map[string]interface{}{"name": "Wanderer"}
+	obs := map[string]interface{}{"name": "Explorer", "nearby_npcs": []interface{}{opponent}}
+	world.AnnotateObservation(obs)
+
+	trail, _ := opponent["recent_positions"].([]interface{})
+	if len(trail) != 3 || trail[0].([]interface{})[0] != 500.0 || trail[2].([]interface{})[0] != 300.0 {
+		t.Errorf("recent_positions = %v, want x 500 -> 300", opponent["recent_positions"])
+	}
+	if opponent["distance_change"] != -200.0 || opponent["history_ticks"] != 2 {
+		t.Errorf("distance_change = %v over %v ticks, want -200 over 2", opponent["distance_change"], opponent["history_ticks"])
+	}
+}
+
+func TestApplyDecision_IdleExploreUsesIdleBehavior(t *testing.T) {
+	world := NewWorld(config.Default())
+	for _, gate := range world.Zones.Gates {
+		gate.Unlocked, gate.UnlockedBy = true, "red"
+	}
+	positions := map[string][2]float64{
+		"Explorer": {150, 150}, "Scout": {1100, 700}, "Wanderer": {1100, 100}, "Seeker": {100, 700},
+	}
+	for name, pos := range positions {
+		world.GetNPCByName(name).Pos = pos
+	}
+
+	result := world.ApplyDecision("Explorer", map[string]interface{}{"action": "explore"})
+	decision := result.Decision
+	if decision["idle"] != IdleGuard || result.Target == nil || *result.Target != [2]float64{300, 400} {
+		t.Errorf("guard: decision = %v, target = %v; want a move to gate_1_3", decision, result.Target)
+	}
+
+	world.idleBehaviors = []string{IdleRegroup}
+	result = world.ApplyDecision("Explorer", map[string]interface{}{"action": "explore"})
+	decision = result.Decision
+	if decision["idle"] != IdleRegroup || result.Target == nil || *result.Target != positions["Scout"] {
+		t.Errorf("regroup: decision = %v, target = %v; want a move to Scout", decision, result.Target)
+	}
+
+	// Someone nearby is something to do: exploring stays exploring
+	world.GetNPCByName("Wanderer").Pos = [2]float64{200, 150}
+	decision = world.ApplyDecision("Explorer", map[string]interface{}{"action": "explore"}).Decision
+	if _, idle := decision["idle"]; idle || decision["action"] != "explore" {
+		t.Errorf("busy NPC's explore was replaced: %v", decision)
+	}
+}
+
+func TestReuseDecision_WhileObservationUnchanged(t *testing.T) {
+	world := NewWorld(config.Default())
+	observe := func(x float64) {
+		world.SyncFromObservation(map[string]interface{}{
+			"name":         "Explorer",
+			"pos":          []interface{}{x, 150.0},
+			"nearby_gates": []interface{}{map[string]interface{}{"id": "gate_1_2", "distance": 320.0, "unlocked": false}},
+		})
+	}
+
+	observe(150)
+	if _, ok := world.ReuseDecision("Explorer"); ok {
+		t.Fatal("reused a decision before any was made")
+	}
+	world.RememberDecision("Explorer", map[string]interface{}{"action": "move", "target": []interface{}{400.0, 150.0}})
+
+	observe(160) // Same 50-unit cell
+	decision, ok := world.ReuseDecision("Explorer")
+	if !ok || decision["action"] != "move" || decision["reused"] != true {
+		t.Fatalf("unchanged observation: got %v, %v", decision, ok)
+	}
+
+	observe(260) // Moved on
+	if explorer := world.GetNPCByName("Explorer"); !world.ObservationChanged(explorer, explorer.decisionHash) {
+		t.Error("new cell not seen as a change")
+	}
+	if _, ok := world.ReuseDecision("Explorer"); ok {
+		t.Error("reused a decision for a changed observation")
+	}
+	if skips := world.DecisionSkips(); skips != 1 {
+		t.Errorf("skips = %d, want 1", skips)
+	}
+}
+
+func TestReuseDecision_ServerBuiltObservations(t *testing.T) {
+	world := NewWorld(config.Default())
+	explorer := world.GetNPCByName("Explorer")
+	observe := func() { world.TrackObservation("Explorer", world.Observation(explorer)) }
+
+	observe()
+	world.RememberDecision("Explorer", map[string]interface{}{"action": "wait"})
+	observe()
+	if decision, ok := world.ReuseDecision("Explorer"); !ok || decision["action"] != "wait" {
+		t.Fatalf("unchanged server observation: got %v, %v", decision, ok)
+	}
+
+	explorer.Pos[0] += 200
+	observe()
+	if _, ok := world.ReuseDecision("Explorer"); ok {
+		t.Error("reused a decision after the NPC moved on")
+	}
+}
+
+func TestNewWorld_ScalesHistoryAndRespawnTicks(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.TickRate = 60
+	cfg.Game.ObservationHistory = 3
+	world := NewWorld(cfg)
+
+	if world.respawn.BaseTicks != 300 || world.respawn.MinTicks != 150 || world.respawn.MaxTicks != 600 {
+		t.Errorf("respawn ticks = %d/%d/%d, want 300/150/600 (5s/2.5s/10s at 60/sec)",
+			world.respawn.BaseTicks, world.respawn.MinTicks, world.respawn.MaxTicks)
+	}
+
+	explorer, wanderer := world.GetNPCByName("Explorer"), world.GetNPCByName("Wanderer")
+	explorer.Pos = [2]float64{100, 100}
+	for tick := 0; tick < 120; tick++ { // 2s: the ring keeps the last 1.5s
+		wanderer.Pos = [2]float64{float64(700 - tick), 100}
+		world.Advance()
+	}
+
+	opponent := map[string]interface{}{"name": "Wanderer"}
+	world.AnnotateObservation(map[string]interface{}{"name": "Explorer", "nearby_npcs": []interface{}{opponent}})
+	trail, _ := opponent["recent_positions"].([]interface{})
+	if len(trail) != 3 || trail[0].([]interface{})[0] != 641.0 || trail[2].([]interface{})[0] != 581.0 {
+		t.Errorf("recent_positions = %v, want 3 points half a second apart ending at x 581", trail)
+	}
+	if opponent["history_ticks"] != 89 || opponent["history_seconds"] != 1.5 {
+		t.Errorf("history = %v ticks / %vs, want 89 / 1.5", opponent["history_ticks"], opponent["history_seconds"])
+	}
+}
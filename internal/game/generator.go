@@ -2,10 +2,14 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
+
+	"github.com/amit/npc/internal/challenge"
+	"github.com/amit/npc/internal/config"
 )
 
 // ZoneGeneratorConfig holds generation settings
@@ -15,14 +19,40 @@ type ZoneGeneratorConfig struct {
 	ExplorationThreshold float64       `json:"exploration_threshold"`
 	ScoreGapThreshold    int           `json:"score_gap_threshold"`
 	MaxZones             int           `json:"max_zones"`
+
+	// Bounds enforced on generated rewards (the prompt asks for 20-60 and 20-50)
+	MinZoneReward  int `json:"min_zone_reward"`
+	MaxZoneReward  int `json:"max_zone_reward"`
+	MinTokenReward int `json:"min_token_reward"`
+	MaxTokenReward int `json:"max_token_reward"`
 }
 
+// safeChallengeType replaces unknown generated challenge types: coordination
+// challenges are judged by rules, so they work even when the judge is down
+const safeChallengeType = challenge.TypeCoordination
+
+// knownChallengeTypes are the challenge types a generated zone may use.
+// Memory isn't one: it's judged against the code the NPCs were shown, which
+// a generated definition doesn't have, so it maps to safeChallengeType.
+var knownChallengeTypes = map[challenge.ChallengeType]bool{
+	challenge.TypeCoordination:  true,
+	challenge.TypeSpatial:       true,
+	challenge.TypeInfoAsymmetry: true,
+	challenge.TypeEncoding:      true,
+	challenge.TypeDebate:        true,
+}
+
+// ErrLLMUnavailable means no capable LLM could produce a zone. Generation is
+// skipped and triggers are paused for one TriggerInterval.
+var ErrLLMUnavailable = errors.New("LLM unavailable")
+
 // ZoneGenerator creates new zones dynamically using LLM
 type ZoneGenerator struct {
-	config      ZoneGeneratorConfig
-	lastGenTime time.Time
-	zoneCount   int
-	genFunc     func(prompt string) (string, error) // LLM call function
+	config        ZoneGeneratorConfig
+	lastGenTime   time.Time
+	cooldownUntil time.Time // Triggers paused after the LLM was unavailable
+	zoneCount     int
+	genFunc       func(prompt string) (string, error) // LLM call function
 }
 
 // NewZoneGenerator creates a generator with default settings
@@ -34,6 +64,10 @@ func NewZoneGenerator() *ZoneGenerator {
 			ExplorationThreshold: 0.8,
 			ScoreGapThreshold:    50,
 			MaxZones:             8,
+			MinZoneReward:        20,
+			MaxZoneReward:        60,
+			MinTokenReward:       20,
+			MaxTokenReward:       50,
 		},
 		lastGenTime: time.Now(),
 		zoneCount:   4, // Starting with 4 zones
@@ -45,9 +79,34 @@ func (zg *ZoneGenerator) SetLLMFunc(fn func(prompt string) (string, error)) {
 	zg.genFunc = fn
 }
 
+// SetRewardBounds sets the range generated zone rewards and challenge token
+// rewards are clamped to; zero values keep the defaults
+func (zg *ZoneGenerator) SetRewardBounds(cfg config.GeneratedRewardsConfig) {
+	if cfg.ZoneMin > 0 {
+		zg.config.MinZoneReward = cfg.ZoneMin
+	}
+	if cfg.ZoneMax > 0 {
+		zg.config.MaxZoneReward = cfg.ZoneMax
+	}
+	if cfg.ChallengeMin > 0 {
+		zg.config.MinTokenReward = cfg.ChallengeMin
+	}
+	if cfg.ChallengeMax > 0 {
+		zg.config.MaxTokenReward = cfg.ChallengeMax
+	}
+}
+
 // CheckTriggers evaluates if a new zone should be generated
 func (zg *ZoneGenerator) CheckTriggers(world *World) TriggerResult {
-	if !zg.config.Enabled || zg.zoneCount >= zg.config.MaxZones {
+	if !zg.config.Enabled || world.SafeMode || zg.zoneCount >= zg.config.MaxZones {
+		return TriggerResult{ShouldGenerate: false}
+	}
+	// Generated zones hang off an existing zone's gate, so there's nothing to
+	// build from in an empty world
+	if world.Zones == nil || len(world.Zones.AllZones()) == 0 {
+		return TriggerResult{ShouldGenerate: false}
+	}
+	if time.Now().Before(zg.cooldownUntil) {
 		return TriggerResult{ShouldGenerate: false}
 	}
 
@@ -61,13 +120,14 @@ func (zg *ZoneGenerator) CheckTriggers(world *World) TriggerResult {
 	}
 
 	// Check exploration threshold
+	zones := world.Zones.AllZones()
 	unlockedCount := 0
-	for _, zone := range world.Zones.Zones {
+	for _, zone := range zones {
 		if zone.Unlocked {
 			unlockedCount++
 		}
 	}
-	explorationRatio := float64(unlockedCount) / float64(len(world.Zones.Zones))
+	explorationRatio := float64(unlockedCount) / float64(len(zones))
 	if explorationRatio >= zg.config.ExplorationThreshold {
 		return TriggerResult{
 			ShouldGenerate: true,
@@ -78,10 +138,10 @@ func (zg *ZoneGenerator) CheckTriggers(world *World) TriggerResult {
 
 	// Check score gap
 	var redScore, blueScore int
-	if red, ok := world.Teams.Teams["red"]; ok {
+	if red := world.Teams.GetTeam("red"); red != nil {
 		redScore = red.Score
 	}
-	if blue, ok := world.Teams.Teams["blue"]; ok {
+	if blue := world.Teams.GetTeam("blue"); blue != nil {
 		blueScore = blue.Score
 	}
 	scoreGap := abs(redScore - blueScore)
@@ -106,16 +166,25 @@ type TriggerResult struct {
 // GenerateZone creates a new zone using the LLM
 func (zg *ZoneGenerator) GenerateZone(world *World, trigger TriggerResult) (*GeneratedZone, error) {
 	if zg.genFunc == nil {
-		return nil, fmt.Errorf("LLM function not set")
+		return nil, zg.unavailable()
 	}
 
 	prompt := zg.buildGenerationPrompt(world, trigger)
 
 	response, err := zg.genFunc(prompt)
+	if errors.Is(err, ErrLLMUnavailable) {
+		return nil, zg.unavailable()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
 
+	// A reply with no JSON object at all means we got a canned/degenerate
+	// answer rather than a malformed attempt
+	if !strings.Contains(response, "{") {
+		return nil, zg.unavailable()
+	}
+
 	generated, err := zg.parseGeneratedZone(response)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
@@ -132,6 +201,12 @@ func (zg *ZoneGenerator) GenerateZone(world *World, trigger TriggerResult) (*Gen
 	return generated, nil
 }
 
+// unavailable pauses triggers for one interval and returns ErrLLMUnavailable
+func (zg *ZoneGenerator) unavailable() error {
+	zg.cooldownUntil = time.Now().Add(zg.config.TriggerInterval)
+	return ErrLLMUnavailable
+}
+
 // GeneratedZone contains the full generation result
 type GeneratedZone struct {
 	Zone       ZoneDefinition        `json:"zone"`
@@ -184,18 +259,18 @@ Create engaging, balanced zones that enhance gameplay.
 - Existing zones: %d
 - Trigger reason: %s (%s)
 
-`, world.Width, world.Height, len(world.Zones.Zones), trigger.Reason, trigger.Description))
+`, world.Width, world.Height, len(world.Zones.AllZones()), trigger.Reason, trigger.Description))
 
 	// Team scores
 	sb.WriteString("## Team Scores\n")
-	for id, team := range world.Teams.Teams {
-		sb.WriteString(fmt.Sprintf("- %s: %d points, controls %d zones\n", id, team.Score, len(team.Zones)))
+	for _, team := range world.Teams.GetLeaderboard() {
+		sb.WriteString(fmt.Sprintf("- %s: %d points, controls %d zones\n", team.ID, team.Score, len(team.Zones)))
 	}
 	sb.WriteString("\n")
 
 	// Existing zones
 	sb.WriteString("## Existing Zone Bounds (avoid overlap)\n")
-	for _, zone := range world.Zones.Zones {
+	for _, zone := range world.Zones.AllZones() {
 		sb.WriteString(fmt.Sprintf("- %s: x=%v, y=%v, w=%v, h=%v\n",
 			zone.ID, zone.Bounds.X, zone.Bounds.Y, zone.Bounds.Width, zone.Bounds.Height))
 	}
@@ -211,7 +286,7 @@ Consider: easier challenges, higher rewards, or strategic positioning.
 	}
 
 	// Challenge type rotation
-	challengeTypes := []string{"coordination", "memory", "spatial", "encoding"}
+	challengeTypes := []string{"coordination", "spatial", "encoding"}
 	nextType := challengeTypes[zg.zoneCount%len(challengeTypes)]
 	sb.WriteString(fmt.Sprintf("## Suggested Challenge Type: %s\n\n", nextType))
 
@@ -231,7 +306,7 @@ Create ONE new zone. Be creative with the theme and name!
     "rewards": <20-60>
   },
   "challenges": [{
-    "type": "coordination|memory|spatial",
+    "type": "coordination|spatial|encoding",
     "name": "Challenge Name",
     "prompt": "The challenge description",
     "options": ["A", "B", "C"],
@@ -296,13 +371,57 @@ func (zg *ZoneGenerator) validateBounds(generated *GeneratedZone, world *World)
 		zone.Y = float64(world.Height) - zone.Height
 	}
 
+	// Rewards feed the economy directly; don't trust the model's numbers
+	if clamped := clampInt(zone.Rewards, zg.config.MinZoneReward, zg.config.MaxZoneReward); clamped != zone.Rewards {
+		log.Printf("⚠️ Generated zone %s: rewards %d clamped to %d", zone.Name, zone.Rewards, clamped)
+		zone.Rewards = clamped
+	}
+	for i := range generated.Challenges {
+		ch := &generated.Challenges[i]
+		if clamped := clampInt(ch.TokenReward, zg.config.MinTokenReward, zg.config.MaxTokenReward); clamped != ch.TokenReward {
+			log.Printf("⚠️ Generated challenge %s: token_reward %d clamped to %d", ch.Name, ch.TokenReward, clamped)
+			ch.TokenReward = clamped
+		}
+		ch.Difficulty = clampInt(ch.Difficulty, 1, 5)
+		if t, ok := challengeTypeOf(ch.Type); ok {
+			ch.Type = string(t)
+		} else {
+			log.Printf("⚠️ Generated challenge %s: unknown type %q, using %s", ch.Name, ch.Type, safeChallengeType)
+			ch.Type = string(safeChallengeType)
+		}
+	}
+
 	return generated
 }
 
-// ApplyGeneratedZone adds the generated zone to the world
+// challengeTypeOf maps a generated challenge's type to its ChallengeType,
+// forgiving case and "info-asymmetry" style separators. Unknown types map
+// to safeChallengeType, with ok false.
+func challengeTypeOf(s string) (t challenge.ChallengeType, ok bool) {
+	t = challenge.ChallengeType(strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(s))))
+	if !knownChallengeTypes[t] {
+		return safeChallengeType, false
+	}
+	return t, true
+}
+
+// clampInt limits v to [lo, hi]
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// ApplyGeneratedZone adds the generated zone to the world, behind a gate
+// whose challenges are the generated ones (see registerChallenges). With
+// more than one, gate attempts draw from all of them.
 func (zg *ZoneGenerator) ApplyGeneratedZone(world *World, generated *GeneratedZone) {
 	// Add zone
-	world.Zones.Zones[generated.Zone.ID] = &Zone{
+	world.Zones.AddZone(&Zone{
 		ID:          generated.Zone.ID,
 		Name:        generated.Zone.Name,
 		Theme:       generated.Zone.Theme,
@@ -315,30 +434,70 @@ func (zg *ZoneGenerator) ApplyGeneratedZone(world *World, generated *GeneratedZo
 		},
 		Unlocked: false,
 		Rewards:  generated.Zone.Rewards,
-	}
+	})
 
-	// Add gate
+	// Add gate, guarded by the generated challenges; a zone generated without
+	// any falls back to the built-in coordination challenge
 	gateID := fmt.Sprintf("gate_%s_%s", generated.Gate.FromZone, generated.Zone.ID)
-	challengeID := fmt.Sprintf("challenge_%s", generated.Zone.ID)
-
-	requiresTeamwork := false
-	if len(generated.Challenges) > 0 {
+	pool := zg.registerChallenges(world, generated)
+	challengeID, requiresTeamwork := fmt.Sprintf("challenge_%s", generated.Zone.ID), false
+	if len(pool) > 0 {
+		challengeID = pool[0]
 		requiresTeamwork = generated.Challenges[0].RequiresTeamwork
+	} else if fallback := world.Challenges.GetChallenge("challenge_coordination"); fallback != nil {
+		challengeID = fallback.ID
+		requiresTeamwork = fallback.RequiresTeamwork
+	}
+	if len(pool) < 2 {
+		pool = nil
 	}
 
-	world.Zones.Gates[gateID] = &Gate{
+	world.Zones.AddGate(&Gate{
 		ID:               gateID,
 		FromZone:         generated.Gate.FromZone,
 		ToZone:           generated.Zone.ID,
 		Position:         generated.Gate.Position,
 		ChallengeID:      challengeID,
+		ChallengePool:    pool,
 		Unlocked:         false,
 		RequiresTeamwork: requiresTeamwork,
-	}
+	})
 
 	log.Printf("✅ Applied zone: %s with gate %s", generated.Zone.Name, gateID)
 }
 
+// registerChallenges adds the generated zone's challenges to the world's
+// challenge manager - the first as challenge_<zone>, the rest as
+// challenge_<zone>_2, _3... - and returns their IDs in order. The time
+// limit and hint cost grow with difficulty.
+func (zg *ZoneGenerator) registerChallenges(world *World, generated *GeneratedZone) []string {
+	var ids []string
+	for i, def := range generated.Challenges {
+		id := fmt.Sprintf("challenge_%s", generated.Zone.ID)
+		if i > 0 {
+			id = fmt.Sprintf("%s_%d", id, i+1)
+		}
+		chType, _ := challengeTypeOf(def.Type)
+		difficulty := clampInt(def.Difficulty, 1, 5)
+
+		world.Challenges.AddChallenge(&challenge.Challenge{
+			ID:               id,
+			Type:             chType,
+			Name:             def.Name,
+			Description:      fmt.Sprintf("Guards %s", generated.Zone.Name),
+			Difficulty:       difficulty,
+			Prompt:           def.Prompt,
+			Options:          def.Options,
+			RequiresTeamwork: def.RequiresTeamwork,
+			TimeLimit:        time.Duration(20+10*difficulty) * time.Second,
+			TokenReward:      def.TokenReward,
+			HintCost:         2 * difficulty,
+		})
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amit/npc/internal/challenge"
+	"github.com/amit/npc/internal/config"
+)
+
+func TestCheckTriggers_EmptyZones(t *testing.T) {
+	world := &World{
+		Teams: NewTeamManager(),
+		Zones: &ZoneManager{Zones: map[string]*Zone{}, Gates: map[string]*Gate{}},
+	}
+	world.Teams.Teams["red"].Score = 500 // Would trip the score-gap trigger
+
+	zg := NewZoneGenerator()
+	zg.lastGenTime = time.Now() // Timer trigger not due
+
+	if trigger := zg.CheckTriggers(world); trigger.ShouldGenerate {
+		t.Errorf("empty world should not generate, got %+v", trigger)
+	}
+
+	npc := &NPC{Name: "Explorer", Pos: [2]float64{100, 100}, CurrentZone: "start"}
+	world.UpdateNPCZone(npc)
+	if npc.CurrentZone != "" {
+		t.Errorf("NPC in an empty world kept zone %q", npc.CurrentZone)
+	}
+}
+
+func TestUpdateNPCZone_OutsideBoundsUsesNearest(t *testing.T) {
+	world := &World{Zones: NewZoneManager(1200, 800)}
+
+	npc := &NPC{Name: "Scout", Pos: [2]float64{1300, 100}} // Off the east edge
+	world.UpdateNPCZone(npc)
+	if npc.CurrentZone != "zone_2" {
+		t.Errorf("CurrentZone = %q, want zone_2", npc.CurrentZone)
+	}
+}
+
+func TestValidateBounds_ClampsGeneratedValues(t *testing.T) {
+	world := &World{Width: 1200, Height: 800}
+	zg := NewZoneGenerator()
+
+	generated := zg.validateBounds(&GeneratedZone{
+		Zone: ZoneDefinition{Name: "Greed", Width: 200, Height: 200, Rewards: 5000},
+		Challenges: []ChallengeDefinition{
+			{Name: "Jackpot", Type: "coordination", Difficulty: 99, TokenReward: 9999},
+			{Name: "Freebie", Type: "memory", Difficulty: -3, TokenReward: 1},
+			{Name: "Mystery", Type: "interpretive_dance", Difficulty: 3, TokenReward: 30},
+		},
+	}, world)
+
+	if got := generated.Zone.Rewards; got != 60 {
+		t.Errorf("zone rewards = %d, want 60", got)
+	}
+
+	want := []ChallengeDefinition{
+		{Name: "Jackpot", Type: "coordination", Difficulty: 5, TokenReward: 50},
+		{Name: "Freebie", Type: "coordination", Difficulty: 1, TokenReward: 20}, // No solution to judge memory by
+		{Name: "Mystery", Type: "coordination", Difficulty: 3, TokenReward: 30},
+	}
+	for i, w := range want {
+		got := generated.Challenges[i]
+		if got.Type != w.Type || got.Difficulty != w.Difficulty || got.TokenReward != w.TokenReward {
+			t.Errorf("challenge %s = {%s, difficulty %d, reward %d}, want {%s, %d, %d}",
+				w.Name, got.Type, got.Difficulty, got.TokenReward, w.Type, w.Difficulty, w.TokenReward)
+		}
+	}
+}
+
+func TestValidateBounds_ConfiguredRewardBounds(t *testing.T) {
+	zg := NewZoneGenerator()
+	zg.SetRewardBounds(config.GeneratedRewardsConfig{ZoneMin: 40, ZoneMax: 100})
+
+	generated := zg.validateBounds(&GeneratedZone{
+		Zone: ZoneDefinition{Width: 200, Height: 200, Rewards: 5},
+	}, &World{Width: 1200, Height: 800})
+	if got := generated.Zone.Rewards; got != 40 {
+		t.Errorf("zone rewards = %d, want configured minimum 40", got)
+	}
+}
+
+func TestApplyGeneratedZone_RegistersGateChallenge(t *testing.T) {
+	world := NewWorld(config.Default())
+	zg := NewZoneGenerator()
+	zg.ApplyGeneratedZone(world, &GeneratedZone{
+		Zone:       ZoneDefinition{ID: "zone_5", Name: "Vault", X: 900, Y: 100, Width: 200, Height: 200, Rewards: 40},
+		Challenges: []ChallengeDefinition{{Type: "Coordination", Name: "Signal", Prompt: "Pick one", Options: []string{"A", "B"}, Difficulty: 2, RequiresTeamwork: true, TokenReward: 30}},
+		Gate:       GateDefinition{FromZone: "zone_2", Position: [2]float64{880, 200}},
+	})
+
+	gate := world.Zones.Gates["gate_zone_2_zone_5"]
+	if gate == nil {
+		t.Fatal("no gate to the generated zone")
+	}
+	ch := world.Challenges.GetChallenge(gate.ChallengeID)
+	if ch == nil || ch.Type != challenge.TypeCoordination || ch.Name != "Signal" || !ch.RequiresTeamwork ||
+		ch.TokenReward != 30 || len(ch.Options) != 2 || ch.TimeLimit <= 0 {
+		t.Fatalf("gate challenge %s registered as %+v", gate.ChallengeID, ch)
+	}
+
+	// The gate's challenge can be started and solved like a built-in one
+	team := world.Teams.Teams[world.NPCs[0].Team]
+	for _, member := range team.Members[:2] {
+		if _, err := world.Challenges.StartChallenge(gate.ID, gate.ChallengeID, member, team.ID); err != nil {
+			t.Fatalf("%s can't start the generated gate's challenge: %v", member, err)
+		}
+		world.Challenges.SubmitResponse(gate.ID, member, "A")
+	}
+	if result := world.Challenges.EvaluateChallenge(gate.ID); result == nil || !result.Success || result.TokensEarned != 30 {
+		t.Errorf("generated challenge result = %+v, want solved for 30 tokens", result)
+	}
+
+	// Every generated challenge is registered; the gate draws from them all
+	zg.ApplyGeneratedZone(world, &GeneratedZone{
+		Zone: ZoneDefinition{ID: "zone_6", Name: "Archive", X: 900, Y: 400, Width: 200, Height: 200, Rewards: 40},
+		Challenges: []ChallengeDefinition{
+			{Type: "memory", Name: "Recall", Prompt: "What was the code?", Difficulty: 1, TokenReward: 20},
+			{Type: "info-asymmetry", Name: "Split Map", Prompt: "Combine your halves", Difficulty: 4, TokenReward: 45},
+		},
+		Gate: GateDefinition{FromZone: "zone_5", Position: [2]float64{1000, 310}},
+	})
+	gate = world.Zones.Gates["gate_zone_5_zone_6"]
+	if got := gate.Pool(); len(got) != 2 || got[0] != "challenge_zone_6" || got[1] != "challenge_zone_6_2" {
+		t.Errorf("gate pool = %v, want both generated challenges", got)
+	}
+	if first := world.Challenges.GetChallenge("challenge_zone_6"); first == nil || first.Type != challenge.TypeCoordination {
+		t.Errorf("generated memory challenge registered as %+v, want coordination", first)
+	}
+	if second := world.Challenges.GetChallenge("challenge_zone_6_2"); second == nil || second.Type != challenge.TypeInfoAsymmetry || second.Difficulty != 4 {
+		t.Errorf("second challenge registered as %+v", second)
+	}
+}
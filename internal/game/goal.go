@@ -0,0 +1,63 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// SetGoal gives an NPC a standing intent (e.g. "reach gate_2_4") that is
+// shown in its prompts until it is achieved, becomes invalid, or is changed
+// deliberately. An empty goal clears it. Goals naming a gate must name a
+// known gate that is still locked.
+func (w *World) SetGoal(npcName, goal string) error {
+	npc := w.GetNPCByName(npcName)
+	if npc == nil {
+		return fmt.Errorf("unknown NPC %q", npcName)
+	}
+	goal = strings.TrimSpace(goal)
+	if goal == npc.Goal {
+		return nil
+	}
+	if gate := w.goalGate(goal); gate != nil && gate.Unlocked {
+		return fmt.Errorf("%s is already unlocked", gate.ID)
+	}
+
+	if goal == "" {
+		log.Printf("🎯 %s dropped goal %q", npcName, npc.Goal)
+	} else {
+		log.Printf("🎯 %s new goal: %s", npcName, goal)
+	}
+	npc.Goal = goal
+	w.markChanged("npc", npc.ID)
+	return nil
+}
+
+// goalGate returns the gate a goal refers to, if it names one
+func (w *World) goalGate(goal string) *Gate {
+	for _, word := range strings.Fields(goal) {
+		word = strings.Trim(word, ".,;:!?\"'()")
+		if gate := w.Zones.GetGate(word); gate != nil {
+			return gate
+		}
+	}
+	return nil
+}
+
+// refreshGoals clears goals whose gate has been unlocked: achieved when the
+// NPC's team (or the NPC) unlocked it, invalid when someone else did
+func (w *World) refreshGoals() {
+	for _, npc := range w.NPCs {
+		gate := w.goalGate(npc.Goal)
+		if gate == nil || !gate.Unlocked {
+			continue
+		}
+		if gate.UnlockedBy == npc.Team || gate.UnlockedBy == npc.Name {
+			log.Printf("🎯 %s achieved goal %q", npc.Name, npc.Goal)
+		} else {
+			log.Printf("🎯 %s's goal %q is moot: %s unlocked %s", npc.Name, npc.Goal, gate.UnlockedBy, gate.ID)
+		}
+		npc.Goal = ""
+		w.markChanged("npc", npc.ID)
+	}
+}
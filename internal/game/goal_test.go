@@ -0,0 +1,80 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestSetGoal(t *testing.T) {
+	world := NewWorld(config.Default())
+	world.Zones.UnlockGate("gate_1_2", "red")
+
+	if err := world.SetGoal("Explorer", "  reach gate_2_4  "); err != nil {
+		t.Fatalf("SetGoal: %v", err)
+	}
+	if goal := world.GetNPCByName("Explorer").Goal; goal != "reach gate_2_4" {
+		t.Errorf("goal = %q, want it trimmed", goal)
+	}
+	if err := world.SetGoal("Explorer", "open gate_1_2."); err == nil {
+		t.Error("a goal naming an unlocked gate was accepted")
+	}
+	if goal := world.GetNPCByName("Explorer").Goal; goal != "reach gate_2_4" {
+		t.Errorf("rejected goal replaced %q", goal)
+	}
+	if err := world.SetGoal("Explorer", "scout the east side"); err != nil {
+		t.Errorf("a goal naming no gate was rejected: %v", err)
+	}
+	if err := world.SetGoal("Explorer", ""); err != nil || world.GetNPCByName("Explorer").Goal != "" {
+		t.Errorf("clearing the goal: err %v, goal %q", err, world.GetNPCByName("Explorer").Goal)
+	}
+	if err := world.SetGoal("Nobody", "reach gate_2_4"); err == nil {
+		t.Error("SetGoal accepted an unknown NPC")
+	}
+}
+
+func TestGoalGate(t *testing.T) {
+	world := NewWorld(config.Default())
+	tests := map[string]string{
+		"reach gate_2_4":          "gate_2_4",
+		"unlock (gate_3_4) first": "gate_3_4",
+		"solve gate_1_3!":         "gate_1_3",
+		"explore the forest":      "",
+		"":                        "",
+		"reach gate_9_9":          "",
+	}
+	for goal, want := range tests {
+		got := ""
+		if gate := world.goalGate(goal); gate != nil {
+			got = gate.ID
+		}
+		if got != want {
+			t.Errorf("goalGate(%q) = %q, want %q", goal, got, want)
+		}
+	}
+}
+
+func TestRefreshGoals_ClearsGoalsOnceTheirGateOpens(t *testing.T) {
+	world := NewWorld(config.Default())
+	goals := map[string]string{
+		"Explorer": "reach gate_2_4", // Red unlocks it: achieved
+		"Wanderer": "reach gate_2_4", // Blue loses it to red: moot
+		"Scout":    "reach gate_3_4", // Still locked: kept
+		"Seeker":   "guard the start",
+	}
+	for name, goal := range goals {
+		if err := world.SetGoal(name, goal); err != nil {
+			t.Fatalf("SetGoal(%s): %v", name, err)
+		}
+	}
+
+	world.Zones.UnlockGate("gate_2_4", "red")
+	world.refreshGoals()
+
+	want := map[string]string{"Explorer": "", "Wanderer": "", "Scout": "reach gate_3_4", "Seeker": "guard the start"}
+	for name, goal := range want {
+		if got := world.GetNPCByName(name).Goal; got != goal {
+			t.Errorf("%s goal = %q, want %q", name, got, goal)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package game
+
+import "math"
+
+// positionRing holds an NPC's positions over its last few ticks, overwriting
+// the oldest once full
+type positionRing struct {
+	points [][2]float64
+	next   int
+	full   bool
+}
+
+func newPositionRing(depth int) *positionRing {
+	return &positionRing{points: make([][2]float64, depth)}
+}
+
+func (r *positionRing) push(p [2]float64) {
+	r.points[r.next] = p
+	r.next = (r.next + 1) % len(r.points)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// oldestFirst returns the recorded positions, oldest first
+func (r *positionRing) oldestFirst() [][2]float64 {
+	if !r.full {
+		return append([][2]float64(nil), r.points[:r.next]...)
+	}
+	return append(append([][2]float64(nil), r.points[r.next:]...), r.points[:r.next]...)
+}
+
+// recordPositions adds every NPC's position to its history (when
+// observation_history is on); called once per tick
+func (w *World) recordPositions() {
+	if w.historyDepth <= 0 {
+		return
+	}
+	for _, npc := range w.NPCs {
+		if npc.history == nil {
+			npc.history = newPositionRing(w.historyDepth)
+		}
+		npc.history.push(npc.Pos)
+	}
+}
+
+// trail is the positions a prompt shows, oldest first: one per historyStride
+// ticks counting back from the newest, so the trail has about as many points
+// as observation_history asks for at any tick rate
+func (w *World) trail(positions [][2]float64) []interface{} {
+	stride := max(1, w.historyStride)
+	trail := make([]interface{}, 0, (len(positions)+stride-1)/stride)
+	for i := (len(positions) - 1) % stride; i < len(positions); i += stride {
+		trail = append(trail, []interface{}{math.Round(positions[i][0]), math.Round(positions[i][1])})
+	}
+	return trail
+}
+
+// annotateHistory gives each nearby_npcs entry the NPC's recent positions
+// (oldest first) and how its distance to npc changed over them
+// (distance_change, negative when closing in, over history_ticks ticks or
+// history_seconds), so prompts can tell an opponent approaching from one
+// retreating
+func (w *World) annotateHistory(npc *NPC, obs map[string]interface{}) {
+	nearby, ok := obs["nearby_npcs"].([]interface{})
+	if !ok || npc.history == nil {
+		return
+	}
+	mine := npc.history.oldestFirst()
+
+	for _, n := range nearby {
+		entry, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := entry["name"].(string)
+		other := w.GetNPCByName(name)
+		if other == nil || other.history == nil {
+			continue
+		}
+		theirs := other.history.oldestFirst()
+		if len(theirs) == 0 {
+			continue
+		}
+
+		entry["recent_positions"] = w.trail(theirs)
+
+		// Both histories are recorded on the same ticks
+		span := min(len(mine), len(theirs))
+		if span < 2 {
+			continue
+		}
+		then := dist(mine[len(mine)-span], theirs[len(theirs)-span])
+		now := dist(npc.Pos, other.Pos)
+		entry["distance_change"] = math.Round(now - then)
+		entry["history_ticks"] = span - 1
+		if w.tickRate > 0 {
+			entry["history_seconds"] = math.Round(float64(span-1)/float64(w.tickRate)*10) / 10
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// Idle behaviors, used when an NPC would otherwise explore with nothing to do
+const (
+	IdlePatrol  = "patrol"  // Walk a loop between objects the NPC has visited
+	IdleGuard   = "guard"   // Stand at the nearest gate the team opened
+	IdleRegroup = "regroup" // Rejoin a teammate
+)
+
+// DefaultIdleBehaviors are tried in this order when none are configured
+var DefaultIdleBehaviors = []string{IdleGuard, IdleRegroup, IdlePatrol}
+
+const (
+	idleAwareness  = 200.0 // Another NPC this close gives the NPC something to do
+	guardRadius    = 40.0  // Close enough to a guarded gate to stop
+	regroupRadius  = 100.0 // Close enough to a teammate to stop
+	patrolReachedR = 30.0  // Close enough to a patrol stop to head for the next
+)
+
+// buildIdleBehaviors keeps the known behavior names, in order
+func buildIdleBehaviors(names []string) []string {
+	if len(names) == 0 {
+		return DefaultIdleBehaviors
+	}
+	behaviors := make([]string, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case IdlePatrol, IdleGuard, IdleRegroup:
+			behaviors = append(behaviors, name)
+		default:
+			log.Printf("⚠️ Unknown idle behavior %q, skipping", name)
+		}
+	}
+	return behaviors
+}
+
+// isIdle reports whether an NPC has nothing worth doing: full energy, no
+// locked gate it could reach and nobody nearby
+func (w *World) isIdle(npc *NPC) bool {
+	if npc.Energy < 100 {
+		return false
+	}
+	for _, gate := range w.Zones.AllGates() {
+		if !gate.Unlocked && w.Zones.CanAccessZone(gate.FromZone, npc.Team) {
+			return false
+		}
+	}
+	for _, other := range w.NPCs {
+		if other != npc && dist(npc.Pos, other.Pos) <= idleAwareness {
+			return false
+		}
+	}
+	return true
+}
+
+// IdleBehavior picks what an idle NPC does instead of exploring at random:
+// the first configured behavior that applies, as a decision tagged with
+// "idle". Returns nil when none applies.
+func (w *World) IdleBehavior(npc *NPC) map[string]interface{} {
+	for _, behavior := range w.idleBehaviors {
+		var decision map[string]interface{}
+		switch behavior {
+		case IdleGuard:
+			decision = w.guardDecision(npc)
+		case IdleRegroup:
+			decision = w.regroupDecision(npc)
+		case IdlePatrol:
+			decision = w.patrolDecision(npc)
+		}
+		if decision != nil {
+			decision["idle"] = behavior
+			return decision
+		}
+	}
+	return nil
+}
+
+// guardDecision heads for (or holds) the nearest gate the NPC's team opened
+func (w *World) guardDecision(npc *NPC) map[string]interface{} {
+	var guarded *Gate
+	best := math.MaxFloat64
+	for _, gate := range w.Zones.AllGates() {
+		if !gate.Unlocked || !w.openedBy(gate, npc.Team) {
+			continue
+		}
+		// Tie-break on ID so the choice doesn't depend on map order
+		if d := dist(npc.Pos, gate.Position); guarded == nil || d < best || (d == best && gate.ID < guarded.ID) {
+			guarded, best = gate, d
+		}
+	}
+	if guarded == nil {
+		return nil
+	}
+	if best <= guardRadius {
+		return map[string]interface{}{"action": "wait", "reason": "guarding " + guarded.ID}
+	}
+	return moveDecision(guarded.Position, "guarding "+guarded.ID)
+}
+
+// openedBy reports whether the team (or one of its members) unlocked the gate
+func (w *World) openedBy(gate *Gate, teamID string) bool {
+	if gate.UnlockedBy == teamID {
+		return true
+	}
+	if npc := w.GetNPCByName(gate.UnlockedBy); npc != nil {
+		return npc.Team == teamID
+	}
+	return false
+}
+
+// regroupDecision walks to the nearest teammate unless already beside one
+func (w *World) regroupDecision(npc *NPC) map[string]interface{} {
+	var mate *NPC
+	best := math.MaxFloat64
+	for _, other := range w.NPCs {
+		if other == npc || other.Team != npc.Team {
+			continue
+		}
+		if d := dist(npc.Pos, other.Pos); d < best {
+			mate, best = other, d
+		}
+	}
+	if mate == nil || best <= regroupRadius {
+		return nil
+	}
+	return moveDecision(mate.Pos, "regrouping with "+mate.Name)
+}
+
+// patrolDecision walks the objects the NPC has visited, in world order,
+// moving on to the next stop once the current one is reached
+func (w *World) patrolDecision(npc *NPC) map[string]interface{} {
+	var stops []*WorldObject
+	for _, obj := range w.Objects {
+		for _, name := range obj.VisitedBy {
+			if name == npc.Name {
+				stops = append(stops, obj)
+				break
+			}
+		}
+	}
+	if len(stops) < 2 {
+		return nil // Nothing to walk between
+	}
+
+	stop := stops[npc.patrolStop%len(stops)]
+	if dist(npc.Pos, stop.Pos) <= patrolReachedR {
+		npc.patrolStop++
+		stop = stops[npc.patrolStop%len(stops)]
+	}
+	return moveDecision(stop.Pos, fmt.Sprintf("patrolling to %s", stop.ID))
+}
+
+func moveDecision(target [2]float64, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"action": "move",
+		"target": []interface{}{target[0], target[1]},
+		"reason": reason,
+	}
+}
+
+func dist(a, b [2]float64) float64 {
+	return math.Hypot(a[0]-b[0], a[1]-b[1])
+}
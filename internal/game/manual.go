@@ -0,0 +1,56 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// SetHumanControlled hands an NPC to a human player (or back to the LLM).
+// Human-controlled NPCs get their decisions from manual_decision messages and
+// are left out of LLM decision requests.
+func (w *World) SetHumanControlled(npcName string, human bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	npc := w.GetNPCByName(npcName)
+	if npc == nil {
+		return fmt.Errorf("unknown NPC %q", npcName)
+	}
+	if npc.HumanControlled == human {
+		return nil
+	}
+	npc.HumanControlled = human
+	if human {
+		log.Printf("🕹️ %s is now human-controlled", npcName)
+	} else {
+		log.Printf("🤖 %s is back under LLM control", npcName)
+	}
+	w.markChanged("npc", npc.ID)
+	return nil
+}
+
+// IsHumanControlled reports whether a human is playing the NPC
+func (w *World) IsHumanControlled(npcName string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	npc := w.GetNPCByName(npcName)
+	return npc != nil && npc.HumanControlled
+}
+
+// ApplyManualDecision applies a client-supplied decision for an NPC, taking
+// it over for the human if needed. It runs through the same validator chain
+// as LLM decisions. A malformed decision leaves the NPC as it was.
+func (w *World) ApplyManualDecision(npcName string, decision map[string]interface{}) (DecisionResult, error) {
+	action, _ := decision["action"].(string)
+	if action == "" {
+		return DecisionResult{}, fmt.Errorf("manual decision for %s has no action", npcName)
+	}
+	if _, ok := LookupAction(action); !ok {
+		return DecisionResult{}, fmt.Errorf("unknown action %q (valid: %s)", action, strings.Join(ActionNames(), ", "))
+	}
+	if err := w.SetHumanControlled(npcName, true); err != nil {
+		return DecisionResult{}, err
+	}
+	return w.ApplyDecision(npcName, decision), nil
+}
@@ -0,0 +1,60 @@
+package game
+
+import (
+	"fmt"
+	"log"
+)
+
+// memoryRevealer is the message sender used for memory code reveals
+const memoryRevealer = "Game Master"
+
+// revealMemoryCodes sends every NPC its memory code as a message at game
+// start. In strict memory mode this message is the only place the code
+// lives: it stays in the NPC's recent messages until newer ones push it out.
+func (w *World) revealMemoryCodes() {
+	for _, npc := range w.NPCs {
+		w.sendMessage(memoryRevealer, npc.Name,
+			fmt.Sprintf("Your secret code is %s. Remember it: memory challenges ask for it.", npc.MemoryCode))
+	}
+	log.Printf("🔐 Strict memory: codes revealed to %d NPCs", len(w.NPCs))
+}
+
+// applyMemoryPolicy decides whether an observation carries the NPC's memory
+// code. Normally it always does; in strict mode it never does, and the NPC
+// has to find the code in its message history (see recentMessages).
+func (w *World) applyMemoryPolicy(npc *NPC, obs map[string]interface{}) {
+	if w.memoryStrict {
+		delete(obs, "memory_code")
+	}
+}
+
+// recentMessages is the NPC's message history as it goes into prompts,
+// oldest first. Prompts carry no conversation of their own, so this is
+// what the NPC gets back of anything it was told.
+func recentMessages(npc *NPC) []interface{} {
+	messages := make([]interface{}, 0, len(npc.Messages))
+	for _, m := range npc.Messages {
+		messages = append(messages, map[string]interface{}{
+			"from":    m.From,
+			"content": m.Content,
+		})
+	}
+	return messages
+}
+
+// ChallengeContext is what an NPC brings to a challenge solve prompt: its
+// name, team, recent messages and memory code, which strict memory mode
+// leaves out
+func (w *World) ChallengeContext(npc *NPC) map[string]interface{} {
+	ctx := map[string]interface{}{
+		"name": npc.Name,
+		"team": npc.Team,
+	}
+	if len(npc.Messages) > 0 {
+		ctx["messages"] = recentMessages(npc)
+	}
+	if !w.memoryStrict {
+		ctx["memory_code"] = npc.MemoryCode
+	}
+	return ctx
+}
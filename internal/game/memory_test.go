@@ -0,0 +1,74 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestMemoryPolicy_StrictKeepsCodeInMessageHistory(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.MemoryStrict = true
+	world := NewWorld(cfg)
+	explorer := world.GetNPCByName("Explorer")
+
+	for turn := 0; turn < 2; turn++ {
+		obs := world.Observation(explorer)
+		world.AnnotateObservation(obs)
+		if _, ok := obs["memory_code"]; ok {
+			t.Fatalf("turn %d: strict observation carries memory_code", turn)
+		}
+		messages, _ := obs["messages"].([]interface{})
+		if len(messages) != 1 || !strings.Contains(messages[0].(map[string]interface{})["content"].(string), explorer.MemoryCode) {
+			t.Fatalf("turn %d: messages = %v, want the Game Master reveal", turn, obs["messages"])
+		}
+	}
+
+	ctx := world.ChallengeContext(explorer)
+	if _, ok := ctx["memory_code"]; ok || ctx["messages"] == nil {
+		t.Errorf("strict challenge context = %v, want messages and no memory_code", ctx)
+	}
+
+	for i := 0; i < 5; i++ { // Chatter pushes the reveal out of the last 5
+		world.SendMessage("Scout", "Explorer", "over here")
+	}
+	obs := world.Observation(explorer)
+	world.AnnotateObservation(obs)
+	for _, m := range obs["messages"].([]interface{}) {
+		if strings.Contains(m.(map[string]interface{})["content"].(string), explorer.MemoryCode) {
+			t.Errorf("reveal still in history after 5 newer messages: %v", obs["messages"])
+		}
+	}
+}
+
+func TestMemoryPolicy_DefaultShowsCode(t *testing.T) {
+	world := NewWorld(config.Default())
+	explorer := world.GetNPCByName("Explorer")
+
+	obs := world.Observation(explorer)
+	world.AnnotateObservation(obs)
+	if obs["memory_code"] != explorer.MemoryCode {
+		t.Errorf("memory_code = %v, want %s", obs["memory_code"], explorer.MemoryCode)
+	}
+	if ctx := world.ChallengeContext(explorer); ctx["memory_code"] != explorer.MemoryCode {
+		t.Errorf("challenge context = %v", ctx)
+	}
+}
+
+func TestMemoryCode_NotInGameState(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.MemoryStrict = true
+	world := NewWorld(cfg)
+
+	data, err := json.Marshal(world.GetGameState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, npc := range world.NPCs {
+		if strings.Contains(string(data), npc.MemoryCode) {
+			t.Errorf("game state leaks %s's memory code", npc.Name)
+		}
+	}
+}
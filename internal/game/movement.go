@@ -0,0 +1,93 @@
+package game
+
+import "context"
+
+// defaultMoveSpeed is how far a server-driven NPC moves per tick
+const defaultMoveSpeed = 10
+
+// ServerDriven reports whether the server moves NPCs and requests their
+// decisions itself (game.server_driven) instead of following clients
+func (w *World) ServerDriven() bool {
+	return w.serverDriven
+}
+
+// moveNPCs steps every NPC with a target moveSpeed closer to it, updating
+// its zone, and clears the target once it's reached. Targets were validated
+// when their decision was applied, so locked zones stay out of reach.
+func (w *World) moveNPCs() {
+	for _, npc := range w.NPCs {
+		if npc.Target == nil {
+			continue
+		}
+		target := *npc.Target
+		if d := dist(npc.Pos, target); d <= w.moveSpeed {
+			npc.Pos = target
+			npc.Target = nil
+		} else {
+			step := w.moveSpeed / d
+			npc.Pos[0] += (target[0] - npc.Pos[0]) * step
+			npc.Pos[1] += (target[1] - npc.Pos[1]) * step
+		}
+		w.UpdateNPCZone(npc)
+		w.markChanged("npc", npc.ID)
+	}
+}
+
+// Observation builds the observation a client would send for npc: its
+// position and vitals, every other NPC and every gate, with distances.
+// Server-driven decisions and the selftest are made from it.
+func (w *World) Observation(npc *NPC) map[string]interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var gates []interface{}
+	for _, gate := range w.Zones.AllGates() {
+		gates = append(gates, map[string]interface{}{
+			"id":               gate.ID,
+			"distance":         dist(gate.Position, npc.Pos),
+			"unlocked":         gate.Unlocked,
+			"requiresTeamwork": gate.RequiresTeamwork,
+		})
+	}
+	var npcs []interface{}
+	for _, other := range w.NPCs {
+		if other == npc {
+			continue
+		}
+		npcs = append(npcs, map[string]interface{}{
+			"id":         other.ID,
+			"name":       other.Name,
+			"team":       other.Team,
+			"distance":   dist(other.Pos, npc.Pos),
+			"state":      other.State,
+			"isTeammate": other.Team == npc.Team,
+		})
+	}
+	return map[string]interface{}{
+		"npc_id":       npc.ID,
+		"name":         npc.Name,
+		"team":         npc.Team,
+		"pos":          []interface{}{npc.Pos[0], npc.Pos[1]},
+		"hp":           float64(npc.HP),
+		"energy":       float64(npc.Energy),
+		"state":        npc.State,
+		"nearby_npcs":  npcs,
+		"nearby_gates": gates,
+		"memory_code":  npc.MemoryCode,
+	}
+}
+
+// RunLoop advances the world tickRate times a second in the background
+// until ctx is cancelled, calling onTick with each new tick (on the loop's
+// goroutine, after Advance). Returns the loop's Simulator for its stats and
+// decision pacing.
+func (w *World) RunLoop(ctx context.Context, tickRate int, onTick func(tick int)) *Simulator {
+	simulator := NewSimulator(tickRate, func() {
+		tick := w.Advance()
+		if onTick != nil {
+			onTick(tick)
+		}
+	})
+	go simulator.Run(ctx)
+	return simulator
+}
@@ -0,0 +1,158 @@
+package game
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/amit/npc/internal/config"
+)
+
+// Object behaviors
+const (
+	BehaviorTokens    = "tokens"    // Grants the NPC's team tokens
+	BehaviorEnergy    = "energy"    // Restores the NPC's energy
+	BehaviorChallenge = "challenge" // Starts a mini-challenge at the object
+	BehaviorWaypoint  = "waypoint"  // Just a place to visit
+)
+
+// InteractRange is how close an NPC must be to use an object
+const InteractRange = 60.0
+
+// ObjectType describes what a world object does when an NPC interacts with it
+type ObjectType struct {
+	Name        string `json:"name"`
+	Behavior    string `json:"behavior"`
+	Amount      int    `json:"amount,omitempty"`       // Tokens or energy granted
+	ChallengeID string `json:"challenge_id,omitempty"` // Mini-challenge for BehaviorChallenge
+	Teammates   int    `json:"teammates,omitempty"`    // NPCs the mini-challenge needs, at least
+}
+
+// DefaultObjectTypes are used when config defines none
+var DefaultObjectTypes = map[string]ObjectType{
+	"treasure": {Name: "treasure", Behavior: BehaviorTokens, Amount: 15},
+	"resource": {Name: "resource", Behavior: BehaviorEnergy, Amount: 30},
+	"mystery":  {Name: "mystery", Behavior: BehaviorChallenge, ChallengeID: "challenge_coordination", Teammates: 2},
+	"landmark": {Name: "landmark", Behavior: BehaviorWaypoint},
+}
+
+// buildObjectTypes merges configured object types over the defaults
+func buildObjectTypes(cfg map[string]config.ObjectTypeConfig) map[string]ObjectType {
+	types := make(map[string]ObjectType, len(DefaultObjectTypes)+len(cfg))
+	for name, t := range DefaultObjectTypes {
+		types[name] = t
+	}
+	for name, c := range cfg {
+		types[name] = ObjectType{Name: name, Behavior: c.Behavior, Amount: c.Amount, ChallengeID: c.ChallengeID, Teammates: c.Teammates}
+	}
+	return types
+}
+
+// ApplyInteract has an NPC use a world object, dispatching on the object's
+// type. Each NPC can use each object once. Returns an "interact_result"
+// message for clients.
+func (w *World) ApplyInteract(npcName, objectID string) (map[string]interface{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.applyInteract(npcName, objectID)
+}
+
+func (w *World) applyInteract(npcName, objectID string) (map[string]interface{}, error) {
+	npc := w.GetNPCByName(npcName)
+	if npc == nil {
+		return nil, fmt.Errorf("unknown NPC %q", npcName)
+	}
+	obj := w.GetObject(objectID)
+	if obj == nil {
+		return nil, fmt.Errorf("unknown object %q", objectID)
+	}
+	if dist := math.Hypot(obj.Pos[0]-npc.Pos[0], obj.Pos[1]-npc.Pos[1]); dist > InteractRange {
+		return nil, fmt.Errorf("%s is %.0f units from %s (max %.0f)", npcName, dist, objectID, InteractRange)
+	}
+	for _, visitor := range obj.VisitedBy {
+		if visitor == npcName {
+			return nil, fmt.Errorf("%s already used %s", npcName, objectID)
+		}
+	}
+
+	objType, ok := w.ObjectTypes[obj.Type]
+	if !ok {
+		objType = ObjectType{Name: obj.Type, Behavior: BehaviorWaypoint}
+	}
+
+	result := map[string]interface{}{
+		"type":      "interact_result",
+		"npc":       npcName,
+		"object_id": objectID,
+		"object":    obj.Type,
+		"behavior":  objType.Behavior,
+	}
+
+	switch objType.Behavior {
+	case BehaviorTokens:
+		w.Teams.AwardReward(npc.Team, objType.Amount, "object_"+obj.Type)
+		result["tokens"] = objType.Amount
+		result["message"] = fmt.Sprintf("%s found %d tokens", npcName, objType.Amount)
+
+	case BehaviorEnergy:
+		before := npc.Energy
+		npc.Energy = int(math.Min(100, float64(npc.Energy+objType.Amount)))
+		result["energy"] = npc.Energy - before
+		result["message"] = fmt.Sprintf("%s restored %d energy", npcName, npc.Energy-before)
+
+	case BehaviorChallenge:
+		if w.SafeMode {
+			return nil, fmt.Errorf("challenges are disabled in safe mode")
+		}
+		active, err := w.Challenges.StartTeamChallenge(objectID, objType.ChallengeID, npcName, npc.Team, objType.Teammates)
+		if err != nil {
+			return nil, err
+		}
+		if active == nil {
+			return nil, fmt.Errorf("%s has no challenge %q", obj.Type, objType.ChallengeID)
+		}
+		result["challenge"] = active.Challenge
+		result["options"] = active.Options
+		result["gate_id"] = objectID // Answer with a challenge_response for this ID
+		result["message"] = fmt.Sprintf("%s uncovered a mystery: %s", npcName, active.Challenge.Name)
+
+	default:
+		result["message"] = fmt.Sprintf("%s reached the %s", npcName, obj.Type)
+	}
+
+	obj.VisitedBy = append(obj.VisitedBy, npcName)
+	w.markChanged("npc", npc.ID)
+	return result, nil
+}
+
+// GetObject returns a world object by ID
+func (w *World) GetObject(id string) *WorldObject {
+	for _, obj := range w.Objects {
+		if obj.ID == id {
+			return obj
+		}
+	}
+	return nil
+}
+
+// nearbyObjects lists objects within vision of pos for observations
+func (w *World) nearbyObjects(npcName string, pos [2]float64, vision float64) []interface{} {
+	var nearby []interface{}
+	for _, obj := range w.Objects {
+		dist := math.Hypot(obj.Pos[0]-pos[0], obj.Pos[1]-pos[1])
+		if dist > vision {
+			continue
+		}
+		used := false
+		for _, visitor := range obj.VisitedBy {
+			used = used || visitor == npcName
+		}
+		nearby = append(nearby, map[string]interface{}{
+			"id":       obj.ID,
+			"type":     obj.Type,
+			"behavior": w.ObjectTypes[obj.Type].Behavior,
+			"distance": dist,
+			"used":     used,
+		})
+	}
+	return nearby
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestApplyInteract_MysteryWaitsForATeammate(t *testing.T) {
+	world := NewWorld(config.Default())
+	var mystery *WorldObject
+	for _, obj := range world.Objects {
+		if obj.Type == "mystery" {
+			mystery = obj
+			break
+		}
+	}
+	if mystery == nil {
+		mystery = &WorldObject{ID: "obj_test", Type: "mystery"}
+		world.Objects = append(world.Objects, mystery)
+	}
+	// Explorer and Scout are both on red
+	for _, name := range []string{"Explorer", "Scout"} {
+		world.GetNPCByName(name).Pos = mystery.Pos
+	}
+
+	if _, err := world.ApplyInteract("Explorer", mystery.ID); err != nil {
+		t.Fatalf("Explorer interact: %v", err)
+	}
+	if ok, feedback := world.Challenges.SubmitResponse(mystery.ID, "Explorer", "ALPHA"); !ok {
+		t.Fatalf("Explorer's response rejected: %s", feedback)
+	}
+	if world.Challenges.ReadyToEvaluate(mystery.ID) {
+		t.Fatal("a lone NPC's answer made the mystery ready to judge")
+	}
+	if result := world.Challenges.EvaluateChallenge(mystery.ID); result != nil {
+		t.Fatalf("a lone NPC finished the mystery: %+v", result)
+	}
+
+	if _, err := world.ApplyInteract("Scout", mystery.ID); err != nil {
+		t.Fatalf("Scout interact: %v", err)
+	}
+	if ok, feedback := world.Challenges.SubmitResponse(mystery.ID, "Scout", "ALPHA"); !ok {
+		t.Fatalf("Scout's response rejected: %s", feedback)
+	}
+	if !world.Challenges.ReadyToEvaluate(mystery.ID) {
+		t.Fatal("mystery not ready once both teammates answered")
+	}
+	if result := world.Challenges.EvaluateChallenge(mystery.ID); result == nil || !result.Success {
+		t.Errorf("matching answers: result = %+v, want success", result)
+	}
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultDecisionReuse is how many times in a row an NPC's decision is
+// reused for an unchanged observation before the LLM is asked again
+const defaultDecisionReuse = 3
+
+// HashObservation reduces an observation to what matters for a decision:
+// the NPC, its position on a 50-unit grid and the locked gates nearby (by
+// distance, on the same grid). Observations with equal hashes get the same
+// decision; the batch decision cache is keyed by it.
+func HashObservation(obs map[string]interface{}) string {
+	key := make(map[string]interface{})
+
+	// Round position to grid (reduces cache variations)
+	if pos, ok := obs["pos"].([]interface{}); ok && len(pos) >= 2 {
+		if x, ok := pos[0].(float64); ok {
+			key["x"] = int(x/50) * 50
+		}
+		if y, ok := pos[1].(float64); ok {
+			key["y"] = int(y/50) * 50
+		}
+	}
+
+	key["name"], _ = obs["name"].(string)
+
+	// Include only locked nearby gates
+	var gateKeys []string
+	gates, _ := obs["nearby_gates"].([]interface{})
+	for _, g := range gates {
+		gate, ok := g.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if unlocked, _ := gate["unlocked"].(bool); !unlocked {
+			gateID, _ := gate["id"].(string)
+			distance, _ := gate["distance"].(float64)
+			gateKeys = append(gateKeys, fmt.Sprintf("%s:%d", gateID, int(distance/50)*50))
+		}
+	}
+	sort.Strings(gateKeys)
+	key["gates"] = strings.Join(gateKeys, ",")
+
+	keyJSON, _ := json.Marshal(key)
+	hash := sha256.Sum256(keyJSON)
+	return hex.EncodeToString(hash[:8]) // Use first 8 bytes
+}
+
+// ObservationChanged reports whether the NPC's latest observation (see
+// SyncFromObservation) differs from the one hashed to prevHash. An NPC with
+// no observation yet, or no previous hash, has always changed.
+func (w *World) ObservationChanged(npc *NPC, prevHash string) bool {
+	return prevHash == "" || npc.observationHash != prevHash
+}
+
+// TrackObservation records obs as the NPC's latest observation, as
+// SyncFromObservation does for the ones clients send, so server-built
+// observations (see Observation) can reuse decisions too
+func (w *World) TrackObservation(npcName string, obs map[string]interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if npc := w.GetNPCByName(npcName); npc != nil {
+		npc.observationHash = HashObservation(obs)
+	}
+}
+
+// RememberDecision keeps the decision an NPC's LLM just made along with the
+// observation it was made for, so ReuseDecision can continue it while that
+// observation holds
+func (w *World) RememberDecision(npcName string, decision map[string]interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	npc := w.GetNPCByName(npcName)
+	if npc == nil || decision == nil {
+		return
+	}
+	npc.lastDecision = make(map[string]interface{}, len(decision))
+	for k, v := range decision {
+		npc.lastDecision[k] = v
+	}
+	npc.decisionHash = npc.observationHash
+	npc.reuses = 0
+}
+
+// ReuseDecision returns a copy of the NPC's remembered decision, flagged
+// "reused", when its observation hasn't changed since that decision was
+// made, skipping the LLM call (and the cache lookup and prompt build before
+// it). After a few reuses in a row the LLM is asked again, so an NPC
+// waiting in place isn't stuck with one decision forever.
+func (w *World) ReuseDecision(npcName string) (map[string]interface{}, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	npc := w.GetNPCByName(npcName)
+	if npc == nil || npc.lastDecision == nil || w.decisionReuse < 0 {
+		return nil, false
+	}
+	if w.ObservationChanged(npc, npc.decisionHash) || npc.reuses >= w.decisionReuse {
+		return nil, false
+	}
+	npc.reuses++
+	w.decisionSkips.Add(1)
+
+	decision := make(map[string]interface{}, len(npc.lastDecision)+1)
+	for k, v := range npc.lastDecision {
+		decision[k] = v
+	}
+	decision["reused"] = true
+	return decision, true
+}
+
+// DecisionSkips returns how many LLM calls ReuseDecision has saved
+func (w *World) DecisionSkips() int64 {
+	return w.decisionSkips.Load()
+}
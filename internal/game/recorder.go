@@ -0,0 +1,336 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amit/npc/internal/challenge"
+	"github.com/amit/npc/internal/storage"
+)
+
+// Tick log record kinds
+const (
+	RecordStart    = "start"    // Full world at the start of recording
+	RecordDecision = "decision" // A decision as applied (after validation)
+	RecordTick     = "tick"     // Every entity that changed since the last tick record
+)
+
+// TickRecord is one line of the tick log. Tick records map entity keys
+// ("npc:npc_0", "gate:gate_1_2", "active:gate_1_2", "match", ...) to the
+// entity's full new state, or null when it was removed.
+type TickRecord struct {
+	Tick int             `json:"t"`
+	Kind string          `json:"k"`
+	NPC  string          `json:"npc,omitempty"`
+	Data json.RawMessage `json:"d,omitempty"`
+}
+
+// matchState is the "match" entity: outcome fields not owned by another entity
+type matchState struct {
+	MatchOver bool       `json:"match_over"`
+	Winner    string     `json:"winner,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// TickRecorder writes an append-only, lossless log of the match: the world
+// at the start, then every applied decision and, each tick, the full state of
+// every entity whose serialized form changed. Unlike the ReplayManager's
+// periodic snapshots, ReplayTickLog can rebuild the world at any tick.
+// Recording marshals every entity each tick, so it's opt-in.
+type TickRecorder struct {
+	mu      sync.Mutex
+	store   storage.Store
+	key     string
+	last    map[string]string // Entity key -> last recorded JSON
+	pending []byte            // Decision records, written with the next tick
+}
+
+// NewTickRecorder creates a recorder appending JSON lines to key in store
+func NewTickRecorder(store storage.Store, key string) *TickRecorder {
+	return &TickRecorder{store: store, key: key, last: make(map[string]string)}
+}
+
+// Start records the full world; call it once before the first tick
+func (r *TickRecorder) Start(w *World) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	for key, state := range recordedEntities(w) {
+		r.last[key] = state
+	}
+	return r.write(TickRecord{Tick: w.Tick, Kind: RecordStart, Data: data})
+}
+
+// RecordDecision logs a decision applied to an NPC (see World.SetDecisionHook)
+func (r *TickRecorder) RecordDecision(tick int, npcName string, decision map[string]interface{}) {
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return
+	}
+	line, _ := json.Marshal(TickRecord{Tick: tick, Kind: RecordDecision, NPC: npcName, Data: data})
+
+	r.mu.Lock()
+	r.pending = append(append(r.pending, line...), '\n')
+	r.mu.Unlock()
+}
+
+// RecordTick logs every entity that changed since the previous tick record,
+// along with the decisions applied in between
+func (r *TickRecorder) RecordTick(w *World) error {
+	current := recordedEntities(w)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := make(map[string]json.RawMessage)
+	for key, state := range current {
+		if r.last[key] != state {
+			changed[key] = json.RawMessage(state)
+			r.last[key] = state
+		}
+	}
+	for key := range r.last {
+		if _, ok := current[key]; !ok {
+			changed[key] = json.RawMessage("null")
+			delete(r.last, key)
+		}
+	}
+	if len(changed) == 0 && len(r.pending) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return err
+	}
+	return r.write(TickRecord{Tick: w.Tick, Kind: RecordTick, Data: data})
+}
+
+// write appends pending decisions and rec (caller holds r.mu)
+func (r *TickRecorder) write(rec TickRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	out := append(r.pending, append(line, '\n')...)
+	r.pending = nil
+	return storage.Append(r.store, r.key, out)
+}
+
+// recordedEntities serializes every piece of mutable world state by key
+func recordedEntities(w *World) map[string]string {
+	entities := make(map[string]string)
+	add := func(key string, v interface{}) {
+		if data, err := json.Marshal(v); err == nil {
+			entities[key] = string(data)
+		}
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, npc := range w.NPCs {
+		add("npc:"+npc.ID, npc)
+	}
+	for _, obj := range w.Objects {
+		add("object:"+obj.ID, obj)
+	}
+	teams, progress := w.Teams.Snapshot()
+	for id, team := range teams {
+		add("team:"+id, team)
+	}
+	for id, p := range progress {
+		add("progress:"+id, p)
+	}
+	zones, gates := w.Zones.Snapshot()
+	for id, zone := range zones {
+		add("zone:"+id, zone)
+	}
+	for id, gate := range gates {
+		add("gate:"+id, gate)
+	}
+	for gateID, active := range w.Challenges.ActiveSnapshot() {
+		add("active:"+gateID, active)
+	}
+	add("match", matchState{MatchOver: w.MatchOver, Winner: w.Winner, EndedAt: w.EndedAt})
+	return entities
+}
+
+// LoadTickLog reads the records of a tick log
+func LoadTickLog(store storage.Store, key string) ([]TickRecord, error) {
+	data, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []TickRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // Start records hold the whole world
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec TickRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", key, line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// ReplayTickLog rebuilds the world state as of tick from a tick log. The
+// result carries state only: validators, hooks and config-derived settings
+// are not recorded, so it is for inspection rather than for resuming play.
+func ReplayTickLog(records []TickRecord, tick int) (*World, error) {
+	var w *World
+	for _, rec := range records {
+		if rec.Tick > tick {
+			break
+		}
+		switch rec.Kind {
+		case RecordStart:
+			w = &World{}
+			if err := json.Unmarshal(rec.Data, w); err != nil {
+				return nil, fmt.Errorf("start record: %w", err)
+			}
+			w.ensureRecordedMaps()
+		case RecordTick:
+			if w == nil {
+				return nil, fmt.Errorf("tick %d recorded before the start record", rec.Tick)
+			}
+			var changed map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Data, &changed); err != nil {
+				return nil, fmt.Errorf("tick %d: %w", rec.Tick, err)
+			}
+			// Deterministic order so removals and re-adds apply consistently
+			keys := make([]string, 0, len(changed))
+			for key := range changed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if err := w.applyRecordedEntity(key, changed[key]); err != nil {
+					return nil, fmt.Errorf("tick %d %s: %w", rec.Tick, key, err)
+				}
+			}
+			w.Tick = rec.Tick
+		}
+	}
+	if w == nil {
+		return nil, fmt.Errorf("no start record at or before tick %d", tick)
+	}
+	return w, nil
+}
+
+// ensureRecordedMaps fills in managers and maps a start record left empty
+func (w *World) ensureRecordedMaps() {
+	w.changes = make(map[string]int)
+	if w.Teams == nil {
+		w.Teams = &TeamManager{}
+	}
+	if w.Teams.Teams == nil {
+		w.Teams.Teams = make(map[string]*Team)
+	}
+	if w.Teams.Progress == nil {
+		w.Teams.Progress = make(map[string]*TeamProgress)
+	}
+	if w.Zones == nil {
+		w.Zones = &ZoneManager{}
+	}
+	if w.Zones.Zones == nil {
+		w.Zones.Zones = make(map[string]*Zone)
+	}
+	if w.Zones.Gates == nil {
+		w.Zones.Gates = make(map[string]*Gate)
+	}
+	if w.Challenges == nil {
+		w.Challenges = &challenge.ChallengeManager{}
+	}
+	if w.Challenges.ActiveChallenges == nil {
+		w.Challenges.ActiveChallenges = make(map[string]*challenge.ActiveChallenge)
+	}
+}
+
+// applyRecordedEntity replaces (or removes, for null) one recorded entity
+func (w *World) applyRecordedEntity(key string, data json.RawMessage) error {
+	kind, id, _ := strings.Cut(key, ":")
+	removed := string(data) == "null"
+
+	switch kind {
+	case "npc":
+		return replaceRecorded(data, removed, &w.NPCs, func(n *NPC) bool { return n.ID == id })
+	case "object":
+		return replaceRecorded(data, removed, &w.Objects, func(o *WorldObject) bool { return o.ID == id })
+	case "team":
+		return putRecorded(data, removed, w.Teams.Teams, id)
+	case "progress":
+		return putRecorded(data, removed, w.Teams.Progress, id)
+	case "zone":
+		return putRecorded(data, removed, w.Zones.Zones, id)
+	case "gate":
+		return putRecorded(data, removed, w.Zones.Gates, id)
+	case "active":
+		return putRecorded(data, removed, w.Challenges.ActiveChallenges, id)
+	case "match":
+		var m matchState
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		w.MatchOver, w.Winner, w.EndedAt = m.MatchOver, m.Winner, m.EndedAt
+		return nil
+	}
+	return fmt.Errorf("unknown entity kind %q", kind)
+}
+
+// replaceRecorded swaps the matching slice element for the recorded one,
+// appending it if new or dropping it if removed
+func replaceRecorded[T any](data json.RawMessage, removed bool, items *[]*T, match func(*T) bool) error {
+	for i, item := range *items {
+		if !match(item) {
+			continue
+		}
+		if removed {
+			*items = append((*items)[:i], (*items)[i+1:]...)
+			return nil
+		}
+		fresh := new(T)
+		if err := json.Unmarshal(data, fresh); err != nil {
+			return err
+		}
+		(*items)[i] = fresh
+		return nil
+	}
+	if removed {
+		return nil
+	}
+	fresh := new(T)
+	if err := json.Unmarshal(data, fresh); err != nil {
+		return err
+	}
+	*items = append(*items, fresh)
+	return nil
+}
+
+// putRecorded sets or deletes a recorded map entry
+func putRecorded[T any](data json.RawMessage, removed bool, items map[string]*T, id string) error {
+	if removed {
+		delete(items, id)
+		return nil
+	}
+	fresh := new(T)
+	if err := json.Unmarshal(data, fresh); err != nil {
+		return err
+	}
+	items[id] = fresh
+	return nil
+}
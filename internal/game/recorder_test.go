@@ -0,0 +1,85 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+	"github.com/amit/npc/internal/storage"
+)
+
+func TestTickRecorder_ReplayRebuildsEveryTick(t *testing.T) {
+	world := NewWorld(&config.Config{
+		NPCs: config.NPCConfig{Count: 4},
+		Game: config.GameConfig{WorldWidth: 1200, WorldHeight: 800},
+	})
+	store := storage.NewFileStore(t.TempDir())
+	recorder := NewTickRecorder(store, "ticks.jsonl")
+	if err := recorder.Start(world); err != nil {
+		t.Fatal(err)
+	}
+	world.SetDecisionHook(recorder.RecordDecision)
+
+	var gateID string
+	for id := range world.Zones.Gates {
+		gateID = id
+		break
+	}
+
+	// Each step mutates different state, then records the tick
+	steps := []func(){
+		func() {
+			world.ApplyDecision("Explorer", map[string]interface{}{"action": "move", "target": []interface{}{300.0, 200.0}})
+			world.GetNPCByName("Explorer").Pos = [2]float64{180, 160}
+		},
+		func() {
+			world.Teams.AwardScore("red", 25, "test")
+			world.Zones.Gates[gateID].Unlocked = true
+			world.Zones.Gates[gateID].UnlockedBy = "red"
+		},
+		func() {
+			world.SendMessage("Scout", "Explorer", "nice one")
+			world.MatchOver, world.Winner = true, "red"
+		},
+	}
+	want := map[int]map[string]string{}
+	for _, step := range steps {
+		world.Advance()
+		step()
+		if err := recorder.RecordTick(world); err != nil {
+			t.Fatal(err)
+		}
+		want[world.Tick] = recordedEntities(world)
+	}
+
+	records, err := LoadTickLog(store, "ticks.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decisions := 0
+	for _, rec := range records {
+		if rec.Kind == RecordDecision {
+			decisions++
+		}
+	}
+	if decisions != 1 {
+		t.Errorf("recorded %d decisions, want 1", decisions)
+	}
+
+	for tick, entities := range want {
+		replayed, err := ReplayTickLog(records, tick)
+		if err != nil {
+			t.Fatalf("tick %d: %v", tick, err)
+		}
+		if replayed.Tick != tick {
+			t.Errorf("replayed tick = %d, want %d", replayed.Tick, tick)
+		}
+		if got := recordedEntities(replayed); !reflect.DeepEqual(got, entities) {
+			for key, state := range entities {
+				if got[key] != state {
+					t.Errorf("tick %d %s:\n got %s\nwant %s", tick, key, got[key], state)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package game
+
+import "strings"
+
+// ActionSchemaVersion is bumped whenever ActionRegistry changes, so clients
+// can tell whether their action handling matches the server's
+const ActionSchemaVersion = 2
+
+// Target kinds an action expects
+const (
+	TargetPosition = "position" // [x, y]
+	TargetGate     = "gate"     // Gate ID
+	TargetNPC      = "npc"      // NPC name
+	TargetObject   = "object"   // World object ID
+	TargetNone     = "none"
+)
+
+// ActionSpec describes one decision action. Prompts, validators, the init
+// message and GET /actions are all generated from ActionRegistry.
+type ActionSpec struct {
+	Name        string   `json:"name"`
+	Target      string   `json:"target"`
+	Description string   `json:"description"`
+	Example     string   `json:"example"`           // Decision JSON shown in prompts
+	Aliases     []string `json:"aliases,omitempty"` // Names models use instead, normalized on validation
+}
+
+// ActionRegistry is the single source of truth for valid decision actions
+var ActionRegistry = []ActionSpec{
+	{
+		Name: "move", Target: TargetPosition,
+		Description: "Move to coordinates",
+		Example:     `{"action":"move","target":[400,200],"reason":"heading to gate"}`,
+		Aliases:     []string{"go", "walk"},
+	},
+	{
+		Name: "challenge", Target: TargetGate,
+		Description: "Attempt a gate's challenge (must be within 60 units!)",
+		Example:     `{"action":"challenge","target":"gate_1_2","reason":"solving puzzle"}`,
+	},
+	{
+		Name: "talk", Target: TargetNPC,
+		Description: "Talk to a nearby NPC",
+		Example:     `{"action":"talk","target":"Scout","message":"Let's team up!"}`,
+	},
+	{
+		Name: "taunt", Target: TargetNPC,
+		Description: "Taunt an opponent",
+		Example:     `{"action":"taunt","target":"Wanderer","message":"You're too slow!"}`,
+	},
+	{
+		Name: "interact", Target: TargetObject,
+		Description: "Use an object (must be within 60 units!)",
+		Example:     `{"action":"interact","target":"obj_3","reason":"grabbing treasure"}`,
+	},
+	{
+		Name: "wait", Target: TargetNone,
+		Description: "Stay and wait",
+		Example:     `{"action":"wait","target":null,"reason":"waiting for teammate"}`,
+		Aliases:     []string{"idle", "stay"},
+	},
+	{
+		Name: "explore", Target: TargetNone,
+		Description: "Random exploration",
+		Example:     `{"action":"explore","target":null,"reason":"looking around"}`,
+	},
+}
+
+// LookupAction finds an action by name or alias (case-insensitive)
+func LookupAction(name string) (ActionSpec, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, spec := range ActionRegistry {
+		if spec.Name == name {
+			return spec, true
+		}
+		for _, alias := range spec.Aliases {
+			if alias == name {
+				return spec, true
+			}
+		}
+	}
+	return ActionSpec{}, false
+}
+
+// ActionNames lists the registered action names in registry order
+func ActionNames() []string {
+	names := make([]string, len(ActionRegistry))
+	for i, spec := range ActionRegistry {
+		names[i] = spec.Name
+	}
+	return names
+}
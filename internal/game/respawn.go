@@ -0,0 +1,61 @@
+package game
+
+import "github.com/amit/npc/internal/config"
+
+// respawnDefaults fills in unset respawn bounds
+func respawnDefaults(cfg config.RespawnConfig) config.RespawnConfig {
+	if cfg.BaseTicks <= 0 {
+		cfg.BaseTicks = 10
+	}
+	if cfg.MinTicks <= 0 {
+		cfg.MinTicks = cfg.BaseTicks / 2
+	}
+	if cfg.MaxTicks <= 0 {
+		cfg.MaxTicks = cfg.BaseTicks * 2
+	}
+	if cfg.MinTicks > cfg.BaseTicks {
+		cfg.MinTicks = cfg.BaseTicks
+	}
+	if cfg.MaxTicks < cfg.BaseTicks {
+		cfg.MaxTicks = cfg.BaseTicks
+	}
+	if cfg.ScoreGapForMax <= 0 {
+		cfg.ScoreGapForMax = 100
+	}
+	return cfg
+}
+
+// RespawnDelay returns how many ticks an eliminated NPC of the team waits
+// before respawning. Like the zone generator's catch-up zones it favors the
+// trailing team: the delay shrinks toward MinTicks as the team falls behind
+// its opponent and grows toward MaxTicks as it pulls ahead.
+func (w *World) RespawnDelay(teamID string) int {
+	team := w.Teams.GetTeam(teamID)
+	opponent := w.Teams.GetOpponentTeam(teamID)
+	if team == nil || opponent == nil {
+		return w.respawn.BaseTicks
+	}
+
+	lead := float64(team.Score-opponent.Score) / float64(w.respawn.ScoreGapForMax)
+	if lead > 1 {
+		lead = 1
+	} else if lead < -1 {
+		lead = -1
+	}
+
+	base := float64(w.respawn.BaseTicks)
+	if lead >= 0 {
+		return int(base + lead*float64(w.respawn.MaxTicks-w.respawn.BaseTicks) + 0.5)
+	}
+	return int(base + lead*float64(w.respawn.BaseTicks-w.respawn.MinTicks) + 0.5)
+}
+
+// RespawnDelays returns the current respawn delay in ticks for every team,
+// for the UI to show the rubber-banding
+func (w *World) RespawnDelays() map[string]int {
+	delays := make(map[string]int)
+	for _, team := range w.Teams.GetLeaderboard() {
+		delays[team.ID] = w.RespawnDelay(team.ID)
+	}
+	return delays
+}
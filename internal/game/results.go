@@ -0,0 +1,175 @@
+package game
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TeamResult is one team's line in the match results
+type TeamResult struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Score              int    `json:"score"`
+	Tokens             int    `json:"tokens"`
+	TokensEarned       int    `json:"tokens_earned"`
+	TokensSpent        int    `json:"tokens_spent"`
+	ChallengesSolved   int    `json:"challenges_solved"`
+	ChallengesFailed   int    `json:"challenges_failed"`
+	ZonesUnlocked      int    `json:"zones_unlocked"`
+	BestStreak         int    `json:"best_streak"`
+	CollaborationCount int    `json:"collaboration_count"`
+	Forfeited          bool   `json:"forfeited"`
+}
+
+// MatchResults summarizes a match for comparing runs across prompt and model changes
+type MatchResults struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	StartedAt   time.Time    `json:"started_at"`
+	DurationSec float64      `json:"duration_sec"`
+	Ticks       int          `json:"ticks"`
+	MatchOver   bool         `json:"match_over"`
+	Winner      string       `json:"winner,omitempty"`
+	Competitive bool         `json:"competitive"`       // False for practice-mode matches
+	Teams       []TeamResult `json:"teams"`             // Highest score first
+	Summary     string       `json:"summary,omitempty"` // Post-match recap (see SetMatchSummary)
+
+	LLMCallsByProvider map[string]int `json:"llm_calls_by_provider"`
+	LLMCalls           int            `json:"llm_calls"`
+	LLMCostUSD         float64        `json:"llm_cost_usd"`
+}
+
+// SetLLMStatsFunc sets the source of per-provider call counts and total LLM
+// cost included in ExportResults
+func (w *World) SetLLMStatsFunc(fn func() (map[string]int, float64)) {
+	w.llmStats = fn
+}
+
+// SetMatchSummary keeps the post-match recap for ExportResults
+func (w *World) SetMatchSummary(summary string) {
+	w.summary = summary
+}
+
+// matchElapsed is the match's running time at now, stopping at its end
+func (w *World) matchElapsed(now time.Time) time.Duration {
+	if w.MatchOver && w.EndedAt != nil {
+		now = *w.EndedAt
+	}
+	return now.Sub(w.StartedAt)
+}
+
+// MatchClock reports when the match started, how long it has run (stopped
+// at its end) and the current tick
+func (w *World) MatchClock() map[string]interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	clock := map[string]interface{}{
+		"started_at":  w.StartedAt,
+		"elapsed_sec": int(w.matchElapsed(time.Now()).Seconds()),
+		"tick":        w.Tick,
+		"match_over":  w.MatchOver,
+		"winner":      w.Winner,
+	}
+	if w.MatchOver && w.EndedAt != nil {
+		clock["ended_at"] = *w.EndedAt
+	}
+	return clock
+}
+
+// ExportResults gathers final scores, team progress and LLM usage. The
+// duration runs to the end of the match, or to now if it's still going.
+func (w *World) ExportResults() MatchResults {
+	now := time.Now()
+
+	results := MatchResults{
+		GeneratedAt:        now,
+		StartedAt:          w.StartedAt,
+		DurationSec:        w.matchElapsed(now).Seconds(),
+		Ticks:              w.Tick,
+		MatchOver:          w.MatchOver,
+		Winner:             w.Winner,
+		Competitive:        !w.PracticeMode,
+		Summary:            w.summary,
+		LLMCallsByProvider: map[string]int{},
+	}
+
+	for _, team := range w.Teams.GetLeaderboard() {
+		result := TeamResult{ID: team.ID, Name: team.Name, Score: team.Score, Tokens: team.Tokens}
+		if p := w.Teams.GetProgress(team.ID); p != nil {
+			result.TokensEarned = p.TotalTokensEarned
+			result.TokensSpent = p.TotalTokensSpent
+			result.ChallengesSolved = p.ChallengesSolved
+			result.ChallengesFailed = p.ChallengesFailed
+			result.ZonesUnlocked = len(p.ZonesUnlocked)
+			result.BestStreak = p.BestStreak
+			result.CollaborationCount = p.CollaborationCount
+			result.Forfeited = p.Forfeited
+		}
+		results.Teams = append(results.Teams, result)
+	}
+
+	if w.llmStats != nil {
+		calls, cost := w.llmStats()
+		for provider, n := range calls {
+			results.LLMCallsByProvider[provider] = n
+			results.LLMCalls += n
+		}
+		results.LLMCostUSD = cost
+	}
+	return results
+}
+
+// CSV renders the results with one row per team; match-wide columns repeat
+// on each row so files from several runs can be concatenated
+func (r MatchResults) CSV() ([]byte, error) {
+	providers := make([]string, 0, len(r.LLMCallsByProvider))
+	for provider := range r.LLMCallsByProvider {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	calls := make([]string, len(providers))
+	for i, provider := range providers {
+		calls[i] = fmt.Sprintf("%s=%d", provider, r.LLMCallsByProvider[provider])
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{
+		"generated_at", "duration_sec", "ticks", "match_over", "winner", "competitive",
+		"team", "score", "tokens", "tokens_earned", "tokens_spent",
+		"challenges_solved", "challenges_failed", "zones_unlocked", "best_streak",
+		"collaboration_count", "forfeited",
+		"llm_calls", "llm_calls_by_provider", "llm_cost_usd",
+	})
+	for _, t := range r.Teams {
+		w.Write([]string{
+			r.GeneratedAt.Format(time.RFC3339),
+			strconv.FormatFloat(r.DurationSec, 'f', 1, 64),
+			strconv.Itoa(r.Ticks),
+			strconv.FormatBool(r.MatchOver),
+			r.Winner,
+			strconv.FormatBool(r.Competitive),
+			t.ID,
+			strconv.Itoa(t.Score),
+			strconv.Itoa(t.Tokens),
+			strconv.Itoa(t.TokensEarned),
+			strconv.Itoa(t.TokensSpent),
+			strconv.Itoa(t.ChallengesSolved),
+			strconv.Itoa(t.ChallengesFailed),
+			strconv.Itoa(t.ZonesUnlocked),
+			strconv.Itoa(t.BestStreak),
+			strconv.Itoa(t.CollaborationCount),
+			strconv.FormatBool(t.Forfeited),
+			strconv.Itoa(r.LLMCalls),
+			strings.Join(calls, ";"),
+			strconv.FormatFloat(r.LLMCostUSD, 'f', 4, 64),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
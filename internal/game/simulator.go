@@ -0,0 +1,263 @@
+package game
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxCatchUp bounds how many missed ticks one wake-up replays; a server that
+// falls further behind drops the rest instead of spiralling
+const maxCatchUp = 5
+
+// baseTickRate is the tick rate tick-denominated settings (zone income
+// interval, taunt cooldown, respawn delays, observation history, move speed)
+// are written for: 10 ticks is ~5s
+const baseTickRate = 2
+
+// scaleTicks converts a tick count written for baseTickRate to the same
+// duration at tickRate, at least one tick
+func scaleTicks(ticks, tickRate int) int {
+	if scaled := ticks * tickRate / baseTickRate; scaled > 0 {
+		return scaled
+	}
+	return 1
+}
+
+// Simulator calls a step function at a fixed tick rate. Missed ticks (a slow
+// step, a stalled scheduler) are replayed on the next wake-up so the tick
+// count keeps pace with wall time, up to maxCatchUp at once.
+type Simulator struct {
+	step     func()
+	rate     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	paused  bool
+	ticks   int // Steps run
+	dropped int // Ticks skipped after falling more than maxCatchUp behind
+	panics  int // Steps that panicked (recovered; the tick still counts)
+
+	// Decision fan-out: at most decisionLimit decisions start per tick (0 =
+	// unlimited); the rest wait in line for a later tick (see AwaitDecision)
+	decisionLimit int
+	decisionsUsed int             // Started this tick
+	decisionQueue []chan struct{} // Waiting, oldest first
+	deferred      int             // Decisions that had to wait for a later tick
+
+	// Actual rate over the last full second
+	windowStart time.Time
+	windowTicks int
+	actualRate  float64
+}
+
+// SimulatorStats reports whether the simulation keeps up with its target rate
+type SimulatorStats struct {
+	TargetRate int     `json:"target_rate"`
+	ActualRate float64 `json:"actual_rate"`
+	Ticks      int     `json:"ticks"`
+	Dropped    int     `json:"dropped"`
+	Panics     int     `json:"panics"`
+	Paused     bool    `json:"paused"`
+
+	DecisionLimit     int `json:"decision_limit"`     // Per tick, 0 = unlimited
+	DeferredDecisions int `json:"deferred_decisions"` // Waited for a later tick
+	WaitingDecisions  int `json:"waiting_decisions"`  // In line right now
+}
+
+// NewSimulator creates a simulator running step tickRate times per second
+// (default 60, like GameConfig.TickRate)
+func NewSimulator(tickRate int, step func()) *Simulator {
+	if tickRate <= 0 {
+		tickRate = 60
+	}
+	return &Simulator{
+		step:     step,
+		rate:     tickRate,
+		interval: time.Second / time.Duration(tickRate),
+	}
+}
+
+// Run steps the simulation until ctx is cancelled
+func (s *Simulator) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	due := 0 // Ticks owed since start
+	s.mu.Lock()
+	s.windowStart = start
+	s.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			paused := s.paused
+			s.mu.Unlock()
+			if paused {
+				// Don't owe ticks for time spent paused
+				start, due = now, 0
+				continue
+			}
+
+			owed := int(now.Sub(start)/s.interval) - due
+			if owed > maxCatchUp {
+				s.mu.Lock()
+				s.dropped += owed - maxCatchUp
+				s.mu.Unlock()
+				due += owed - maxCatchUp
+				owed = maxCatchUp
+			}
+			for i := 0; i < owed; i++ {
+				if ctx.Err() != nil {
+					return
+				}
+				s.safeStep()
+				due++
+				s.recordTick(time.Now())
+			}
+		}
+	}
+}
+
+// safeStep runs one step, recovering a panic so one bad tick doesn't take
+// down the server
+func (s *Simulator) safeStep() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("💥 Tick step panicked: %v\n%s", r, debug.Stack())
+			s.mu.Lock()
+			s.panics++
+			s.mu.Unlock()
+		}
+	}()
+	s.step()
+}
+
+// recordTick counts a step toward the measured tick rate
+func (s *Simulator) recordTick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ticks++
+	s.windowTicks++
+	s.openDecisionSlots()
+	if elapsed := now.Sub(s.windowStart); elapsed >= time.Second {
+		s.actualRate = float64(s.windowTicks) / elapsed.Seconds()
+		s.windowStart, s.windowTicks = now, 0
+	}
+}
+
+// Pause stops stepping until Resume; Run keeps waiting on ctx meanwhile
+func (s *Simulator) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+	s.actualRate = 0
+	// No ticks are coming to spread decisions over
+	for _, ch := range s.decisionQueue {
+		close(ch)
+	}
+	s.decisionQueue = nil
+}
+
+// Resume restarts stepping after Pause
+func (s *Simulator) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused {
+		s.paused = false
+		s.windowStart, s.windowTicks = time.Now(), 0
+	}
+}
+
+// Paused reports whether the simulation is paused
+func (s *Simulator) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Stats returns the target and measured tick rates and the decision backlog
+func (s *Simulator) Stats() SimulatorStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SimulatorStats{
+		TargetRate: s.rate,
+		ActualRate: s.actualRate,
+		Ticks:      s.ticks,
+		Dropped:    s.dropped,
+		Panics:     s.panics,
+		Paused:     s.paused,
+
+		DecisionLimit:     s.decisionLimit,
+		DeferredDecisions: s.deferred,
+		WaitingDecisions:  len(s.decisionQueue),
+	}
+}
+
+// SetDecisionLimit caps how many decisions may start per tick (0 or less =
+// unlimited), smoothing LLM load when many NPCs decide at once
+func (s *Simulator) SetDecisionLimit(perTick int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.decisionLimit = max(perTick, 0)
+	if s.decisionLimit == 0 {
+		for _, turn := range s.decisionQueue {
+			close(turn)
+		}
+		s.decisionQueue = nil
+	}
+}
+
+// AwaitDecision blocks until a decision may start: right away while this
+// tick has room, otherwise on a later tick. Waiting decisions are let
+// through in arrival order ahead of new ones, so every NPC gets its turn.
+// Returns false if ctx ends first.
+func (s *Simulator) AwaitDecision(ctx context.Context) bool {
+	s.mu.Lock()
+	if s.decisionLimit == 0 || s.paused ||
+		(len(s.decisionQueue) == 0 && s.decisionsUsed < s.decisionLimit) {
+		s.decisionsUsed++
+		s.mu.Unlock()
+		return true
+	}
+	turn := make(chan struct{})
+	s.decisionQueue = append(s.decisionQueue, turn)
+	s.deferred++
+	s.mu.Unlock()
+
+	select {
+	case <-turn:
+		return true
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if i := slices.Index(s.decisionQueue, turn); i >= 0 {
+			s.decisionQueue = slices.Delete(s.decisionQueue, i, i+1)
+			return false
+		}
+		return true // Our turn came as ctx ended; take it
+	}
+}
+
+// openDecisionSlots starts a tick's decision budget, first on those waiting.
+// Callers hold s.mu.
+func (s *Simulator) openDecisionSlots() {
+	s.decisionsUsed = 0
+	if s.decisionLimit == 0 {
+		return
+	}
+	n := min(s.decisionLimit, len(s.decisionQueue))
+	for _, turn := range s.decisionQueue[:n] {
+		close(turn)
+	}
+	s.decisionQueue = s.decisionQueue[n:]
+	s.decisionsUsed = n
+}
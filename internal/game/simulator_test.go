@@ -0,0 +1,153 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestAwaitDecision_DefersPastLimitInArrivalOrder(t *testing.T) {
+	sim := NewSimulator(2, func() {})
+	sim.SetDecisionLimit(1)
+
+	if !sim.AwaitDecision(context.Background()) {
+		t.Fatal("first decision of the tick should start right away")
+	}
+
+	order := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		go func(i int) {
+			if sim.AwaitDecision(context.Background()) {
+				order <- i
+			}
+		}(i)
+		// Queue them in a known order
+		for sim.Stats().WaitingDecisions < i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for want := 1; want <= 2; want++ {
+		sim.recordTick(time.Now())
+		if got := <-order; got != want {
+			t.Fatalf("tick %d let decision %d through, want %d", want, got, want)
+		}
+	}
+
+	stats := sim.Stats()
+	if stats.DeferredDecisions != 2 || stats.WaitingDecisions != 0 {
+		t.Errorf("stats = %+v, want 2 deferred and none waiting", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sim.AwaitDecision(ctx) {
+		t.Error("decision past the limit started without waiting for a tick")
+	}
+}
+
+func TestAdvance_MovesServerDrivenNPCs(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.ServerDriven = true
+	cfg.Game.TickRate = baseTickRate // move_speed 10 per tick, unscaled
+	world := NewWorld(cfg)
+	// Explorer starts at (150, 150) in the top-left start zone; 25 units
+	// east is still inside it, so the target isn't coerced
+	npc := world.GetNPCByName("Explorer")
+	start := npc.Pos
+	target := [2]float64{start[0] + 25, start[1]}
+
+	world.EnqueueDecision(npc.Name, map[string]interface{}{
+		"action": "move",
+		"target": []interface{}{target[0], target[1]},
+	})
+	for _, want := range []float64{10, 20, 25} {
+		world.Advance()
+		if got := npc.Pos[0] - start[0]; got != want {
+			t.Fatalf("after tick %d moved %.0f, want %.0f", world.Tick, got, want)
+		}
+	}
+	if npc.Target != nil || npc.CurrentZone == "" {
+		t.Errorf("arrived with target %v in zone %q, want no target and a zone", npc.Target, npc.CurrentZone)
+	}
+
+	// Off by default: positions come from client observations
+	world = NewWorld(config.Default())
+	npc = world.GetNPCByName("Explorer")
+	start = npc.Pos
+	npc.Target = &target
+	world.Advance()
+	if npc.Pos != start {
+		t.Errorf("client-driven NPC moved to %v on Advance", npc.Pos)
+	}
+}
+
+func TestNewWorld_ScalesTickTimingsToTickRate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.TickRate = 60
+	cfg.Game.ZoneIncome.IntervalTicks = 20 // ~10s at baseTickRate
+	cfg.Game.MoveSpeed = 10
+	world := NewWorld(cfg)
+
+	if got := world.zoneIncome.IntervalTicks; got != 600 {
+		t.Errorf("income interval = %d ticks at 60/sec, want 600 (still ~10s)", got)
+	}
+	if got, want := world.moveSpeed*60, 10.0*baseTickRate; got != want {
+		t.Errorf("move speed = %.1f per second at 60/sec, want %.1f", got, want)
+	}
+	if got := scaleTicks(1, 1); got != 1 {
+		t.Errorf("scaleTicks(1, 1) = %d, want at least one tick", got)
+	}
+}
+
+func TestMatchClock_ReadsUnderTheWorldLock(t *testing.T) {
+	world := NewWorld(config.Default())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			world.Advance()
+		}
+		world.Concede("red")
+	}()
+	for i := 0; i < 50; i++ {
+		world.MatchClock()
+	}
+	<-done
+
+	clock := world.MatchClock()
+	if clock["match_over"] != true {
+		t.Fatalf("clock = %v, want the conceded match over", clock)
+	}
+	if _, ok := clock["ended_at"]; !ok {
+		t.Errorf("clock = %v, want ended_at once the match is over", clock)
+	}
+}
+
+func TestWorldJSON_EndedAtOnlyOnceTheMatchEnds(t *testing.T) {
+	world := NewWorld(config.Default())
+	endedAt := func() (interface{}, bool) {
+		data, err := json.Marshal(world)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var fields map[string]interface{}
+		json.Unmarshal(data, &fields)
+		v, ok := fields["ended_at"]
+		return v, ok
+	}
+
+	if v, ok := endedAt(); ok {
+		t.Errorf("ended_at = %v before the match ended, want it omitted", v)
+	}
+	world.Concede("red")
+	if v, ok := endedAt(); !ok {
+		t.Error("ended_at missing after the match ended")
+	} else if _, err := time.Parse(time.RFC3339Nano, v.(string)); err != nil {
+		t.Errorf("ended_at = %v: %v", v, err)
+	}
+}
@@ -1,5 +1,25 @@
 package game
 
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Team strategies bias a team's prompts toward fighting or objectives
+const (
+	StrategyAggressive = "aggressive" // Taunt, intercept, contest gates
+	StrategyObjective  = "objective"  // Challenge gates, explore
+	StrategyBalanced   = "balanced"
+)
+
+// StrategyDirectives are injected into prompts for each team strategy
+var StrategyDirectives = map[string]string{
+	StrategyAggressive: "Play AGGRESSIVELY: shadow and taunt opponents, intercept them, and contest gates they are attempting.",
+	StrategyObjective:  "Play for OBJECTIVES: ignore opponents unless they block you, prioritize challenging gates and exploring new zones.",
+	StrategyBalanced:   "Play BALANCED: pursue gates, but take chances to taunt or contest opponents when they are close.",
+}
+
 // Team represents a team of NPCs working together
 type Team struct {
 	ID      string   `json:"id"`
@@ -9,6 +29,8 @@ type Team struct {
 	Score   int      `json:"score"`
 	Tokens  int      `json:"tokens"`
 	Zones   []string `json:"zones"` // Zone IDs controlled by this team
+
+	Strategy string `json:"strategy"` // aggressive, objective or balanced
 }
 
 // TeamProgress tracks team achievements
@@ -20,13 +42,41 @@ type TeamProgress struct {
 	BestStreak         int      `json:"best_streak"`
 	TotalTokensEarned  int      `json:"total_tokens_earned"`
 	TotalTokensSpent   int      `json:"total_tokens_spent"`
+	TotalScoreEarned   int      `json:"total_score_earned"`
 	CollaborationCount int      `json:"collaboration_count"` // Times both members worked together
+	Forfeited          bool     `json:"forfeited"`           // Team conceded the match
 }
 
-// TeamManager handles team operations
+// TeamManager handles team operations. Its methods are safe for concurrent
+// use; code touching Teams and Progress directly must hold mu.
 type TeamManager struct {
 	Teams    map[string]*Team         `json:"teams"`
 	Progress map[string]*TeamProgress `json:"progress"`
+
+	mu sync.RWMutex
+
+	onChange func(teamID string) // Change tracking hook (set by World)
+
+	// Called for every Score or Tokens change (kind is "score" or "tokens")
+	onLedger func(teamID, kind string, amount, balance int, reason string)
+}
+
+// changed notifies the change tracking hook
+func (tm *TeamManager) changed(teamID string) {
+	if tm.onChange != nil {
+		tm.onChange(teamID)
+	}
+}
+
+// SetLedgerHook sets the function that audits each Score and Tokens change
+func (tm *TeamManager) SetLedgerHook(fn func(teamID, kind string, amount, balance int, reason string)) {
+	tm.onLedger = fn
+}
+
+func (tm *TeamManager) ledger(teamID, kind string, amount, balance int, reason string) {
+	if tm.onLedger != nil {
+		tm.onLedger(teamID, kind, amount, balance, reason)
+	}
 }
 
 // NewTeamManager creates a team manager with default 2v2 setup
@@ -45,6 +95,8 @@ func NewTeamManager() *TeamManager {
 		Score:   0,
 		Tokens:  50, // Starting tokens
 		Zones:   []string{"start"},
+
+		Strategy: StrategyBalanced,
 	}
 
 	// Create Team Blue
@@ -56,6 +108,8 @@ func NewTeamManager() *TeamManager {
 		Score:   0,
 		Tokens:  50,
 		Zones:   []string{"start"},
+
+		Strategy: StrategyBalanced,
 	}
 
 	// Initialize progress tracking
@@ -65,8 +119,68 @@ func NewTeamManager() *TeamManager {
 	return tm
 }
 
-// GetTeamForNPC returns the team that contains the given NPC
+// clone copies the team, including its member and zone lists
+func (t *Team) clone() *Team {
+	c := *t
+	c.Members = slices.Clone(t.Members)
+	c.Zones = slices.Clone(t.Zones)
+	return &c
+}
+
+// GetTeam returns a copy of the team with the given ID, or nil
+func (tm *TeamManager) GetTeam(teamID string) *Team {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if team, ok := tm.Teams[teamID]; ok {
+		return team.clone()
+	}
+	return nil
+}
+
+// GetProgress returns a copy of the team's progress, or nil
+func (tm *TeamManager) GetProgress(teamID string) *TeamProgress {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if progress, ok := tm.Progress[teamID]; ok {
+		p := *progress
+		p.ZonesUnlocked = slices.Clone(progress.ZonesUnlocked)
+		return &p
+	}
+	return nil
+}
+
+// Snapshot returns copies of every team and its progress keyed by team ID,
+// safe to read (e.g. encode) while scores change
+func (tm *TeamManager) Snapshot() (map[string]*Team, map[string]*TeamProgress) {
+	tm.mu.RLock()
+	teams := make(map[string]*Team, len(tm.Teams))
+	ids := make([]string, 0, len(tm.Teams))
+	for id, team := range tm.Teams {
+		teams[id] = team.clone()
+		ids = append(ids, id)
+	}
+	tm.mu.RUnlock()
+
+	progress := make(map[string]*TeamProgress, len(ids))
+	for _, id := range ids {
+		if p := tm.GetProgress(id); p != nil {
+			progress[id] = p
+		}
+	}
+	return teams, progress
+}
+
+// GetTeamForNPC returns a copy of the team that contains the given NPC
 func (tm *TeamManager) GetTeamForNPC(npcName string) *Team {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if team := tm.teamForNPC(npcName); team != nil {
+		return team.clone()
+	}
+	return nil
+}
+
+func (tm *TeamManager) teamForNPC(npcName string) *Team {
 	for _, team := range tm.Teams {
 		for _, member := range team.Members {
 			if member == npcName {
@@ -79,7 +193,9 @@ func (tm *TeamManager) GetTeamForNPC(npcName string) *Team {
 
 // GetTeammate returns the teammate of the given NPC
 func (tm *TeamManager) GetTeammate(npcName string) string {
-	team := tm.GetTeamForNPC(npcName)
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	team := tm.teamForNPC(npcName)
 	if team == nil {
 		return ""
 	}
@@ -91,43 +207,97 @@ func (tm *TeamManager) GetTeammate(npcName string) string {
 	return ""
 }
 
-// GetOpponentTeam returns the opposing team
+// GetOpponentTeam returns a copy of the opposing team
 func (tm *TeamManager) GetOpponentTeam(teamID string) *Team {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	for id, team := range tm.Teams {
 		if id != teamID {
-			return team
+			return team.clone()
 		}
 	}
 	return nil
 }
 
-// AwardTokens adds tokens to a team
+// Score is the achievement total shown on the leaderboard; spending never
+// reduces it. Tokens are the spendable currency. Rewards usually grant both
+// (AwardReward), but each can change on its own.
+
+// AwardReward grants amount as both score and spendable tokens
+func (tm *TeamManager) AwardReward(teamID string, amount int, reason string) {
+	tm.AwardScore(teamID, amount, reason)
+	tm.AwardTokens(teamID, amount, reason)
+}
+
+// AwardScore adds achievement points to a team (negative for penalties)
+func (tm *TeamManager) AwardScore(teamID string, points int, reason string) {
+	tm.mu.Lock()
+	balance, ok := tm.awardScore(teamID, points)
+	tm.mu.Unlock()
+	if ok {
+		tm.changed(teamID)
+		tm.ledger(teamID, "score", points, balance, reason)
+	}
+}
+
+func (tm *TeamManager) awardScore(teamID string, points int) (int, bool) {
+	team, ok := tm.Teams[teamID]
+	if !ok {
+		return 0, false
+	}
+	team.Score += points
+	if progress, ok := tm.Progress[teamID]; ok && points > 0 {
+		progress.TotalScoreEarned += points
+	}
+	return team.Score, true
+}
+
+// AwardTokens adds spendable tokens to a team without affecting its score
 func (tm *TeamManager) AwardTokens(teamID string, amount int, reason string) {
-	if team, ok := tm.Teams[teamID]; ok {
-		team.Tokens += amount
-		team.Score += amount
-		if progress, ok := tm.Progress[teamID]; ok {
-			progress.TotalTokensEarned += amount
-		}
+	tm.mu.Lock()
+	balance, ok := tm.awardTokens(teamID, amount)
+	tm.mu.Unlock()
+	if ok {
+		tm.changed(teamID)
+		tm.ledger(teamID, "tokens", amount, balance, reason)
 	}
 }
 
-// SpendTokens deducts tokens from a team (for hints, skips, etc.)
-func (tm *TeamManager) SpendTokens(teamID string, amount int) bool {
-	if team, ok := tm.Teams[teamID]; ok {
-		if team.Tokens >= amount {
-			team.Tokens -= amount
-			if progress, ok := tm.Progress[teamID]; ok {
-				progress.TotalTokensSpent += amount
-			}
-			return true
-		}
+func (tm *TeamManager) awardTokens(teamID string, amount int) (int, bool) {
+	team, ok := tm.Teams[teamID]
+	if !ok {
+		return 0, false
+	}
+	team.Tokens += amount
+	if progress, ok := tm.Progress[teamID]; ok {
+		progress.TotalTokensEarned += amount
 	}
-	return false
+	return team.Tokens, true
+}
+
+// SpendTokens deducts tokens from a team (for hints, skips, etc.). Score is unaffected.
+func (tm *TeamManager) SpendTokens(teamID string, amount int, reason string) bool {
+	tm.mu.Lock()
+	team, ok := tm.Teams[teamID]
+	if !ok || team.Tokens < amount {
+		tm.mu.Unlock()
+		return false
+	}
+	team.Tokens -= amount
+	if progress, ok := tm.Progress[teamID]; ok {
+		progress.TotalTokensSpent += amount
+	}
+	balance := team.Tokens
+	tm.mu.Unlock()
+
+	tm.changed(teamID)
+	tm.ledger(teamID, "tokens", -amount, balance, reason)
+	return true
 }
 
 // RecordChallengeSolved records a successful challenge completion
 func (tm *TeamManager) RecordChallengeSolved(teamID string, tokensEarned int) {
+	tm.mu.Lock()
 	if progress, ok := tm.Progress[teamID]; ok {
 		progress.ChallengesSolved++
 		progress.CurrentStreak++
@@ -135,39 +305,100 @@ func (tm *TeamManager) RecordChallengeSolved(teamID string, tokensEarned int) {
 			progress.BestStreak = progress.CurrentStreak
 		}
 	}
-	tm.AwardTokens(teamID, tokensEarned, "challenge_solved")
+	tm.mu.Unlock()
+	tm.AwardReward(teamID, tokensEarned, "challenge_solved")
 }
 
-// RecordChallengeFailed records a failed challenge attempt
-func (tm *TeamManager) RecordChallengeFailed(teamID string) {
+// RecordChallengeFailed records a failed challenge attempt, awarding any
+// partial-credit tokens the attempt still earned
+func (tm *TeamManager) RecordChallengeFailed(teamID string, partialTokens int) {
+	tm.mu.Lock()
 	if progress, ok := tm.Progress[teamID]; ok {
 		progress.ChallengesFailed++
 		progress.CurrentStreak = 0
 	}
+	tm.mu.Unlock()
+	if partialTokens > 0 {
+		tm.AwardReward(teamID, partialTokens, "partial_credit")
+	}
 }
 
 // ClaimZone marks a zone as controlled by a team
 func (tm *TeamManager) ClaimZone(teamID, zoneID string) {
-	if team, ok := tm.Teams[teamID]; ok {
-		// Check if already claimed
-		for _, z := range team.Zones {
-			if z == zoneID {
-				return
-			}
-		}
-		team.Zones = append(team.Zones, zoneID)
-		if progress, ok := tm.Progress[teamID]; ok {
-			progress.ZonesUnlocked = append(progress.ZonesUnlocked, zoneID)
+	tm.mu.Lock()
+	team, ok := tm.Teams[teamID]
+	// Check if already claimed
+	if !ok || slices.Contains(team.Zones, zoneID) {
+		tm.mu.Unlock()
+		return
+	}
+	team.Zones = append(team.Zones, zoneID)
+	if progress, ok := tm.Progress[teamID]; ok {
+		progress.ZonesUnlocked = append(progress.ZonesUnlocked, zoneID)
+	}
+	tm.mu.Unlock()
+	tm.changed(teamID)
+}
+
+// Forfeit marks the team as having conceded, reporting whether it exists
+func (tm *TeamManager) Forfeit(teamID string) bool {
+	tm.mu.Lock()
+	progress, ok := tm.Progress[teamID]
+	if ok {
+		progress.Forfeited = true
+	}
+	tm.mu.Unlock()
+	return ok
+}
+
+// SetStrategy changes a team's strategy. An empty strategy means balanced.
+func (tm *TeamManager) SetStrategy(teamID, strategy string) error {
+	if strategy == "" {
+		strategy = StrategyBalanced
+	}
+	if _, ok := StrategyDirectives[strategy]; !ok {
+		return fmt.Errorf("unknown strategy %q (want aggressive, objective or balanced)", strategy)
+	}
+	tm.mu.Lock()
+	team, ok := tm.Teams[teamID]
+	if ok {
+		team.Strategy = strategy
+	}
+	tm.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown team %q", teamID)
+	}
+	tm.changed(teamID)
+	return nil
+}
+
+// Leader returns the ID of the team with the strictly highest score, or "" on a tie
+func (tm *TeamManager) Leader() string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	leader, best, tied := "", -1, false
+	for id, team := range tm.Teams {
+		switch {
+		case team.Score > best:
+			leader, best, tied = id, team.Score, false
+		case team.Score == best:
+			tied = true
 		}
 	}
+	if tied {
+		return ""
+	}
+	return leader
 }
 
-// GetLeaderboard returns teams sorted by score
+// GetLeaderboard returns copies of the teams sorted by score
 func (tm *TeamManager) GetLeaderboard() []*Team {
+	tm.mu.RLock()
 	teams := make([]*Team, 0, len(tm.Teams))
 	for _, team := range tm.Teams {
-		teams = append(teams, team)
+		teams = append(teams, team.clone())
 	}
+	tm.mu.RUnlock()
 	// Sort by score (descending)
 	for i := 0; i < len(teams)-1; i++ {
 		for j := i + 1; j < len(teams); j++ {
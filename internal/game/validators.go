@@ -0,0 +1,198 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// DecisionValidator checks or rewrites one aspect of a parsed decision before
+// it is applied. Validate may modify decision in place and returns a short
+// note describing what it changed, or "" if the decision was fine.
+type DecisionValidator interface {
+	Name() string
+	Validate(w *World, npc *NPC, decision map[string]interface{}) string
+}
+
+// DefaultValidators is the chain used when config doesn't name one
+var DefaultValidators = []string{"known_action", "self_target", "unlocked_gate", "taunt_cooldown", "world_bounds", "locked_zone"}
+
+// newValidator builds a validator by config name
+func newValidator(name string, tauntCooldown int) (DecisionValidator, bool) {
+	switch name {
+	case "known_action":
+		return knownActionValidator{}, true
+	case "self_target":
+		return selfTargetValidator{}, true
+	case "unlocked_gate":
+		return unlockedGateValidator{}, true
+	case "taunt_cooldown":
+		return tauntCooldownValidator{ticks: tauntCooldown}, true
+	case "world_bounds":
+		return worldBoundsValidator{}, true
+	case "locked_zone":
+		return lockedZoneValidator{}, true
+	}
+	return nil, false
+}
+
+// buildValidators turns config names into a chain, skipping unknown names
+func buildValidators(names []string, tauntCooldown int) []DecisionValidator {
+	if len(names) == 0 {
+		names = DefaultValidators
+	}
+	if tauntCooldown <= 0 {
+		tauntCooldown = 10
+	}
+
+	chain := make([]DecisionValidator, 0, len(names))
+	for _, name := range names {
+		v, ok := newValidator(name, tauntCooldown)
+		if !ok {
+			log.Printf("⚠️ Unknown decision validator %q, skipping", name)
+			continue
+		}
+		chain = append(chain, v)
+	}
+	return chain
+}
+
+// knownActionValidator normalizes action aliases (e.g. "idle") to their
+// registered name and replaces unknown actions with wait
+type knownActionValidator struct{}
+
+func (knownActionValidator) Name() string { return "known_action" }
+
+func (knownActionValidator) Validate(w *World, npc *NPC, decision map[string]interface{}) string {
+	action, _ := decision["action"].(string)
+	spec, ok := LookupAction(action)
+	if !ok {
+		decision["action"] = "wait"
+		return fmt.Sprintf("unknown action %q, waiting instead (valid: %s)", action, strings.Join(ActionNames(), ", "))
+	}
+	if spec.Name != action {
+		decision["action"] = spec.Name // Alias, not worth feedback
+	}
+	return ""
+}
+
+// selfTargetValidator points talk/taunt actions aimed at the speaker (or at
+// nobody) to the nearest NPC on another team
+type selfTargetValidator struct{}
+
+func (selfTargetValidator) Name() string { return "self_target" }
+
+func (selfTargetValidator) Validate(w *World, npc *NPC, decision map[string]interface{}) string {
+	action, _ := decision["action"].(string)
+	if action != "talk" && action != "taunt" {
+		return ""
+	}
+	target, _ := decision["target"].(string)
+	if target != "" && target != npc.Name {
+		return ""
+	}
+
+	var nearest *NPC
+	bestDist := math.MaxFloat64
+	for _, other := range w.NPCs {
+		if other == npc || other.Team == npc.Team {
+			continue
+		}
+		if dist := math.Hypot(other.Pos[0]-npc.Pos[0], other.Pos[1]-npc.Pos[1]); dist < bestDist {
+			nearest, bestDist = other, dist
+		}
+	}
+	if nearest == nil {
+		return ""
+	}
+	decision["target"] = nearest.Name
+	return fmt.Sprintf("%s target corrected to %s", action, nearest.Name)
+}
+
+// unlockedGateValidator turns challenges of already-open gates into waits
+type unlockedGateValidator struct{}
+
+func (unlockedGateValidator) Name() string { return "unlocked_gate" }
+
+func (unlockedGateValidator) Validate(w *World, npc *NPC, decision map[string]interface{}) string {
+	if action, _ := decision["action"].(string); action != "challenge" {
+		return ""
+	}
+	gateID, _ := decision["target"].(string)
+	gate := w.Zones.GetGate(gateID)
+	if gate == nil || !gate.Unlocked {
+		return ""
+	}
+	decision["action"] = "wait"
+	decision["target"] = nil
+	return fmt.Sprintf("%s is already open - no challenge needed, move through it", gateID)
+}
+
+// tauntCooldownValidator limits each NPC to one taunt per cooldown period
+type tauntCooldownValidator struct {
+	ticks int
+}
+
+func (tauntCooldownValidator) Name() string { return "taunt_cooldown" }
+
+func (v tauntCooldownValidator) Validate(w *World, npc *NPC, decision map[string]interface{}) string {
+	if action, _ := decision["action"].(string); action != "taunt" {
+		return ""
+	}
+	if npc.tauntedAt > 0 && w.Tick-npc.tauntedAt < v.ticks {
+		decision["action"] = "wait"
+		delete(decision, "message")
+		return fmt.Sprintf("taunt on cooldown for %d more ticks", v.ticks-(w.Tick-npc.tauntedAt))
+	}
+	npc.tauntedAt = max(w.Tick, 1) // 0 means never
+	return ""
+}
+
+// worldBoundsValidator pulls move targets back inside the world
+type worldBoundsValidator struct{}
+
+func (worldBoundsValidator) Name() string { return "world_bounds" }
+
+func (worldBoundsValidator) Validate(w *World, npc *NPC, decision map[string]interface{}) string {
+	if action, _ := decision["action"].(string); action != "move" && action != "explore" {
+		return ""
+	}
+	target, ok := parseTarget(decision["target"])
+	if !ok {
+		return ""
+	}
+
+	clamped := [2]float64{
+		math.Max(0, math.Min(float64(w.Width), target[0])),
+		math.Max(0, math.Min(float64(w.Height), target[1])),
+	}
+	if clamped == target {
+		return ""
+	}
+	decision["target"] = []float64{clamped[0], clamped[1]}
+	return fmt.Sprintf("target (%.0f, %.0f) is off the map - moved to (%.0f, %.0f)", target[0], target[1], clamped[0], clamped[1])
+}
+
+// lockedZoneValidator clamps move targets inside zones the NPC's team can't enter
+type lockedZoneValidator struct{}
+
+func (lockedZoneValidator) Name() string { return "locked_zone" }
+
+func (lockedZoneValidator) Validate(w *World, npc *NPC, decision map[string]interface{}) string {
+	if action, _ := decision["action"].(string); action != "move" && action != "explore" {
+		return ""
+	}
+	target, ok := parseTarget(decision["target"])
+	if !ok {
+		return ""
+	}
+
+	var result DecisionResult
+	w.validateMoveTarget(npc, target, &result)
+	if !result.Clamped {
+		return ""
+	}
+	decision["target"] = []float64{result.Target[0], result.Target[1]}
+	return result.Feedback
+}
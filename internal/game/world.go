@@ -2,6 +2,13 @@ package game
 
 import (
 	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/amit/npc/internal/challenge"
 	"github.com/amit/npc/internal/config"
@@ -15,10 +22,60 @@ type World struct {
 	Objects []*WorldObject `json:"objects"`
 	Tick    int            `json:"tick"`
 
+	ObjectTypes map[string]ObjectType `json:"object_types"` // Behavior registry for Objects
+
 	// New v2 systems
 	Teams      *TeamManager                `json:"teams"`
 	Zones      *ZoneManager                `json:"zones"`
 	Challenges *challenge.ChallengeManager `json:"challenges"`
+	Actions    *ActionStats                `json:"-"`
+
+	// Match outcome (set when a team concedes)
+	MatchOver bool       `json:"match_over"`
+	Winner    string     `json:"winner,omitempty"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"` // Nil until the match ends
+	summary   string     // Post-match recap (see SetMatchSummary)
+
+	// Safe mode: start zone only, no challenges or zone generation
+	SafeMode bool `json:"safe_mode"`
+
+	// Practice mode: free hints, refunded failures, non-competitive results
+	PracticeMode bool `json:"practice_mode"`
+
+	memoryStrict bool // Memory codes appear in one prompt only (see applyMemoryPolicy)
+	looseBounds  bool // Skip clamping off-map observation positions (see enforceBounds)
+
+	idleBehaviors []string // Tried in order for NPCs with nothing to do (see IdleBehavior)
+
+	historyDepth  int          // Ticks of position history per NPC; 0 = off (see annotateHistory)
+	historyStride int          // Ticks between the positions a trail shows (one per baseTickRate tick)
+	tickRate      int          // Ticks per second (game.tick_rate)
+	decisionReuse int          // Reuses in a row before asking again; negative disables (see ReuseDecision)
+	decisionSkips atomic.Int64 // LLM calls saved by ReuseDecision
+
+	serverDriven bool    // Advance moves NPCs toward their targets (see moveNPCs)
+	moveSpeed    float64 // Distance a server-driven NPC covers per tick
+
+	contestRadius float64
+	zoneIncome    config.ZoneIncomeConfig
+	respawn       config.RespawnConfig
+	validators    []DecisionValidator              // Applied to every decision by ApplyDecision
+	llmStats      func() (map[string]int, float64) // Calls per provider and total cost (set by main)
+	onDecision    func(tick int, npcName string, decision map[string]interface{})
+
+	// Guards NPC state, objects and the match outcome. Exported methods
+	// take it; their unexported counterparts expect it held. Lock order is
+	// mu, then the Teams or Zones lock.
+	mu sync.RWMutex
+
+	// Newest pending decision per NPC, applied on the next Advance
+	queue   map[string]map[string]interface{}
+	queueMu sync.Mutex
+
+	// Change tracking for delta broadcasts: "kind:id" -> tick of last change
+	changes   map[string]int
+	changesMu sync.Mutex
 }
 
 // NPC represents a non-player character
@@ -34,8 +91,35 @@ type NPC struct {
 	// New v2 fields
 	Team        string    `json:"team"`         // Team ID
 	CurrentZone string    `json:"current_zone"` // Zone ID
-	MemoryCode  string    `json:"memory_code"`  // For memory challenges
-	Messages    []Message `json:"messages"`     // Recent messages from teammate
+	MemoryCode  string    `json:"-"`            // For memory challenges; never sent to clients
+	Messages    []Message `json:"-"`            // Recent messages, the last 5 (may hold the memory code)
+
+	Target       *[2]float64 `json:"target,omitempty"`        // Current move target (validated)
+	LastFeedback string      `json:"last_feedback,omitempty"` // Why the last decision was adjusted
+	Goal         string      `json:"goal,omitempty"`          // Standing intent, e.g. "reach gate_2_4" (see SetGoal)
+
+	HumanControlled bool `json:"human_controlled,omitempty"` // Decisions come from a player, not the LLM
+
+	tauntedAt  int // Tick of the last allowed taunt (0 = never)
+	patrolStop int // Idle patrol: index of the current stop
+
+	observationHash string                 // HashObservation of the latest synced observation
+	decisionHash    string                 // observationHash when lastDecision was made
+	lastDecision    map[string]interface{} // Latest LLM decision (see ReuseDecision)
+	reuses          int                    // Times lastDecision has been reused in a row
+	history         *positionRing          // Recent positions, one per tick (see recordPositions)
+}
+
+// clone copies the NPC's exported state for encoding outside the world lock
+func (npc *NPC) clone() *NPC {
+	c := *npc
+	c.Inventory = slices.Clone(npc.Inventory)
+	c.Messages = slices.Clone(npc.Messages)
+	if npc.Target != nil {
+		target := *npc.Target
+		c.Target = &target
+	}
+	return &c
 }
 
 // Message represents a chat message between NPCs
@@ -62,8 +146,110 @@ func NewWorld(cfg *config.Config) *World {
 		Teams:      NewTeamManager(),
 		Zones:      NewZoneManager(cfg.Game.WorldWidth, cfg.Game.WorldHeight),
 		Challenges: challenge.NewChallengeManager(),
+		Actions:    NewActionStats(),
+		SafeMode:   cfg.Game.SafeMode,
+
+		PracticeMode: cfg.Game.PracticeMode,
+
+		memoryStrict: cfg.Game.MemoryStrict,
+		looseBounds:  cfg.Game.LooseBounds,
+		StartedAt:    time.Now(),
+
+		ObjectTypes: buildObjectTypes(cfg.Game.ObjectTypes),
+
+		contestRadius: cfg.Game.ContestRadius,
+		zoneIncome:    cfg.Game.ZoneIncome,
+		respawn:       respawnDefaults(cfg.Game.Respawn),
+		idleBehaviors: buildIdleBehaviors(cfg.Game.IdleBehaviors),
+		historyDepth:  cfg.Game.ObservationHistory,
+		decisionReuse: cfg.Game.DecisionReuse,
+		serverDriven:  cfg.Game.ServerDriven,
+		moveSpeed:     cfg.Game.MoveSpeed,
+		changes:       make(map[string]int),
+	}
+	world.Zones.onChange = world.markChanged
+	world.Challenges.SetShuffleOptions(cfg.Game.ShuffleChallengeOptions)
+	world.Challenges.SetPracticeMode(world.PracticeMode)
+	if cfg.Game.Seed != 0 {
+		world.Challenges.SetSeed(cfg.Game.Seed)
+	}
+	world.Zones.gatesFrozen = world.SafeMode
+	world.Teams.onChange = func(teamID string) { world.markChanged("team", teamID) }
+	if world.contestRadius <= 0 {
+		world.contestRadius = 150
+	}
+	if world.decisionReuse == 0 {
+		world.decisionReuse = defaultDecisionReuse
+	}
+	if world.moveSpeed <= 0 {
+		world.moveSpeed = defaultMoveSpeed
+	}
+	if world.zoneIncome.Divisor <= 0 {
+		world.zoneIncome.Divisor = 10
+	}
+	if world.zoneIncome.IntervalTicks <= 0 {
+		world.zoneIncome.IntervalTicks = 20
+	}
+	tauntCooldown := cfg.Game.TauntCooldownTicks
+	if tauntCooldown <= 0 {
+		tauntCooldown = 10
 	}
 
+	// Tick-based settings are written for baseTickRate; rescale them so they
+	// keep their real-time meaning at game.tick_rate
+	tickRate := cfg.Game.TickRate
+	if tickRate <= 0 {
+		tickRate = 60
+	}
+	world.zoneIncome.IntervalTicks = scaleTicks(world.zoneIncome.IntervalTicks, tickRate)
+	world.respawn.BaseTicks = scaleTicks(world.respawn.BaseTicks, tickRate)
+	world.respawn.MinTicks = scaleTicks(world.respawn.MinTicks, tickRate)
+	world.respawn.MaxTicks = scaleTicks(world.respawn.MaxTicks, tickRate)
+	if world.historyDepth > 0 {
+		world.historyDepth = scaleTicks(world.historyDepth, tickRate)
+	}
+	world.historyStride = max(1, tickRate/baseTickRate)
+	world.tickRate = tickRate
+	world.moveSpeed = world.moveSpeed * baseTickRate / float64(tickRate)
+	world.validators = buildValidators(cfg.Game.DecisionValidators, scaleTicks(tauntCooldown, tickRate))
+
+	for teamID, teamCfg := range map[string]config.TeamConfig{"red": cfg.Teams.Red, "blue": cfg.Teams.Blue} {
+		if err := world.Teams.SetStrategy(teamID, teamCfg.Strategy); err != nil {
+			log.Printf("⚠️ Team %s: %v, using balanced", teamID, err)
+		}
+	}
+
+	contestBonus := cfg.Game.ContestBonus
+	if contestBonus <= 0 {
+		contestBonus = 1.0 // No bonus
+	}
+	world.Challenges.SetContestCheck(world.IsGateContested, contestBonus)
+	world.Challenges.SetGateResolver(func(gateID string) string {
+		if gate := world.Zones.GetGate(gateID); gate != nil {
+			return gate.ChallengeID
+		}
+		return ""
+	})
+	world.Challenges.SetPoolResolver(func(gateID string) []string {
+		if gate := world.Zones.GetGate(gateID); gate != nil {
+			return gate.Pool()
+		}
+		return nil
+	})
+	// Drawing from the pool points the gate at a pool member; anything else
+	// (a regenerated challenge) takes the current one's place in the pool
+	world.Challenges.SetGateAssigner(func(gateID, challengeID string) {
+		world.Zones.UpdateGate(gateID, func(gate *Gate) {
+			if slices.Index(gate.ChallengePool, challengeID) < 0 {
+				if i := slices.Index(gate.ChallengePool, gate.ChallengeID); i >= 0 {
+					gate.ChallengePool[i] = challengeID
+				}
+			}
+			gate.ChallengeID = challengeID
+		})
+	})
+	world.applyChallengePools(cfg.Game.ChallengePools)
+
 	// Create NPCs in team positions
 	// Team Red (Explorer, Scout) starts top-left
 	// Team Blue (Wanderer, Seeker) starts bottom-right
@@ -108,6 +294,10 @@ func NewWorld(cfg *config.Config) *World {
 		}
 	}
 
+	if world.memoryStrict {
+		world.revealMemoryCodes()
+	}
+
 	// Create world objects (treasures, landmarks)
 	objectTypes := []string{"treasure", "landmark", "resource", "mystery"}
 	for i := 0; i < 12; i++ {
@@ -123,6 +313,34 @@ func NewWorld(cfg *config.Config) *World {
 	return world
 }
 
+// applyChallengePools gives the configured gates challenge pools, keeping
+// only challenges that exist. A gate whose current challenge isn't in its
+// pool starts on the pool's first.
+func (w *World) applyChallengePools(pools map[string][]string) {
+	for gateID, ids := range pools {
+		gate, ok := w.Zones.Gates[gateID]
+		if !ok {
+			log.Printf("⚠️ Challenge pool for unknown gate %s, skipping", gateID)
+			continue
+		}
+		var pool []string
+		for _, id := range ids {
+			if w.Challenges.GetChallenge(id) == nil {
+				log.Printf("⚠️ Unknown challenge %q in %s's pool, skipping", id, gateID)
+				continue
+			}
+			pool = append(pool, id)
+		}
+		if len(pool) == 0 {
+			continue
+		}
+		gate.ChallengePool = pool
+		if slices.Index(pool, gate.ChallengeID) < 0 {
+			gate.ChallengeID = pool[0]
+		}
+	}
+}
+
 // GetNPCByName returns an NPC by name
 func (w *World) GetNPCByName(name string) *NPC {
 	for _, npc := range w.NPCs {
@@ -143,16 +361,25 @@ func (w *World) GetNPCByID(id string) *NPC {
 	return nil
 }
 
-// UpdateNPCZone updates which zone an NPC is in based on position
+// UpdateNPCZone updates which zone an NPC is in based on position. An NPC
+// outside every zone (e.g. off the edge of the map) is assigned the nearest
+// one; with no zones at all it has none.
 func (w *World) UpdateNPCZone(npc *NPC) {
-	zone := w.Zones.GetZoneAt(npc.Pos[0], npc.Pos[1])
-	if zone != nil {
+	if zone := w.Zones.NearestZone(npc.Pos[0], npc.Pos[1]); zone != nil {
 		npc.CurrentZone = zone.ID
+	} else {
+		npc.CurrentZone = ""
 	}
 }
 
 // SendMessage sends a message from one NPC to another (teammate)
 func (w *World) SendMessage(fromNPC, toNPC, content string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sendMessage(fromNPC, toNPC, content)
+}
+
+func (w *World) sendMessage(fromNPC, toNPC, content string) {
 	to := w.GetNPCByName(toNPC)
 	if to == nil {
 		return
@@ -171,28 +398,334 @@ func (w *World) SendMessage(fromNPC, toNPC, content string) {
 	}
 }
 
+// SyncFromObservation updates an NPC's position and vitals from a client observation
+func (w *World) SyncFromObservation(obs map[string]interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	name, _ := obs["name"].(string)
+	npc := w.GetNPCByName(name)
+	if npc == nil {
+		return
+	}
+
+	if pos, ok := obs["pos"].([]interface{}); ok && len(pos) >= 2 {
+		x, okX := pos[0].(float64)
+		y, okY := pos[1].(float64)
+		if okX && okY {
+			npc.Pos = [2]float64{x, y}
+			w.UpdateNPCZone(npc)
+		}
+	}
+	if energy, ok := obs["energy"].(float64); ok {
+		npc.Energy = int(energy)
+	}
+	if state, ok := obs["state"].(string); ok {
+		npc.State = state
+	}
+	npc.observationHash = HashObservation(obs)
+	w.markChanged("npc", npc.ID)
+}
+
+// RecordAction counts a decision's action in the match histogram
+func (w *World) RecordAction(npcName string, decision map[string]interface{}) {
+	action, _ := decision["action"].(string)
+	teamID := ""
+	if npc := w.GetNPCByName(npcName); npc != nil {
+		teamID = npc.Team
+	}
+	w.Actions.Record(npcName, teamID, action)
+}
+
+// AnnotateObservation adds server-side knowledge to a client observation:
+// the NPC's zone (clamping an off-map position back into the world), feedback
+// on its last decision, its current goal, its team's strategy directive, the world objects it can see (nearby_objects), and a "challenge" preview
+// (type/difficulty/reward/teamwork) on each nearby_gates entry. With
+// observation_history on, nearby_npcs entries also get recent positions.
+func (w *World) AnnotateObservation(obs map[string]interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	name, _ := obs["name"].(string)
+	if npc := w.GetNPCByName(name); npc != nil {
+		w.applyMemoryPolicy(npc, obs)
+		if !w.looseBounds {
+			w.enforceBounds(npc, obs)
+		}
+		if npc.LastFeedback != "" {
+			obs["last_feedback"] = npc.LastFeedback
+		}
+		if npc.Goal != "" {
+			obs["goal"] = npc.Goal
+		}
+		if len(npc.Messages) > 0 {
+			obs["messages"] = recentMessages(npc)
+		}
+		if team := w.Teams.GetTeam(npc.Team); team != nil {
+			obs["team_strategy"] = StrategyDirectives[team.Strategy]
+		}
+		if objects := w.nearbyObjects(npc.Name, npc.Pos, 200); len(objects) > 0 {
+			obs["nearby_objects"] = objects
+		}
+		w.annotateHistory(npc, obs)
+	}
+
+	gates, ok := obs["nearby_gates"].([]interface{})
+	if !ok {
+		return
+	}
+	for _, g := range gates {
+		gate, ok := g.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		gateID, _ := gate["id"].(string)
+		if preview := w.Challenges.PreviewChallenge(gateID); preview != nil {
+			gate["challenge"] = preview
+		}
+		if active := w.Challenges.GetActiveChallenge(gateID); active != nil {
+			gate["seconds_remaining"] = active.SecondsRemaining()
+		}
+	}
+}
+
+// enforceBounds clamps an observation's reported position to the world and
+// flags it out_of_bounds when it had drifted off the map, moving the NPC back
+// with it. The NPC's zone (nearest, if it sits between zones) is reported as
+// current_zone either way.
+func (w *World) enforceBounds(npc *NPC, obs map[string]interface{}) {
+	if pos, ok := obs["pos"].([]interface{}); ok && len(pos) >= 2 && w.Width > 0 && w.Height > 0 {
+		x, okX := pos[0].(float64)
+		y, okY := pos[1].(float64)
+		if okX && okY {
+			clamped := [2]float64{
+				math.Max(0, math.Min(float64(w.Width), x)),
+				math.Max(0, math.Min(float64(w.Height), y)),
+			}
+			if clamped != [2]float64{x, y} {
+				obs["pos"] = []interface{}{clamped[0], clamped[1]}
+				obs["out_of_bounds"] = true
+				npc.Pos = clamped
+				w.UpdateNPCZone(npc)
+				w.markChanged("npc", npc.ID)
+			}
+		}
+	}
+	if npc.CurrentZone == "" {
+		w.UpdateNPCZone(npc)
+	}
+	if npc.CurrentZone != "" {
+		obs["current_zone"] = npc.CurrentZone
+	}
+}
+
+// IsGateContested reports whether an NPC from a team other than teamID is near the gate
+func (w *World) IsGateContested(gateID, teamID string) bool {
+	gate := w.Zones.GetGate(gateID)
+	if gate == nil {
+		return false
+	}
+
+	for _, npc := range w.NPCs {
+		if npc.Team == teamID {
+			continue
+		}
+		dx := npc.Pos[0] - gate.Position[0]
+		dy := npc.Pos[1] - gate.Position[1]
+		if dx*dx+dy*dy <= w.contestRadius*w.contestRadius {
+			return true
+		}
+	}
+	return false
+}
+
+// Concede ends the match with teamID forfeiting to its opponent. Any challenge
+// the conceding team was attempting is abandoned and its gate released.
+// Returns the match_over message to broadcast.
+func (w *World) Concede(teamID string) (map[string]interface{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.Teams.GetTeam(teamID) == nil {
+		return nil, fmt.Errorf("unknown team %q", teamID)
+	}
+	if w.MatchOver {
+		return nil, fmt.Errorf("match already over")
+	}
+
+	for _, gateID := range w.Challenges.ReleaseTeam(teamID) {
+		w.markChanged("gate", gateID)
+	}
+
+	w.Teams.Forfeit(teamID)
+	w.MatchOver = true
+	ended := time.Now()
+	w.EndedAt = &ended
+	if opponent := w.Teams.GetOpponentTeam(teamID); opponent != nil {
+		w.Winner = opponent.ID
+	}
+	w.markChanged("team", teamID)
+
+	return map[string]interface{}{
+		"type":         "match_over",
+		"reason":       "forfeit",
+		"forfeited_by": teamID,
+		"winner":       w.Winner,
+		"scores":       w.GetTeamScores(),
+	}, nil
+}
+
 // GetNearbyGatesForNPC returns gates near the NPC
 func (w *World) GetNearbyGatesForNPC(npc *NPC, range_ float64) []*Gate {
 	return w.Zones.GetNearbyGates(npc.Pos[0], npc.Pos[1], range_)
 }
 
-// GetGameState returns the current game state for broadcasting
+// GetGameState returns a copy of the current game state for broadcasting
 func (w *World) GetGameState() map[string]interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	npcs := make([]*NPC, len(w.NPCs))
+	for i, npc := range w.NPCs {
+		npcs[i] = npc.clone()
+	}
+	zones, gates := w.Zones.Snapshot()
 	return map[string]interface{}{
 		"tick":              w.Tick,
 		"teams":             w.Teams.GetLeaderboard(),
-		"zones":             w.Zones.Zones,
-		"gates":             w.Zones.Gates,
-		"npcs":              w.NPCs,
-		"active_challenges": w.Challenges.ActiveChallenges,
+		"zones":             zones,
+		"gates":             gates,
+		"npcs":              npcs,
+		"active_challenges": w.Challenges.ActiveSnapshot(),
+		"match_over":        w.MatchOver,
+		"winner":            w.Winner,
+		"respawn_delays":    w.RespawnDelays(),
 	}
 }
 
+// Advance moves the world clock forward by one tick, applies queued
+// decisions, clears finished goals and pays zone income when due
+func (w *World) Advance() int {
+	w.mu.Lock()
+	w.changesMu.Lock()
+	w.Tick++
+	tick := w.Tick
+	w.changesMu.Unlock()
+
+	w.applyQueuedDecisions()
+	if w.serverDriven {
+		w.moveNPCs()
+	}
+	w.refreshGoals()
+	w.recordPositions()
+	w.mu.Unlock()
+
+	if w.zoneIncome.Enabled && tick%w.zoneIncome.IntervalTicks == 0 {
+		w.PayZoneIncome()
+	}
+	for _, gateID := range w.Challenges.SweepExpired(time.Now()) {
+		log.Printf("⌛ Challenge at %s expired", gateID)
+	}
+	return tick
+}
+
+// PayZoneIncome awards each team Zone.Rewards / divisor tokens for every zone
+// it controls
+func (w *World) PayZoneIncome() {
+	w.mu.RLock()
+	over := w.MatchOver
+	w.mu.RUnlock()
+	if over {
+		return
+	}
+	for _, team := range w.Teams.GetLeaderboard() {
+		income := 0
+		for _, zoneID := range team.Zones {
+			if zone := w.Zones.GetZone(zoneID); zone != nil {
+				income += zone.Rewards / w.zoneIncome.Divisor
+			}
+		}
+		if income > 0 {
+			w.Teams.AwardReward(team.ID, income, "zone_income")
+		}
+	}
+}
+
+// markChanged records that an entity changed at the current tick
+func (w *World) markChanged(kind, id string) {
+	w.changesMu.Lock()
+	w.changes[kind+":"+id] = w.Tick
+	w.changesMu.Unlock()
+}
+
+// Delta returns the entities that changed after sinceTick, keyed by ID.
+// Kinds with no changes are omitted, so an unchanged world yields only tick/since.
+func (w *World) Delta(sinceTick int) map[string]interface{} {
+	w.changesMu.Lock()
+	changed := make(map[string][]string)
+	for key, tick := range w.changes {
+		if tick <= sinceTick {
+			continue
+		}
+		if kind, id, ok := strings.Cut(key, ":"); ok {
+			changed[kind] = append(changed[kind], id)
+		}
+	}
+	currentTick := w.Tick
+	w.changesMu.Unlock()
+
+	delta := map[string]interface{}{
+		"tick":  currentTick,
+		"since": sinceTick,
+	}
+
+	if ids := changed["npc"]; len(ids) > 0 {
+		npcs := make(map[string]*NPC, len(ids))
+		w.mu.RLock()
+		for _, id := range ids {
+			if npc := w.GetNPCByID(id); npc != nil {
+				npcs[id] = npc.clone()
+			}
+		}
+		w.mu.RUnlock()
+		delta["npcs"] = npcs
+	}
+	if ids := changed["gate"]; len(ids) > 0 {
+		gates := make(map[string]*Gate, len(ids))
+		for _, id := range ids {
+			if gate := w.Zones.GetGate(id); gate != nil {
+				gates[id] = gate
+			}
+		}
+		delta["gates"] = gates
+	}
+	if ids := changed["zone"]; len(ids) > 0 {
+		zones := make(map[string]*Zone, len(ids))
+		for _, id := range ids {
+			if zone := w.Zones.GetZone(id); zone != nil {
+				zones[id] = zone
+			}
+		}
+		delta["zones"] = zones
+	}
+	if ids := changed["team"]; len(ids) > 0 {
+		teams := make(map[string]*Team, len(ids))
+		for _, id := range ids {
+			if team := w.Teams.GetTeam(id); team != nil {
+				teams[id] = team
+			}
+		}
+		delta["teams"] = teams
+	}
+
+	return delta
+}
+
 // GetTeamScores returns current team scores
 func (w *World) GetTeamScores() map[string]int {
 	scores := make(map[string]int)
-	for id, team := range w.Teams.Teams {
-		scores[id] = team.Score
+	for _, team := range w.Teams.GetLeaderboard() {
+		scores[team.ID] = team.Score
 	}
 	return scores
 }
@@ -1,5 +1,11 @@
 package game
 
+import (
+	"math"
+	"slices"
+	"sync"
+)
+
 // Zone represents an area in the game world
 type Zone struct {
 	ID           string    `json:"id"`
@@ -26,16 +32,46 @@ type Gate struct {
 	FromZone         string     `json:"from_zone"`
 	ToZone           string     `json:"to_zone"`
 	Position         [2]float64 `json:"position"`
-	ChallengeID      string     `json:"challenge_id"`
+	ChallengeID      string     `json:"challenge_id"`             // The current (or last drawn) challenge
+	ChallengePool    []string   `json:"challenge_pool,omitempty"` // Attempts draw from these when set
 	Unlocked         bool       `json:"unlocked"`
 	UnlockedBy       string     `json:"unlocked_by"`       // Team or NPC that solved it
 	RequiresTeamwork bool       `json:"requires_teamwork"` // Both teammates needed
 }
 
-// ZoneManager handles zone and gate operations
+// Pool returns the challenges the gate's attempts draw from: its
+// ChallengePool, or just ChallengeID when it has none
+func (g *Gate) Pool() []string {
+	if len(g.ChallengePool) > 0 {
+		return g.ChallengePool
+	}
+	return []string{g.ChallengeID}
+}
+
+// clone copies the gate, including its pool
+func (g *Gate) clone() *Gate {
+	c := *g
+	c.ChallengePool = slices.Clone(g.ChallengePool)
+	return &c
+}
+
+// ZoneManager handles zone and gate operations. Its methods are safe for
+// concurrent use; code touching Zones and Gates directly must hold mu.
 type ZoneManager struct {
 	Zones map[string]*Zone `json:"zones"`
 	Gates map[string]*Gate `json:"gates"`
+
+	mu sync.RWMutex
+
+	onChange    func(kind, id string) // Change tracking hook (set by World)
+	gatesFrozen bool                  // Safe mode: gates never unlock
+}
+
+// changed notifies the change tracking hook
+func (zm *ZoneManager) changed(kind, id string) {
+	if zm.onChange != nil {
+		zm.onChange(kind, id)
+	}
 }
 
 // NewZoneManager creates a zone manager with default layout
@@ -137,8 +173,103 @@ func NewZoneManager(worldWidth, worldHeight int) *ZoneManager {
 	return zm
 }
 
+// AddZone adds (or replaces) a zone
+func (zm *ZoneManager) AddZone(zone *Zone) {
+	zm.mu.Lock()
+	zm.Zones[zone.ID] = zone
+	zm.mu.Unlock()
+	zm.changed("zone", zone.ID)
+}
+
+// AddGate adds (or replaces) a gate
+func (zm *ZoneManager) AddGate(gate *Gate) {
+	zm.mu.Lock()
+	zm.Gates[gate.ID] = gate
+	zm.mu.Unlock()
+	zm.changed("gate", gate.ID)
+}
+
+// GetZone returns a copy of the zone with the given ID, or nil
+func (zm *ZoneManager) GetZone(zoneID string) *Zone {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
+	if zone, ok := zm.Zones[zoneID]; ok {
+		z := *zone
+		return &z
+	}
+	return nil
+}
+
+// GetGate returns a copy of the gate with the given ID, or nil
+func (zm *ZoneManager) GetGate(gateID string) *Gate {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
+	if gate, ok := zm.Gates[gateID]; ok {
+		return gate.clone()
+	}
+	return nil
+}
+
+// AllZones returns copies of every zone, in no particular order
+func (zm *ZoneManager) AllZones() []*Zone {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
+	zones := make([]*Zone, 0, len(zm.Zones))
+	for _, zone := range zm.Zones {
+		z := *zone
+		zones = append(zones, &z)
+	}
+	return zones
+}
+
+// AllGates returns copies of every gate, in no particular order
+func (zm *ZoneManager) AllGates() []*Gate {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
+	gates := make([]*Gate, 0, len(zm.Gates))
+	for _, gate := range zm.Gates {
+		gates = append(gates, gate.clone())
+	}
+	return gates
+}
+
+// Snapshot returns copies of every zone and gate keyed by ID, safe to read
+// (e.g. encode) while the world changes
+func (zm *ZoneManager) Snapshot() (map[string]*Zone, map[string]*Gate) {
+	zones := make(map[string]*Zone)
+	for _, zone := range zm.AllZones() {
+		zones[zone.ID] = zone
+	}
+	gates := make(map[string]*Gate)
+	for _, gate := range zm.AllGates() {
+		gates[gate.ID] = gate
+	}
+	return zones, gates
+}
+
+// UpdateGate calls fn with the gate under the write lock, reporting whether
+// the gate exists
+func (zm *ZoneManager) UpdateGate(gateID string, fn func(gate *Gate)) bool {
+	zm.mu.Lock()
+	gate, ok := zm.Gates[gateID]
+	if ok {
+		fn(gate)
+	}
+	zm.mu.Unlock()
+	if ok {
+		zm.changed("gate", gateID)
+	}
+	return ok
+}
+
 // GetZoneAt returns the zone at the given position
 func (zm *ZoneManager) GetZoneAt(x, y float64) *Zone {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
+	return zm.zoneAt(x, y)
+}
+
+func (zm *ZoneManager) zoneAt(x, y float64) *Zone {
 	for _, zone := range zm.Zones {
 		if zm.IsInZone(x, y, zone) {
 			return zone
@@ -147,6 +278,28 @@ func (zm *ZoneManager) GetZoneAt(x, y float64) *Zone {
 	return nil
 }
 
+// NearestZone returns the zone whose bounds are closest to a position (the
+// containing zone if there is one), or nil if there are no zones
+func (zm *ZoneManager) NearestZone(x, y float64) *Zone {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
+	if zone := zm.zoneAt(x, y); zone != nil {
+		return zone
+	}
+
+	var nearest *Zone
+	bestDist := math.MaxFloat64
+	for _, zone := range zm.Zones {
+		dx := math.Max(0, math.Max(zone.Bounds.X-x, x-(zone.Bounds.X+zone.Bounds.Width)))
+		dy := math.Max(0, math.Max(zone.Bounds.Y-y, y-(zone.Bounds.Y+zone.Bounds.Height)))
+		// Tie-break on ID so the result doesn't depend on map order
+		if dist := math.Hypot(dx, dy); nearest == nil || dist < bestDist || (dist == bestDist && zone.ID < nearest.ID) {
+			nearest, bestDist = zone, dist
+		}
+	}
+	return nearest
+}
+
 // IsInZone checks if a position is within a zone
 func (zm *ZoneManager) IsInZone(x, y float64, zone *Zone) bool {
 	return x >= zone.Bounds.X &&
@@ -157,6 +310,8 @@ func (zm *ZoneManager) IsInZone(x, y float64, zone *Zone) bool {
 
 // GetNearbyGates returns gates within range of a position
 func (zm *ZoneManager) GetNearbyGates(x, y, range_ float64) []*Gate {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
 	var nearby []*Gate
 	for _, gate := range zm.Gates {
 		dx := gate.Position[0] - x
@@ -171,8 +326,10 @@ func (zm *ZoneManager) GetNearbyGates(x, y, range_ float64) []*Gate {
 
 // UnlockGate marks a gate as unlocked and the destination zone as accessible
 func (zm *ZoneManager) UnlockGate(gateID, unlockedBy string) bool {
+	zm.mu.Lock()
 	gate, ok := zm.Gates[gateID]
-	if !ok || gate.Unlocked {
+	if !ok || gate.Unlocked || zm.gatesFrozen {
+		zm.mu.Unlock()
 		return false
 	}
 
@@ -180,15 +337,23 @@ func (zm *ZoneManager) UnlockGate(gateID, unlockedBy string) bool {
 	gate.UnlockedBy = unlockedBy
 
 	// Unlock the destination zone
-	if zone, ok := zm.Zones[gate.ToZone]; ok {
+	zone, hasZone := zm.Zones[gate.ToZone]
+	if hasZone {
 		zone.Unlocked = true
 	}
+	zm.mu.Unlock()
 
+	zm.changed("gate", gateID)
+	if hasZone {
+		zm.changed("zone", zone.ID)
+	}
 	return true
 }
 
 // CanAccessZone checks if a team can enter a zone
 func (zm *ZoneManager) CanAccessZone(zoneID, teamID string) bool {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
 	zone, ok := zm.Zones[zoneID]
 	if !ok {
 		return false
@@ -211,9 +376,13 @@ func (zm *ZoneManager) CanAccessZone(zoneID, teamID string) bool {
 
 // GetGateForChallenge finds the gate associated with a challenge
 func (zm *ZoneManager) GetGateForChallenge(challengeID string) *Gate {
+	zm.mu.RLock()
+	defer zm.mu.RUnlock()
 	for _, gate := range zm.Gates {
-		if gate.ChallengeID == challengeID {
-			return gate
+		for _, id := range gate.Pool() {
+			if id == challengeID {
+				return gate
+			}
 		}
 	}
 	return nil
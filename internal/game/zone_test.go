@@ -0,0 +1,81 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/amit/npc/internal/config"
+)
+
+func TestChallengePool_RetryDrawsAnotherChallenge(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.ChallengePools = map[string][]string{
+		"gate_1_2": {"challenge_coordination", "challenge_memory", "challenge_missing"},
+	}
+	world := NewWorld(cfg)
+	gate := world.Zones.Gates["gate_1_2"]
+	if len(gate.ChallengePool) != 2 {
+		t.Fatalf("pool = %v, want the two known challenges", gate.ChallengePool)
+	}
+	if pool := world.Zones.Gates["gate_1_3"].Pool(); len(pool) != 1 || pool[0] != "challenge_teamwork" {
+		t.Errorf("unpooled gate's pool = %v, want just its challenge", pool)
+	}
+
+	last := ""
+	for attempt := 0; attempt < 5; attempt++ {
+		active, err := world.Challenges.StartChallenge(gate.ID, gate.ChallengeID, "Explorer", "red")
+		if err != nil || active == nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+		if active.Challenge.ID == last {
+			t.Errorf("attempt %d repeated %s", attempt, last)
+		}
+		if gate.ChallengeID != active.Challenge.ID {
+			t.Errorf("gate points at %s, attempt faces %s", gate.ChallengeID, active.Challenge.ID)
+		}
+		last = active.Challenge.ID
+		world.Challenges.ForceResolve(gate.ID, false)
+	}
+}
+
+// Run with -race: the socket handlers, HTTP endpoints and tick loop all
+// touch the world at once
+func TestWorld_ConcurrentUnlocksAndReads(t *testing.T) {
+	world := NewWorld(config.Default())
+	before := world.Teams.GetTeam("red").Score
+	gateIDs := make([]string, 0, len(world.Zones.Gates))
+	for id := range world.Zones.Gates {
+		gateIDs = append(gateIDs, id)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			world.Zones.UnlockGate(gateIDs[i%len(gateIDs)], "red")
+			world.Teams.RecordChallengeSolved("red", 10)
+			world.SendMessage("Explorer", "Scout", fmt.Sprintf("message %d", i))
+			world.ApplyDecision("Wanderer", map[string]interface{}{"action": "move", "target": []interface{}{float64(100 + i), 100.0}})
+			world.Advance()
+			if _, err := json.Marshal(world.GetGameState()); err != nil {
+				t.Errorf("encoding state: %v", err)
+			}
+			if _, err := json.Marshal(world.Delta(0)); err != nil {
+				t.Errorf("encoding delta: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := world.Teams.GetTeam("red").Score - before; got != 200 {
+		t.Errorf("red gained %d points, want 200", got)
+	}
+	for _, id := range gateIDs {
+		if gate := world.Zones.GetGate(id); !gate.Unlocked {
+			t.Errorf("%s still locked", id)
+		}
+	}
+}
@@ -2,31 +2,58 @@ package llm
 
 import (
 	"sync"
+	"time"
 )
 
-// Balancer implements weighted round-robin load balancing.
-// Uses nginx-style algorithm where weight determines request distribution.
+// Balancer picks a provider for each request, skipping providers flagged
+// unhealthy. Which healthy provider it picks is up to its Strategy:
+// weighted round-robin by default, or e.g. least latency.
 type Balancer struct {
 	providers []weightedProvider
+	strategy  Strategy
 	mu        sync.Mutex
-
-	// Weighted round-robin state
-	currentWeight int
-	maxWeight     int
-	gcd           int
-	lastIndex     int
 }
 
 type weightedProvider struct {
 	provider Provider
 	weight   int
+
+	unavailable bool      // Flagged unhealthy via SetAvailable
+	failedAt    time.Time // When it was last flagged unhealthy
+}
+
+// Candidate is a provider offered to a Strategy
+type Candidate struct {
+	Provider  Provider
+	Weight    int
+	Available bool // False if flagged unhealthy; strategies must not pick it
+}
+
+// Strategy chooses among the balancer's providers. Its methods are called
+// with the balancer's lock held, so implementations need no locking of their
+// own. Pick is only called when at least one candidate is available.
+type Strategy interface {
+	// Pick returns the provider for the next request. Candidates are always
+	// passed in the same order.
+	Pick(candidates []Candidate) Provider
+	// Observe records how a call to the named provider went
+	Observe(provider string, latency time.Duration, err error)
 }
 
-// NewBalancer creates a balancer from provider configs
+// NewBalancer creates a weighted round-robin balancer from provider configs
 func NewBalancer(providers []Provider, weights map[string]int) *Balancer {
+	return NewBalancerWithStrategy(providers, weights, NewWeightedRoundRobin())
+}
+
+// NewBalancerWithStrategy creates a balancer that picks providers with the
+// given strategy (weighted round-robin if nil)
+func NewBalancerWithStrategy(providers []Provider, weights map[string]int, strategy Strategy) *Balancer {
+	if strategy == nil {
+		strategy = NewWeightedRoundRobin()
+	}
 	b := &Balancer{
 		providers: make([]weightedProvider, 0, len(providers)),
-		lastIndex: -1,
+		strategy:  strategy,
 	}
 
 	for _, p := range providers {
@@ -40,25 +67,12 @@ func NewBalancer(providers []Provider, weights map[string]int) *Balancer {
 		})
 	}
 
-	// Calculate GCD and max weight for nginx-style algorithm
-	if len(b.providers) > 0 {
-		b.maxWeight = b.providers[0].weight
-		b.gcd = b.providers[0].weight
-
-		for i := 1; i < len(b.providers); i++ {
-			w := b.providers[i].weight
-			if w > b.maxWeight {
-				b.maxWeight = w
-			}
-			b.gcd = gcd(b.gcd, w)
-		}
-	}
-
 	return b
 }
 
-// Next returns the next provider using weighted round-robin.
-// Algorithm: nginx-style smooth weighted round-robin
+// Next returns the strategy's pick among providers not flagged unhealthy. If
+// every provider is unhealthy it returns the one that failed longest ago, as
+// the most likely to have recovered.
 func (b *Balancer) Next() Provider {
 	if len(b.providers) == 0 {
 		return nil
@@ -70,21 +84,70 @@ func (b *Balancer) Next() Provider {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// Nginx-style weighted round-robin
-	for {
-		b.lastIndex = (b.lastIndex + 1) % len(b.providers)
+	if last := b.lastResortLocked(); last != nil {
+		return last
+	}
 
-		if b.lastIndex == 0 {
-			b.currentWeight -= b.gcd
-			if b.currentWeight <= 0 {
-				b.currentWeight = b.maxWeight
-			}
+	candidates := make([]Candidate, len(b.providers))
+	for i, wp := range b.providers {
+		candidates[i] = Candidate{Provider: wp.provider, Weight: wp.weight, Available: !wp.unavailable}
+	}
+	return b.strategy.Pick(candidates)
+}
+
+// Observe reports a completed call to the strategy (e.g. so least-latency
+// can update its averages)
+func (b *Balancer) Observe(name string, latency time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.strategy.Observe(name, latency, err)
+}
+
+// lastResortLocked returns the least-recently-failed provider when none are
+// available, or nil if at least one is. Caller holds b.mu.
+func (b *Balancer) lastResortLocked() Provider {
+	var oldest *weightedProvider
+	for i := range b.providers {
+		wp := &b.providers[i]
+		if !wp.unavailable {
+			return nil
+		}
+		if oldest == nil || wp.failedAt.Before(oldest.failedAt) {
+			oldest = wp
+		}
+	}
+	return oldest.provider
+}
+
+// SetAvailable flags a provider healthy or unhealthy. Unhealthy providers are
+// skipped by Next until flagged available again.
+func (b *Balancer) SetAvailable(name string, available bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.providers {
+		wp := &b.providers[i]
+		if wp.provider.Name() != name {
+			continue
 		}
+		if !available && !wp.unavailable {
+			wp.failedAt = time.Now()
+		}
+		wp.unavailable = !available
+	}
+}
+
+// Available reports whether a provider is selectable by Next
+func (b *Balancer) Available(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-		if b.providers[b.lastIndex].weight >= b.currentWeight {
-			return b.providers[b.lastIndex].provider
+	for _, wp := range b.providers {
+		if wp.provider.Name() == name {
+			return !wp.unavailable
 		}
 	}
+	return false
 }
 
 // GetAll returns all registered providers
@@ -118,3 +181,45 @@ func gcd(a, b int) int {
 	}
 	return a
 }
+
+// WeightedRoundRobin spreads requests across providers in proportion to their
+// weights. Algorithm: nginx-style smooth weighted round-robin.
+type WeightedRoundRobin struct {
+	currentWeight int
+	lastIndex     int
+}
+
+// NewWeightedRoundRobin creates the default balancing strategy
+func NewWeightedRoundRobin() *WeightedRoundRobin {
+	return &WeightedRoundRobin{lastIndex: -1}
+}
+
+// Pick returns the next available provider in weighted rotation
+func (w *WeightedRoundRobin) Pick(candidates []Candidate) Provider {
+	maxWeight, divisor := candidates[0].Weight, candidates[0].Weight
+	for _, c := range candidates[1:] {
+		if c.Weight > maxWeight {
+			maxWeight = c.Weight
+		}
+		divisor = gcd(divisor, c.Weight)
+	}
+
+	for {
+		w.lastIndex = (w.lastIndex + 1) % len(candidates)
+
+		if w.lastIndex == 0 {
+			w.currentWeight -= divisor
+			if w.currentWeight <= 0 {
+				w.currentWeight = maxWeight
+			}
+		}
+
+		c := candidates[w.lastIndex]
+		if c.Available && c.Weight >= w.currentWeight {
+			return c.Provider
+		}
+	}
+}
+
+// Observe is a no-op: round-robin ignores call outcomes
+func (w *WeightedRoundRobin) Observe(string, time.Duration, error) {}
@@ -2,7 +2,10 @@ package llm
 
 import (
 	"context"
+	"errors"
+	"math/rand"
 	"testing"
+	"time"
 )
 
 // mockProvider for testing
@@ -16,6 +19,9 @@ func (m *mockProvider) HealthCheck(ctx context.Context) error { return nil }
 func (m *mockProvider) Complete(ctx context.Context, prompt string, opts CompletionOpts) (*CompletionResult, error) {
 	return &CompletionResult{Content: "mock", Provider: m.name}, nil
 }
+func (m *mockProvider) CompleteStream(ctx context.Context, prompt string, opts CompletionOpts, onChunk func(string)) (*CompletionResult, error) {
+	return completeAsStream(ctx, m, prompt, opts, onChunk)
+}
 
 func TestBalancer_WeightedRoundRobin(t *testing.T) {
 	// Create 3 providers with weights 3, 2, 1
@@ -92,3 +98,147 @@ func TestBalancer_GetByName(t *testing.T) {
 		t.Error("expected nil for unknown provider")
 	}
 }
+
+func TestBalancer_SkipsUnhealthy(t *testing.T) {
+	providers := []Provider{
+		&mockProvider{name: "heavy"},
+		&mockProvider{name: "medium"},
+		&mockProvider{name: "light"},
+	}
+	weights := map[string]int{"heavy": 3, "medium": 2, "light": 1}
+
+	b := NewBalancer(providers, weights)
+	b.SetAvailable("heavy", false)
+
+	counts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		counts[b.Next().Name()]++
+	}
+	if counts["heavy"] != 0 {
+		t.Errorf("unhealthy provider selected %d times", counts["heavy"])
+	}
+	if counts["medium"] < 15 || counts["light"] < 5 {
+		t.Errorf("healthy providers should keep their 2:1 share, got medium=%d light=%d", counts["medium"], counts["light"])
+	}
+
+	b.SetAvailable("heavy", true)
+	counts = make(map[string]int)
+	for i := 0; i < 60; i++ {
+		counts[b.Next().Name()]++
+	}
+	if counts["heavy"] < 25 {
+		t.Errorf("recovered provider got %d of 60 calls, expected ~30", counts["heavy"])
+	}
+}
+
+func TestBalancer_AllUnhealthyReturnsLeastRecentlyFailed(t *testing.T) {
+	providers := []Provider{
+		&mockProvider{name: "groq"},
+		&mockProvider{name: "gemini"},
+		&mockProvider{name: "hf"},
+	}
+	b := NewBalancer(providers, nil)
+
+	b.SetAvailable("gemini", false)
+	time.Sleep(time.Millisecond)
+	b.SetAvailable("groq", false)
+	time.Sleep(time.Millisecond)
+	b.SetAvailable("hf", false)
+
+	for i := 0; i < 5; i++ {
+		if p := b.Next(); p == nil || p.Name() != "gemini" {
+			t.Fatalf("all unhealthy: got %v, want gemini (failed first)", p)
+		}
+	}
+
+	// Re-flagging an already unhealthy provider keeps its original failure time
+	b.SetAvailable("gemini", false)
+	if p := b.Next(); p.Name() != "gemini" {
+		t.Errorf("got %s after re-flagging, want gemini", p.Name())
+	}
+}
+
+// delayedProvider answers after a fixed delay
+type delayedProvider struct {
+	mockProvider
+	delay time.Duration
+}
+
+func (d *delayedProvider) Complete(ctx context.Context, prompt string, opts CompletionOpts) (*CompletionResult, error) {
+	time.Sleep(d.delay)
+	return &CompletionResult{Content: "mock", Provider: d.name}, nil
+}
+
+func TestBalancer_LeastLatencyConvergesToFastProvider(t *testing.T) {
+	providers := []Provider{
+		&delayedProvider{mockProvider{name: "slow"}, 10 * time.Millisecond},
+		&delayedProvider{mockProvider{name: "fast"}, 2 * time.Millisecond},
+	}
+	strategy := NewLeastLatency(0.5, 0.1)
+	strategy.rand = rand.New(rand.NewSource(1))
+
+	r := &Router{
+		balancer:     NewBalancerWithStrategy(providers, nil, strategy),
+		rateLimiter:  NewRateLimiter(100, 1000),
+		npcMapping:   make(map[string]Provider),
+		successCount: make(map[string]int),
+		errorCount:   make(map[string]int),
+		lastError:    make(map[string]string),
+	}
+
+	for i := 0; i < 40; i++ {
+		if _, err := r.Complete(context.Background(), "prompt", CompletionOpts{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts := r.GetStats()["success"].(map[string]int)
+	if counts["fast"] < 30 {
+		t.Errorf("fast provider got %d of 40 calls, want most (slow got %d)", counts["fast"], counts["slow"])
+	}
+	if counts["slow"] == 0 {
+		t.Error("slow provider was never measured or explored")
+	}
+}
+
+func TestLeastLatency_ExploresAndPenalizesErrors(t *testing.T) {
+	providers := []Provider{
+		&mockProvider{name: "groq"},
+		&mockProvider{name: "gemini"},
+	}
+	strategy := NewLeastLatency(1, -1) // Latest sample only, never explore
+	b := NewBalancerWithStrategy(providers, nil, strategy)
+
+	b.Observe("groq", 50*time.Millisecond, nil)
+	b.Observe("gemini", 200*time.Millisecond, nil)
+	if p := b.Next(); p.Name() != "groq" {
+		t.Fatalf("got %s, want lower-latency groq", p.Name())
+	}
+
+	// A fast failure counts as slow
+	b.Observe("groq", time.Millisecond, errors.New("boom"))
+	if p := b.Next(); p.Name() != "gemini" {
+		t.Errorf("got %s after groq failed, want gemini", p.Name())
+	}
+
+	// Unhealthy providers are skipped even if fastest
+	b.Observe("groq", time.Millisecond, nil)
+	b.SetAvailable("groq", false)
+	if p := b.Next(); p.Name() != "gemini" {
+		t.Errorf("got %s, want gemini while groq is unhealthy", p.Name())
+	}
+
+	// With exploration every provider keeps getting some traffic
+	explorer := NewLeastLatency(0.3, 0.5)
+	explorer.rand = rand.New(rand.NewSource(1))
+	b = NewBalancerWithStrategy(providers, nil, explorer)
+	b.Observe("groq", 10*time.Millisecond, nil)
+	b.Observe("gemini", time.Second, nil)
+	picked := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		picked[b.Next().Name()]++
+	}
+	if picked["gemini"] == 0 || picked["groq"] <= picked["gemini"] {
+		t.Errorf("exploring picks = %v, want mostly groq with some gemini", picked)
+	}
+}
@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// anthropicVersion is the Messages API version the adapter speaks
+const anthropicVersion = "2023-06-01"
+
+// ClaudeAdapter handles Anthropic's Messages API
+type ClaudeAdapter struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewClaudeAdapter creates a new Anthropic Messages API adapter
+func NewClaudeAdapter(cfg ProviderConfig) *ClaudeAdapter {
+	baseURL := strings.TrimSuffix(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	model := cfg.Model
+	if model == "" {
+		model = "claude-3-5-haiku-latest"
+	}
+	return &ClaudeAdapter{
+		name:    cfg.Name,
+		baseURL: baseURL,
+		apiKey:  cfg.APIKey,
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns the provider identifier
+func (a *ClaudeAdapter) Name() string {
+	return a.name
+}
+
+// Protocol returns ProtocolAnthropic
+func (a *ClaudeAdapter) Protocol() Protocol {
+	return ProtocolAnthropic
+}
+
+// Complete sends a completion request to the Messages API
+func (a *ClaudeAdapter) Complete(ctx context.Context, prompt string, opts CompletionOpts) (*CompletionResult, error) {
+	startTime := time.Now()
+
+	reqBody := map[string]interface{}{
+		"model": a.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"max_tokens":  opts.MaxTokens,
+		"temperature": opts.Temperature,
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result claudeResponse
+	if resp.StatusCode != http.StatusOK {
+		if json.Unmarshal(respBody, &result) == nil && result.Error.Type == "not_found_error" {
+			return nil, fmt.Errorf("[%s] %w: %s", a.name, ErrModelNotFound, a.model)
+		}
+		return nil, fmt.Errorf("[%s] HTTP %d: %s", a.name, resp.StatusCode, truncateString(string(respBody), 200))
+	}
+
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("[%s] failed to parse response: %w", a.name, err)
+	}
+
+	if result.Error.Message != "" {
+		return nil, fmt.Errorf("[%s] API error: %s", a.name, result.Error.Message)
+	}
+
+	// The answer is the response's text blocks, in order
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		if result.StopReason == "refusal" {
+			return nil, fmt.Errorf("[%s] response %w", a.name, ErrSafetyBlocked)
+		}
+		return nil, fmt.Errorf("[%s] no text content returned", a.name)
+	}
+
+	return &CompletionResult{
+		Content:   text.String(),
+		Provider:  a.name,
+		Model:     a.model,
+		Latency:   time.Since(startTime),
+		TokensIn:  result.Usage.InputTokens,
+		TokensOut: result.Usage.OutputTokens,
+	}, nil
+}
+
+// CompleteStream delivers the response as one chunk; the adapter doesn't stream
+func (a *ClaudeAdapter) CompleteStream(ctx context.Context, prompt string, opts CompletionOpts, onChunk func(chunk string)) (*CompletionResult, error) {
+	return completeAsStream(ctx, a, prompt, opts, onChunk)
+}
+
+// HealthCheck verifies the provider is working
+func (a *ClaudeAdapter) HealthCheck(ctx context.Context) error {
+	_, err := a.Complete(ctx, "Say 'ok'", CompletionOpts{MaxTokens: 5, Temperature: 0})
+	return err
+}
+
+// claudeResponse represents the Messages API response format
+type claudeResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
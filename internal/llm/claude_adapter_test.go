@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc lets a test answer an adapter's HTTP requests in process
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestClaudeAdapter_Complete(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	status, reply := http.StatusOK, `{
+		"content": [{"type": "text", "text": "{\"action\":"}, {"type": "text", "text": "\"explore\"}"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 42, "output_tokens": 7}
+	}`
+
+	adapter := NewClaudeAdapter(ProviderConfig{Name: "claude", APIKey: "sk-test", Model: "claude-test"})
+	adapter.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(reply)), Header: make(http.Header)}, nil
+	})
+
+	result, err := adapter.Complete(context.Background(), "Decide", CompletionOpts{MaxTokens: 64, Temperature: 0.2})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	if got.URL.String() != "https://api.anthropic.com/v1/messages" {
+		t.Errorf("URL = %s", got.URL)
+	}
+	for header, want := range map[string]string{
+		"x-api-key":         "sk-test",
+		"anthropic-version": anthropicVersion,
+		"Content-Type":      "application/json",
+		"Authorization":     "",
+	} {
+		if v := got.Header.Get(header); v != want {
+			t.Errorf("%s header = %q, want %q", header, v, want)
+		}
+	}
+	for _, want := range []string{`"model":"claude-test"`, `"max_tokens":64`, `"temperature":0.2`, `"content":"Decide"`} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("request body %s lacks %s", gotBody, want)
+		}
+	}
+
+	if result.Content != `{"action":"explore"}` || result.TokensIn != 42 || result.TokensOut != 7 || result.Provider != "claude" {
+		t.Errorf("result = %+v", result)
+	}
+
+	status, reply = http.StatusNotFound, `{"type": "error", "error": {"type": "not_found_error", "message": "model: claude-test"}}`
+	if _, err := adapter.Complete(context.Background(), "Decide", DefaultCompletionOpts()); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("unknown model error = %v, want ErrModelNotFound", err)
+	}
+}
@@ -7,15 +7,25 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"time"
 )
 
+// DefaultGeminiSafetySettings relaxes Gemini's filters so playful taunts aren't blocked
+var DefaultGeminiSafetySettings = map[string]string{
+	"HARM_CATEGORY_HARASSMENT":        "BLOCK_ONLY_HIGH",
+	"HARM_CATEGORY_HATE_SPEECH":       "BLOCK_ONLY_HIGH",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT": "BLOCK_ONLY_HIGH",
+	"HARM_CATEGORY_DANGEROUS_CONTENT": "BLOCK_ONLY_HIGH",
+}
+
 // GeminiAdapter handles Google Gemini API
 type GeminiAdapter struct {
-	name       string
-	apiKey     string
-	model      string
-	httpClient *http.Client
+	name           string
+	apiKey         string
+	model          string
+	safetySettings []GeminiSafetySetting
+	httpClient     *http.Client
 }
 
 // NewGeminiAdapter creates a new Gemini adapter
@@ -25,9 +35,10 @@ func NewGeminiAdapter(cfg ProviderConfig) *GeminiAdapter {
 		model = "gemini-2.0-flash"
 	}
 	return &GeminiAdapter{
-		name:   cfg.Name,
-		apiKey: cfg.APIKey,
-		model:  model,
+		name:           cfg.Name,
+		apiKey:         cfg.APIKey,
+		model:          model,
+		safetySettings: GeminiSafetySettings(cfg.SafetySettings),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -65,6 +76,11 @@ func (a *GeminiAdapter) Complete(ctx context.Context, prompt string, opts Comple
 			Temperature:     opts.Temperature,
 			MaxOutputTokens: opts.MaxTokens,
 		},
+		SafetySettings: a.safetySettings,
+	}
+	if fields := GeminiToolFields(opts.Tools); fields != nil {
+		reqBody.Tools = fields["tools"]
+		reqBody.ToolConfig = fields["toolConfig"]
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -102,16 +118,40 @@ func (a *GeminiAdapter) Complete(ctx context.Context, prompt string, opts Comple
 		return nil, fmt.Errorf("[%s] API error: %s", a.name, result.Error.Message)
 	}
 
+	if result.PromptFeedback.BlockReason != "" {
+		return nil, fmt.Errorf("[%s] prompt %w (%s)", a.name, ErrSafetyBlocked, result.PromptFeedback.BlockReason)
+	}
+	if len(result.Candidates) > 0 && result.Candidates[0].FinishReason == "SAFETY" {
+		return nil, fmt.Errorf("[%s] response %w", a.name, ErrSafetyBlocked)
+	}
+
 	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
 		return nil, fmt.Errorf("[%s] no response returned", a.name)
 	}
 
-	return &CompletionResult{
+	completion := &CompletionResult{
 		Content:  result.Candidates[0].Content.Parts[0].Text,
 		Provider: a.name,
 		Model:    a.model,
 		Latency:  time.Since(startTime),
-	}, nil
+	}
+	for _, part := range result.Candidates[0].Content.Parts {
+		if call := part.FunctionCall.ToolCall(); call != nil {
+			completion.ToolCall = call
+			break
+		}
+	}
+	return completion, nil
+}
+
+// Capabilities reports tool calling support
+func (a *GeminiAdapter) Capabilities() Capabilities {
+	return Capabilities{ToolCalling: true}
+}
+
+// CompleteStream delivers the response as one chunk; the adapter doesn't stream
+func (a *GeminiAdapter) CompleteStream(ctx context.Context, prompt string, opts CompletionOpts, onChunk func(chunk string)) (*CompletionResult, error) {
+	return completeAsStream(ctx, a, prompt, opts, onChunk)
 }
 
 // HealthCheck verifies the provider is working
@@ -120,10 +160,36 @@ func (a *GeminiAdapter) HealthCheck(ctx context.Context) error {
 	return err
 }
 
+// GeminiSafetySettings converts a category->threshold map into the
+// safetySettings request field, falling back to DefaultGeminiSafetySettings
+// when none are configured. Every Gemini request is built with it, adapter or not.
+func GeminiSafetySettings(settings map[string]string) []GeminiSafetySetting {
+	if len(settings) == 0 {
+		settings = DefaultGeminiSafetySettings
+	}
+
+	result := make([]GeminiSafetySetting, 0, len(settings))
+	for category, threshold := range settings {
+		result = append(result, GeminiSafetySetting{Category: category, Threshold: threshold})
+	}
+	// Stable ordering keeps requests reproducible
+	sort.Slice(result, func(i, j int) bool { return result[i].Category < result[j].Category })
+	return result
+}
+
 // Gemini API request/response structures
 type geminiRequest struct {
 	Contents         []geminiContent        `json:"contents"`
 	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+	SafetySettings   []GeminiSafetySetting  `json:"safetySettings,omitempty"`
+	Tools            interface{}            `json:"tools,omitempty"`
+	ToolConfig       interface{}            `json:"toolConfig,omitempty"`
+}
+
+// GeminiSafetySetting is one category's block threshold in a Gemini request
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
 }
 
 type geminiContent struct {
@@ -143,10 +209,15 @@ type geminiResponse struct {
 	Candidates []struct {
 		Content struct {
 			Parts []struct {
-				Text string `json:"text"`
+				Text         string              `json:"text"`
+				FunctionCall *GeminiFunctionCall `json:"functionCall"`
 			} `json:"parts"`
 		} `json:"content"`
+		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
 	Error struct {
 		Message string `json:"message"`
 	} `json:"error"`
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGeminiSafetySettings_ConfiguredOrDefaultSorted(t *testing.T) {
+	got := GeminiSafetySettings(map[string]string{
+		"HARM_CATEGORY_HATE_SPEECH": "BLOCK_NONE",
+		"HARM_CATEGORY_HARASSMENT":  "BLOCK_LOW_AND_ABOVE",
+	})
+	want := []GeminiSafetySetting{
+		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"},
+		{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("configured = %+v, want %+v", got, want)
+	}
+
+	defaults := GeminiSafetySettings(nil)
+	if len(defaults) != len(DefaultGeminiSafetySettings) {
+		t.Fatalf("defaults = %+v, want all of DefaultGeminiSafetySettings", defaults)
+	}
+	for i, s := range defaults {
+		if s.Threshold != DefaultGeminiSafetySettings[s.Category] {
+			t.Errorf("default %s = %s", s.Category, s.Threshold)
+		}
+		if i > 0 && defaults[i-1].Category >= s.Category {
+			t.Errorf("defaults not sorted by category: %+v", defaults)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"math/rand"
+	"time"
+)
+
+// errorLatency is the latency a failed call counts as, so a provider that
+// fails fast doesn't look fast
+const errorLatency = 10 * time.Second
+
+// LeastLatency routes each request to the available provider with the lowest
+// exponential moving average latency. Providers with no measurements yet are
+// tried first, and a fraction of requests go to a random provider so one
+// that was slow (or failing) gets retried and can win back traffic once it
+// recovers.
+type LeastLatency struct {
+	alpha   float64            // EMA weight of the newest sample
+	explore float64            // Fraction of picks made at random
+	ema     map[string]float64 // Provider → average latency in ms
+	rand    *rand.Rand
+}
+
+// NewLeastLatency creates a least-latency strategy. alpha is the weight of
+// each new latency sample (default 0.3) and explore the fraction of requests
+// sent to a random provider (default 0.05; negative disables exploring).
+func NewLeastLatency(alpha, explore float64) *LeastLatency {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+	if explore == 0 {
+		explore = 0.05
+	} else if explore < 0 {
+		explore = 0
+	}
+	return &LeastLatency{
+		alpha:   alpha,
+		explore: explore,
+		ema:     make(map[string]float64),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Pick returns the fastest available provider, an unmeasured one, or now and
+// then a random one
+func (l *LeastLatency) Pick(candidates []Candidate) Provider {
+	available := make([]Provider, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Available {
+			available = append(available, c.Provider)
+		}
+	}
+
+	if l.explore > 0 && l.rand.Float64() < l.explore {
+		return available[l.rand.Intn(len(available))]
+	}
+
+	var best Provider
+	bestMs := 0.0
+	for _, p := range available {
+		ms, ok := l.ema[p.Name()]
+		if !ok {
+			return p // Measure it before comparing
+		}
+		if best == nil || ms < bestMs {
+			best, bestMs = p, ms
+		}
+	}
+	return best
+}
+
+// Observe folds a call's latency into the provider's average
+func (l *LeastLatency) Observe(provider string, latency time.Duration, err error) {
+	if err != nil && latency < errorLatency {
+		latency = errorLatency
+	}
+	ms := float64(latency) / float64(time.Millisecond)
+	if prev, ok := l.ema[provider]; ok {
+		ms = l.alpha*ms + (1-l.alpha)*prev
+	}
+	l.ema[provider] = ms
+}
@@ -1,12 +1,14 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -55,6 +57,9 @@ func (a *OpenAIAdapter) Complete(ctx context.Context, prompt string, opts Comple
 		"temperature": opts.Temperature,
 		"max_tokens":  opts.MaxTokens,
 	}
+	for k, v := range OpenAIToolFields(opts.Tools) {
+		reqBody[k] = v
+	}
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
@@ -98,14 +103,107 @@ func (a *OpenAIAdapter) Complete(ctx context.Context, prompt string, opts Comple
 		return nil, fmt.Errorf("[%s] no response choices returned", a.name)
 	}
 
-	return &CompletionResult{
+	completion := &CompletionResult{
 		Content:   result.Choices[0].Message.Content,
 		Provider:  a.name,
 		Model:     a.model,
 		Latency:   time.Since(startTime),
 		TokensIn:  result.Usage.PromptTokens,
 		TokensOut: result.Usage.CompletionTokens,
-	}, nil
+	}
+	if calls := result.Choices[0].Message.ToolCalls; len(calls) > 0 {
+		completion.ToolCall = calls[0].ToolCall()
+	}
+	return completion, nil
+}
+
+// CompleteStream sends a streaming completion request and calls onChunk
+// with each content delta from the server-sent events as it arrives.
+// Cancelling ctx aborts the request and closes the response body.
+func (a *OpenAIAdapter) CompleteStream(ctx context.Context, prompt string, opts CompletionOpts, onChunk func(chunk string)) (*CompletionResult, error) {
+	startTime := time.Now()
+
+	reqBody := map[string]interface{}{
+		"model": a.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"temperature": opts.Temperature,
+		"max_tokens":  opts.MaxTokens,
+		"stream":      true,
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := a.baseURL + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("[%s] HTTP %d: %s", a.name, resp.StatusCode, truncateString(string(respBody), 200))
+	}
+
+	var content strings.Builder
+	completion := &CompletionResult{Provider: a.name, Model: a.model}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // Blank separators, comments and other SSE fields
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var event openAIStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("[%s] failed to parse stream event: %w", a.name, err)
+		}
+		if event.Error.Message != "" {
+			return nil, fmt.Errorf("[%s] API error: %s", a.name, event.Error.Message)
+		}
+		if event.Usage != nil {
+			completion.TokensIn = event.Usage.PromptTokens
+			completion.TokensOut = event.Usage.CompletionTokens
+		}
+		if len(event.Choices) > 0 && event.Choices[0].Delta.Content != "" {
+			chunk := event.Choices[0].Delta.Content
+			content.WriteString(chunk)
+			onChunk(chunk)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("[%s] stream cancelled: %w", a.name, err)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("[%s] stream read failed: %w", a.name, err)
+	}
+
+	completion.Content = content.String()
+	completion.Latency = time.Since(startTime)
+	return completion, nil
+}
+
+// Capabilities reports tool calling support
+func (a *OpenAIAdapter) Capabilities() Capabilities {
+	return Capabilities{ToolCalling: true}
 }
 
 // HealthCheck verifies the provider is working
@@ -118,7 +216,8 @@ func (a *OpenAIAdapter) HealthCheck(ctx context.Context) error {
 type openAIResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string           `json:"content"`
+			ToolCalls []OpenAIToolCall `json:"tool_calls"`
 		} `json:"message"`
 	} `json:"choices"`
 	Usage struct {
@@ -130,6 +229,22 @@ type openAIResponse struct {
 	} `json:"error"`
 }
 
+// openAIStreamEvent is one server-sent event of a streaming response
+type openAIStreamEvent struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
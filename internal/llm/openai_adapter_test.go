@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseServer streams chunks as OpenAI-style server-sent events, then blocks
+// (until the client goes away) if hang is set, else ends the stream
+func sseServer(chunks []string, hang bool, gone chan<- struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", c)
+			w.(http.Flusher).Flush()
+		}
+		if hang {
+			<-r.Context().Done()
+			close(gone)
+			return
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3}}\n\ndata: [DONE]\n\n")
+	}))
+}
+
+func TestOpenAIAdapter_CompleteStream(t *testing.T) {
+	server := sseServer([]string{"What ", "a ", "play!"}, false, nil)
+	defer server.Close()
+	adapter := NewOpenAIAdapter(ProviderConfig{Name: "groq", BaseURL: server.URL, APIKey: "test", Model: "m"})
+
+	var chunks []string
+	result, err := adapter.CompleteStream(context.Background(), "Commentate", DefaultCompletionOpts(), func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("CompleteStream: %v", err)
+	}
+	if strings.Join(chunks, "|") != "What |a |play!" {
+		t.Errorf("chunks = %q", chunks)
+	}
+	if result.Content != "What a play!" || result.TokensIn != 12 || result.TokensOut != 3 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestOpenAIAdapter_CompleteStreamCancelled(t *testing.T) {
+	gone := make(chan struct{})
+	server := sseServer([]string{"What "}, true, gone)
+	defer server.Close()
+	adapter := NewOpenAIAdapter(ProviderConfig{Name: "groq", BaseURL: server.URL, APIKey: "test", Model: "m"})
+
+	// The client stops listening after the first chunk
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := adapter.CompleteStream(ctx, "Commentate", DefaultCompletionOpts(), func(string) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	select {
+	case <-gone:
+	case <-time.After(2 * time.Second):
+		t.Error("server still streaming after the stream was cancelled")
+	}
+}
@@ -3,9 +3,18 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrSafetyBlocked is returned when a provider refuses a completion on safety
+// grounds. Retrying the same prompt will not help.
+var ErrSafetyBlocked = errors.New("blocked by safety filter")
+
+// ErrModelNotFound is returned when a provider doesn't know the requested
+// model (a typo or a retired model). Retrying the same model will not help.
+var ErrModelNotFound = errors.New("model not found")
+
 // Protocol defines the API format for a provider
 type Protocol string
 
@@ -14,6 +23,8 @@ const (
 	ProtocolOpenAI Protocol = "openai"
 	// ProtocolGemini is for Google Gemini API
 	ProtocolGemini Protocol = "gemini"
+	// ProtocolAnthropic is for Anthropic's Messages API (Claude)
+	ProtocolAnthropic Protocol = "anthropic"
 )
 
 // Provider is the interface that all LLM adapters must implement.
@@ -25,6 +36,12 @@ type Provider interface {
 	// Complete sends a prompt to the LLM and returns the response
 	Complete(ctx context.Context, prompt string, opts CompletionOpts) (*CompletionResult, error)
 
+	// CompleteStream is Complete, calling onChunk with each piece of the
+	// response as it arrives; the result holds the whole response. Adapters
+	// that can't stream deliver it as one chunk (see completeAsStream).
+	// Cancelling ctx stops the stream.
+	CompleteStream(ctx context.Context, prompt string, opts CompletionOpts, onChunk func(chunk string)) (*CompletionResult, error)
+
 	// HealthCheck verifies the provider is working
 	HealthCheck(ctx context.Context) error
 
@@ -32,10 +49,27 @@ type Provider interface {
 	Protocol() Protocol
 }
 
+// completeAsStream is CompleteStream for adapters that can't stream: the
+// whole response is delivered as a single chunk
+func completeAsStream(ctx context.Context, p Provider, prompt string, opts CompletionOpts, onChunk func(chunk string)) (*CompletionResult, error) {
+	result, err := p.Complete(ctx, prompt, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Content != "" {
+		onChunk(result.Content)
+	}
+	return result, nil
+}
+
 // CompletionOpts contains parameters for an LLM completion request
 type CompletionOpts struct {
 	MaxTokens   int
 	Temperature float64
+
+	// Tools are offered to providers whose Capabilities include ToolCalling;
+	// other providers ignore them and answer in text
+	Tools []Tool
 }
 
 // DefaultCompletionOpts returns sensible defaults
@@ -54,6 +88,7 @@ type CompletionResult struct {
 	Latency   time.Duration // How long the request took
 	TokensIn  int           // Input tokens (if available)
 	TokensOut int           // Output tokens (if available)
+	ToolCall  *ToolCall     // Set when the model answered by calling one of opts.Tools
 }
 
 // ProviderConfig holds configuration for a single provider
@@ -65,4 +100,8 @@ type ProviderConfig struct {
 	Model    string   `yaml:"model"`
 	Weight   int      `yaml:"weight"` // For load balancing (higher = more requests)
 	Enabled  bool     `yaml:"enabled"`
+
+	// SafetySettings maps Gemini harm categories to block thresholds
+	// (e.g. HARM_CATEGORY_HARASSMENT: BLOCK_ONLY_HIGH). Empty uses relaxed defaults.
+	SafetySettings map[string]string `yaml:"safety_settings"`
 }
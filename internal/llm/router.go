@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -32,6 +33,11 @@ type RateLimiter struct {
 	refillRate float64 // tokens per second
 	lastRefill time.Time
 	mu         sync.Mutex
+
+	// Counters are atomic so Stats never contends with Wait
+	calls     atomic.Int64
+	throttled atomic.Int64
+	waitNanos atomic.Int64
 }
 
 // NewRateLimiter creates a rate limiter
@@ -48,6 +54,7 @@ func NewRateLimiter(maxTokens, refillRate float64) *RateLimiter {
 func (r *RateLimiter) Wait(tokens float64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.calls.Add(1)
 
 	now := time.Now()
 	elapsed := now.Sub(r.lastRefill).Seconds()
@@ -57,12 +64,35 @@ func (r *RateLimiter) Wait(tokens float64) {
 	if r.tokens < tokens {
 		waitTime := time.Duration((tokens - r.tokens) / r.refillRate * float64(time.Second))
 		time.Sleep(waitTime)
+		r.throttled.Add(1)
+		r.waitNanos.Add(int64(waitTime))
 		r.tokens = 0
 	} else {
 		r.tokens -= tokens
 	}
 }
 
+// RateLimiterStats shows how much time callers lose to rate limiting
+type RateLimiterStats struct {
+	Calls        int64   `json:"calls"`
+	Throttled    int64   `json:"throttled"` // Calls that found the bucket empty and waited
+	TotalWaitMs  int64   `json:"total_wait_ms"`
+	ThrottleRate float64 `json:"throttle_rate"` // Throttled / Calls
+}
+
+// Stats returns cumulative throttling counters without taking the limiter lock
+func (r *RateLimiter) Stats() RateLimiterStats {
+	stats := RateLimiterStats{
+		Calls:       r.calls.Load(),
+		Throttled:   r.throttled.Load(),
+		TotalWaitMs: time.Duration(r.waitNanos.Load()).Milliseconds(),
+	}
+	if stats.Calls > 0 {
+		stats.ThrottleRate = float64(stats.Throttled) / float64(stats.Calls)
+	}
+	return stats
+}
+
 func min(a, b float64) float64 {
 	if a < b {
 		return a
@@ -113,9 +143,17 @@ func NewRouter(configs []ProviderConfig) *Router {
 		switch cfg.Protocol {
 		case ProtocolGemini:
 			provider = NewGeminiAdapter(ProviderConfig{
-				Name:   cfg.Name,
-				APIKey: apiKey,
-				Model:  model,
+				Name:           cfg.Name,
+				APIKey:         apiKey,
+				Model:          model,
+				SafetySettings: cfg.SafetySettings,
+			})
+		case ProtocolAnthropic:
+			provider = NewClaudeAdapter(ProviderConfig{
+				Name:    cfg.Name,
+				BaseURL: cfg.BaseURL,
+				APIKey:  apiKey,
+				Model:   model,
 			})
 		case ProtocolOpenAI:
 			fallthrough
@@ -151,7 +189,9 @@ func (r *Router) Complete(ctx context.Context, prompt string, opts CompletionOpt
 		return nil, fmt.Errorf("no providers available")
 	}
 
+	start := time.Now()
 	result, err := provider.Complete(ctx, prompt, opts)
+	r.balancer.Observe(provider.Name(), time.Since(start), err)
 	if err != nil {
 		r.recordError(provider.Name(), err)
 		return nil, err
@@ -174,7 +214,9 @@ func (r *Router) CompleteWithProvider(ctx context.Context, providerName, prompt
 		return nil, fmt.Errorf("no providers available")
 	}
 
+	start := time.Now()
 	result, err := provider.Complete(ctx, prompt, opts)
+	r.balancer.Observe(provider.Name(), time.Since(start), err)
 	if err != nil {
 		r.recordError(provider.Name(), err)
 		return nil, err
@@ -223,9 +265,10 @@ func (r *Router) GetStats() map[string]interface{} {
 	defer r.mu.RUnlock()
 
 	return map[string]interface{}{
-		"success":   r.successCount,
-		"errors":    r.errorCount,
-		"lastError": r.lastError,
+		"success":      r.successCount,
+		"errors":       r.errorCount,
+		"lastError":    r.lastError,
+		"rate_limiter": r.rateLimiter.Stats(),
 	}
 }
 
@@ -250,6 +293,7 @@ func (r *Router) TestProviders(ctx context.Context) []ProviderTestResult {
 		} else {
 			result.Status = "ok"
 		}
+		r.balancer.SetAvailable(p.Name(), err == nil) // Failing providers leave the rotation
 
 		results = append(results, result)
 	}
@@ -288,6 +332,8 @@ func getEnvAPIKey(provider string) string {
 		"nebius":      "NEBIUS_API_KEY",
 		"gemini":      "GEMINI_API_KEY",
 		"openai":      "OPENAI_API_KEY",
+		"anthropic":   "ANTHROPIC_API_KEY",
+		"claude":      "ANTHROPIC_API_KEY",
 	}
 	if envName, ok := envMap[strings.ToLower(provider)]; ok {
 		return os.Getenv(envName)
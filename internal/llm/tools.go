@@ -0,0 +1,132 @@
+package llm
+
+import "encoding/json"
+
+// Tool describes a function the model may call instead of answering in text.
+// Parameters is a JSON Schema object describing the call's arguments.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+}
+
+// ToolCall is a model's structured call to one of CompletionOpts.Tools
+type ToolCall struct {
+	Name      string
+	Arguments json.RawMessage // JSON object matching the tool's Parameters
+}
+
+// Capabilities describes optional API features a provider supports
+type Capabilities struct {
+	// ToolCalling providers honor CompletionOpts.Tools and return the
+	// model's answer as CompletionResult.ToolCall
+	ToolCalling bool
+}
+
+// CapabilityReporter is implemented by providers with optional features
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns p's capabilities; providers that don't report any
+// support none
+func CapabilitiesOf(p Provider) Capabilities {
+	if r, ok := p.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	return Capabilities{}
+}
+
+// OpenAIToolFields returns the request fields offering tools to an
+// OpenAI-compatible chat completion. A single tool is forced so the answer
+// always comes back as a call to it.
+func OpenAIToolFields(tools []Tool) map[string]interface{} {
+	if len(tools) == 0 {
+		return nil
+	}
+	defs := make([]map[string]interface{}, 0, len(tools))
+	for _, t := range tools {
+		defs = append(defs, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        t.Name,
+				"description": t.Description,
+				"parameters":  t.Parameters,
+			},
+		})
+	}
+
+	var choice interface{} = "required"
+	if len(tools) == 1 {
+		choice = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": tools[0].Name},
+		}
+	}
+	return map[string]interface{}{"tools": defs, "tool_choice": choice}
+}
+
+// GeminiToolFields returns the request fields offering tools to Gemini, in
+// ANY mode so the model must call one of them
+func GeminiToolFields(tools []Tool) map[string]interface{} {
+	if len(tools) == 0 {
+		return nil
+	}
+	decls := make([]map[string]interface{}, 0, len(tools))
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		decls = append(decls, map[string]interface{}{
+			"name":        t.Name,
+			"description": t.Description,
+			"parameters":  t.Parameters,
+		})
+		names = append(names, t.Name)
+	}
+	return map[string]interface{}{
+		"tools": []map[string]interface{}{{"functionDeclarations": decls}},
+		"toolConfig": map[string]interface{}{
+			"functionCallingConfig": map[string]interface{}{
+				"mode":                 "ANY",
+				"allowedFunctionNames": names,
+			},
+		},
+	}
+}
+
+// OpenAIToolCall is a tool call in an OpenAI-compatible response message
+type OpenAIToolCall struct {
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON-encoded, as a string
+	} `json:"function"`
+}
+
+// ToolCall converts c, or returns nil if it has no name
+func (c OpenAIToolCall) ToolCall() *ToolCall {
+	if c.Function.Name == "" {
+		return nil
+	}
+	args := json.RawMessage(c.Function.Arguments)
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	return &ToolCall{Name: c.Function.Name, Arguments: args}
+}
+
+// GeminiFunctionCall is a functionCall part in a Gemini response
+type GeminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+}
+
+// ToolCall converts c, or returns nil if it has no name
+func (c *GeminiFunctionCall) ToolCall() *ToolCall {
+	if c == nil || c.Name == "" {
+		return nil
+	}
+	args := c.Args
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	return &ToolCall{Name: c.Name, Arguments: args}
+}
@@ -0,0 +1,31 @@
+package observability
+
+import "time"
+
+// ErrorGuard decides when LLM decision requests should pause because most
+// recent calls are failing (expired keys, provider outage) and when they can
+// resume. Not safe for concurrent use; call Check from a single loop.
+type ErrorGuard struct {
+	PauseRate  float64       // Pause at or above this error rate
+	ResumeRate float64       // Resume once the rate falls below this
+	Window     time.Duration // Rolling window of calls considered
+	MinCalls   int           // Don't pause on fewer calls than this
+
+	paused bool
+}
+
+// Check updates the guard from the observer's rolling error rate. changed is
+// true when the guard just paused or resumed. While paused no new calls are
+// made, so the window drains and the guard resumes after at most Window.
+func (g *ErrorGuard) Check(o *Observer) (paused, changed bool, rate float64) {
+	rate, calls := o.ErrorRate(g.Window)
+	switch {
+	case !g.paused && calls >= g.MinCalls && rate >= g.PauseRate:
+		g.paused = true
+		return true, true, rate
+	case g.paused && (calls < g.MinCalls || rate < g.ResumeRate):
+		g.paused = false
+		return false, true, rate
+	}
+	return g.paused, false, rate
+}
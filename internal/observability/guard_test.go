@@ -0,0 +1,61 @@
+package observability
+
+import (
+	"testing"
+	"time"
+)
+
+func TestErrorGuard_PausesAndResumesWithHysteresis(t *testing.T) {
+	o := &Observer{}
+	record := func(n int, success bool) {
+		for i := 0; i < n; i++ {
+			o.recentTraces = append(o.recentTraces, TraceEntry{Timestamp: time.Now(), Success: success})
+		}
+	}
+	guard := &ErrorGuard{PauseRate: 0.8, ResumeRate: 0.4, Window: time.Minute, MinCalls: 5}
+
+	steps := []struct {
+		name        string
+		failures    int
+		successes   int
+		wantPaused  bool
+		wantChanged bool
+	}{
+		{"too few calls to judge", 4, 0, false, false},
+		{"MinCalls reached, all failing", 1, 0, true, true},
+		{"still failing", 0, 0, true, false},
+		{"between the rates stays paused", 0, 3, true, false}, // 5/8 failed
+		{"below ResumeRate", 0, 5, false, true},               // 5/13 failed
+		{"recovered", 0, 0, false, false},
+		{"failing under PauseRate", 2, 0, false, false}, // 7/15 failed
+	}
+	for _, step := range steps {
+		record(step.failures, false)
+		record(step.successes, true)
+		paused, changed, rate := guard.Check(o)
+		if paused != step.wantPaused || changed != step.wantChanged {
+			t.Errorf("%s: paused %v, changed %v (rate %.2f); want %v, %v",
+				step.name, paused, changed, rate, step.wantPaused, step.wantChanged)
+		}
+	}
+}
+
+func TestErrorGuard_ResumesOnceWindowDrains(t *testing.T) {
+	o := &Observer{}
+	for i := 0; i < 10; i++ {
+		o.recentTraces = append(o.recentTraces, TraceEntry{Timestamp: time.Now(), Success: false})
+	}
+	guard := &ErrorGuard{PauseRate: 0.8, ResumeRate: 0.4, Window: time.Minute, MinCalls: 5}
+	if paused, changed, _ := guard.Check(o); !paused || !changed {
+		t.Fatalf("10 failures: paused %v, changed %v; want a pause", paused, changed)
+	}
+
+	// No calls are made while paused; once the failures age out of the
+	// window there are too few calls to stay paused on
+	for i := range o.recentTraces {
+		o.recentTraces[i].Timestamp = time.Now().Add(-2 * time.Minute)
+	}
+	if paused, changed, _ := guard.Check(o); paused || !changed {
+		t.Errorf("after the window drained: paused %v, changed %v; want a resume", paused, changed)
+	}
+}
@@ -0,0 +1,131 @@
+package observability
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Client is anything that can receive JSON messages (e.g. a WebSocket connection)
+type Client interface {
+	WriteJSON(v interface{}) error
+}
+
+// HubClient wraps a connection so hub broadcasts and the owning handler
+// never write to it concurrently
+type HubClient struct {
+	conn Client
+	mu   sync.Mutex
+}
+
+// WriteJSON sends a message to the client
+func (hc *HubClient) WriteJSON(v interface{}) error {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return hc.conn.WriteJSON(v)
+}
+
+// Hub fans out messages to all registered clients
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*HubClient]struct{}
+}
+
+// NewHub creates an empty connection hub
+func NewHub() *Hub {
+	return &Hub{
+		clients: make(map[*HubClient]struct{}),
+	}
+}
+
+// Register adds a connection to the hub
+func (h *Hub) Register(conn Client) *HubClient {
+	client := &HubClient{conn: conn}
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	return client
+}
+
+// Unregister removes a client from the hub
+func (h *Hub) Unregister(client *HubClient) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	h.mu.Unlock()
+}
+
+// Broadcast sends a message to every client, dropping clients that fail
+func (h *Hub) Broadcast(msg interface{}) {
+	h.mu.RLock()
+	clients := make([]*HubClient, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range clients {
+		if err := c.WriteJSON(msg); err != nil {
+			h.Unregister(c)
+		}
+	}
+}
+
+// Len returns the number of connected clients
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// LiveStats pushes stats snapshots to a hub, coalescing bursts of changes
+// into at most one push per interval
+type LiveStats struct {
+	hub      *Hub
+	interval time.Duration
+	collect  func() interface{}
+	dirty    atomic.Bool
+}
+
+// NewLiveStats creates a publisher that builds snapshots with collect
+func NewLiveStats(hub *Hub, interval time.Duration, collect func() interface{}) *LiveStats {
+	ls := &LiveStats{
+		hub:      hub,
+		interval: interval,
+		collect:  collect,
+	}
+	ls.dirty.Store(true)
+	return ls
+}
+
+// MarkDirty records that stats changed since the last push
+func (ls *LiveStats) MarkDirty() {
+	ls.dirty.Store(true)
+}
+
+// Snapshot builds the current stats message
+func (ls *LiveStats) Snapshot() interface{} {
+	return ls.collect()
+}
+
+// Run pushes a snapshot every interval while something has changed
+func (ls *LiveStats) Run(ctx context.Context) {
+	ticker := time.NewTicker(ls.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ls.hub.Len() == 0 {
+				continue
+			}
+			if ls.dirty.Swap(false) {
+				ls.hub.Broadcast(ls.collect())
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package observability
+
+import "sort"
+
+// maxNPCSamples bounds the latency history kept per NPC
+const maxNPCSamples = 200
+
+type latencySample struct {
+	ms       int64
+	provider string
+}
+
+// NPCLatency summarizes an NPC's recent LLM call latency
+type NPCLatency struct {
+	NPC       string         `json:"npc"`
+	Calls     int            `json:"calls"`
+	P50Ms     int64          `json:"p50_ms"`
+	P95Ms     int64          `json:"p95_ms"`
+	Provider  string         `json:"provider"`  // Provider of the latest call
+	Providers map[string]int `json:"providers"` // Calls per provider in the sample
+	Slow      bool           `json:"slow"`      // P95 above the slow threshold
+}
+
+// ProviderLatency summarizes a provider's recent LLM calls
+type ProviderLatency struct {
+	Provider  string  `json:"provider"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+}
+
+// recordNPCLatency keeps a call's latency in the history of the NPC it
+// decided for, or of each NPC a batch call decided for (caller holds o.mu)
+func (o *Observer) recordNPCLatency(entry TraceEntry) {
+	npcs := entry.NPCs
+	if entry.NPC != "" {
+		npcs = append([]string{entry.NPC}, npcs...)
+	}
+	if len(npcs) > 0 && o.npcLatency == nil {
+		o.npcLatency = make(map[string][]latencySample)
+	}
+	for _, npc := range npcs {
+		samples := append(o.npcLatency[npc], latencySample{entry.LatencyMs, entry.Provider})
+		if len(samples) > maxNPCSamples {
+			samples = samples[len(samples)-maxNPCSamples:]
+		}
+		o.npcLatency[npc] = samples
+	}
+}
+
+// NPCLatency returns p50/p95 latency over the NPC's last calls; an NPC is
+// flagged slow when its p95 exceeds slowP95Ms (0 disables the flag)
+func (o *Observer) NPCLatency(npc string, slowP95Ms int64) NPCLatency {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return summarizeLatency(npc, o.npcLatency[npc], slowP95Ms)
+}
+
+// NPCLatencies returns the latency summary of every NPC with traced calls,
+// slowest p95 first
+func (o *Observer) NPCLatencies(slowP95Ms int64) []NPCLatency {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := make([]NPCLatency, 0, len(o.npcLatency))
+	for npc, samples := range o.npcLatency {
+		stats = append(stats, summarizeLatency(npc, samples, slowP95Ms))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].P95Ms != stats[j].P95Ms {
+			return stats[i].P95Ms > stats[j].P95Ms
+		}
+		return stats[i].NPC < stats[j].NPC
+	})
+	return stats
+}
+
+func summarizeLatency(npc string, samples []latencySample, slowP95Ms int64) NPCLatency {
+	stats := NPCLatency{NPC: npc, Calls: len(samples), Providers: map[string]int{}}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	latencies := make([]int64, len(samples))
+	for i, s := range samples {
+		latencies[i] = s.ms
+		stats.Providers[s.provider]++
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	stats.P50Ms = percentile(latencies, 50)
+	stats.P95Ms = percentile(latencies, 95)
+	stats.Provider = samples[len(samples)-1].provider
+	stats.Slow = slowP95Ms > 0 && stats.P95Ms > slowP95Ms
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// ProviderLatencies returns call counts, error rate and p50/p95 latency per
+// provider over the recent traces, ordered by provider name
+func (o *Observer) ProviderLatencies() []ProviderLatency {
+	o.mu.Lock()
+	byProvider := make(map[string][]TraceEntry)
+	for _, t := range o.recentTraces {
+		byProvider[t.Provider] = append(byProvider[t.Provider], t)
+	}
+	o.mu.Unlock()
+
+	stats := make([]ProviderLatency, 0, len(byProvider))
+	for provider, traces := range byProvider {
+		s := ProviderLatency{Provider: provider, Calls: len(traces)}
+		latencies := make([]int64, len(traces))
+		for i, t := range traces {
+			latencies[i] = t.LatencyMs
+			if t.Error != "" || !t.Success {
+				s.Errors++
+			}
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		s.P50Ms = percentile(latencies, 50)
+		s.P95Ms = percentile(latencies, 95)
+		s.ErrorRate = float64(s.Errors) / float64(s.Calls)
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Provider < stats[j].Provider })
+	return stats
+}
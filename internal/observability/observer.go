@@ -1,35 +1,55 @@
 package observability
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"os"
 	"sync"
 	"time"
+
+	"github.com/amit/npc/internal/storage"
 )
 
 // TraceEntry records a single LLM API call
 type TraceEntry struct {
 	Timestamp time.Time `json:"ts"`
 	TraceID   string    `json:"id"`
-	Role      string    `json:"role"` // movement, challenge, judge, etc.
+	RequestID string    `json:"request_id,omitempty"` // Originating client request
+	Role      string    `json:"role"`                 // movement, challenge, judge, etc.
 	NPC       string    `json:"npc,omitempty"`
+	NPCs      []string  `json:"npcs,omitempty"` // Every NPC a batch call decided for
 	Team      string    `json:"team,omitempty"`
 	Provider  string    `json:"provider"`
 	Model     string    `json:"model"`
 	Prompt    string    `json:"prompt"`
 	Response  string    `json:"response"`
 	LatencyMs int64     `json:"latency_ms"`
-	TokensIn  int       `json:"tokens_in,omitempty"`
-	TokensOut int       `json:"tokens_out,omitempty"`
-	CostUSD   float64   `json:"cost_usd,omitempty"`
-	Error     string    `json:"error,omitempty"`
-	Success   bool      `json:"success"`
+
+	// Identify the full prompt even when its text isn't stored
+	PromptHash string `json:"prompt_hash,omitempty"`
+	PromptLen  int    `json:"prompt_len,omitempty"`
+
+	TokensIn  int     `json:"tokens_in,omitempty"`
+	TokensOut int     `json:"tokens_out,omitempty"`
+	CostUSD   float64 `json:"cost_usd,omitempty"`
+	Error     string  `json:"error,omitempty"`
+
+	// Set on fallback attempts: position in the fallback order (1 = first)
+	// and price relative to the provider that failed (0 = unknown)
+	Fallback     int     `json:"fallback,omitempty"`
+	RelativeCost float64 `json:"relative_cost,omitempty"`
+
+	Success bool `json:"success"`
 }
 
 // AuditEntry records a game event
 type AuditEntry struct {
 	Timestamp time.Time              `json:"ts"`
+	RequestID string                 `json:"request_id,omitempty"` // Originating client request
 	Event     string                 `json:"event"`
 	NPC       string                 `json:"npc,omitempty"`
 	Team      string                 `json:"team,omitempty"`
@@ -38,22 +58,34 @@ type AuditEntry struct {
 
 // Observer handles all observability operations
 type Observer struct {
-	traceFile  *os.File
-	auditFile  *os.File
+	store      storage.Store
+	traces     *bufferedAppender // Trace log writer; nil disables writing
+	auditPath  string            // Store key; "" disables writing
 	mu         sync.Mutex
 	enabled    bool
 	traceCount int
 
+	includePrompts bool // false keeps only PromptHash/PromptLen in traces
+
 	// Stats
 	TotalCalls   int     `json:"total_calls"`
 	TotalLatency int64   `json:"total_latency_ms"`
 	TotalCost    float64 `json:"total_cost_usd"`
 	ErrorCount   int     `json:"error_count"`
 
+	callsByProvider map[string]int
+	npcLatency      map[string][]latencySample // Recent call latencies per NPC
+
 	// Recent entries for quick access
 	recentTraces []TraceEntry
 	recentAudits []AuditEntry
 	maxRecent    int
+
+	// Called after every trace or audit (e.g. to refresh live dashboards)
+	onChange func()
+
+	// Receive every audit entry (e.g. webhook notifiers)
+	subscribers []func(AuditEntry)
 }
 
 // Config for observer
@@ -62,6 +94,11 @@ type ObserverConfig struct {
 	TracePath      string
 	AuditPath      string
 	IncludePrompts bool
+	Store          storage.Store // Defaults to the local filesystem
+
+	// How often buffered traces are written out (default 1s). A TracePath
+	// ending in .gz is gzip-compressed.
+	TraceFlushInterval time.Duration
 }
 
 var (
@@ -73,10 +110,11 @@ var (
 func GetObserver() *Observer {
 	observerOnce.Do(func() {
 		globalObserver = &Observer{
-			enabled:      true,
-			maxRecent:    100,
-			recentTraces: make([]TraceEntry, 0, 100),
-			recentAudits: make([]AuditEntry, 0, 100),
+			enabled:        true,
+			includePrompts: true,
+			maxRecent:      100,
+			recentTraces:   make([]TraceEntry, 0, 100),
+			recentAudits:   make([]AuditEntry, 0, 100),
 		}
 	})
 	return globalObserver
@@ -88,29 +126,61 @@ func (o *Observer) Initialize(cfg ObserverConfig) error {
 	defer o.mu.Unlock()
 
 	o.enabled = cfg.Enabled
+	o.includePrompts = cfg.IncludePrompts
 	if !o.enabled {
 		return nil
 	}
 
+	o.store = cfg.Store
+	if o.store == nil {
+		o.store = storage.NewFileStore("")
+	}
+
+	// Touch both logs so a bad path fails at startup rather than on first write
 	if cfg.TracePath != "" {
-		f, err := os.OpenFile(cfg.TracePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
+		if err := storage.Append(o.store, cfg.TracePath, nil); err != nil {
 			return fmt.Errorf("failed to open trace file: %w", err)
 		}
-		o.traceFile = f
+		interval := cfg.TraceFlushInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		o.traces = newBufferedAppender(o.store, cfg.TracePath, interval)
 	}
 
 	if cfg.AuditPath != "" {
-		f, err := os.OpenFile(cfg.AuditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
+		if err := storage.Append(o.store, cfg.AuditPath, nil); err != nil {
 			return fmt.Errorf("failed to open audit file: %w", err)
 		}
-		o.auditFile = f
+		o.auditPath = cfg.AuditPath
 	}
 
 	return nil
 }
 
+// OnChange registers a callback invoked after each recorded trace or audit.
+// The callback runs under the observer lock and must not block.
+func (o *Observer) OnChange(fn func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onChange = fn
+}
+
+// PromptDigest returns a short stable hash of a prompt, so redacted logs can
+// still tell whether two calls used the same prompt
+func PromptDigest(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Subscribe registers fn to receive every audit entry. fn is called outside
+// the observer lock, on the auditing goroutine, and should not block.
+func (o *Observer) Subscribe(fn func(AuditEntry)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.subscribers = append(o.subscribers, fn)
+}
+
 // TraceCall records an LLM API call
 func (o *Observer) TraceCall(entry TraceEntry) {
 	if !o.enabled {
@@ -120,6 +190,13 @@ func (o *Observer) TraceCall(entry TraceEntry) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
+	if entry.Prompt != "" && entry.PromptHash == "" {
+		entry.PromptHash, entry.PromptLen = PromptDigest(entry.Prompt), len(entry.Prompt)
+	}
+	if !o.includePrompts {
+		entry.Prompt = ""
+	}
+
 	// Generate trace ID
 	o.traceCount++
 	entry.TraceID = fmt.Sprintf("trace_%06d", o.traceCount)
@@ -132,6 +209,11 @@ func (o *Observer) TraceCall(entry TraceEntry) {
 	if entry.Error != "" || !entry.Success {
 		o.ErrorCount++
 	}
+	if o.callsByProvider == nil {
+		o.callsByProvider = make(map[string]int)
+	}
+	o.callsByProvider[entry.Provider]++
+	o.recordNPCLatency(entry)
 
 	// Store in recent
 	if len(o.recentTraces) >= o.maxRecent {
@@ -139,21 +221,35 @@ func (o *Observer) TraceCall(entry TraceEntry) {
 	}
 	o.recentTraces = append(o.recentTraces, entry)
 
-	// Write to file
-	if o.traceFile != nil {
+	// Buffered: the disk write happens off this lock
+	if o.traces != nil {
 		data, _ := json.Marshal(entry)
-		o.traceFile.Write(append(data, '\n'))
+		o.traces.Write(append(data, '\n'))
+	}
+
+	if o.onChange != nil {
+		o.onChange()
 	}
 }
 
 // Audit records a game event
 func (o *Observer) Audit(event, npc, team string, data map[string]interface{}) {
+	o.audit("", event, npc, team, data)
+}
+
+// AuditRequest records a game event tagged with the request ID carried by ctx
+func (o *Observer) AuditRequest(ctx context.Context, event, npc, team string, data map[string]interface{}) {
+	o.audit(RequestID(ctx), event, npc, team, data)
+}
+
+func (o *Observer) audit(requestID, event, npc, team string, data map[string]interface{}) {
 	if !o.enabled {
 		return
 	}
 
 	entry := AuditEntry{
 		Timestamp: time.Now(),
+		RequestID: requestID,
 		Event:     event,
 		NPC:       npc,
 		Team:      team,
@@ -161,7 +257,6 @@ func (o *Observer) Audit(event, npc, team string, data map[string]interface{}) {
 	}
 
 	o.mu.Lock()
-	defer o.mu.Unlock()
 
 	// Store in recent
 	if len(o.recentAudits) >= o.maxRecent {
@@ -170,9 +265,19 @@ func (o *Observer) Audit(event, npc, team string, data map[string]interface{}) {
 	o.recentAudits = append(o.recentAudits, entry)
 
 	// Write to file
-	if o.auditFile != nil {
+	if o.auditPath != "" {
 		data, _ := json.Marshal(entry)
-		o.auditFile.Write(append(data, '\n'))
+		storage.Append(o.store, o.auditPath, append(data, '\n'))
+	}
+
+	if o.onChange != nil {
+		o.onChange()
+	}
+	subscribers := o.subscribers
+	o.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(entry)
 	}
 }
 
@@ -200,6 +305,41 @@ func (o *Observer) GetStats() map[string]interface{} {
 	}
 }
 
+// CallsByProvider returns the LLM call count per provider and the total cost
+func (o *Observer) CallsByProvider() (map[string]int, float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	calls := make(map[string]int, len(o.callsByProvider))
+	for provider, n := range o.callsByProvider {
+		calls[provider] = n
+	}
+	return calls, o.TotalCost
+}
+
+// ErrorRate returns the fraction of failed LLM calls among the recent traces
+// from the last window, and how many calls that covers
+func (o *Observer) ErrorRate(window time.Duration) (float64, int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	since := time.Now().Add(-window)
+	calls, failed := 0, 0
+	for _, t := range o.recentTraces {
+		if t.Timestamp.Before(since) {
+			continue
+		}
+		calls++
+		if !t.Success {
+			failed++
+		}
+	}
+	if calls == 0 {
+		return 0, 0
+	}
+	return float64(failed) / float64(calls), calls
+}
+
 // GetRecentTraces returns the most recent trace entries
 func (o *Observer) GetRecentTraces(limit int) []TraceEntry {
 	o.mu.Lock()
@@ -224,16 +364,142 @@ func (o *Observer) GetRecentAudits(limit int) []AuditEntry {
 	return o.recentAudits[start:]
 }
 
-// Close closes the observer's file handles
-func (o *Observer) Close() {
+// AuditFilter selects game events. Zero fields match everything.
+type AuditFilter struct {
+	Team        string
+	NPC         string
+	Event       string
+	Since       time.Time
+	Until       time.Time
+	Limit       int  // Default 50
+	IncludeDisk bool // Also scan the audit file for events older than the buffer
+}
+
+// Matches reports whether an entry passes the filter
+func (f AuditFilter) Matches(e AuditEntry) bool {
+	switch {
+	case f.Team != "" && e.Team != f.Team:
+		return false
+	case f.NPC != "" && e.NPC != f.NPC:
+		return false
+	case f.Event != "" && e.Event != f.Event:
+		return false
+	case !f.Since.IsZero() && e.Timestamp.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Timestamp.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// GetAuditsFiltered returns matching game events, most recent first
+func (o *Observer) GetAuditsFiltered(filter AuditFilter) []AuditEntry {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	o.mu.Lock()
+	source := make([]AuditEntry, len(o.recentAudits))
+	copy(source, o.recentAudits)
+	store, path := o.store, o.auditPath
+	o.mu.Unlock()
+
+	if filter.IncludeDisk && path != "" {
+		source = readAuditFile(store, path)
+	}
+
+	result := []AuditEntry{}
+	for i := len(source) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		if filter.Matches(source[i]) {
+			result = append(result, source[i])
+		}
+	}
+	return result
+}
+
+// keyEventTypes are the game events a match's story is told from
+var keyEventTypes = map[string]bool{
+	"challenge_complete": true, // Successful ones only
+	"zone_unlocked":      true,
+	"zone_generated":     true,
+	"lead_change":        true,
+	"match_over":         true,
+}
+
+// KeyEvents returns up to limit of the match's turning points since start,
+// oldest first: challenges solved, zones unlocked or generated, lead changes
+// and the end of the match
+func (o *Observer) KeyEvents(since time.Time, limit int) []AuditEntry {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
-	if o.traceFile != nil {
-		o.traceFile.Close()
+	var events []AuditEntry
+	for _, e := range o.recentAudits {
+		if !keyEventTypes[e.Event] || e.Timestamp.Before(since) {
+			continue
+		}
+		if success, ok := e.Data["success"].(bool); ok && !success {
+			continue
+		}
+		events = append(events, e)
+	}
+	if len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events
+}
+
+// readAuditFile loads every game event from an audit file, skipping lines
+// that aren't events (the API call log may share the file)
+func readAuditFile(store storage.Store, path string) []AuditEntry {
+	data, err := store.Get(path)
+	if err != nil {
+		return nil
+	}
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Event == "" {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// GetRequestEntries returns the recent traces and audits tagged with a request ID
+func (o *Observer) GetRequestEntries(requestID string) ([]TraceEntry, []AuditEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	traces := []TraceEntry{}
+	for _, t := range o.recentTraces {
+		if t.RequestID == requestID {
+			traces = append(traces, t)
+		}
+	}
+	audits := []AuditEntry{}
+	for _, a := range o.recentAudits {
+		if a.RequestID == requestID {
+			audits = append(audits, a)
+		}
 	}
-	if o.auditFile != nil {
-		o.auditFile.Close()
+	return traces, audits
+}
+
+// Close flushes buffered traces and stops the observer writing to its store
+func (o *Observer) Close() {
+	o.mu.Lock()
+	traces := o.traces
+	o.traces = nil
+	o.auditPath = ""
+	o.mu.Unlock()
+
+	if traces != nil {
+		traces.Close()
 	}
 }
 
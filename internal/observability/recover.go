@@ -0,0 +1,34 @@
+package observability
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// maxPanicMessageLog caps how much of the offending message a panic logs
+const maxPanicMessageLog = 500
+
+// HandleMessage runs handle on one client message, recovering a panic (e.g.
+// an unchecked type assertion on a malformed message) so it costs the client
+// an error reply instead of its connection. The panic is logged with the
+// offending message and a stack trace.
+func HandleMessage(client Client, msg map[string]interface{}, handle func(msg map[string]interface{})) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		raw, _ := json.Marshal(msg)
+		if len(raw) > maxPanicMessageLog {
+			raw = append(raw[:maxPanicMessageLog], "..."...)
+		}
+		log.Printf("💥 Panic handling %v message: %v\nmessage: %s\n%s", msg["type"], r, raw, debug.Stack())
+		client.WriteJSON(map[string]interface{}{
+			"type":  "error",
+			"error": fmt.Sprintf("internal error handling %v message", msg["type"]),
+		})
+	}()
+	handle(msg)
+}
@@ -0,0 +1,39 @@
+package observability
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+type recordingClient struct {
+	sent []map[string]interface{}
+}
+
+func (c *recordingClient) WriteJSON(v interface{}) error {
+	c.sent = append(c.sent, v.(map[string]interface{}))
+	return nil
+}
+
+func TestHandleMessage_RecoversPanics(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	client := &recordingClient{}
+	handle := func(msg map[string]interface{}) {
+		gateID := msg["gate_id"].(string) // Panics when gate_id is missing
+		client.WriteJSON(map[string]interface{}{"type": "ok", "gate_id": gateID})
+	}
+
+	HandleMessage(client, map[string]interface{}{"type": "challenge_start"}, handle)
+	if len(client.sent) != 1 || client.sent[0]["type"] != "error" {
+		t.Fatalf("after panic sent %v, want one error reply", client.sent)
+	}
+
+	// The connection's next message is handled as usual
+	HandleMessage(client, map[string]interface{}{"type": "challenge_start", "gate_id": "gate_1_2"}, handle)
+	if len(client.sent) != 2 || client.sent[1]["gate_id"] != "gate_1_2" {
+		t.Errorf("message after panic sent %v", client.sent)
+	}
+}
@@ -1,12 +1,18 @@
 package observability
 
 import (
+	"context"
 	"encoding/json"
-	"os"
+	"errors"
 	"sync"
 	"time"
+
+	"github.com/amit/npc/internal/storage"
 )
 
+// ErrReplayActive is returned by Play when a replay is already running
+var ErrReplayActive = errors.New("replay already playing")
+
 // Replay system for game state snapshots
 
 // GameSnapshot represents a point-in-time game state for replay
@@ -24,7 +30,14 @@ type ReplayManager struct {
 	lastSnapshotTime time.Time
 	mu               sync.RWMutex
 	enabled          bool
-	filePath         string
+	filePath         string // Store key for SaveToFile/LoadFromFile
+	store            storage.Store
+
+	// Playback: speed multiplier (0 = paused), woken on change
+	playing     bool
+	playSpeed   float64
+	speedChange chan struct{}
+	stopPlay    context.CancelFunc
 }
 
 // NewReplayManager creates a new replay manager
@@ -35,14 +48,28 @@ func NewReplayManager(enabled bool, filePath string) *ReplayManager {
 		snapshotInterval: 5 * time.Second,
 		enabled:          enabled,
 		filePath:         filePath,
+		store:            storage.NewFileStore(""),
+		speedChange:      make(chan struct{}, 1),
 	}
 }
 
+// SetSnapshotInterval sets the minimum time between snapshots (default 5s)
+func (rm *ReplayManager) SetSnapshotInterval(d time.Duration) {
+	if d <= 0 {
+		d = 5 * time.Second
+	}
+	rm.mu.Lock()
+	rm.snapshotInterval = d
+	rm.mu.Unlock()
+}
+
 // ShouldSnapshot checks if it's time to create a new snapshot
 func (rm *ReplayManager) ShouldSnapshot() bool {
 	if !rm.enabled {
 		return false
 	}
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
 	return time.Since(rm.lastSnapshotTime) >= rm.snapshotInterval
 }
 
@@ -128,6 +155,13 @@ func (rm *ReplayManager) GetSnapshotByTick(tick int) *GameSnapshot {
 	return result
 }
 
+// SetStore replaces the filesystem store used to save and load snapshots
+func (rm *ReplayManager) SetStore(store storage.Store) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.store = store
+}
+
 // SaveToFile writes all snapshots to a file
 func (rm *ReplayManager) SaveToFile() error {
 	if rm.filePath == "" {
@@ -136,13 +170,14 @@ func (rm *ReplayManager) SaveToFile() error {
 
 	rm.mu.RLock()
 	data, err := json.Marshal(rm.snapshots)
+	store := rm.store
 	rm.mu.RUnlock()
 
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(rm.filePath, data, 0644)
+	return store.Put(rm.filePath, data)
 }
 
 // LoadFromFile reads snapshots from a file
@@ -151,14 +186,14 @@ func (rm *ReplayManager) LoadFromFile() error {
 		return nil
 	}
 
-	data, err := os.ReadFile(rm.filePath)
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	data, err := rm.store.Get(rm.filePath)
 	if err != nil {
 		return err
 	}
 
-	rm.mu.Lock()
-	defer rm.mu.Unlock()
-
 	return json.Unmarshal(data, &rm.snapshots)
 }
 
@@ -178,6 +213,121 @@ func (rm *ReplayManager) GetTimeline() []map[string]interface{} {
 	return timeline
 }
 
+// Play broadcasts the stored snapshots to hub as "replay_frame" messages,
+// waiting the original time between snapshots divided by speed. A speed of 0
+// starts paused; SetSpeed pauses, resumes or changes speed mid-playback.
+// Blocks until the last frame is sent, ctx is cancelled or Stop is called.
+func (rm *ReplayManager) Play(ctx context.Context, hub *Hub, speed float64) error {
+	rm.mu.Lock()
+	if rm.playing {
+		rm.mu.Unlock()
+		return ErrReplayActive
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rm.playing = true
+	rm.playSpeed = speed
+	rm.stopPlay = cancel
+	snapshots := make([]GameSnapshot, len(rm.snapshots))
+	copy(snapshots, rm.snapshots)
+	rm.mu.Unlock()
+
+	defer func() {
+		rm.mu.Lock()
+		rm.playing = false
+		rm.stopPlay = nil
+		rm.mu.Unlock()
+	}()
+
+	for i, snap := range snapshots {
+		if i > 0 {
+			if err := rm.waitScaled(ctx, snap.Timestamp.Sub(snapshots[i-1].Timestamp)); err != nil {
+				return err
+			}
+		}
+		hub.Broadcast(map[string]interface{}{
+			"type":      "replay_frame",
+			"index":     i,
+			"total":     len(snapshots),
+			"tick":      snap.Tick,
+			"timestamp": snap.Timestamp.Format(time.RFC3339),
+			"state":     snap.State,
+		})
+	}
+
+	hub.Broadcast(map[string]interface{}{"type": "replay_end", "frames": len(snapshots)})
+	return nil
+}
+
+// waitScaled sleeps for gap of recorded time at the current playback speed,
+// re-planning whenever the speed changes and waiting indefinitely while paused
+func (rm *ReplayManager) waitScaled(ctx context.Context, gap time.Duration) error {
+	remaining := gap
+	for remaining > 0 {
+		speed := rm.Speed()
+
+		var timer *time.Timer
+		var fired <-chan time.Time
+		started := time.Now()
+		if speed > 0 {
+			timer = time.NewTimer(time.Duration(float64(remaining) / speed))
+			fired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-fired:
+			return nil
+		case <-rm.speedChange:
+			if timer != nil {
+				timer.Stop()
+				remaining -= time.Duration(float64(time.Since(started)) * speed)
+			}
+		}
+	}
+	return nil
+}
+
+// SetSpeed changes playback speed; 0 pauses, any positive value resumes
+func (rm *ReplayManager) SetSpeed(speed float64) {
+	if speed < 0 {
+		speed = 0
+	}
+	rm.mu.Lock()
+	rm.playSpeed = speed
+	rm.mu.Unlock()
+
+	select {
+	case rm.speedChange <- struct{}{}:
+	default:
+	}
+}
+
+// Stop ends the replay in progress, if any
+func (rm *ReplayManager) Stop() {
+	rm.mu.RLock()
+	stop := rm.stopPlay
+	rm.mu.RUnlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// Speed returns the current playback speed (0 while paused)
+func (rm *ReplayManager) Speed() float64 {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.playSpeed
+}
+
+// Playing reports whether a replay is in progress
+func (rm *ReplayManager) Playing() bool {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.playing
+}
+
 // Clear removes all snapshots
 func (rm *ReplayManager) Clear() {
 	rm.mu.Lock()
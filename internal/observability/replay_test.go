@@ -0,0 +1,49 @@
+package observability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amit/npc/internal/storage"
+)
+
+func TestReplayManager_SnapshotsAsTicksPass(t *testing.T) {
+	rm := NewReplayManager(true, "replay.json")
+	rm.SetStore(storage.NewFileStore(t.TempDir()))
+	rm.SetSnapshotInterval(5 * time.Millisecond)
+
+	for tick := 1; tick <= 30; tick++ {
+		if rm.ShouldSnapshot() {
+			rm.CreateSnapshot(tick, map[string]interface{}{"tick": tick})
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	timeline := rm.GetTimeline()
+	if len(timeline) < 2 {
+		t.Fatalf("timeline has %d snapshots, want several", len(timeline))
+	}
+	if first := timeline[0]["tick"]; first != 1 {
+		t.Errorf("first snapshot at tick %v, want 1", first)
+	}
+	for i := 1; i < len(timeline); i++ {
+		if timeline[i]["tick"].(int) <= timeline[i-1]["tick"].(int) {
+			t.Errorf("snapshot %d at tick %v follows tick %v", i, timeline[i]["tick"], timeline[i-1]["tick"])
+		}
+	}
+	if snap := rm.GetSnapshotByTick(30); snap == nil || snap.Tick != timeline[len(timeline)-1]["tick"] {
+		t.Errorf("GetSnapshotByTick(30) = %+v, want the latest snapshot", snap)
+	}
+
+	if err := rm.SaveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewReplayManager(true, "replay.json")
+	loaded.store = rm.store
+	if err := loaded.LoadFromFile(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.GetSnapshots()) != len(timeline) {
+		t.Errorf("reloaded %d snapshots, saved %d", len(loaded.GetSnapshots()), len(timeline))
+	}
+}
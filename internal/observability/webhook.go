@@ -0,0 +1,156 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DefaultWebhookEvents are forwarded when no event filter is configured
+var DefaultWebhookEvents = []string{"match_over", "lead_change", "zone_generated"}
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed by the shared secret
+const SignatureHeader = "X-NPC-Arena-Signature"
+
+// WebhookConfig for the webhook notifier
+type WebhookConfig struct {
+	URL    string
+	Secret string
+	Events []string // Audit events to forward; empty means DefaultWebhookEvents
+}
+
+// WebhookPayload is the body POSTed for each event
+type WebhookPayload struct {
+	Event     string                 `json:"event"`
+	Timestamp time.Time              `json:"ts"`
+	Team      string                 `json:"team,omitempty"`
+	NPC       string                 `json:"npc,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// WebhookNotifier POSTs selected audit events to an external URL (Discord,
+// Slack, ...). Delivery happens on a background queue so the game never waits;
+// failed deliveries are retried a couple of times and then dropped.
+type WebhookNotifier struct {
+	url     string
+	secret  string
+	events  map[string]bool
+	client  *http.Client
+	queue   chan WebhookPayload
+	retries int
+	backoff time.Duration
+}
+
+// NewWebhookNotifier creates a notifier. Call Run to start delivering.
+func NewWebhookNotifier(cfg WebhookConfig) *WebhookNotifier {
+	events := cfg.Events
+	if len(events) == 0 {
+		events = DefaultWebhookEvents
+	}
+
+	wn := &WebhookNotifier{
+		url:     cfg.URL,
+		secret:  cfg.Secret,
+		events:  make(map[string]bool, len(events)),
+		client:  &http.Client{Timeout: 5 * time.Second},
+		queue:   make(chan WebhookPayload, 64),
+		retries: 2,
+		backoff: time.Second,
+	}
+	for _, event := range events {
+		wn.events[event] = true
+	}
+	return wn
+}
+
+// Notify queues an audit entry for delivery if it passes the event filter.
+// Never blocks: when the queue is full the event is dropped.
+func (wn *WebhookNotifier) Notify(entry AuditEntry) {
+	if !wn.events[entry.Event] {
+		return
+	}
+
+	payload := WebhookPayload{
+		Event:     entry.Event,
+		Timestamp: entry.Timestamp,
+		Team:      entry.Team,
+		NPC:       entry.NPC,
+		Data:      entry.Data,
+	}
+	select {
+	case wn.queue <- payload:
+	default:
+		log.Printf("⚠️ Webhook queue full, dropping %s", entry.Event)
+	}
+}
+
+// Run delivers queued events until ctx is cancelled
+func (wn *WebhookNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-wn.queue:
+			wn.deliver(ctx, payload)
+		}
+	}
+}
+
+func (wn *WebhookNotifier) deliver(ctx context.Context, payload WebhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("⚠️ Webhook %s: %v", payload.Event, err)
+		return
+	}
+
+	for attempt := 0; attempt <= wn.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wn.backoff * time.Duration(attempt)):
+			}
+		}
+
+		if err = wn.post(ctx, body); err == nil {
+			return
+		}
+	}
+	log.Printf("⚠️ Webhook %s dropped after %d attempts: %v", payload.Event, wn.retries+1, err)
+}
+
+func (wn *WebhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wn.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wn.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(wn.secret, body))
+	}
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed by secret, as sent in SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
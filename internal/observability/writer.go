@@ -0,0 +1,110 @@
+package observability
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amit/npc/internal/storage"
+)
+
+// traceFlushBytes is how much buffered output triggers a flush before the
+// next tick of the flush interval
+const traceFlushBytes = 64 * 1024
+
+// bufferedAppender collects log lines in memory and appends them to a store
+// key from a background goroutine, on a ticker, when the buffer fills and on
+// Close, so writers never wait on the disk. Keys ending in ".gz" are
+// gzip-compressed; each flush appends a complete gzip member, which gzip
+// readers treat as one stream, so a crash loses at most one interval.
+type bufferedAppender struct {
+	store    storage.Store
+	key      string
+	compress bool
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newBufferedAppender starts flushing to key every interval
+func newBufferedAppender(store storage.Store, key string, interval time.Duration) *bufferedAppender {
+	a := &bufferedAppender{
+		store:    store,
+		key:      key,
+		compress: strings.HasSuffix(key, ".gz"),
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go a.run(interval)
+	return a
+}
+
+// Write buffers p; it never touches the store
+func (a *bufferedAppender) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	n, _ := a.buf.Write(p)
+	full := a.buf.Len() >= traceFlushBytes
+	a.mu.Unlock()
+
+	if full {
+		select {
+		case a.kick <- struct{}{}:
+		default: // A flush is already pending
+		}
+	}
+	return n, nil
+}
+
+func (a *bufferedAppender) run(interval time.Duration) {
+	defer close(a.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.kick:
+		case <-a.stop:
+			a.flush()
+			return
+		}
+		a.flush()
+	}
+}
+
+// flush appends everything buffered so far to the store
+func (a *bufferedAppender) flush() {
+	a.mu.Lock()
+	if a.buf.Len() == 0 {
+		a.mu.Unlock()
+		return
+	}
+	data := append([]byte(nil), a.buf.Bytes()...)
+	a.buf.Reset()
+	a.mu.Unlock()
+
+	if a.compress {
+		var zipped bytes.Buffer
+		zw := gzip.NewWriter(&zipped)
+		zw.Write(data)
+		zw.Close()
+		data = zipped.Bytes()
+	}
+	if err := storage.Append(a.store, a.key, data); err != nil {
+		log.Printf("⚠️ Trace write to %s failed: %v", a.key, err)
+	}
+}
+
+// Close flushes what's buffered and stops the background writer
+func (a *bufferedAppender) Close() {
+	close(a.stop)
+	<-a.done
+}
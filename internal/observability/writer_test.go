@@ -0,0 +1,93 @@
+package observability
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amit/npc/internal/storage"
+)
+
+func TestBufferedAppender_CloseFlushes(t *testing.T) {
+	store := storage.NewFileStore(t.TempDir())
+	a := newBufferedAppender(store, "logs/trace.jsonl", time.Hour)
+	a.Write([]byte("one\n"))
+	a.Write([]byte("two\n"))
+
+	if _, err := store.Get("logs/trace.jsonl"); err == nil {
+		t.Fatal("Write reached the store before any flush")
+	}
+	a.Close()
+
+	data, err := store.Get("logs/trace.jsonl")
+	if err != nil || string(data) != "one\ntwo\n" {
+		t.Errorf("after Close store has %q, %v; want both lines", data, err)
+	}
+}
+
+func TestBufferedAppender_FullBufferFlushesEarly(t *testing.T) {
+	store := storage.NewFileStore(t.TempDir())
+	a := newBufferedAppender(store, "logs/trace.jsonl", time.Hour)
+	defer a.Close()
+
+	line := strings.Repeat("x", traceFlushBytes)
+	a.Write([]byte(line))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if data, err := store.Get("logs/trace.jsonl"); err == nil && len(data) == len(line) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("a full buffer wasn't flushed before the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBufferedAppender_GzipAppendsOneMemberPerFlush(t *testing.T) {
+	store := storage.NewFileStore(t.TempDir())
+	a := newBufferedAppender(store, "logs/trace.jsonl.gz", time.Hour)
+	a.Write([]byte("first\n"))
+	a.flush()
+	a.Write([]byte("second\n"))
+	a.Close()
+
+	data, err := store.Get("logs/trace.jsonl.gz")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	// Readers see the members as one stream
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	all, err := io.ReadAll(zr)
+	if err != nil || string(all) != "first\nsecond\n" {
+		t.Errorf("decompressed %q, %v; want both flushes in order", all, err)
+	}
+
+	// Each flush is its own complete member
+	br := bytes.NewReader(data)
+	zr, _ = gzip.NewReader(br)
+	var members []string
+	for {
+		zr.Multistream(false)
+		member, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("member %d: %v", len(members)+1, err)
+		}
+		members = append(members, string(member))
+		if err := zr.Reset(br); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("after member %d: %v", len(members), err)
+		}
+	}
+	if len(members) != 2 || members[0] != "first\n" || members[1] != "second\n" {
+		t.Errorf("members = %q, want one per flush", members)
+	}
+}
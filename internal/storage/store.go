@@ -0,0 +1,137 @@
+// Package storage abstracts where the server persists replays and logs, so
+// deployments can swap the local filesystem for object storage.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when a key doesn't exist
+var ErrNotFound = errors.New("storage: key not found")
+
+// Store persists blobs by slash-separated key (e.g. "logs/replay.json")
+type Store interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	List(prefix string) ([]string, error)
+}
+
+// Appender is implemented by stores that can append to a key in place.
+// Log writers use it through Append.
+type Appender interface {
+	Append(key string, data []byte) error
+}
+
+// Append adds data to the end of key, using the store's native append when
+// it has one and a read-modify-write otherwise
+func Append(s Store, key string, data []byte) error {
+	if a, ok := s.(Appender); ok {
+		return a.Append(key, data)
+	}
+	existing, err := s.Get(key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return s.Put(key, append(existing, data...))
+}
+
+// New creates the store for a configured backend. Only "file" (the default)
+// is built in; root is its base directory.
+func New(backend, root string) (Store, error) {
+	switch backend {
+	case "", "file":
+		return NewFileStore(root), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", backend)
+}
+
+// FileStore keeps each key as a file under Root
+type FileStore struct {
+	Root string
+}
+
+// NewFileStore creates a filesystem store. An empty root means the working
+// directory, so keys behave like the relative paths they replace.
+func NewFileStore(root string) *FileStore {
+	if root == "" {
+		root = "."
+	}
+	return &FileStore{Root: root}
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(key))
+}
+
+// Put writes data to key, creating parent directories
+func (s *FileStore) Put(key string, data []byte) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// Get reads key, returning ErrNotFound if it doesn't exist
+func (s *FileStore) Get(key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return data, err
+}
+
+// Append adds data to the end of key, creating it if needed
+func (s *FileStore) Append(key string, data []byte) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List returns the sorted keys that start with prefix
+func (s *FileStore) List(prefix string) ([]string, error) {
+	prefix = strings.TrimPrefix(prefix, "./")
+	start := s.Root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = s.path(prefix[:i]) // Only walk the prefix's directory
+	}
+
+	var keys []string
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == start {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Root, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFileStore_PutGetRoundTrip(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	if err := s.Put("logs/replays/match.json", []byte(`{"tick":1}`)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, err := s.Get("logs/replays/match.json")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(data) != `{"tick":1}` {
+		t.Errorf("Get = %q, want what was put", data)
+	}
+
+	if err := s.Put("logs/replays/match.json", []byte("{}")); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if data, _ := s.Get("logs/replays/match.json"); string(data) != "{}" {
+		t.Errorf("after overwrite Get = %q, want {}", data)
+	}
+}
+
+func TestFileStore_GetMissingKey(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	for _, key := range []string{"missing.json", "no/such/dir/missing.json"} {
+		if _, err := s.Get(key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrNotFound", key, err)
+		}
+	}
+}
+
+func TestFileStore_List(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	for _, key := range []string{"logs/b.json", "logs/a.json", "logs/old/c.json", "other.txt"} {
+		if err := s.Put(key, []byte("x")); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+
+	keys, err := s.List("logs/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if want := []string{"logs/a.json", "logs/b.json", "logs/old/c.json"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List(logs/) = %v, want %v", keys, want)
+	}
+	if keys, err := s.List("nowhere/"); err != nil || len(keys) != 0 {
+		t.Errorf("List(nowhere/) = %v, %v; want nothing and no error", keys, err)
+	}
+}
+
+// putGetStore hides FileStore's Append so Append falls back to Get+Put
+type putGetStore struct{ Store }
+
+func TestAppend(t *testing.T) {
+	stores := map[string]Store{
+		"native":   NewFileStore(t.TempDir()),
+		"fallback": putGetStore{NewFileStore(t.TempDir())},
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			for _, line := range []string{"one\n", "two\n"} {
+				if err := Append(s, "logs/audit.log", []byte(line)); err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+			}
+			if data, err := s.Get("logs/audit.log"); err != nil || string(data) != "one\ntwo\n" {
+				t.Errorf("Get = %q, %v; want both lines in order", data, err)
+			}
+		})
+	}
+}
+
+func TestNew_UnknownBackend(t *testing.T) {
+	if _, err := New("s3", ""); err == nil {
+		t.Error("New(s3) succeeded, want an unknown backend error")
+	}
+	if s, err := New("", "data"); err != nil || s.(*FileStore).Root != "data" {
+		t.Errorf("New(\"\") = %v, %v; want a FileStore at data", s, err)
+	}
+}
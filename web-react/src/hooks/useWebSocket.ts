@@ -154,9 +154,14 @@ export function useWebSocket() {
                         }
                         break;
 
-                    case 'brain_strategy':
+                    case 'brain_strategy': {
+                        const team = useGameStore.getState().teams[data.team];
+                        setCommentary(team ? `${team.name}: ${data.strategy}` : data.strategy || '');
+                        break;
+                    }
+
                     case 'commentary':
-                        setCommentary(data.strategy || data.commentary || '');
+                        setCommentary(data.commentary || '');
                         break;
 
                     case 'challenge_result':
@@ -189,12 +194,47 @@ export function useWebSocket() {
                     case 'game_state':
                         if (data.state?.teams) setTeams(data.state.teams);
                         break;
+
+                    case 'state_delta': {
+                        // Merge only the entities that changed since the last broadcast
+                        const current = useGameStore.getState();
+                        if (data.teams) setTeams({ ...current.teams, ...data.teams });
+                        if (data.zones) setZones({ ...current.zones, ...data.zones });
+                        if (data.gates) setGates({ ...current.gates, ...data.gates });
+                        // Positions stay client-driven; take the server-owned stats
+                        Object.entries(data.npcs || {}).forEach(([id, npc]: [string, any]) => {
+                            updateNPC(id, { hp: npc.hp, energy: npc.energy });
+                        });
+                        break;
+                    }
+
+                    case 'replay_frame': {
+                        // POST /replay/play is showing a recorded match; NPCs
+                        // jump to their recorded positions
+                        const state = data.state || {};
+                        if (state.teams) {
+                            // The leaderboard: an array sorted by score
+                            const teams = { ...useGameStore.getState().teams };
+                            state.teams.forEach((team: any) => { teams[team.id] = team; });
+                            setTeams(teams);
+                        }
+                        if (state.zones) setZones(state.zones);
+                        if (state.gates) setGates(state.gates);
+                        (state.npcs || []).forEach((npc: any) => {
+                            const [x, y] = npc.pos;
+                            updateNPC(npc.id, {
+                                x, y, targetX: x, targetY: y,
+                                hp: npc.hp, energy: npc.energy, state: npc.state
+                            });
+                        });
+                        break;
+                    }
                 }
             } catch (e) {
                 console.error('Failed to parse WebSocket message:', e);
             }
         };
-    }, [setConnected, setProviders, setTeams, setZones, setGates, handleDecision, setCommentary, addParticle, updateTeamScore]);
+    }, [setConnected, setProviders, setTeams, setZones, setGates, updateNPC, handleDecision, setCommentary, addParticle, updateTeamScore]);
 
     // Send batch decision request
     const requestBatchDecisions = useCallback(() => {
@@ -249,14 +289,20 @@ export function useWebSocket() {
         console.log(`📦 Batch request: ${observations.length} NPCs`);
     }, [npcs]);
 
-    // Request commentary
+    // Request commentary: one team's strategy per call, taking turns, so the
+    // server can fall back to that team's last good strategy if the brain fails
+    const commentaryTurn = useRef(0);
     const requestCommentary = useCallback(() => {
         if (!ws.current || ws.current.readyState !== WebSocket.OPEN) return;
 
         const teams = useGameStore.getState().teams;
+        const ids = Object.keys(teams);
+        if (ids.length === 0) return;
+        const team = ids[commentaryTurn.current++ % ids.length];
         ws.current.send(JSON.stringify({
             type: 'brain_request',
-            summary: `Team Red: ${teams.red?.score || 0} pts, Team Blue: ${teams.blue?.score || 0} pts`
+            team,
+            summary: `Advise ${teams[team].name}. Team Red: ${teams.red?.score || 0} pts, Team Blue: ${teams.blue?.score || 0} pts`
         }));
     }, []);
 
@@ -49,6 +49,7 @@ const gameState = {
     chatBubbles: [],
     feedItems: [],
     activeChallenge: null,
+    activeChallengeGateId: null, // From challenge_active; the Challenge itself has no gate_id
     ws: null,
     lastDecisionTime: {}
 };
@@ -585,6 +586,42 @@ function simulateAIDecision(npc) {
     addFeedItem(npc, npc.thought, 'explore');
 }
 
+// Merge a state_delta into the local state. Teams, zones and gates are
+// replaced whole; NPC positions stay client-driven, so NPCs only take the
+// server-owned stats.
+function applyStateDelta(data) {
+    if (data.teams) Object.assign(gameState.teams, data.teams);
+    if (data.zones) Object.assign(gameState.zones, data.zones);
+    if (data.gates) Object.assign(gameState.gates, data.gates);
+    Object.entries(data.npcs || {}).forEach(([id, update]) => {
+        const npc = gameState.npcs.find(n => n.id === id);
+        if (npc) {
+            npc.hp = update.hp;
+            npc.energy = update.energy;
+        }
+    });
+}
+
+// A replay_frame carries a whole recorded game state: NPCs jump to their
+// recorded positions instead of walking there
+function applyReplayFrame(data) {
+    const state = data.state || {};
+    // teams is the leaderboard, an array sorted by score
+    (state.teams || []).forEach(team => { gameState.teams[team.id] = team; });
+    if (state.zones) Object.assign(gameState.zones, state.zones);
+    if (state.gates) Object.assign(gameState.gates, state.gates);
+    (state.npcs || []).forEach(recorded => {
+        const npc = gameState.npcs.find(n => n.id === recorded.id);
+        if (!npc) return;
+        npc.x = npc.targetX = recorded.pos[0];
+        npc.y = npc.targetY = recorded.pos[1];
+        npc.hp = recorded.hp;
+        npc.energy = recorded.energy;
+        npc.state = recorded.state;
+    });
+    gameState.tick = data.tick;
+}
+
 function handleAIResponse(data) {
     const npc = gameState.npcs.find(n => n.id === data.npc_id);
     if (!npc) return;
@@ -732,6 +769,15 @@ function connectWebSocket() {
 
                 case 'challenge_active':
                     showChallengeModal(data.challenge);
+                    gameState.activeChallengeGateId = data.gate_id;
+                    updateChallengeTimer(data.seconds_remaining);
+                    break;
+
+                case 'challenge_countdown':
+                    if (gameState.activeChallenge && gameState.activeChallengeGateId) {
+                        const secs = data.countdowns[gameState.activeChallengeGateId];
+                        if (secs !== undefined) updateChallengeTimer(secs);
+                    }
                     break;
 
                 case 'challenge_result':
@@ -754,11 +800,42 @@ function connectWebSocket() {
                     updateUI();
                     break;
 
+                case 'state_delta':
+                    // Only the entities that changed since the last broadcast
+                    applyStateDelta(data);
+                    updateUI();
+                    break;
+
+                case 'replay_frame':
+                    // POST /replay/play is showing a recorded match
+                    applyReplayFrame(data);
+                    updateUI();
+                    break;
+
+                case 'replay_end':
+                    addFeedItem(
+                        { name: 'Replay', team: 'red', color: '#a855f7' },
+                        `🎞️ Replay finished (${data.frames} frames)`,
+                        'zone'
+                    );
+                    break;
+
                 case 'commentary':
                     // Live commentary from LLM
                     updateCommentary(data.commentary);
                     break;
 
+                case 'commentary_chunk':
+                    // Commentary streaming in as the LLM writes it
+                    appendCommentary(data.text);
+                    break;
+
+                case 'commentary_done':
+                    // The whole commentary, cleaned up
+                    commentaryStreaming = false;
+                    updateCommentary(data.commentary);
+                    break;
+
                 case 'zone_generated':
                     // New zone was generated by Gemini
                     if (data.zones) {
@@ -853,6 +930,27 @@ function updateCommentary(text) {
     container.innerHTML = `"${text}"`;
 }
 
+// The first chunk of a streamed commentary replaces the last one
+let commentaryStreaming = false;
+
+function appendCommentary(chunk) {
+    const container = document.getElementById('commentary');
+    if (!commentaryStreaming) {
+        commentaryStreaming = true;
+        container.textContent = '';
+    }
+    container.textContent += chunk;
+}
+
+// Show the seconds left on the open challenge next to its status
+function updateChallengeTimer(seconds) {
+    const status = document.getElementById('challenge-status');
+    if (!status || seconds === undefined) return;
+    const base = status.dataset.base || status.textContent;
+    status.dataset.base = base;
+    status.textContent = `${base} · ⏳ ${seconds}s left`;
+}
+
 function showChallengeModal(challenge) {
     const modal = document.getElementById('challenge-modal');
     const title = document.getElementById('challenge-title');
@@ -886,6 +984,7 @@ function showChallengeModal(challenge) {
 
     // Status
     status.textContent = challenge.requires_teamwork ? '👥 Requires both teammates!' : '🎯 Solo challenge';
+    delete status.dataset.base;
 
     modal.classList.remove('hidden');
     gameState.activeChallenge = challenge;
@@ -985,6 +1084,7 @@ function showChallengeResult(success, feedback, points) {
 function hideChallengeModal() {
     document.getElementById('challenge-modal').classList.add('hidden');
     gameState.activeChallenge = null;
+    gameState.activeChallengeGateId = null;
 }
 
 // Handle NPC challenge without modal (runs in background)
@@ -1171,6 +1271,9 @@ document.getElementById('btn-pause').addEventListener('click', () => {
 
 document.getElementById('btn-reset').addEventListener('click', () => {
     gameState.running = false;
+    if (gameState.ws && gameState.ws.readyState === WebSocket.OPEN) {
+        gameState.ws.send(JSON.stringify({ type: 'reset' }));
+    }
     gameState.tick = 0;
     gameState.chatBubbles = [];
     document.getElementById('live-feed').innerHTML = '';